@@ -0,0 +1,286 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/cache"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/catalog"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/config"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/duckdb"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/lakehouse"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/logging"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/manifest"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/security"
+	"github.com/spf13/cobra"
+)
+
+func NewCacheCmd(cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the local cache of data products",
+		Long: `Pull data products into encrypted local DuckDB databases for offline
+analysis. dmesh query uses a pulled product instead of S3 while it is
+younger than cache_ttl (default 1h) and holds all rows of the current
+snapshot, or with --cached; it is deleted once it is older than
+cache_retention (default 7d).`,
+	}
+
+	cmd.AddCommand(NewCachePullCmd(cfg, secCtx, log))
+	cmd.AddCommand(NewCacheLsCmd(cfg, secCtx, log))
+	cmd.AddCommand(NewCachePruneCmd(cfg, secCtx, log))
+
+	return cmd
+}
+
+func NewCachePullCmd(cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) *cobra.Command {
+	var where string
+	var snapshot string
+
+	cmd := &cobra.Command{
+		Use:   "pull [data_product]",
+		Short: "Pull a data product into the local cache",
+		Long: `Materialize a data product, or the rows matching --where, into an
+encrypted DuckDB database in the local cache. Use --snapshot to pull an
+Iceberg snapshot or Delta version, given like --as-of of dmesh query.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return pullToCache(args[0], where, snapshot, cfg, secCtx, log)
+		},
+	}
+
+	cmd.Flags().StringVar(&where, "where", "", "Only pull rows matching a SQL condition")
+	cmd.Flags().StringVar(&snapshot, "snapshot", "", "Snapshot ID, Delta version or timestamp to pull")
+
+	return cmd
+}
+
+func NewCacheLsCmd(cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) *cobra.Command {
+	return &cobra.Command{
+		Use:   "ls",
+		Short: "List cached data products",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listCache(cfg, secCtx, log)
+		},
+	}
+}
+
+func NewCachePruneCmd(cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) *cobra.Command {
+	var stale bool
+	var all bool
+
+	cmd := &cobra.Command{
+		Use:   "prune [data_product...]",
+		Short: "Delete cached data products",
+		Long: `Delete cached products past retention, and with --stale also those past
+their TTL. Named products, or all with --all, are deleted regardless of age.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return pruneCache(args, stale, all, cfg, secCtx, log)
+		},
+	}
+
+	cmd.Flags().BoolVar(&stale, "stale", false, "Also delete products past their TTL")
+	cmd.Flags().BoolVar(&all, "all", false, "Delete all cached products")
+
+	return cmd
+}
+
+func pullToCache(dataProduct, where, snapshot string, cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) error {
+	c, err := cache.New(cfg, secCtx, log)
+	if err != nil {
+		return err
+	}
+
+	// Check for data product access
+	canAccess, err := secCtx.CanAccessDataProduct(dataProduct)
+	if err != nil {
+		return fmt.Errorf("failed to check access: %w", err)
+	}
+	if !canAccess {
+		return fmt.Errorf("access denied to data product: %s", dataProduct)
+	}
+
+	// Look up format and location in the catalog
+	catalogClient, err := catalog.NewClient(cfg, secCtx, log)
+	if err != nil {
+		return err
+	}
+	product, err := catalogClient.GetDataProduct(dataProduct)
+	if err != nil {
+		return err
+	}
+
+	// Initialize DuckDB connection
+	db, err := duckdb.NewConnection(cfg, secCtx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	entry := &cache.Entry{
+		Product:  dataProduct,
+		Format:   product.Format,
+		Location: product.Location,
+		Where:    where,
+		PulledAt: time.Now().UTC(),
+		// Recorded for reference; queries of the cache are marked with
+		// the classification the catalog has when they run
+		Classification: product.Classification,
+	}
+
+	lakehouseClient := lakehouse.NewClient(cfg, secCtx, log)
+	if snapshot != "" {
+		at, err := lakehouse.ParseAsOf(snapshot)
+		if err != nil {
+			return err
+		}
+		version, err := lakehouseClient.TableVersion(product.Format, product.Location, at)
+		if err != nil {
+			return err
+		}
+		if err := db.RegisterDataProductAsOf(dataProduct, version); err != nil {
+			return err
+		}
+		entry.SnapshotID = &version.Snapshot.ID
+	} else {
+		if err := db.RegisterDataProduct(dataProduct, product.Location); err != nil {
+			return err
+		}
+		// Record the snapshot being pulled when the table has one
+		if history, err := lakehouseClient.History(product.Format, product.Location); err != nil {
+			log.Debugf("Snapshot of %s unknown: %v", dataProduct, err)
+		} else if current := history.Current(); current != nil {
+			entry.SnapshotID = &current.ID
+		}
+	}
+
+	query := "SELECT * FROM " + duckdb.QuoteName(dataProduct)
+	if where != "" {
+		query += " WHERE " + where
+	}
+
+	// Materialize into a plaintext database in the private temporary
+	// directory, then encrypt it into the cache
+	tmp, cleanup, err := c.TempFile()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if entry.Rows, err = db.CopyToDatabase(tmp, cache.Table, query); err != nil {
+		return err
+	}
+	if err := c.Store(entry, tmp); err != nil {
+		return err
+	}
+
+	stale, _ := c.Expires(entry)
+	fmt.Printf("Cached %d rows of %s (%s encrypted); used by queries until %s\n",
+		entry.Rows, dataProduct, formatBytes(entry.Size), stale.Local().Format("2006-01-02 15:04"))
+	return nil
+}
+
+// useCache registers the cached copy of a data product and returns a
+// function that removes the decrypted copy after the query
+func useCache(db *duckdb.Connection, c *cache.Cache, entry *cache.Entry) (func(), error) {
+	path, cleanup, err := c.Open(entry)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.RegisterDatabaseTable(entry.Product, path, cache.Table); err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	// Say where the data came from, without mixing it into the results
+	note := fmt.Sprintf("Using local cache of %s pulled %s ago", entry.Product,
+		manifest.FormatDuration(time.Since(entry.PulledAt).Truncate(time.Second)))
+	if entry.Where != "" {
+		note += fmt.Sprintf(", rows where %s", entry.Where)
+	}
+	fmt.Fprintln(os.Stderr, note+" (--no-cache to query S3)")
+	return cleanup, nil
+}
+
+func listCache(cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) error {
+	c, err := cache.New(cfg, secCtx, log)
+	if err != nil {
+		return err
+	}
+	entries, err := c.List()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("No cached data products")
+		return nil
+	}
+
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PRODUCT\tSNAPSHOT\tFILTER\tROWS\tSIZE\tPULLED\tSTATE\tDELETED AFTER")
+	for _, e := range entries {
+		snapshot := "-"
+		if e.SnapshotID != nil {
+			snapshot = strconv.FormatInt(*e.SnapshotID, 10)
+		}
+		filter := e.Where
+		if filter == "" {
+			filter = "-"
+		}
+		_, expires := c.Expires(e)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\n", e.Product, snapshot, filter, e.Rows, formatBytes(e.Size),
+			e.PulledAt.Local().Format("2006-01-02 15:04"), c.State(e, now), expires.Local().Format("2006-01-02 15:04"))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nCache directory: %s\n", c.Dir())
+	return nil
+}
+
+func pruneCache(products []string, stale, all bool, cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) error {
+	c, err := cache.New(cfg, secCtx, log)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	removed, err := c.Prune(func(e *cache.Entry) bool {
+		for _, p := range products {
+			if e.Product == p {
+				return true
+			}
+		}
+		return all || (stale && c.State(e, now) == cache.Stale)
+	})
+	for _, e := range removed {
+		fmt.Printf("Deleted %s (pulled %s)\n", e.Product, e.PulledAt.Local().Format("2006-01-02 15:04"))
+	}
+	if err != nil {
+		return err
+	}
+	if len(removed) == 0 {
+		fmt.Println("Nothing to prune")
+	}
+	return nil
+}
+
+// formatBytes renders a size such as 12.3 MiB
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/config"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/logging"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/securefile"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/security"
+	"github.com/spf13/cobra"
+)
+
+func NewDecryptCmd(cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) *cobra.Command {
+	var outputFile string
+
+	cmd := &cobra.Command{
+		Use:   "decrypt [file]",
+		Short: "Decrypt a file written by dmesh",
+		Long: `Decrypt an export, report, cached database or log file written by dmesh
+and print it, or write it to --output with mode 0600.
+
+Files are encrypted with the key set by encryption_key: a key in the OS
+keyring (keyring, the default), a KMS data key (kms, with kms_key_id) or
+a key file in ~/.fedramp-data-mesh/keys (file). Only users who can read
+that key, or call kms:Decrypt on the KMS key, can decrypt the files.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return decryptFile(args[0], outputFile, cfg, secCtx, log)
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Write the plaintext to a file instead of stdout")
+
+	return cmd
+}
+
+func decryptFile(path, outputFile string, cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) error {
+	store, err := securefile.NewStore(cfg, secCtx)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if outputFile != "" {
+		if same, _ := sameFile(path, outputFile); same {
+			return fmt.Errorf("output file must differ from the encrypted file")
+		}
+		f, err := os.OpenFile(outputFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer f.Close()
+		if err := f.Chmod(0600); err != nil {
+			return err
+		}
+		w = f
+	}
+
+	if err := store.Decrypt(w, path); err != nil {
+		if outputFile != "" {
+			os.Remove(outputFile)
+		}
+		if errors.Is(err, securefile.ErrNotEncrypted) {
+			return fmt.Errorf("%s is not encrypted by dmesh", path)
+		}
+		return fmt.Errorf("failed to decrypt %s: %w", path, err)
+	}
+
+	// Record what was read; the log is encrypted too
+	target := "stdout"
+	if outputFile != "" {
+		target = outputFile
+	}
+	if role := secCtx.GetCurrentRole(); role != "" {
+		target += " as " + role
+	}
+	log.Infof("Decrypted %s to %s", path, target)
+	return nil
+}
+
+// sameFile reports whether two paths name the same existing file
+func sameFile(a, b string) (bool, error) {
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	infoB, err := os.Stat(b)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return filepath.Clean(a) == filepath.Clean(b), nil
+		}
+		return false, err
+	}
+	return os.SameFile(infoA, infoB), nil
+}
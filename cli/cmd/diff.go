@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/catalog"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/config"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/diff"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/duckdb"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/lakehouse"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/logging"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/marking"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/securefile"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/security"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+type diffOptions struct {
+	from    string
+	to      string
+	key     []string
+	format  string
+	samples int
+	export  string
+}
+
+func NewDiffCmd(cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) *cobra.Command {
+	var opts diffOptions
+
+	cmd := &cobra.Command{
+		Use:   "diff [data_product]",
+		Short: "Compare two snapshots of a data product",
+		Long: `Compare a data product at two Iceberg snapshots or Delta versions, given
+as snapshot IDs, versions or timestamps like --as-of of dmesh query. Rows
+are matched on --key and reported as inserted, deleted or updated, with
+the columns that changed.
+
+Use --export to write the changed rows with the values of both snapshots
+to an encrypted CSV or Parquet file, marked with the product's
+classification; dmesh decrypt reads it.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDiff(args[0], opts, cfg, secCtx, log)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.from, "from", "", "Snapshot ID, Delta version or timestamp to compare from")
+	cmd.Flags().StringVar(&opts.to, "to", "", "Snapshot ID, Delta version or timestamp to compare to (default: current)")
+	cmd.Flags().StringSliceVar(&opts.key, "key", nil, "Key columns that identify rows")
+	cmd.Flags().StringVarP(&opts.format, "format", "f", "text", "Output format (text, json)")
+	cmd.Flags().IntVar(&opts.samples, "samples", 10, "Changed rows to show")
+	cmd.Flags().StringVar(&opts.export, "export", "", "Export changed rows to a .csv or .parquet file")
+	cmd.MarkFlagRequired("from")
+	cmd.MarkFlagRequired("key")
+
+	return cmd
+}
+
+func runDiff(dataProduct string, opts diffOptions, cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) error {
+	if opts.format != "text" && opts.format != "json" {
+		return fmt.Errorf("unsupported output format: %s", opts.format)
+	}
+	exportFormat := ""
+	if opts.export != "" {
+		exportFormat = strings.TrimPrefix(strings.ToLower(filepath.Ext(opts.export)), ".")
+		if exportFormat != "csv" && exportFormat != "parquet" {
+			return fmt.Errorf("export file must end in .csv or .parquet: %s", opts.export)
+		}
+	}
+
+	from, err := lakehouse.ParseAsOf(opts.from)
+	if err != nil {
+		return err
+	}
+	var to lakehouse.AsOf
+	if opts.to != "" {
+		if to, err = lakehouse.ParseAsOf(opts.to); err != nil {
+			return err
+		}
+	}
+
+	// Check for data product access
+	canAccess, err := secCtx.CanAccessDataProduct(dataProduct)
+	if err != nil {
+		return fmt.Errorf("failed to check access: %w", err)
+	}
+	if !canAccess {
+		return fmt.Errorf("access denied to data product: %s", dataProduct)
+	}
+
+	// Look up format and location in the catalog
+	catalogClient, err := catalog.NewClient(cfg, secCtx, log)
+	if err != nil {
+		return err
+	}
+	product, err := catalogClient.GetDataProduct(dataProduct)
+	if err != nil {
+		return err
+	}
+
+	// Find both snapshots in the table metadata
+	lakehouseClient := lakehouse.NewClient(cfg, secCtx, log)
+	fromVersion, err := lakehouseClient.TableVersion(product.Format, product.Location, from)
+	if err != nil {
+		return err
+	}
+	toVersion, err := lakehouseClient.TableVersion(product.Format, product.Location, to)
+	if err != nil {
+		return err
+	}
+
+	// Initialize DuckDB connection
+	db, err := duckdb.NewConnection(cfg, secCtx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	fromView := fmt.Sprintf("snapshot_%d", fromVersion.Snapshot.ID)
+	toView := fmt.Sprintf("snapshot_%d", toVersion.Snapshot.ID)
+	if err := db.RegisterDataProductAsOf(fromView, fromVersion); err != nil {
+		return err
+	}
+	if toView != fromView {
+		if err := db.RegisterDataProductAsOf(toView, toVersion); err != nil {
+			return err
+		}
+	}
+
+	result, err := diff.Compare(db, fromView, toView, opts.key, diff.Options{Samples: opts.samples})
+	if err != nil {
+		return err
+	}
+	if err := diff.WriteReport(os.Stdout, result, opts.format); err != nil {
+		return err
+	}
+
+	if opts.export != "" {
+		mark := marking.New(map[string]string{dataProduct: product.Classification})
+		if err := exportDiff(db, opts, exportFormat, mark, cfg, secCtx); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Exported %d changed rows to %s, encrypted (dmesh decrypt %s to read it)\n",
+			result.Inserted+result.Deleted+result.Updated, opts.export, opts.export)
+	}
+	return nil
+}
+
+// exportDiff has DuckDB write the changed rows to a private temporary
+// directory, marks the file and encrypts it to the export path
+func exportDiff(db *duckdb.Connection, opts diffOptions, format string, mark marking.Marking, cfg *config.Config, secCtx *security.SecurityContext) error {
+	store, err := securefile.NewStore(cfg, secCtx)
+	if err != nil {
+		return err
+	}
+	dir, cleanup, err := store.TempDir()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	plain := filepath.Join(dir, "changes."+format)
+	if err := diff.Export(db, opts.key, plain, format); err != nil {
+		return err
+	}
+	if err := ui.MarkExport(plain, format, mark); err != nil {
+		return err
+	}
+	if err := store.EncryptFile(opts.export, plain); err != nil {
+		return fmt.Errorf("failed to encrypt export: %w", err)
+	}
+	return nil
+}
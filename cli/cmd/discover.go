@@ -19,7 +19,12 @@ func NewDiscoverCmd(cfg *config.Config, secCtx *security.SecurityContext, log *l
 	cmd := &cobra.Command{
 		Use:   "discover",
 		Short: "Discover available data products",
-		Long:  `Browse and discover available data products in the data mesh`,
+		Long: `Browse and discover available data products in the data mesh.
+
+With --interactive, / searches names, descriptions, owners, tags and column
+names, and f filters by domain, format, classification, tags and owner.
+Enter shows the details of a product: its overview, schema, sample rows,
+profile, lineage and SLA. Q opens the query TUI for the selected product.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if interactive {
 				return launchDiscoverUI(cfg, secCtx, log, domainFilter)
@@ -62,8 +67,21 @@ func listDataProducts(cfg *config.Config, secCtx *security.SecurityContext, log
 }
 
 func launchDiscoverUI(cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger, domainFilter string) error {
+	values, err := ui.NewValueFormat(cfg.NullDisplay, cfg.TimestampFormat, cfg.TimeZone, cfg.DecimalPlaces)
+	if err != nil {
+		return err
+	}
+
 	model := ui.NewDiscoverModel(cfg, secCtx, log, domainFilter)
+	model.SetValueFormat(values)
 	p := tea.NewProgram(model, tea.WithAltScreen())
-	_, err := p.Run()
-	return err
+	if _, err := p.Run(); err != nil {
+		return err
+	}
+
+	// Q in the TUI opens the query TUI for the selected product
+	if product := model.QueryProduct(); product != "" {
+		return launchQueryUI(queryOptions{dataProduct: product, values: values}, cfg, secCtx, log)
+	}
+	return nil
 }
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/catalog"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/config"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/duckdb"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/logging"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/profile"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/security"
+	"github.com/spf13/cobra"
+)
+
+func NewProfileCmd(cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) *cobra.Command {
+	var sample string
+	var format string
+	var opts profile.Options
+
+	cmd := &cobra.Command{
+		Use:   "profile [data_product]",
+		Short: "Profile the columns of a data product",
+		Long: `Compute column statistics of a data product with DuckDB: SUMMARIZE plus
+null ratio, exact distinct count, min/max, the most frequent values, a
+histogram of numeric columns and the length distribution of strings.
+
+Use --sample to profile a share of the rows (1%) or a number of rows (10000).`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Sample = sample
+			return runProfile(args[0], opts, format, cfg, secCtx, log)
+		},
+	}
+
+	cmd.Flags().StringVar(&sample, "sample", "", "Profile a sample, as a percentage (1%) or row count (10000)")
+	cmd.Flags().StringVarP(&format, "format", "f", "table", "Output format (table, json, markdown)")
+	cmd.Flags().IntVar(&opts.TopK, "top", profile.DefaultTopK, "Most frequent values to show per column")
+	cmd.Flags().IntVar(&opts.Bins, "bins", profile.DefaultBins, "Histogram bins")
+
+	return cmd
+}
+
+func runProfile(dataProduct string, opts profile.Options, format string, cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) error {
+	switch format {
+	case "table", "json", "markdown":
+	default:
+		return fmt.Errorf("unsupported output format: %s", format)
+	}
+	if opts.Sample != "" {
+		if _, err := profile.SampleClause(opts.Sample); err != nil {
+			return err
+		}
+	}
+
+	p, err := profileDataProduct(dataProduct, opts, cfg, secCtx, log)
+	if err != nil {
+		return err
+	}
+	return profile.WriteReport(os.Stdout, p, format)
+}
+
+// profileDataProduct profiles a data product of the catalog after checking
+// access to it
+func profileDataProduct(dataProduct string, opts profile.Options, cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) (*profile.Profile, error) {
+	// Check for data product access
+	canAccess, err := secCtx.CanAccessDataProduct(dataProduct)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check access: %w", err)
+	}
+	if !canAccess {
+		return nil, fmt.Errorf("access denied to data product: %s", dataProduct)
+	}
+
+	// Look up format and location in the catalog
+	catalogClient, err := catalog.NewClient(cfg, secCtx, log)
+	if err != nil {
+		return nil, err
+	}
+	product, err := catalogClient.GetDataProduct(dataProduct)
+	if err != nil {
+		return nil, err
+	}
+
+	// Initialize DuckDB connection
+	db, err := duckdb.NewConnection(cfg, secCtx)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if err := db.RegisterTable(dataProduct, product.Format, product.Location); err != nil {
+		return nil, err
+	}
+
+	log.Debugf("Profiling %s (%s at %s)", dataProduct, product.Format, product.Location)
+	return profile.Table(db, dataProduct, opts)
+}
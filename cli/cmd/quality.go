@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/config"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/duckdb"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/lakehouse"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/logging"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/manifest"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/quality"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/securefile"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/security"
+	"github.com/spf13/cobra"
+)
+
+type qualityRunOptions struct {
+	file       string
+	format     string
+	outputFile string
+	samples    int
+}
+
+func NewQualityCmd(cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "quality",
+		Short: "Check data products against quality expectations",
+		Long:  `Run the declarative data quality expectations kept next to data product manifests`,
+	}
+
+	cmd.AddCommand(NewQualityRunCmd(cfg, secCtx, log))
+
+	return cmd
+}
+
+func NewQualityRunCmd(cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) *cobra.Command {
+	var opts qualityRunOptions
+
+	cmd := &cobra.Command{
+		Use:   "run [data_product]",
+		Short: "Run a data product's quality expectations",
+		Long: `Evaluate the expectations in the file next to the product manifest
+(<manifest>.quality.yaml) as DuckDB queries over the product's tables:
+not_null, unique, accepted_values (defaulting to the symbols of the column's
+Avro enum), range, references (referential integrity, also across products)
+and row_count_delta (against the previous Iceberg snapshot).
+
+The command exits with a non-zero status when an expectation with error
+severity fails or cannot be evaluated.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := runQuality(args[0], opts, cfg, secCtx, log)
+			if err == errQualityFailures {
+				// Failures are already in the report; don't print usage
+				cmd.SilenceUsage = true
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.file, "file", "", "Expectations file (default: <manifest>.quality.yaml)")
+	cmd.Flags().StringVarP(&opts.format, "format", "f", "text", "Report format (text, json, junit)")
+	cmd.Flags().StringVarP(&opts.outputFile, "output", "o", "", "Write the report to an encrypted file")
+	cmd.Flags().IntVar(&opts.samples, "samples", 5, "Failing rows to include per expectation")
+
+	return cmd
+}
+
+var errQualityFailures = fmt.Errorf("data quality expectations failed")
+
+func runQuality(dataProduct string, opts qualityRunOptions, cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) error {
+	switch opts.format {
+	case "text", "json", "junit":
+	default:
+		return fmt.Errorf("unsupported output format: %s", opts.format)
+	}
+
+	products, err := manifest.Discover(cfg.ManifestDir)
+	if err != nil {
+		return err
+	}
+	product := manifest.Lookup(products, dataProduct)
+	if product == nil {
+		return fmt.Errorf("no data product manifest found for %s under %s", dataProduct, cfg.ManifestDir)
+	}
+
+	// Load expectations
+	file := opts.file
+	if file == "" {
+		file = quality.SuitePath(product)
+	}
+	suite, err := quality.LoadSuite(file)
+	if err != nil {
+		return err
+	}
+	log.Debugf("Running %d expectations from %s", len(suite.Expectations), suite.File)
+
+	// Initialize DuckDB connection
+	db, err := duckdb.NewConnection(cfg, secCtx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	runner := &quality.Runner{
+		DB:        db,
+		Lakehouse: lakehouse.NewClient(cfg, secCtx, log),
+		Products:  products,
+		Samples:   opts.samples,
+		Authorize: func(p *manifest.DataProduct) error {
+			canAccess, err := secCtx.CanAccessDataProduct(p.ID())
+			if err != nil {
+				return fmt.Errorf("failed to check access: %w", err)
+			}
+			if !canAccess {
+				return fmt.Errorf("access denied to data product: %s", p.ID())
+			}
+			return nil
+		},
+	}
+	report := runner.Run(product, suite)
+
+	// Reports hold sample rows, so report files are encrypted
+	var w io.Writer = os.Stdout
+	var reportFile io.WriteCloser
+	if opts.outputFile != "" {
+		store, err := securefile.NewStore(cfg, secCtx)
+		if err != nil {
+			return err
+		}
+		if reportFile, err = store.Create(opts.outputFile); err != nil {
+			return fmt.Errorf("failed to create report file: %w", err)
+		}
+		defer reportFile.Close()
+		w = reportFile
+	}
+
+	if err := quality.WriteReport(w, report, opts.format); err != nil {
+		return err
+	}
+	if reportFile != nil {
+		if err := reportFile.Close(); err != nil {
+			return fmt.Errorf("failed to write report file: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Wrote encrypted report to %s (dmesh decrypt %s to read it)\n", opts.outputFile, opts.outputFile)
+	}
+
+	if report.HasFailures() {
+		return errQualityFailures
+	}
+	return nil
+}
@@ -1,43 +1,196 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+	"time"
+	
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/cache"
 	"github.com/frocore/fedramp-data-mesh/cli/internal/catalog"
 	"github.com/frocore/fedramp-data-mesh/cli/internal/config"
 	"github.com/frocore/fedramp-data-mesh/cli/internal/duckdb"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/lakehouse"
 	"github.com/frocore/fedramp-data-mesh/cli/internal/logging"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/marking"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/securefile"
 	"github.com/frocore/fedramp-data-mesh/cli/internal/security"
 	"github.com/frocore/fedramp-data-mesh/cli/internal/ui"
 	"github.com/spf13/cobra"
 )
 
+type queryOptions struct {
+	dataProduct  string
+	outputFormat string
+	asOf         string
+	noCache      bool
+	cached       bool
+	outFile      string
+	
+	// classification raises the marking of the results above the
+	// product's classification
+	classification string
+	
+	// Rendering of values, defaulting to the configuration; values is
+	// the format derived from them
+	nullDisplay     string
+	timestampFormat string
+	timeZone        string
+	decimals        int
+	values          ui.ValueFormat
+	
+	// expanded prints table rows as lines of column names and values
+	expanded bool
+}
+
 func NewQueryCmd(cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) *cobra.Command {
-	var dataProduct string
-	var outputFormat string
+	var opts queryOptions
 	
 	cmd := &cobra.Command{
 		Use:   "query",
 		Short: "Query data products using DuckDB",
-		Long:  `Execute SQL queries against data products using DuckDB`,
+		Long:  `Execute SQL queries against data products using DuckDB.
+
+Use --as-of to query an Iceberg or Delta data product as it was at a
+snapshot ID, Delta version or timestamp; list them with dmesh snapshots.
+
+A data product pulled with dmesh cache pull is queried locally while the
+cached copy is fresh and holds all rows of the current snapshot, unless
+--no-cache is given. --cached uses a copy pulled with --where or at an
+older snapshot too. Results are marked with the classification the
+catalog has now, not the one at the pull.
+
+Results are marked with the data product's security classification: a
+banner above and below tables, comment lines in CSV and the
+classification field of JSON output. --classification marks them higher;
+they cannot be marked lower than the product. Results of a product
+without a classification are marked CONFIDENTIAL unless --classification
+gives one.
+
+Only the data products given with -p or registered in the editor count
+towards the marking, not files a query reads itself, such as with
+read_parquet('s3://...'); mark such results with --classification.
+
+Use --out-file to write the results to an encrypted file instead; dmesh
+decrypt reads it. Parquet, Arrow and XLSX output keep the column types,
+such as decimals, timestamps and nested structs and lists, and can only be
+written to a file.
+
+On a terminal, tables are truncated to its width and output longer than
+the screen is shown through $PAGER. Use --expanded to print each row as
+lines of column names and values instead.
+
+Without a query, an editor opens: Ctrl+Enter or F5 runs the statement at
+the cursor and Alt+Enter all of them. Names of tables, the columns of
+those the query reads, functions and keywords are completed as you type
+or with Ctrl+Space. Queries are kept, encrypted, in a history of
+query_history_size entries searched with Ctrl+R.
+
+The editor opens at once while DuckDB, the catalog and the history load
+in the background; steps that fail are shown with their errors and
+Ctrl+T tries them again.
+
+Ctrl+O opens a panel of the catalog's data products, where Space
+registers or unregisters one in the session, Enter shows its schema and
+i inserts the name under the cursor into the editor. Results are marked
+for every product registered when they were queried.
+
+Results are shown in a grid: Enter inspects the cell under the cursor, s
+sorts the rows by its column and S sorts in the query instead, - hides a
+column, + shows hidden ones and < and > move one. v selects a block,
+which y copies, or the cell under the cursor; Y copies whole rows. Only
+results marked up to clipboard_classification are copied, between
+banners; unmarked results, of no registered product, are not.
+
+Ctrl+S exports the results shown, or those of running the query again,
+to an encrypted file in any output format, marked and recorded in the
+audit log like --out-file.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if ui.IsBinaryFormat(opts.outputFormat) && opts.outFile == "" {
+				return fmt.Errorf("%s output must be written to a file with --out-file", opts.outputFormat)
+			}
+			values, err := ui.NewValueFormat(opts.nullDisplay, opts.timestampFormat, opts.timeZone, opts.decimals)
+			if err != nil {
+				return err
+			}
+			opts.values = values
+			
 			if len(args) > 0 {
 				// Direct query from command line
 				query := args[0]
-				return executeQuery(query, dataProduct, outputFormat, cfg, secCtx, log)
+				return executeQuery(query, opts, cfg, secCtx, log)
 			} else {
+				if opts.asOf != "" {
+					return fmt.Errorf("--as-of requires a query argument")
+				}
+				if opts.outFile != "" {
+					return fmt.Errorf("--out-file requires a query argument")
+				}
+				
 				// Launch interactive UI
-				return launchQueryUI(dataProduct, cfg, secCtx, log)
+				return launchQueryUI(opts, cfg, secCtx, log)
 			}
 		},
 	}
 	
-	cmd.Flags().StringVarP(&dataProduct, "product", "p", "", "Data product to query")
-	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, csv, json)")
+	cmd.Flags().StringVarP(&opts.dataProduct, "product", "p", "", "Data product to query")
+	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "table", "Output format (table, csv, json, ndjson, markdown, html, parquet, arrow, xlsx)")
+	cmd.Flags().StringVar(&opts.outFile, "out-file", "", "Write the results to an encrypted file")
+	cmd.Flags().StringVar(&opts.asOf, "as-of", "", "Query the data product as of a snapshot ID, Delta version or timestamp")
+	cmd.Flags().BoolVar(&opts.noCache, "no-cache", false, "Query S3 even when the data product is cached locally")
+	cmd.Flags().BoolVar(&opts.cached, "cached", false, "Query the local cache of the data product even when it holds a subset or an older snapshot")
+	cmd.Flags().StringVar(&opts.classification, "classification", "", "Mark results with a higher classification than the data product's")
+	cmd.Flags().StringVar(&opts.nullDisplay, "null", cfg.NullDisplay, "Text shown for NULL values in tables; CSV leaves them empty")
+	cmd.Flags().StringVar(&opts.timestampFormat, "timestamp-format", cfg.TimestampFormat, "Go time layout of timestamps, or rfc3339")
+	cmd.Flags().StringVar(&opts.timeZone, "time-zone", cfg.TimeZone, "Time zone timestamps are shown in: UTC, Local or a name like America/New_York")
+	cmd.Flags().IntVar(&opts.decimals, "decimals", cfg.DecimalPlaces, "Digits shown after the decimal point, -1 for all")
+	cmd.Flags().BoolVarP(&opts.expanded, "expanded", "x", false, "Print each row of a table as lines of column names and values")
 	
 	return cmd
 }
 
-func executeQuery(query, dataProduct, outputFormat string, cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) error {
+func executeQuery(query string, opts queryOptions, cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) error {
+	dataProduct := opts.dataProduct
+	if opts.cached && (dataProduct == "" || opts.noCache || opts.asOf != "") {
+		return fmt.Errorf("--cached requires --product and cannot be combined with --no-cache or --as-of")
+	}
+	
+	// Look up the data product in the catalog
+	product, err := resolveDataProduct(dataProduct, cfg, secCtx, log)
+	if err != nil {
+		return err
+	}
+	
+	// Results carry the product's classification, also when read from the
+	// cache: it may have been raised since the pull
+	mark, err := marking.New(map[string]string{dataProduct: product.Classification}).Raise(opts.classification)
+	if err != nil {
+		return err
+	}
+	if product.Classification == "" && opts.classification == "" {
+		fmt.Fprintf(os.Stderr, "%s has no classification; results are marked %s (--classification to mark them otherwise)\n",
+			dataProduct, mark.Banner())
+	}
+	
+	// Use the local cache of the data product when it is fresh and holds
+	// what S3 would return
+	if dataProduct != "" && opts.asOf == "" && !opts.noCache {
+		c, err := cache.New(cfg, secCtx, log)
+		if err != nil {
+			return err
+		}
+		entry, err := c.Lookup(dataProduct)
+		if err != nil {
+			return err
+		}
+		if entry != nil && (opts.cached || cacheCurrent(entry, product, cfg, secCtx, log)) {
+			return executeCachedQuery(query, opts, c, entry, mark, cfg, secCtx, log)
+		}
+		if entry == nil && opts.cached {
+			return fmt.Errorf("%s is not cached; pull it with dmesh cache pull %s", dataProduct, dataProduct)
+		}
+	}
+	
 	// Initialize DuckDB connection
 	db, err := duckdb.NewConnection(cfg, secCtx)
 	if err != nil {
@@ -45,30 +198,98 @@ func executeQuery(query, dataProduct, outputFormat string, cfg *config.Config, s
 	}
 	defer db.Close()
 	
-	// Resolve full path for data product
-	productPath, err := resolveDataProductPath(dataProduct, cfg, secCtx, log)
+	if opts.asOf != "" {
+		// Register data product at a snapshot
+		if err := registerDataProductAsOf(db, product, opts.asOf, cfg, secCtx, log); err != nil {
+			return err
+		}
+	} else {
+		// Register data product in DuckDB
+		if err := db.RegisterDataProduct(dataProduct, product.Location); err != nil {
+			return err
+		}
+	}
+	
+	// Execute query and output results
+	return outputResults(db, query, dataProduct, opts, mark, cfg, secCtx, log)
+}
+
+// cacheCurrent reports whether a cache entry holds all rows of the
+// product's current snapshot, and says why it is not used otherwise
+func cacheCurrent(entry *cache.Entry, product *catalog.DataProduct, cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) bool {
+	var current *int64
+	if history, err := lakehouse.NewClient(cfg, secCtx, log).History(product.Format, product.Location); err != nil {
+		log.Debugf("Snapshot of %s unknown: %v", entry.Product, err)
+	} else if snapshot := history.Current(); snapshot != nil {
+		current = &snapshot.ID
+	}
+	if entry.Complete(current) {
+		return true
+	}
+	
+	reason := "it may not hold the current snapshot"
+	if entry.Where != "" {
+		reason = "it only holds rows where " + entry.Where
+	}
+	fmt.Fprintf(os.Stderr, "Not using the local cache of %s: %s (--cached to use it)\n", entry.Product, reason)
+	return false
+}
+
+func executeCachedQuery(query string, opts queryOptions, c *cache.Cache, entry *cache.Entry, mark marking.Marking, cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) error {
+	// The cache is local; no S3 access needed
+	db, err := duckdb.NewLocalConnection(cfg, secCtx)
 	if err != nil {
 		return err
 	}
 	
-	// Register data product in DuckDB
-	if err := db.RegisterDataProduct(dataProduct, productPath); err != nil {
+	cleanup, err := useCache(db, c, entry)
+	if err != nil {
+		db.Close()
 		return err
 	}
 	
-	// Execute query
-	result, err := db.ExecuteQuery(query)
+	// Close the database before removing the decrypted copy
+	defer cleanup()
+	defer db.Close()
+	
+	return outputResults(db, query, entry.Product, opts, mark, cfg, secCtx, log)
+}
+
+// outputResults runs a query and displays the results, or writes them to
+// an encrypted --out-file, which is recorded in the audit log
+func outputResults(db *duckdb.Connection, query, dataProduct string, opts queryOptions, mark marking.Marking, cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) error {
+	if opts.outFile == "" {
+		result, err := db.ExecuteQuery(query)
+		if err != nil {
+			return err
+		}
+		
+		// Format and display results
+		return ui.DisplayQueryResults(result, opts.outputFormat, mark, opts.values, opts.expanded)
+	}
+	
+	store, err := securefile.NewStore(cfg, secCtx)
 	if err != nil {
 		return err
 	}
 	
-	// Format and display results
-	return ui.DisplayQueryResults(result, outputFormat)
+	rows, err := ui.ExportQuery(db, store, query, opts.outFile, opts.outputFormat, mark, opts.values, ui.TableLayout{Expanded: opts.expanded})
+	if err != nil {
+		return err
+	}
+	ui.LogExport(log, []string{dataProduct}, query, opts.outFile, opts.outputFormat, mark, rows)
+	
+	fmt.Fprintf(os.Stderr, "Wrote %d rows to %s, encrypted (dmesh decrypt %s to read it)\n", rows, opts.outFile, opts.outFile)
+	return nil
 }
 
-func launchQueryUI(dataProduct string, cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) error {
+func launchQueryUI(opts queryOptions, cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) error {
 	// Initialize model for Bubble Tea UI
-	model := ui.NewQueryModel(cfg, secCtx, log, dataProduct)
+	model := ui.NewQueryModel(cfg, secCtx, log, opts.dataProduct)
+	if err := model.RaiseMarking(opts.classification); err != nil {
+		return err
+	}
+	model.SetValueFormat(opts.values)
 	
 	// Start the UI
 	p := tea.NewProgram(model, tea.WithAltScreen())
@@ -76,12 +297,32 @@ func launchQueryUI(dataProduct string, cfg *config.Config, secCtx *security.Secu
 	return err
 }
 
-func resolveDataProductPath(dataProduct string, cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) (string, error) {
-	// Query the data catalog to get the S3 path for the data product
+func resolveDataProduct(dataProduct string, cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) (*catalog.DataProduct, error) {
+	// Query the data catalog for the S3 path and classification of the data product
 	catalogClient, err := catalog.NewClient(cfg, secCtx, log)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	
+	return catalogClient.GetDataProduct(dataProduct)
+}
+
+func registerDataProductAsOf(db *duckdb.Connection, product *catalog.DataProduct, asOf string, cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) error {
+	dataProduct := product.Name
+	at, err := lakehouse.ParseAsOf(asOf)
+	if err != nil {
+		return err
+	}
+	
+	// Find the snapshot in the table metadata
+	version, err := lakehouse.NewClient(cfg, secCtx, log).TableVersion(product.Format, product.Location, at)
+	if err != nil {
+		return err
 	}
 	
-	return catalogClient.GetDataProductPath(dataProduct)
+	// Tell auditors which snapshot answered the query, without mixing it into the results
+	fmt.Fprintf(os.Stderr, "Querying %s as of snapshot %d (%s, committed %s)\n", dataProduct,
+		version.Snapshot.ID, version.Snapshot.Operation, version.Snapshot.Timestamp.Format(time.RFC3339))
+	
+	return db.RegisterDataProductAsOf(dataProduct, version)
 }
@@ -1,26 +1,72 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/frocore/fedramp-data-mesh/cli/internal/config"
 	"github.com/frocore/fedramp-data-mesh/cli/internal/logging"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/securefile"
 	"github.com/frocore/fedramp-data-mesh/cli/internal/security"
 	"github.com/spf13/cobra"
 )
 
 var rootCmd *cobra.Command
 
+// localOnly annotates commands that only read and write local files, such
+// as schemas, and so never log data from data products
+var localOnly = map[string]string{localOnlyKey: "true"}
+
+const localOnlyKey = "local_only"
+
 func Execute(cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) error {
 	rootCmd = &cobra.Command{
 		Use:   "dmesh",
 		Short: "FroCore Data Mesh CLI",
 		Long:  `Command-line tool for interacting with the FroCore Event-Driven Data Mesh`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return encryptLogs(cmd, cfg, secCtx, log)
+		},
 	}
 	
 	// Add subcommands
 	rootCmd.AddCommand(NewDiscoverCmd(cfg, secCtx, log))
 	rootCmd.AddCommand(NewQueryCmd(cfg, secCtx, log))
+	rootCmd.AddCommand(NewSnapshotsCmd(cfg, secCtx, log))
+	rootCmd.AddCommand(NewDiffCmd(cfg, secCtx, log))
+	rootCmd.AddCommand(NewCacheCmd(cfg, secCtx, log))
+	rootCmd.AddCommand(NewDecryptCmd(cfg, secCtx, log))
 	rootCmd.AddCommand(NewSchemaCmd(cfg, secCtx, log))
 	rootCmd.AddCommand(NewInfoCmd(cfg, secCtx, log))
+	rootCmd.AddCommand(NewStreamCmd(cfg, secCtx, log))
+	rootCmd.AddCommand(NewSLACmd(cfg, secCtx, log))
+	rootCmd.AddCommand(NewQualityCmd(cfg, secCtx, log))
+	rootCmd.AddCommand(NewProfileCmd(cfg, secCtx, log))
 	
 	return rootCmd.Execute()
 }
+
+// encryptLogs encrypts the log files, which may hold data from queries,
+// before commands that handle data products run. Without the key such
+// commands fail rather than run without their log; help and local only
+// commands log in plaintext.
+func encryptLogs(cmd *cobra.Command, cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) error {
+	for c := cmd; c != nil; c = c.Parent() {
+		switch c.Name() {
+		case "help", "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+			return nil
+		}
+		if c.Annotations[localOnlyKey] == "true" {
+			return nil
+		}
+	}
+	
+	store, err := securefile.NewStore(cfg, secCtx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize local encryption: %w", err)
+	}
+	if _, err := store.Key(); err != nil {
+		return fmt.Errorf("failed to get the key log files are encrypted with: %w", err)
+	}
+	log.EncryptFiles(store.EncryptLine)
+	return nil
+}
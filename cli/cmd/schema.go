@@ -1,51 +1,74 @@
 package cmd
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
-	"os"
+	"strings"
 
 	"github.com/frocore/fedramp-data-mesh/cli/internal/catalog"
 	"github.com/frocore/fedramp-data-mesh/cli/internal/config"
 	"github.com/frocore/fedramp-data-mesh/cli/internal/logging"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/schemaformat"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/securefile"
 	"github.com/frocore/fedramp-data-mesh/cli/internal/security"
 	"github.com/spf13/cobra"
 )
 
 func NewSchemaCmd(cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) *cobra.Command {
 	var outputFile string
-	var formatOutput bool
+	var format string
+	var compact bool
 	
 	cmd := &cobra.Command{
 		Use:   "schema [data_product]",
 		Short: "Show schema for a data product",
-		Long:  `Display the schema definition for a specific data product`,
+		Long: `Display the schema definition for a specific data product.
+Schema formats fail on Glue types they can't express, such as uniontype;
+json and markdown show them as they are.`,
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			dataProduct := args[0]
-			return getSchema(dataProduct, outputFile, formatOutput, cfg, secCtx, log)
+			return getSchema(dataProduct, outputFile, format, compact, cfg, secCtx, log)
 		},
 	}
 	
 	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Write schema to file")
-	cmd.Flags().BoolVarP(&formatOutput, "format", "f", true, "Format JSON output")
+	cmd.Flags().StringVarP(&format, "format", "f", "json",
+		fmt.Sprintf("Output format (json, %s)", strings.Join(schemaformat.Formats, ", ")))
+	cmd.Flags().BoolVar(&compact, "compact", false, "Print json output without indentation")
+	
+	cmd.AddCommand(NewSchemaLintCmd(cfg, secCtx, log))
+	cmd.AddCommand(NewSchemaCodegenCmd(cfg, secCtx, log))
 	
 	return cmd
 }
 
-func getSchema(dataProduct, outputFile string, formatOutput bool, cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) error {
+func getSchema(dataProduct, outputFile, format string, compact bool, cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) error {
 	catalogClient, err := catalog.NewClient(cfg, secCtx, log)
 	if err != nil {
 		return err
 	}
 	
+	if format != "json" {
+		tableSchema, err := catalogClient.GetDataProductTableSchema(dataProduct)
+		if err != nil {
+			return err
+		}
+		output, err := schemaformat.Render(tableSchema, format)
+		if err != nil {
+			return err
+		}
+		return writeSchemaOutput(output, outputFile)
+	}
+	
 	schema, err := catalogClient.GetDataProductSchema(dataProduct)
 	if err != nil {
 		return err
 	}
 	
 	var output []byte
-	if formatOutput {
+	if !compact {
 		var jsonObj interface{}
 		if err := json.Unmarshal([]byte(schema), &jsonObj); err != nil {
 			return err
@@ -55,13 +78,21 @@ func getSchema(dataProduct, outputFile string, formatOutput bool, cfg *config.Co
 			return err
 		}
 	} else {
-		output = []byte(schema)
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, []byte(schema)); err != nil {
+			return err
+		}
+		output = buf.Bytes()
 	}
 	
+	return writeSchemaOutput(output, outputFile)
+}
+
+func writeSchemaOutput(output []byte, outputFile string) error {
 	if outputFile != "" {
-		return os.WriteFile(outputFile, output, 0644)
+		return securefile.WritePrivateFile(outputFile, output)
 	}
 	
-	fmt.Println(string(output))
+	fmt.Println(strings.TrimRight(string(output), "\n"))
 	return nil
 }
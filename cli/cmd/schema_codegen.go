@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/avro"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/codegen"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/config"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/logging"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/registry"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/securefile"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/security"
+	"github.com/spf13/cobra"
+)
+
+func NewSchemaCodegenCmd(cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) *cobra.Command {
+	var lang string
+	var schemaFile string
+	var subject string
+	var version string
+	var packageName string
+	var outputFile string
+
+	cmd := &cobra.Command{
+		Use:   "codegen",
+		Annotations: localOnly,
+		Short: "Generate typed code from an Avro event schema",
+		Long: `Generate types and binary Avro codecs with Confluent wire-format framing
+from an Avro schema file or a schema registry subject`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return generateCode(lang, schemaFile, subject, version, packageName, outputFile, cfg, log)
+		},
+	}
+
+	cmd.Flags().StringVarP(&lang, "lang", "l", "go", "Target language (go)")
+	cmd.Flags().StringVarP(&schemaFile, "file", "f", "", "Avro schema file (.avsc)")
+	cmd.Flags().StringVarP(&subject, "subject", "s", "", "Schema registry subject")
+	cmd.Flags().StringVar(&version, "version", "latest", "Schema registry subject version")
+	cmd.Flags().StringVarP(&packageName, "package", "p", "events", "Package name of the generated code")
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Write generated code to file")
+
+	return cmd
+}
+
+func generateCode(lang, schemaFile, subject, version, packageName, outputFile string, cfg *config.Config, log *logging.Logger) error {
+	if lang != "go" {
+		return fmt.Errorf("unsupported language: %s", lang)
+	}
+	if (schemaFile == "") == (subject == "") {
+		return fmt.Errorf("exactly one of --file or --subject is required")
+	}
+
+	// Load the schema text from a file or the registry
+	var schemaText, source string
+	if schemaFile != "" {
+		data, err := os.ReadFile(schemaFile)
+		if err != nil {
+			return fmt.Errorf("failed to read schema file: %w", err)
+		}
+		schemaText = string(data)
+		source = schemaFile
+	} else {
+		registryClient, err := registry.NewClient(cfg, log)
+		if err != nil {
+			return err
+		}
+		registered, err := registryClient.GetSchema(subject, version)
+		if err != nil {
+			return err
+		}
+		schemaText = registered.Schema
+		source = fmt.Sprintf("schema registry subject %s version %d (id %d)", subject, registered.Version, registered.ID)
+	}
+
+	schema, err := avro.Parse([]byte(schemaText))
+	if err != nil {
+		return err
+	}
+
+	code, err := codegen.GenerateGo(schema, codegen.GoOptions{
+		Package: packageName,
+		Source:  source,
+		Schema:  schemaText,
+	})
+	if err != nil {
+		return err
+	}
+
+	if outputFile != "" {
+		return securefile.WritePrivateFile(outputFile, code)
+	}
+
+	fmt.Print(string(code))
+	return nil
+}
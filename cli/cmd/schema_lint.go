@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/config"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/lint"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/logging"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/security"
+	"github.com/spf13/cobra"
+)
+
+func NewSchemaLintCmd(cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) *cobra.Command {
+	var rulesFile string
+	var format string
+	var outputFile string
+
+	cmd := &cobra.Command{
+		Use:   "lint [schema_file...]",
+		Annotations: localOnly,
+		Short: "Check Avro schemas against data mesh governance rules",
+		Long: `Lint Avro schema files (.avsc) against the data mesh governance rules.
+Without arguments every .avsc file under the current directory is checked.
+Rules can be tuned with a rules file (default: ` + lint.DefaultConfigFile + `).`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := lintSchemas(args, rulesFile, format, outputFile, log)
+			if err == errLintViolations {
+				// Violations are already in the report; don't print usage
+				cmd.SilenceUsage = true
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().StringVarP(&rulesFile, "rules", "r", "", "Lint rules file")
+	cmd.Flags().StringVarP(&format, "format", "f", "text", "Report format (text, json, sarif)")
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Write report to file")
+
+	return cmd
+}
+
+var errLintViolations = fmt.Errorf("schema lint found violations with error severity")
+
+func lintSchemas(paths []string, rulesFile, format, outputFile string, log *logging.Logger) error {
+	// Load rules configuration
+	var lintCfg *lint.Config
+	if rulesFile == "" {
+		if _, err := os.Stat(lint.DefaultConfigFile); err == nil {
+			rulesFile = lint.DefaultConfigFile
+		}
+	}
+	if rulesFile != "" {
+		var err error
+		lintCfg, err = lint.LoadConfig(rulesFile)
+		if err != nil {
+			return err
+		}
+		log.Debugf("Using lint rules from %s", rulesFile)
+	}
+
+	// Collect schema files
+	files, err := findSchemaFiles(paths)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no schema files found")
+	}
+
+	linter := lint.NewLinter(lintCfg)
+	result, err := linter.LintFiles(files)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if outputFile != "" {
+		f, err := os.OpenFile(outputFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to create report file: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := lint.WriteReport(w, result, linter.Rules(), format); err != nil {
+		return err
+	}
+
+	if result.HasErrors() {
+		return errLintViolations
+	}
+	return nil
+}
+
+// findSchemaFiles expands directories into the .avsc files they contain
+func findSchemaFiles(paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	var files []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+
+		err = filepath.Walk(p, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				name := info.Name()
+				if path != p && (strings.HasPrefix(name, ".") || name == "node_modules") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if strings.HasSuffix(path, ".avsc") {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error walking %s: %w", p, err)
+		}
+	}
+
+	return files, nil
+}
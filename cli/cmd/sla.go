@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/config"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/duckdb"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/lakehouse"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/logging"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/manifest"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/security"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/sla"
+	"github.com/spf13/cobra"
+)
+
+type slaCheckOptions struct {
+	format          string
+	watch           time.Duration
+	source          string
+	timestampColumn string
+}
+
+func NewSLACmd(cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sla",
+		Short: "Check data product service levels",
+		Long:  `Check data products against the sla block of their manifests`,
+	}
+
+	cmd.AddCommand(NewSLACheckCmd(cfg, secCtx, log))
+
+	return cmd
+}
+
+func NewSLACheckCmd(cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) *cobra.Command {
+	var opts slaCheckOptions
+
+	cmd := &cobra.Command{
+		Use:   "check [data_product...]",
+		Short: "Check data freshness against the declared latency",
+		Long: `Compute how old the newest data of each table in spec.tables is and
+compare it with sla.latency. Freshness is read from the latest Iceberg
+snapshot or Delta commit, falling back to max(event_timestamp) of the table.
+Without arguments every data product under manifest_dir is checked.
+
+The command exits with a non-zero status when a product misses its SLA or
+its freshness cannot be determined. With --watch the check repeats until
+interrupted.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := checkSLAs(args, opts, cfg, secCtx, log)
+			if err == errSLAViolations {
+				// Failures are already in the report; don't print usage
+				cmd.SilenceUsage = true
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.format, "format", "f", "text", "Report format (text, json)")
+	cmd.Flags().DurationVarP(&opts.watch, "watch", "w", 0, "Repeat the check at this interval, e.g. 1m")
+	cmd.Flags().StringVar(&opts.source, "source", sla.SourceAuto, "Freshness source (auto, snapshot, event-timestamp)")
+	cmd.Flags().StringVar(&opts.timestampColumn, "timestamp-column", sla.DefaultTimestampColumn, "Event time column for the event-timestamp source")
+
+	return cmd
+}
+
+var errSLAViolations = fmt.Errorf("data products missed their SLA")
+
+func checkSLAs(names []string, opts slaCheckOptions, cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) error {
+	switch opts.source {
+	case sla.SourceAuto, sla.SourceSnapshot, sla.SourceEventTimestamp:
+	default:
+		return fmt.Errorf("unsupported freshness source: %s", opts.source)
+	}
+	if opts.format != "text" && opts.format != "json" {
+		return fmt.Errorf("unsupported output format: %s", opts.format)
+	}
+
+	products, err := selectProducts(cfg.ManifestDir, names)
+	if err != nil {
+		return err
+	}
+
+	events := newEventTimeReader(cfg, secCtx, log)
+	defer events.Close()
+
+	checker := &sla.Checker{
+		Lakehouse:       lakehouse.NewClient(cfg, secCtx, log),
+		EventTime:       events.MaxTimestamp,
+		Source:          opts.source,
+		TimestampColumn: opts.timestampColumn,
+	}
+
+	check := func() (bool, error) {
+		report := checker.Check(products)
+		if err := sla.WriteReport(os.Stdout, report, opts.format); err != nil {
+			return false, err
+		}
+		return report.HasFailures(), nil
+	}
+
+	failed, err := check()
+	if err != nil {
+		return err
+	}
+
+	if opts.watch > 0 {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		ticker := time.NewTicker(opts.watch)
+		defer ticker.Stop()
+	watch:
+		for {
+			select {
+			case <-ctx.Done():
+				break watch
+			case <-ticker.C:
+				if opts.format == "text" {
+					fmt.Println()
+				}
+				if failed, err = check(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if failed {
+		return errSLAViolations
+	}
+	return nil
+}
+
+// selectProducts returns the named data products, by domain.name ID or
+// catalog.table name, or all products when no names are given
+func selectProducts(root string, names []string) ([]*manifest.DataProduct, error) {
+	products, err := manifest.Discover(root)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		if len(products) == 0 {
+			return nil, fmt.Errorf("no data product manifests found under %s", root)
+		}
+		return products, nil
+	}
+
+	var selected []*manifest.DataProduct
+	for _, name := range names {
+		found := manifest.Lookup(products, name)
+		if found == nil {
+			return nil, fmt.Errorf("no data product manifest found for %s under %s", name, root)
+		}
+		selected = append(selected, found)
+	}
+	return selected, nil
+}
+
+// eventTimeReader queries the newest event timestamp of tables with
+// DuckDB. The connection is opened on first use, since most tables are
+// checked through their snapshots.
+type eventTimeReader struct {
+	cfg     *config.Config
+	secCtx  *security.SecurityContext
+	log     *logging.Logger
+	db      *duckdb.Connection
+	allowed map[string]bool
+}
+
+func newEventTimeReader(cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) *eventTimeReader {
+	return &eventTimeReader{
+		cfg:     cfg,
+		secCtx:  secCtx,
+		log:     log,
+		allowed: make(map[string]bool),
+	}
+}
+
+// MaxTimestamp implements sla.EventTimeFunc
+func (r *eventTimeReader) MaxTimestamp(product *manifest.DataProduct, table *manifest.Table, column string) (time.Time, error) {
+	// Reading the table reads product data; check access once per product
+	allowed, checked := r.allowed[product.ID()]
+	if !checked {
+		canAccess, err := r.secCtx.CanAccessDataProduct(product.ID())
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to check access: %w", err)
+		}
+		allowed = canAccess
+		r.allowed[product.ID()] = allowed
+	}
+	if !allowed {
+		return time.Time{}, fmt.Errorf("access denied to data product: %s", product.ID())
+	}
+
+	if r.db == nil {
+		db, err := duckdb.NewConnection(r.cfg, r.secCtx)
+		if err != nil {
+			return time.Time{}, err
+		}
+		r.db = db
+	}
+
+	if err := r.db.RegisterTable(table.Name, table.Format, table.Location); err != nil {
+		return time.Time{}, err
+	}
+	result, err := r.db.ExecuteQuery(fmt.Sprintf(`SELECT max("%s") FROM "%s"`,
+		strings.ReplaceAll(column, `"`, `""`), strings.ReplaceAll(table.Name, `"`, `""`)))
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(result.Rows) == 0 || len(result.Rows[0]) == 0 {
+		return time.Time{}, nil
+	}
+
+	switch v := result.Rows[0][0].(type) {
+	case nil:
+		return time.Time{}, nil
+	case time.Time:
+		return v, nil
+	default:
+		return time.Time{}, fmt.Errorf("column %s is not a timestamp (got %T)", column, v)
+	}
+}
+
+func (r *eventTimeReader) Close() error {
+	if r.db == nil {
+		return nil
+	}
+	return r.db.Close()
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/catalog"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/config"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/lakehouse"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/logging"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/security"
+	"github.com/spf13/cobra"
+)
+
+func NewSnapshotsCmd(cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "snapshots [data_product]",
+		Short: "List the snapshots of a data product",
+		Long: `List the Iceberg snapshots or Delta versions of a data product with the
+operation, the files added and removed and the record counts of each.
+
+Query a data product at one of them with dmesh query --as-of.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listSnapshots(args[0], format, cfg, secCtx, log)
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "format", "f", "table", "Output format (table, json)")
+
+	return cmd
+}
+
+func listSnapshots(dataProduct, format string, cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) error {
+	if format != "table" && format != "json" {
+		return fmt.Errorf("unsupported output format: %s", format)
+	}
+
+	// Check for data product access
+	canAccess, err := secCtx.CanAccessDataProduct(dataProduct)
+	if err != nil {
+		return fmt.Errorf("failed to check access: %w", err)
+	}
+	if !canAccess {
+		return fmt.Errorf("access denied to data product: %s", dataProduct)
+	}
+
+	// Look up format and location in the catalog
+	catalogClient, err := catalog.NewClient(cfg, secCtx, log)
+	if err != nil {
+		return err
+	}
+	product, err := catalogClient.GetDataProduct(dataProduct)
+	if err != nil {
+		return err
+	}
+
+	history, err := lakehouse.NewClient(cfg, secCtx, log).History(product.Format, product.Location)
+	if err != nil {
+		return err
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(history)
+	}
+
+	if len(history.Snapshots) == 0 {
+		fmt.Printf("%s has no snapshots\n", dataProduct)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SNAPSHOT\tCOMMITTED\tOPERATION\tFILES ADDED\tFILES REMOVED\tRECORDS ADDED\tRECORDS DELETED\tTOTAL RECORDS")
+	for _, s := range history.Snapshots {
+		id := strconv.FormatInt(s.ID, 10)
+		if s.Current {
+			id += " *"
+		}
+		committed := ""
+		if !s.Timestamp.IsZero() {
+			committed = s.Timestamp.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", id, committed, s.Operation,
+			optionalCount(s.Stats.AddedFiles), optionalCount(s.Stats.RemovedFiles),
+			optionalCount(s.Stats.AddedRecords), optionalCount(s.Stats.DeletedRecords), optionalCount(s.Stats.TotalRecords))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n%d %s snapshot(s) of %s; * marks the current one\n", len(history.Snapshots), history.Format, dataProduct)
+	return nil
+}
+
+// optionalCount renders an optional count, with - when unknown
+func optionalCount(n *int64) string {
+	if n == nil {
+		return "-"
+	}
+	return strconv.FormatInt(*n, 10)
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/config"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/logging"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/manifest"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/security"
+	"github.com/spf13/cobra"
+)
+
+func NewStreamCmd(cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stream",
+		Short: "Work with data product event streams",
+		Long: `Read, produce and inspect the Kafka event streams declared by data
+product manifests (spec.eventStream)`,
+	}
+
+	cmd.AddCommand(NewStreamTailCmd(cfg, secCtx, log))
+	cmd.AddCommand(NewStreamProduceCmd(cfg, secCtx, log))
+	cmd.AddCommand(NewStreamStatusCmd(cfg, secCtx, log))
+
+	return cmd
+}
+
+// loadStreamProduct finds the manifest of a data product that declares an
+// event stream
+func loadStreamProduct(dataProduct string, cfg *config.Config) (*manifest.DataProduct, error) {
+	product, err := manifest.Find(cfg.ManifestDir, dataProduct)
+	if err != nil {
+		return nil, err
+	}
+
+	if product.Spec.EventStream == nil || product.Spec.EventStream.TopicName == "" {
+		return nil, fmt.Errorf("data product %s does not declare spec.eventStream.topicName", product.ID())
+	}
+
+	return product, nil
+}
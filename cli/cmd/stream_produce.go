@@ -0,0 +1,289 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/avro"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/config"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/logging"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/registry"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/security"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/stream"
+	"github.com/segmentio/kafka-go"
+	"github.com/spf13/cobra"
+)
+
+type produceOptions struct {
+	file        string
+	count       int
+	rate        float64
+	seed        int64
+	nullRate    float64
+	subject     string
+	allowRemote bool
+	dryRun      bool
+}
+
+func NewStreamProduceCmd(cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) *cobra.Command {
+	var opts produceOptions
+
+	cmd := &cobra.Command{
+		Use:   "produce [data_product]",
+		Short: "Publish test events to a data product's topic",
+		Long: `Generate schema-valid synthetic events from the data product's Avro
+schema, or read events from a JSON file, and publish them to the topic in
+Confluent wire format. The schema ID is looked up in the schema registry
+and the schema is registered when it is missing.
+
+Only brokers on this machine are accepted unless --allow-remote is given.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return produceEvents(args[0], opts, cfg, secCtx, log)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.file, "file", "f", "", "JSON file with events (array or one object per line) instead of synthetic events")
+	cmd.Flags().IntVarP(&opts.count, "count", "n", 10, "Number of synthetic events (0 to produce until interrupted)")
+	cmd.Flags().Float64VarP(&opts.rate, "rate", "r", 1, "Events per second (0 for no limit)")
+	cmd.Flags().Int64Var(&opts.seed, "seed", 0, "Random seed for synthetic events (default: current time)")
+	cmd.Flags().Float64Var(&opts.nullRate, "null-rate", 0.2, "Probability that an optional field is null in synthetic events")
+	cmd.Flags().StringVar(&opts.subject, "subject", "", "Schema registry subject (default: <topic>-value)")
+	cmd.Flags().BoolVar(&opts.allowRemote, "allow-remote", false, "Allow publishing to brokers that are not on this machine")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "Print the events as JSON instead of publishing them")
+
+	return cmd
+}
+
+func produceEvents(dataProduct string, opts produceOptions, cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) error {
+	if opts.rate < 0 {
+		return fmt.Errorf("--rate must not be negative")
+	}
+
+	product, err := loadStreamProduct(dataProduct, cfg)
+	if err != nil {
+		return err
+	}
+	topic := product.Spec.EventStream.TopicName
+
+	schemaText, err := os.ReadFile(product.SchemaPath())
+	if err != nil {
+		return fmt.Errorf("failed to read schema file: %w", err)
+	}
+	schema, err := avro.Parse(schemaText)
+	if err != nil {
+		return fmt.Errorf("%s: %w", product.SchemaPath(), err)
+	}
+
+	// Event source
+	next, err := eventSource(schema, opts)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if opts.dryRun {
+		encoder := json.NewEncoder(os.Stdout)
+		return produceLoop(ctx, opts.rate, next, schema, func(event interface{}, payload []byte) error {
+			return encoder.Encode(event)
+		})
+	}
+
+	streamClient, err := stream.NewClient(cfg, secCtx, log)
+	if err != nil {
+		return err
+	}
+	if !streamClient.IsLocal() && !opts.allowRemote {
+		return fmt.Errorf("refusing to publish test events to non-local brokers %v; use --allow-remote to override", streamClient.Brokers())
+	}
+
+	// Check for data product access before publishing to its topic
+	canAccess, err := secCtx.CanAccessDataProduct(product.ID())
+	if err != nil {
+		return fmt.Errorf("failed to check access: %w", err)
+	}
+	if !canAccess {
+		return fmt.Errorf("access denied to data product: %s", product.ID())
+	}
+
+	schemaID, err := resolveSchemaID(opts.subject, topic, string(schemaText), cfg, log)
+	if err != nil {
+		return err
+	}
+
+	writer := streamClient.NewWriter(topic)
+	defer writer.Close()
+
+	keyField := product.Spec.EventStream.PartitionKey
+	produced := 0
+	err = produceLoop(ctx, opts.rate, next, schema, func(event interface{}, payload []byte) error {
+		msg := kafka.Message{Value: stream.Frame(schemaID, payload)}
+		if key := eventKey(event, keyField); key != "" {
+			msg.Key = []byte(key)
+		}
+		if err := writer.WriteMessages(ctx, msg); err != nil {
+			return fmt.Errorf("failed to publish event: %w", err)
+		}
+		produced++
+		return nil
+	})
+
+	fmt.Fprintf(os.Stderr, "Produced %d events to %s (schema id %d)\n", produced, topic, schemaID)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// eventSource returns a function yielding the next event, or io.EOF when
+// there are no more events
+func eventSource(schema *avro.Schema, opts produceOptions) (func() (interface{}, error), error) {
+	if opts.file != "" {
+		data, err := os.ReadFile(opts.file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read events file: %w", err)
+		}
+
+		// A JSON array or a stream of JSON objects
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		var events []interface{}
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+			if err := decoder.Decode(&events); err != nil {
+				return nil, fmt.Errorf("failed to parse events file: %w", err)
+			}
+		} else {
+			for {
+				var event interface{}
+				if err := decoder.Decode(&event); err == io.EOF {
+					break
+				} else if err != nil {
+					return nil, fmt.Errorf("failed to parse events file: %w", err)
+				}
+				events = append(events, event)
+			}
+		}
+
+		i := 0
+		return func() (interface{}, error) {
+			if i >= len(events) {
+				return nil, io.EOF
+			}
+			i++
+			return events[i-1], nil
+		}, nil
+	}
+
+	seed := opts.seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	generator := avro.NewGenerator(seed)
+	generator.NullRate = opts.nullRate
+
+	n := 0
+	return func() (interface{}, error) {
+		if opts.count > 0 && n >= opts.count {
+			return nil, io.EOF
+		}
+		n++
+		return generator.Generate(schema), nil
+	}, nil
+}
+
+// produceLoop encodes events and hands them to emit at the given rate
+func produceLoop(ctx context.Context, rate float64, next func() (interface{}, error), schema *avro.Schema,
+	emit func(event interface{}, payload []byte) error) error {
+	var ticker *time.Ticker
+	if rate > 0 {
+		ticker = time.NewTicker(time.Duration(float64(time.Second) / rate))
+		defer ticker.Stop()
+	}
+
+	for i := 1; ; i++ {
+		event, err := next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// Encoding validates the event against the schema
+		payload, err := avro.Encode(schema, event)
+		if err != nil {
+			return fmt.Errorf("event %d does not match the schema: %w", i, err)
+		}
+
+		if err := emit(event, payload); err != nil {
+			return err
+		}
+
+		if ticker != nil {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return nil
+			}
+		} else if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// resolveSchemaID looks up the schema's ID, registering it when the
+// subject doesn't hold it yet
+func resolveSchemaID(subject, topic, schemaText string, cfg *config.Config, log *logging.Logger) (int, error) {
+	if subject == "" {
+		subject = topic + "-value"
+	}
+
+	registryClient, err := registry.NewClient(cfg, log)
+	if err != nil {
+		return 0, err
+	}
+
+	registered, err := registryClient.LookupSchema(subject, schemaText)
+	if err == nil {
+		return registered.ID, nil
+	}
+	if !registry.IsNotFound(err) {
+		return 0, err
+	}
+
+	id, err := registryClient.RegisterSchema(subject, schemaText)
+	if err != nil {
+		return 0, err
+	}
+	fmt.Fprintf(os.Stderr, "Registered schema under subject %s with id %d\n", subject, id)
+	return id, nil
+}
+
+// eventKey returns the partition key of an event as a string
+func eventKey(event interface{}, keyField string) string {
+	if keyField == "" {
+		return ""
+	}
+
+	var value interface{}
+	switch e := event.(type) {
+	case *avro.RecordValue:
+		value, _ = e.Get(keyField)
+	case map[string]interface{}:
+		value = e[keyField]
+	}
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,288 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/config"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/lakehouse"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/logging"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/manifest"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/security"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/stream"
+	"github.com/spf13/cobra"
+)
+
+// streamStatus is the health report of a data product's event stream
+type streamStatus struct {
+	Product    string              `json:"product"`
+	Topic      *stream.TopicStatus `json:"topic"`
+	Retention  retentionStatus     `json:"retention"`
+	SLALatency string              `json:"sla_latency,omitempty"`
+	// LatestEvent is the newest message timestamp across partitions
+	LatestEvent *time.Time    `json:"latest_event,omitempty"`
+	Tables      []tableStatus `json:"tables"`
+}
+
+type retentionStatus struct {
+	// Topic is the broker's retention.ms as a duration, or "unlimited"
+	// or "unknown" when the broker doesn't expose it
+	Topic    string `json:"topic"`
+	Declared string `json:"declared,omitempty"`
+	OK       bool   `json:"ok"`
+	Message  string `json:"message,omitempty"`
+}
+
+// tableStatus compares a table fed by the stream with the topic
+type tableStatus struct {
+	Table         string              `json:"table"`
+	Format        string              `json:"format"`
+	ConsumerGroup string              `json:"consumer_group,omitempty"`
+	Group         *stream.GroupStatus `json:"group,omitempty"`
+	GroupError    string              `json:"group_error,omitempty"`
+	LatestCommit  *time.Time          `json:"latest_commit,omitempty"`
+	CommitError   string              `json:"commit_error,omitempty"`
+	// FreshnessLag is how far the latest commit trails the latest event
+	FreshnessLag *float64 `json:"freshness_lag_seconds,omitempty"`
+	WithinSLA    *bool    `json:"within_sla,omitempty"`
+}
+
+func NewStreamStatusCmd(cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "status [data_product]",
+		Short: "Show the health of a data product's event stream",
+		Long: `Show the partitions, high-water marks and retention of the topic declared
+in spec.eventStream, the lag of the consumer group feeding each table in
+spec.tables (declared with consumerGroup), and how far the latest commit of
+each table trails the latest event, compared with sla.latency.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "table" && format != "json" {
+				return fmt.Errorf("unsupported output format: %s", format)
+			}
+			return showStreamStatus(args[0], format, cfg, secCtx, log)
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "format", "f", "table", "Output format (table, json)")
+
+	return cmd
+}
+
+func showStreamStatus(dataProduct, format string, cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) error {
+	product, err := loadStreamProduct(dataProduct, cfg)
+	if err != nil {
+		return err
+	}
+
+	streamClient, err := stream.NewClient(cfg, secCtx, log)
+	if err != nil {
+		return err
+	}
+
+	// Check for data product access; local test brokers hold no product data
+	if !streamClient.IsLocal() {
+		canAccess, err := secCtx.CanAccessDataProduct(product.ID())
+		if err != nil {
+			return fmt.Errorf("failed to check access: %w", err)
+		}
+		if !canAccess {
+			return fmt.Errorf("access denied to data product: %s", product.ID())
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	topic, err := streamClient.TopicStatus(ctx, product.Spec.EventStream.TopicName)
+	if err != nil {
+		return err
+	}
+
+	status := &streamStatus{
+		Product:    product.ID(),
+		Topic:      topic,
+		Retention:  compareRetention(topic.Retention, product.Spec.EventStream.Retention.Time),
+		SLALatency: product.Spec.SLA.Latency,
+	}
+	if latest := topic.LatestTime(); !latest.IsZero() {
+		status.LatestEvent = &latest
+	}
+
+	var slaLatency time.Duration
+	if status.SLALatency != "" {
+		if slaLatency, err = manifest.ParseDuration(status.SLALatency); err != nil {
+			return fmt.Errorf("data product %s: sla.latency: %w", product.ID(), err)
+		}
+	}
+
+	lakehouseClient := lakehouse.NewClient(cfg, secCtx, log)
+	for _, table := range product.Spec.Tables {
+		ts := tableStatus{
+			Table:         table.QualifiedName(),
+			Format:        table.Format,
+			ConsumerGroup: table.ConsumerGroup,
+		}
+
+		// Consumer group lag
+		if table.ConsumerGroup != "" {
+			if ts.Group, err = streamClient.GroupStatus(ctx, table.ConsumerGroup, topic); err != nil {
+				ts.GroupError = err.Error()
+			}
+		}
+
+		// Freshness of the latest commit
+		history, err := lakehouseClient.History(table.Format, table.Location)
+		if err != nil {
+			ts.CommitError = err.Error()
+		} else if latest := history.Latest(); latest != nil {
+			ts.LatestCommit = &latest.Timestamp
+		}
+		if ts.LatestCommit != nil && status.LatestEvent != nil {
+			lag := status.LatestEvent.Sub(*ts.LatestCommit)
+			if lag < 0 {
+				lag = 0
+			}
+			seconds := lag.Seconds()
+			ts.FreshnessLag = &seconds
+			if slaLatency > 0 {
+				within := lag <= slaLatency
+				ts.WithinSLA = &within
+			}
+		}
+
+		status.Tables = append(status.Tables, ts)
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(status)
+	}
+	printStreamStatus(status)
+	return nil
+}
+
+// compareRetention checks that the topic keeps events at least as long as
+// the manifest declares
+func compareRetention(topic time.Duration, declared string) retentionStatus {
+	rs := retentionStatus{Topic: manifest.FormatDuration(topic), Declared: declared, OK: true}
+	switch {
+	case topic < 0:
+		rs.Topic = "unlimited"
+	case topic == 0:
+		rs.Topic = "unknown"
+	}
+	if declared == "" {
+		rs.Message = "no retention.time declared"
+		return rs
+	}
+
+	want, err := manifest.ParseDuration(declared)
+	switch {
+	case err != nil:
+		rs.OK = false
+		rs.Message = err.Error()
+	case topic == 0:
+		rs.Message = "the broker did not return retention.ms"
+	case topic > 0 && topic < want:
+		rs.OK = false
+		rs.Message = fmt.Sprintf("topic retention is shorter than the declared %s", declared)
+	case topic > 0 && topic > want:
+		rs.Message = fmt.Sprintf("topic retention is longer than the declared %s", declared)
+	}
+	return rs
+}
+
+func printStreamStatus(status *streamStatus) {
+	topic := status.Topic
+
+	fmt.Println("Stream Status")
+	fmt.Println("=============")
+	fmt.Printf("Data Product:  %s\n", status.Product)
+	fmt.Printf("Topic:         %s\n", topic.Topic)
+	fmt.Printf("Replication:   %d\n", topic.ReplicationFactor)
+	fmt.Printf("Messages:      %d retained\n", topic.Messages())
+	retention := status.Retention.Topic
+	if status.Retention.Declared != "" {
+		retention += fmt.Sprintf(" (declared %s)", status.Retention.Declared)
+	}
+	if status.Retention.Message != "" {
+		retention += " - " + status.Retention.Message
+	}
+	fmt.Printf("Retention:     %s\n", retention)
+	if status.LatestEvent != nil {
+		fmt.Printf("Latest Event:  %s (%s ago)\n", status.LatestEvent.Local().Format("2006-01-02 15:04:05"),
+			manifest.FormatDuration(time.Since(*status.LatestEvent)))
+	} else {
+		fmt.Println("Latest Event:  none")
+	}
+
+	fmt.Println("\nPartitions:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  PARTITION\tFIRST OFFSET\tHIGH WATERMARK\tLATEST EVENT")
+	for _, p := range topic.Partitions {
+		latest := "-"
+		if !p.LatestTime.IsZero() {
+			latest = p.LatestTime.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "  %d\t%d\t%d\t%s\n", p.Partition, p.FirstOffset, p.HighWatermark, latest)
+	}
+	w.Flush()
+
+	fmt.Println("\nTables:")
+	if len(status.Tables) == 0 {
+		fmt.Println("  No tables declared in spec.tables")
+		return
+	}
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  TABLE\tCONSUMER GROUP\tLAG\tLATEST COMMIT\tBEHIND LATEST EVENT")
+	var errs []string
+	for _, t := range status.Tables {
+		group, lag := "not declared", "-"
+		if t.ConsumerGroup != "" {
+			group = t.ConsumerGroup
+			switch {
+			case t.Group != nil:
+				lag = fmt.Sprintf("%d", t.Group.Lag)
+				if t.Group.State != "" {
+					group += " (" + t.Group.State + ")"
+				}
+			case t.GroupError != "":
+				lag = "error"
+				errs = append(errs, fmt.Sprintf("%s: %s", t.Table, t.GroupError))
+			}
+		}
+
+		commit, behind := "-", "-"
+		if t.LatestCommit != nil {
+			commit = t.LatestCommit.Local().Format("2006-01-02 15:04:05")
+		} else if t.CommitError != "" {
+			commit = "error"
+			errs = append(errs, fmt.Sprintf("%s: %s", t.Table, t.CommitError))
+		}
+		if t.FreshnessLag != nil {
+			behind = manifest.FormatDuration(time.Duration(*t.FreshnessLag * float64(time.Second)))
+			if t.WithinSLA != nil {
+				if *t.WithinSLA {
+					behind += " (within SLA " + status.SLALatency + ")"
+				} else {
+					behind += " (SLA " + status.SLALatency + " MISSED)"
+				}
+			}
+		}
+
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", t.Table, group, lag, commit, behind)
+	}
+	w.Flush()
+
+	for _, e := range errs {
+		fmt.Printf("  ! %s\n", e)
+	}
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/config"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/logging"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/manifest"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/registry"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/security"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/stream"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/ui"
+	"github.com/segmentio/kafka-go"
+	"github.com/spf13/cobra"
+)
+
+type tailOptions struct {
+	fromBeginning bool
+	since         string
+	partitionKey  string
+	format        string
+	maxMessages   int
+	metadata      bool
+}
+
+func NewStreamTailCmd(cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) *cobra.Command {
+	var opts tailOptions
+
+	cmd := &cobra.Command{
+		Use:   "tail [data_product]",
+		Short: "Print events from a data product's topic",
+		Long: `Consume the topic declared in spec.eventStream.topicName, decode the
+Confluent-framed Avro events through the schema registry and print them as
+JSON lines or a live table. Without --from-beginning or --since only new
+events are shown.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return tailStream(args[0], opts, cfg, secCtx, log)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.fromBeginning, "from-beginning", false, "Start at the earliest retained event")
+	cmd.Flags().StringVar(&opts.since, "since", "", "Start at events newer than a duration (15m, 2d) or an RFC 3339 time")
+	cmd.Flags().StringVarP(&opts.partitionKey, "partition-key", "k", "", "Only show events with this partition key")
+	cmd.Flags().StringVarP(&opts.format, "format", "f", "json", "Output format (json, table)")
+	cmd.Flags().IntVarP(&opts.maxMessages, "max-messages", "n", 0, "Stop after this many events (0 for no limit)")
+	cmd.Flags().BoolVar(&opts.metadata, "metadata", false, "Wrap JSON events with partition, offset, timestamp and key")
+
+	return cmd
+}
+
+func tailStream(dataProduct string, opts tailOptions, cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) error {
+	if opts.format != "json" && opts.format != "table" {
+		return fmt.Errorf("unsupported output format: %s", opts.format)
+	}
+	if opts.fromBeginning && opts.since != "" {
+		return fmt.Errorf("--from-beginning and --since are mutually exclusive")
+	}
+
+	product, err := loadStreamProduct(dataProduct, cfg)
+	if err != nil {
+		return err
+	}
+
+	tail := stream.TailOptions{
+		Topic:         product.Spec.EventStream.TopicName,
+		FromBeginning: opts.fromBeginning,
+	}
+	if opts.since != "" {
+		if tail.Since, err = parseSince(opts.since, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	streamClient, err := stream.NewClient(cfg, secCtx, log)
+	if err != nil {
+		return err
+	}
+
+	// Check for data product access, also on brokers that look local: a
+	// tunnel to a production cluster does too
+	canAccess, err := secCtx.CanAccessDataProduct(product.ID())
+	if err != nil {
+		return fmt.Errorf("failed to check access: %w", err)
+	}
+	if !canAccess {
+		return fmt.Errorf("access denied to data product: %s", product.ID())
+	}
+
+	registryClient, err := registry.NewClient(cfg, log)
+	if err != nil {
+		return err
+	}
+	serde := stream.NewSerde(registryClient)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Decode, filter and count events before handing them to the output,
+	// which also hears of events that can't be decoded
+	count := 0
+	handler := func(emit func(*stream.Event) error, skip func(error)) func(kafka.Message) error {
+		return func(msg kafka.Message) error {
+			event, err := serde.DecodeMessage(msg)
+			if err != nil {
+				skip(err)
+				return nil
+			}
+			if opts.partitionKey != "" && !event.MatchesKey(product.Spec.EventStream.PartitionKey, opts.partitionKey) {
+				return nil
+			}
+			if err := emit(event); err != nil {
+				return err
+			}
+			count++
+			if opts.maxMessages > 0 && count >= opts.maxMessages {
+				return stream.ErrStop
+			}
+			return nil
+		}
+	}
+
+	if opts.format == "table" {
+		return tailTable(ctx, product, streamClient, tail, handler)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	return streamClient.Tail(ctx, tail, handler(func(event *stream.Event) error {
+		if !opts.metadata {
+			return encoder.Encode(event.Value)
+		}
+		return encoder.Encode(map[string]interface{}{
+			"topic":     event.Topic,
+			"partition": event.Partition,
+			"offset":    event.Offset,
+			"timestamp": event.Time.UTC(),
+			"key":       event.Key,
+			"schema_id": event.SchemaID,
+			"value":     event.Value,
+		})
+	}, func(err error) {
+		log.Errorf("Skipping event: %v", err)
+	}))
+}
+
+// tailTable runs the live table UI while events are read in the background;
+// events that can't be decoded are counted there
+func tailTable(ctx context.Context, product *manifest.DataProduct, streamClient *stream.Client, tail stream.TailOptions,
+	handler func(func(*stream.Event) error, func(error)) func(kafka.Message) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	p := tea.NewProgram(ui.NewStreamModel(product.ID(), tail.Topic), tea.WithAltScreen(), tea.WithContext(ctx))
+
+	go func() {
+		err := streamClient.Tail(ctx, tail, handler(func(event *stream.Event) error {
+			p.Send(ui.StreamEventMsg{Event: event})
+			return nil
+		}, func(err error) {
+			// Shown by the table; stderr would write over the screen
+			p.Send(ui.StreamSkipMsg{Err: err})
+		}))
+		if err != nil {
+			p.Send(ui.StreamErrorMsg{Err: err})
+		}
+	}()
+
+	_, err := p.Run()
+	if err == tea.ErrProgramKilled {
+		return nil
+	}
+	return err
+}
+
+// parseSince accepts a duration before now, an RFC 3339 time or a date
+func parseSince(since string, now time.Time) (time.Time, error) {
+	if d, err := manifest.ParseDuration(since); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, since); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", since, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since value %q, expected a duration such as 15m or a time such as 2024-01-02T15:04:05Z", since)
+}
@@ -0,0 +1,425 @@
+package arrowipc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/duckdb"
+	godb "github.com/marcboeker/go-duckdb"
+)
+
+// Ids of the Arrow types in the Type union
+const (
+	typeInt           = 2
+	typeFloatingPoint = 3
+	typeBinary        = 4
+	typeUtf8          = 5
+	typeBool          = 6
+	typeDecimal       = 7
+	typeDate          = 8
+	typeTime          = 9
+	typeTimestamp     = 10
+	typeInterval      = 11
+	typeList          = 12
+	typeStruct        = 13
+	typeMap           = 17
+)
+
+// Units of dates, times and intervals
+const (
+	unitSecond       = 0
+	unitMillisecond  = 1
+	unitMicrosecond  = 2
+	unitNanosecond   = 3
+	dateDay          = 0
+	intervalMonthDay = 2
+	floatSingle      = 1
+	floatDouble      = 2
+)
+
+// builder collects the values of a column into Arrow buffers
+type builder struct {
+	// typeID and typ describe the Arrow type
+	typeID uint8
+	typ    *fbTable
+	// width is the byte width of fixed-width values
+	width int
+	// unit of times and timestamps
+	unit int
+	// scale of decimals
+	scale int
+
+	length   int
+	nulls    int
+	validity []byte
+	offsets  []byte
+	data     []byte
+
+	children []child
+}
+
+// child is a child array with its field name
+type child struct {
+	name     string
+	nullable bool
+	*builder
+}
+
+var intWidths = map[string]int{"TINYINT": 1, "SMALLINT": 2, "INTEGER": 4, "BIGINT": 8}
+
+// newBuilder maps a DuckDB type to its Arrow type
+func newBuilder(t *duckdb.Type) *builder {
+	b := &builder{}
+	switch t.ID {
+	case "BOOLEAN":
+		b.typeID, b.typ = typeBool, newTable()
+	case "TINYINT", "SMALLINT", "INTEGER", "BIGINT", "UTINYINT", "USMALLINT", "UINTEGER", "UBIGINT":
+		b.width = intWidths[strings.TrimPrefix(t.ID, "U")]
+		b.typeID, b.typ = typeInt, newTable().int32(0, int32(b.width*8)).bool(1, !strings.HasPrefix(t.ID, "U"))
+	case "FLOAT":
+		b.width, b.typeID, b.typ = 4, typeFloatingPoint, newTable().int16(0, floatSingle)
+	case "DOUBLE":
+		b.width, b.typeID, b.typ = 8, typeFloatingPoint, newTable().int16(0, floatDouble)
+	case "DECIMAL":
+		b.width, b.scale = 16, t.Scale
+		b.typeID, b.typ = typeDecimal, newTable().int32(0, int32(t.Width)).int32(1, int32(t.Scale)).int32(2, 128)
+	case "HUGEINT":
+		b.width, b.typeID, b.typ = 16, typeDecimal, newTable().int32(0, 38).int32(1, 0).int32(2, 128)
+	case "DATE":
+		b.width, b.typeID, b.typ = 4, typeDate, newTable().int16(0, dateDay)
+	case "TIME":
+		b.width, b.unit = 8, unitMicrosecond
+		b.typeID, b.typ = typeTime, newTable().int16(0, unitMicrosecond).int32(1, 64)
+	case "TIMESTAMP", "TIMESTAMP_S", "TIMESTAMP_MS", "TIMESTAMP_NS", "TIMESTAMP WITH TIME ZONE", "TIMESTAMPTZ":
+		b.width = 8
+		switch t.ID {
+		case "TIMESTAMP_S":
+			b.unit = unitSecond
+		case "TIMESTAMP_MS":
+			b.unit = unitMillisecond
+		case "TIMESTAMP_NS":
+			b.unit = unitNanosecond
+		default:
+			b.unit = unitMicrosecond
+		}
+		b.typeID, b.typ = typeTimestamp, newTable().int16(0, int16(b.unit))
+		if t.ID == "TIMESTAMP WITH TIME ZONE" || t.ID == "TIMESTAMPTZ" {
+			b.typ.ref(1, fbString("UTC"))
+		}
+	case "INTERVAL":
+		b.width, b.typeID, b.typ = 16, typeInterval, newTable().int16(0, intervalMonthDay)
+	case "BLOB":
+		b.typeID, b.typ = typeBinary, newTable()
+		b.offsets = make([]byte, 4)
+	case "LIST":
+		b.typeID, b.typ = typeList, newTable()
+		b.offsets = make([]byte, 4)
+		b.children = []child{{name: "item", nullable: true, builder: newBuilder(t.Elem)}}
+	case "STRUCT":
+		b.typeID, b.typ = typeStruct, newTable()
+		for _, f := range t.Fields {
+			b.children = append(b.children, child{name: f.Name, nullable: true, builder: newBuilder(f.Type)})
+		}
+	case "MAP":
+		b.typeID, b.typ = typeMap, newTable().bool(0, false)
+		b.offsets = make([]byte, 4)
+		// Entries and their keys can't be null
+		entries := &builder{typeID: typeStruct, typ: newTable()}
+		entries.children = []child{
+			{name: "key", builder: newBuilder(t.Key)},
+			{name: "value", nullable: true, builder: newBuilder(t.Value)},
+		}
+		b.children = []child{{name: "entries", builder: entries}}
+	default:
+		// VARCHAR, ENUM and UUID, and types without an Arrow equivalent as
+		// text
+		b.typeID, b.typ = typeUtf8, newTable()
+		b.offsets = make([]byte, 4)
+	}
+	return b
+}
+
+// field describes the column in the schema
+func (b *builder) field(name string, nullable bool) *fbTable {
+	children := make(fbTables, len(b.children))
+	for i, c := range b.children {
+		children[i] = c.field(c.name, c.nullable)
+	}
+	return newTable().
+		ref(0, fbString(name)).
+		bool(1, nullable).
+		uint8(2, b.typeID).
+		ref(3, b.typ).
+		ref(5, children)
+}
+
+// flatten appends the field nodes and buffers of the array and its
+// children in depth-first order
+func (b *builder) flatten(nodes *[]byte, buffers *[][]byte) {
+	*nodes = binary.LittleEndian.AppendUint64(*nodes, uint64(b.length))
+	*nodes = binary.LittleEndian.AppendUint64(*nodes, uint64(b.nulls))
+
+	// The validity bitmap may be left out when there are no nulls
+	if b.nulls > 0 {
+		*buffers = append(*buffers, b.validity)
+	} else {
+		*buffers = append(*buffers, nil)
+	}
+	switch b.typeID {
+	case typeStruct:
+	case typeList, typeMap:
+		*buffers = append(*buffers, b.offsets)
+	case typeUtf8, typeBinary:
+		*buffers = append(*buffers, b.offsets, b.data)
+	default:
+		*buffers = append(*buffers, b.data)
+	}
+
+	for _, child := range b.children {
+		child.flatten(nodes, buffers)
+	}
+}
+
+// setValid records whether the next value is valid
+func (b *builder) setValid(valid bool) {
+	if b.length%8 == 0 {
+		b.validity = append(b.validity, 0)
+	}
+	if valid {
+		b.validity[b.length/8] |= 1 << (b.length % 8)
+	} else {
+		b.nulls++
+	}
+	b.length++
+}
+
+// appendOffset ends a variable-length value at the current end
+func (b *builder) appendOffset(end int) {
+	b.offsets = binary.LittleEndian.AppendUint32(b.offsets, uint32(end))
+}
+
+func (b *builder) appendNull() {
+	switch b.typeID {
+	case typeBool:
+		if b.length%8 == 0 {
+			b.data = append(b.data, 0)
+		}
+	case typeStruct:
+		for _, child := range b.children {
+			child.appendNull()
+		}
+	case typeList, typeMap:
+		b.appendOffset(b.children[0].length)
+	case typeUtf8, typeBinary:
+		b.appendOffset(len(b.data))
+	default:
+		b.data = append(b.data, make([]byte, b.width)...)
+	}
+	b.setValid(false)
+}
+
+func (b *builder) append(v interface{}) error {
+	if v == nil {
+		b.appendNull()
+		return nil
+	}
+
+	switch b.typeID {
+	case typeBool:
+		value, ok := v.(bool)
+		if !ok {
+			return unexpected(v, "BOOLEAN")
+		}
+		if b.length%8 == 0 {
+			b.data = append(b.data, 0)
+		}
+		if value {
+			b.data[b.length/8] |= 1 << (b.length % 8)
+		}
+	case typeInt:
+		rv := reflect.ValueOf(v)
+		var bits uint64
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			bits = uint64(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			bits = rv.Uint()
+		default:
+			return unexpected(v, "integer")
+		}
+		// Little-endian, so the low bytes come first
+		b.data = binary.LittleEndian.AppendUint64(b.data, bits)[:len(b.data)+b.width]
+	case typeFloatingPoint:
+		switch value := v.(type) {
+		case float32:
+			if b.width == 4 {
+				b.data = binary.LittleEndian.AppendUint32(b.data, math.Float32bits(value))
+			} else {
+				b.data = binary.LittleEndian.AppendUint64(b.data, math.Float64bits(float64(value)))
+			}
+		case float64:
+			if b.width == 4 {
+				b.data = binary.LittleEndian.AppendUint32(b.data, math.Float32bits(float32(value)))
+			} else {
+				b.data = binary.LittleEndian.AppendUint64(b.data, math.Float64bits(value))
+			}
+		default:
+			return unexpected(v, "floating point")
+		}
+	case typeDecimal:
+		var unscaled *big.Int
+		switch value := v.(type) {
+		case godb.Decimal:
+			unscaled = value.Value
+			if int(value.Scale) != b.scale {
+				return fmt.Errorf("decimal with scale %d in a column of scale %d", value.Scale, b.scale)
+			}
+		case *big.Int:
+			unscaled = value
+		default:
+			return unexpected(v, "DECIMAL")
+		}
+		if unscaled.BitLen() > 127 {
+			return fmt.Errorf("decimal %s does not fit in 128 bits", unscaled)
+		}
+		b.data = appendInt128(b.data, unscaled)
+	case typeDate:
+		value, ok := v.(time.Time)
+		if !ok {
+			return unexpected(v, "DATE")
+		}
+		secs := value.Unix()
+		days := secs / 86400
+		if secs%86400 < 0 {
+			days--
+		}
+		b.data = binary.LittleEndian.AppendUint32(b.data, uint32(int32(days)))
+	case typeTime, typeTimestamp:
+		value, ok := v.(time.Time)
+		if !ok {
+			return unexpected(v, "TIMESTAMP")
+		}
+		var ticks int64
+		switch b.unit {
+		case unitSecond:
+			ticks = value.Unix()
+		case unitMillisecond:
+			ticks = value.UnixMilli()
+		case unitNanosecond:
+			ticks = value.UnixNano()
+		default:
+			ticks = value.UnixMicro()
+		}
+		b.data = binary.LittleEndian.AppendUint64(b.data, uint64(ticks))
+	case typeInterval:
+		value, ok := v.(godb.Interval)
+		if !ok {
+			return unexpected(v, "INTERVAL")
+		}
+		b.data = binary.LittleEndian.AppendUint32(b.data, uint32(value.Months))
+		b.data = binary.LittleEndian.AppendUint32(b.data, uint32(value.Days))
+		b.data = binary.LittleEndian.AppendUint64(b.data, uint64(value.Micros*1000))
+	case typeBinary:
+		value, ok := v.([]byte)
+		if !ok {
+			return unexpected(v, "BLOB")
+		}
+		b.data = append(b.data, value...)
+		b.appendOffset(len(b.data))
+	case typeUtf8:
+		b.data = append(b.data, formatText(v)...)
+		b.appendOffset(len(b.data))
+	case typeList:
+		values, ok := v.([]interface{})
+		if !ok {
+			return unexpected(v, "LIST")
+		}
+		for _, value := range values {
+			if err := b.children[0].append(value); err != nil {
+				return err
+			}
+		}
+		b.appendOffset(b.children[0].length)
+	case typeStruct:
+		values, ok := v.(map[string]interface{})
+		if !ok {
+			return unexpected(v, "STRUCT")
+		}
+		for _, child := range b.children {
+			if err := child.append(values[child.name]); err != nil {
+				return err
+			}
+		}
+	case typeMap:
+		values, ok := v.(godb.Map)
+		if !ok {
+			return unexpected(v, "MAP")
+		}
+		// Go maps have no order, so entries are sorted by key
+		keys := make([]interface{}, 0, len(values))
+		for k := range values {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		entries := b.children[0]
+		for _, k := range keys {
+			if err := entries.children[0].append(k); err != nil {
+				return err
+			}
+			if err := entries.children[1].append(values[k]); err != nil {
+				return err
+			}
+			entries.setValid(true)
+		}
+		b.appendOffset(entries.length)
+	}
+
+	b.setValid(true)
+	return nil
+}
+
+// formatText formats a value of a text column; UUIDs arrive as 16 bytes
+func formatText(v interface{}) string {
+	switch value := v.(type) {
+	case string:
+		return value
+	case []byte:
+		if len(value) == 16 {
+			return fmt.Sprintf("%x-%x-%x-%x-%x", value[0:4], value[4:6], value[6:8], value[8:10], value[10:16])
+		}
+		return string(value)
+	}
+	return fmt.Sprint(v)
+}
+
+// appendInt128 appends a little-endian two's complement 128-bit integer
+func appendInt128(data []byte, v *big.Int) []byte {
+	var buf [16]byte
+	abs := new(big.Int).Abs(v)
+	abs.FillBytes(buf[:])
+	if v.Sign() < 0 {
+		// Negate: invert and add one
+		carry := true
+		for i := 15; i >= 0; i-- {
+			buf[i] = ^buf[i]
+			if carry {
+				buf[i]++
+				carry = buf[i] == 0
+			}
+		}
+	}
+	for i := 15; i >= 0; i-- {
+		data = append(data, buf[i])
+	}
+	return data
+}
+
+func unexpected(v interface{}, want string) error {
+	return fmt.Errorf("unexpected %T value for %s", v, want)
+}
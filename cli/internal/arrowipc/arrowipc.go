@@ -0,0 +1,181 @@
+// Package arrowipc writes query results as Arrow IPC files, also known as
+// Feather v2, keeping the column types DuckDB reports: decimals stay
+// Decimal128, timestamps keep their unit, and lists, structs and maps
+// stay nested.
+//
+// Only what the CLI needs is written: a schema, record batches and the
+// file footer, without dictionaries or compression.
+package arrowipc
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/duckdb"
+)
+
+// batchSize is the number of rows per record batch
+const batchSize = 64 * 1024
+
+var magic = []byte("ARROW1")
+
+// metadataVersion is V5, the current version of the format
+const metadataVersion = 4
+
+// Message header types
+const (
+	headerSchema      = 1
+	headerRecordBatch = 3
+)
+
+// Write writes a query result as an Arrow IPC file with key-value metadata
+// on the schema
+func Write(w io.Writer, result *duckdb.QueryResult, metadata map[string]string) error {
+	types := make([]*duckdb.Type, len(result.Columns))
+	for i, name := range result.ColumnTypes {
+		t, err := duckdb.ParseType(name)
+		if err != nil {
+			return err
+		}
+		types[i] = t
+	}
+
+	fw := &fileWriter{w: bufio.NewWriter(w)}
+	fw.write(append(append([]byte{}, magic...), 0, 0))
+
+	schema := schemaTable(result.Columns, types, metadata)
+	fw.message(finish(message(headerSchema, schema, 0)), nil)
+
+	var blocks []byte
+	for start := 0; start < len(result.Rows); start += batchSize {
+		end := start + batchSize
+		if end > len(result.Rows) {
+			end = len(result.Rows)
+		}
+
+		builders := make([]*builder, len(types))
+		for i, t := range types {
+			builders[i] = newBuilder(t)
+		}
+		for _, row := range result.Rows[start:end] {
+			for i, b := range builders {
+				if err := b.append(row[i]); err != nil {
+					return fmt.Errorf("column %s: %w", result.Columns[i], err)
+				}
+			}
+		}
+
+		offset := fw.pos
+		metaLen, bodyLen := fw.recordBatch(builders, end-start)
+		blocks = binary.LittleEndian.AppendUint64(blocks, uint64(offset))
+		blocks = binary.LittleEndian.AppendUint32(blocks, uint32(metaLen))
+		blocks = append(blocks, 0, 0, 0, 0)
+		blocks = binary.LittleEndian.AppendUint64(blocks, uint64(bodyLen))
+	}
+
+	// End of stream, then the footer that indexes the record batches
+	fw.write([]byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0})
+	footer := finish(newTable().
+		int16(0, metadataVersion).
+		ref(1, schema).
+		ref(2, &fbStructs{size: 24, align: 8}).
+		ref(3, &fbStructs{size: 24, align: 8, data: blocks}))
+	fw.write(footer)
+	fw.write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer))))
+	fw.write(magic)
+
+	if fw.err != nil {
+		return fw.err
+	}
+	return fw.w.Flush()
+}
+
+// fileWriter tracks the position in the file and the first error
+type fileWriter struct {
+	w   *bufio.Writer
+	pos int64
+	err error
+}
+
+func (fw *fileWriter) write(p []byte) {
+	if fw.err != nil {
+		return
+	}
+	n, err := fw.w.Write(p)
+	fw.pos += int64(n)
+	fw.err = err
+}
+
+// message writes an encapsulated message and returns the length of its
+// metadata including the prefix
+func (fw *fileWriter) message(meta []byte, body [][]byte) int {
+	fw.write([]byte{0xff, 0xff, 0xff, 0xff})
+	fw.write(binary.LittleEndian.AppendUint32(nil, uint32(len(meta))))
+	fw.write(meta)
+	for _, buf := range body {
+		fw.write(buf)
+		fw.write(make([]byte, padding(len(buf))))
+	}
+	return 8 + len(meta)
+}
+
+// recordBatch writes the arrays of a batch and returns the lengths of its
+// metadata and body
+func (fw *fileWriter) recordBatch(builders []*builder, length int) (int, int64) {
+	var nodes []byte
+	var buffers [][]byte
+	for _, b := range builders {
+		b.flatten(&nodes, &buffers)
+	}
+
+	var layout []byte
+	var bodyLen int64
+	for _, buf := range buffers {
+		layout = binary.LittleEndian.AppendUint64(layout, uint64(bodyLen))
+		layout = binary.LittleEndian.AppendUint64(layout, uint64(len(buf)))
+		bodyLen += int64(len(buf) + padding(len(buf)))
+	}
+
+	batch := newTable().
+		int64(0, int64(length)).
+		ref(1, &fbStructs{size: 16, align: 8, data: nodes}).
+		ref(2, &fbStructs{size: 16, align: 8, data: layout})
+	meta := finish(message(headerRecordBatch, batch, bodyLen))
+	return fw.message(meta, buffers), bodyLen
+}
+
+func message(headerType uint8, header *fbTable, bodyLen int64) *fbTable {
+	return newTable().
+		int16(0, metadataVersion).
+		uint8(1, headerType).
+		ref(2, header).
+		int64(3, bodyLen)
+}
+
+func schemaTable(columns []string, types []*duckdb.Type, metadata map[string]string) *fbTable {
+	fields := make(fbTables, len(columns))
+	for i, name := range columns {
+		fields[i] = newBuilder(types[i]).field(name, true)
+	}
+
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	keyValues := make(fbTables, len(keys))
+	for i, k := range keys {
+		keyValues[i] = newTable().ref(0, fbString(k)).ref(1, fbString(metadata[k]))
+	}
+
+	// Little-endian is the default endianness
+	return newTable().ref(1, fields).ref(2, keyValues)
+}
+
+// padding is the number of bytes to the next multiple of 8
+func padding(n int) int {
+	return (8 - n%8) % 8
+}
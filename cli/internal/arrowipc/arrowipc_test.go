@@ -0,0 +1,146 @@
+package arrowipc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/apache/arrow/go/v14/arrow"
+	"github.com/apache/arrow/go/v14/arrow/array"
+	"github.com/apache/arrow/go/v14/arrow/ipc"
+	"github.com/apache/arrow/go/v14/arrow/memory"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/duckdb"
+)
+
+// query runs a query in an in-memory database
+func query(t *testing.T, sql string) *duckdb.QueryResult {
+	t.Helper()
+	db, err := duckdb.NewLocalConnection(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	result, err := db.ExecuteQuery(sql)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+// roundTrip writes a result and reads it back with the Arrow library
+func roundTrip(t *testing.T, result *duckdb.QueryResult, metadata map[string]string) (*arrow.Schema, []arrow.Record) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Write(&buf, result, metadata); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := ipc.NewFileReader(bytes.NewReader(buf.Bytes()), ipc.WithAllocator(memory.DefaultAllocator))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var records []arrow.Record
+	for i := 0; i < r.NumRecords(); i++ {
+		rec, err := r.Record(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec.Retain()
+		records = append(records, rec)
+	}
+	return r.Schema(), records
+}
+
+func TestWriteTypes(t *testing.T) {
+	result := query(t, `SELECT * FROM (VALUES
+		(true, 1::TINYINT, -2, 3000000000::UBIGINT, 1.5::FLOAT, 2.25::DOUBLE, 'a', '\x00\x01'::BLOB,
+			12345.67::DECIMAL(18, 2), -0.001::DECIMAL(38, 3), 170141183460469231731687303715884105727::HUGEINT,
+			DATE '2024-03-01', TIME '12:34:56.789', TIMESTAMP '2024-03-01 12:34:56.789',
+			TIMESTAMP_MS '1969-12-31 23:59:59.999', INTERVAL 1 MONTH + INTERVAL 2 DAY + INTERVAL 3 SECOND,
+			[1, NULL, 3], {'x': 1, 'y': [{'z': 'b'}]}, MAP {'k': 1.5, 'j': NULL}),
+		(NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL)
+	) t(bool, tiny, int, ubig, float, double, text, blob, amount, small, huge, date, time, at, at_ms, interval, list, struct, map)`)
+
+	schema, records := roundTrip(t, result, map[string]string{"classification": "CONFIDENTIAL", "marking": "CONFIDENTIAL"})
+	if len(records) != 1 {
+		t.Fatalf("got %d record batches, want 1", len(records))
+	}
+	rec := records[0]
+	defer rec.Release()
+
+	if v, _ := schema.Metadata().GetValue("marking"); v != "CONFIDENTIAL" {
+		t.Errorf("marking metadata = %q, want CONFIDENTIAL", v)
+	}
+
+	types := []string{
+		"bool", "int8", "int32", "uint64", "float32", "float64", "utf8", "binary",
+		"decimal(18, 2)", "decimal(38, 3)", "decimal(38, 0)",
+		"date32", "time64[us]", "timestamp[us]",
+		"timestamp[ms]", "month_day_nano_interval",
+		"list<item: int32, nullable>", "struct<x: int32, y: list<item: struct<z: utf8>, nullable>>", "map<utf8, decimal(2, 1), items_nullable>",
+	}
+	// As the Arrow library renders them; decimals are compared unscaled,
+	// as it renders them through floating point
+	values := []string{
+		"true", "1", "-2", "3000000000", "1.5", "2.25", "a", "AAE=",
+		"1234567", "-1", "170141183460469231731687303715884105727",
+		"2024-03-01", "12:34:56.789000", "2024-03-01 12:34:56.789Z",
+		"1969-12-31 23:59:59.999Z", `{"months":1,"days":2,"nanoseconds":3000000000}`,
+		`[1,null,3]`, `{"x":1,"y":[{"z":"b"}]}`, `[{"key":"j","value":null},{"key":"k","value":"1.5"}]`,
+	}
+	if int(rec.NumCols()) != len(types) || rec.NumRows() != 2 {
+		t.Fatalf("got %d columns and %d rows, want %d and 2", rec.NumCols(), rec.NumRows(), len(types))
+	}
+	for i, col := range rec.Columns() {
+		name := rec.ColumnName(i)
+		if got := col.DataType().String(); got != types[i] {
+			t.Errorf("%s: type %s, want %s", name, got, types[i])
+		}
+		got := col.ValueStr(0)
+		if d, ok := col.(*array.Decimal128); ok {
+			got = d.Value(0).BigInt().String()
+		}
+		if got != values[i] {
+			t.Errorf("%s: value %q, want %q", name, got, values[i])
+		}
+		if !col.IsNull(1) || col.NullN() != 1 {
+			t.Errorf("%s: second row is not NULL", name)
+		}
+	}
+}
+
+func TestWriteBatches(t *testing.T) {
+	result := query(t, "SELECT range AS id, CASE WHEN range % 3 = 0 THEN NULL ELSE range::VARCHAR END AS name FROM range(100000)")
+
+	_, records := roundTrip(t, result, nil)
+	if len(records) != 2 {
+		t.Fatalf("got %d record batches, want 2", len(records))
+	}
+	var rows, nulls int64
+	for _, rec := range records {
+		ids := rec.Column(0).(*array.Int64)
+		for i := 0; i < int(rec.NumRows()); i++ {
+			if id := ids.Value(i); id != rows+int64(i) {
+				t.Fatalf("row %d: id %d", rows+int64(i), id)
+			}
+		}
+		rows += rec.NumRows()
+		nulls += int64(rec.Column(1).NullN())
+		rec.Release()
+	}
+	if rows != 100000 || nulls != 33334 {
+		t.Errorf("got %d rows and %d NULL names, want 100000 and 33334", rows, nulls)
+	}
+}
+
+func TestWriteEmpty(t *testing.T) {
+	result := query(t, "SELECT 1 AS id, 'a' AS name WHERE false")
+
+	schema, records := roundTrip(t, result, nil)
+	if len(records) != 0 {
+		t.Errorf("got %d record batches, want none", len(records))
+	}
+	if got := schema.String(); got != "schema:\n  fields: 2\n    - id: type=int32, nullable\n    - name: type=utf8, nullable" {
+		t.Errorf("schema = %s", got)
+	}
+}
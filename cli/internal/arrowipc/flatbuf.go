@@ -0,0 +1,204 @@
+package arrowipc
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// The Arrow IPC metadata are flatbuffers. They are small, so instead of
+// the usual back-to-front builder they are laid out front to back from a
+// tree of objects: every object is written before the objects it
+// references, so all offsets point forward as flatbuffers require.
+
+// fbObject is a table, string or vector
+type fbObject interface{}
+
+// fbTable is a table; fields are set by their id in the schema
+type fbTable struct {
+	fields []fbField
+}
+
+type fbField struct {
+	id     int
+	scalar []byte
+	ref    fbObject
+}
+
+// fbString is a string
+type fbString string
+
+// fbTables is a vector of tables
+type fbTables []*fbTable
+
+// fbStructs is a vector of structs of a size and alignment
+type fbStructs struct {
+	size  int
+	align int
+	data  []byte
+}
+
+func newTable() *fbTable {
+	return &fbTable{}
+}
+
+func (t *fbTable) uint8(id int, v uint8) *fbTable {
+	t.fields = append(t.fields, fbField{id: id, scalar: []byte{v}})
+	return t
+}
+
+func (t *fbTable) bool(id int, v bool) *fbTable {
+	if v {
+		return t.uint8(id, 1)
+	}
+	return t.uint8(id, 0)
+}
+
+func (t *fbTable) int16(id int, v int16) *fbTable {
+	t.fields = append(t.fields, fbField{id: id, scalar: binary.LittleEndian.AppendUint16(nil, uint16(v))})
+	return t
+}
+
+func (t *fbTable) int32(id int, v int32) *fbTable {
+	t.fields = append(t.fields, fbField{id: id, scalar: binary.LittleEndian.AppendUint32(nil, uint32(v))})
+	return t
+}
+
+func (t *fbTable) int64(id int, v int64) *fbTable {
+	t.fields = append(t.fields, fbField{id: id, scalar: binary.LittleEndian.AppendUint64(nil, uint64(v))})
+	return t
+}
+
+func (t *fbTable) ref(id int, obj fbObject) *fbTable {
+	t.fields = append(t.fields, fbField{id: id, ref: obj})
+	return t
+}
+
+// fieldSize is the size of a field in its table; references are 32-bit
+// offsets
+func (f fbField) size() int {
+	if f.ref != nil {
+		return 4
+	}
+	return len(f.scalar)
+}
+
+// fbBuilder serializes a tree of objects
+type fbBuilder struct {
+	buf []byte
+}
+
+// pending is an offset slot waiting for the position of its object
+type pending struct {
+	slot int
+	obj  fbObject
+}
+
+// finish serializes a root table into a flatbuffer padded to 8 bytes
+func finish(root *fbTable) []byte {
+	b := &fbBuilder{buf: make([]byte, 4)}
+	queue := []pending{{slot: 0, obj: root}}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		pos, refs := b.write(p.obj)
+		binary.LittleEndian.PutUint32(b.buf[p.slot:], uint32(pos-p.slot))
+		queue = append(queue, refs...)
+	}
+	b.pad(8, 0)
+	return b.buf
+}
+
+// pad appends zeros until the length is mod modulo align
+func (b *fbBuilder) pad(align, mod int) {
+	for len(b.buf)%align != mod {
+		b.buf = append(b.buf, 0)
+	}
+}
+
+// write appends an object and returns its position and the references it
+// holds
+func (b *fbBuilder) write(obj fbObject) (int, []pending) {
+	switch o := obj.(type) {
+	case *fbTable:
+		return b.writeTable(o)
+	case fbString:
+		b.pad(4, 0)
+		pos := len(b.buf)
+		b.buf = binary.LittleEndian.AppendUint32(b.buf, uint32(len(o)))
+		b.buf = append(b.buf, o...)
+		b.buf = append(b.buf, 0)
+		return pos, nil
+	case fbTables:
+		b.pad(4, 0)
+		pos := len(b.buf)
+		b.buf = binary.LittleEndian.AppendUint32(b.buf, uint32(len(o)))
+		var refs []pending
+		for _, t := range o {
+			refs = append(refs, pending{slot: len(b.buf), obj: t})
+			b.buf = append(b.buf, 0, 0, 0, 0)
+		}
+		return pos, refs
+	case *fbStructs:
+		// The elements after the length must be aligned
+		if o.align > 4 {
+			b.pad(o.align, o.align-4)
+		} else {
+			b.pad(4, 0)
+		}
+		pos := len(b.buf)
+		b.buf = binary.LittleEndian.AppendUint32(b.buf, uint32(len(o.data)/o.size))
+		b.buf = append(b.buf, o.data...)
+		return pos, nil
+	}
+	panic("arrowipc: unknown flatbuffer object")
+}
+
+// writeTable writes a vtable followed by the table. Fields are ordered by
+// size, largest first, so they are aligned once the table is.
+func (b *fbBuilder) writeTable(t *fbTable) (int, []pending) {
+	fields := append([]fbField(nil), t.fields...)
+	sort.SliceStable(fields, func(i, j int) bool { return fields[i].size() > fields[j].size() })
+
+	maxID := -1
+	for _, f := range fields {
+		if f.id > maxID {
+			maxID = f.id
+		}
+	}
+	offsets := make([]uint16, maxID+1)
+	tableSize := 4
+	for _, f := range fields {
+		offsets[f.id] = uint16(tableSize)
+		tableSize += f.size()
+	}
+
+	// vtable: its size, the table size and the offset of every field
+	b.pad(2, 0)
+	vtable := len(b.buf)
+	b.buf = binary.LittleEndian.AppendUint16(b.buf, uint16(4+2*len(offsets)))
+	b.buf = binary.LittleEndian.AppendUint16(b.buf, uint16(tableSize))
+	for _, off := range offsets {
+		b.buf = binary.LittleEndian.AppendUint16(b.buf, off)
+	}
+
+	// The table starts with the offset back to its vtable; 8-byte fields
+	// follow it, so it starts 4 bytes before an 8-byte boundary
+	if len(fields) > 0 && fields[0].size() == 8 {
+		b.pad(8, 4)
+	} else {
+		b.pad(4, 0)
+	}
+	pos := len(b.buf)
+	b.buf = binary.LittleEndian.AppendUint32(b.buf, uint32(int32(pos-vtable)))
+
+	var refs []pending
+	for _, f := range fields {
+		if f.ref != nil {
+			refs = append(refs, pending{slot: len(b.buf), obj: f.ref})
+			b.buf = append(b.buf, 0, 0, 0, 0)
+		} else {
+			b.buf = append(b.buf, f.scalar...)
+		}
+	}
+	return pos, refs
+}
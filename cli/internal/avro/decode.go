@@ -0,0 +1,332 @@
+package avro
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+)
+
+// Decoded values use these Go types:
+//
+//	null                      nil
+//	boolean                   bool
+//	int, long                 int32, int64
+//	float, double             float32, float64
+//	bytes, fixed              []byte
+//	string                    string
+//	enum                      string (the symbol)
+//	array                     []interface{}
+//	map                       map[string]interface{}
+//	record                    *RecordValue
+//	union                     the value of the selected branch
+//	date                      Date
+//	timestamp-millis/-micros  time.Time (UTC)
+//	decimal                   *Decimal
+
+// RecordValue is a decoded record; values are kept in schema field order
+type RecordValue struct {
+	Schema *Schema
+	Values []interface{}
+}
+
+// Get returns the value of the named field
+func (r *RecordValue) Get(name string) (interface{}, bool) {
+	for i, f := range r.Schema.Fields {
+		if f.Name == name {
+			return r.Values[i], true
+		}
+	}
+	return nil, false
+}
+
+// MarshalJSON writes the record as a JSON object in field order
+func (r *RecordValue) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, f := range r.Schema.Fields {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		name, _ := json.Marshal(f.Name)
+		b.Write(name)
+		b.WriteByte(':')
+		value, err := json.Marshal(r.Values[i])
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// Date is a decoded date logical value
+type Date struct {
+	time.Time
+}
+
+func (d Date) String() string {
+	return d.Format("2006-01-02")
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// Decimal is a decoded decimal logical value
+type Decimal struct {
+	Value *big.Rat
+	Scale int
+}
+
+func (d *Decimal) String() string {
+	return d.Value.FloatString(d.Scale)
+}
+
+// MarshalJSON writes the decimal as an exact JSON number
+func (d *Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+var errShortData = errors.New("unexpected end of Avro data")
+
+// Decode decodes a single Avro binary encoded value; all of data must be
+// consumed
+func Decode(s *Schema, data []byte) (interface{}, error) {
+	d := &decoder{buf: data}
+	v, err := d.value(s)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.buf) {
+		return nil, fmt.Errorf("%d trailing bytes after Avro value", len(d.buf)-d.pos)
+	}
+	return v, nil
+}
+
+type decoder struct {
+	buf []byte
+	pos int
+}
+
+func (d *decoder) value(s *Schema) (interface{}, error) {
+	switch s.Type {
+	case Null:
+		return nil, nil
+
+	case Boolean:
+		b, err := d.fixed(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0] != 0, nil
+
+	case Int:
+		n, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if n < math.MinInt32 || n > math.MaxInt32 {
+			return nil, fmt.Errorf("value %d overflows an int", n)
+		}
+		if s.LogicalType == "date" {
+			return Date{time.Unix(n*86400, 0).UTC()}, nil
+		}
+		return int32(n), nil
+
+	case Long:
+		n, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		switch s.LogicalType {
+		case "timestamp-millis":
+			return time.UnixMilli(n).UTC(), nil
+		case "timestamp-micros":
+			return time.UnixMicro(n).UTC(), nil
+		}
+		return n, nil
+
+	case Float:
+		b, err := d.fixed(4)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float32frombits(binary.LittleEndian.Uint32(b)), nil
+
+	case Double:
+		b, err := d.fixed(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+
+	case Bytes:
+		b, err := d.bytes()
+		if err != nil {
+			return nil, err
+		}
+		if s.LogicalType == "decimal" {
+			return decodeDecimal(b, s.Scale), nil
+		}
+		return b, nil
+
+	case String:
+		b, err := d.bytes()
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+
+	case Fixed:
+		b, err := d.fixed(s.Size)
+		if err != nil {
+			return nil, err
+		}
+		if s.LogicalType == "decimal" {
+			return decodeDecimal(b, s.Scale), nil
+		}
+		return append([]byte(nil), b...), nil
+
+	case Enum:
+		i, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i >= int64(len(s.Symbols)) {
+			return nil, fmt.Errorf("enum %s: invalid symbol index %d", s.FullName(), i)
+		}
+		return s.Symbols[i], nil
+
+	case Array:
+		items := []interface{}{}
+		err := d.blocks(func() error {
+			v, err := d.value(s.Items)
+			if err != nil {
+				return err
+			}
+			items = append(items, v)
+			return nil
+		})
+		return items, err
+
+	case Map:
+		values := map[string]interface{}{}
+		err := d.blocks(func() error {
+			key, err := d.bytes()
+			if err != nil {
+				return err
+			}
+			v, err := d.value(s.Values)
+			if err != nil {
+				return err
+			}
+			values[string(key)] = v
+			return nil
+		})
+		return values, err
+
+	case Union:
+		i, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i >= int64(len(s.Branches)) {
+			return nil, fmt.Errorf("invalid union branch %d", i)
+		}
+		return d.value(s.Branches[i])
+
+	case Record:
+		r := &RecordValue{Schema: s, Values: make([]interface{}, len(s.Fields))}
+		for i, f := range s.Fields {
+			v, err := d.value(f.Type)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", f.Name, err)
+			}
+			r.Values[i] = v
+		}
+		return r, nil
+	}
+
+	return nil, fmt.Errorf("unsupported schema type %s", s.Type)
+}
+
+func (d *decoder) long() (int64, error) {
+	var u uint64
+	for shift := uint(0); ; shift += 7 {
+		if d.pos >= len(d.buf) {
+			return 0, errShortData
+		}
+		if shift >= 64 {
+			return 0, errors.New("Avro varint overflows a long")
+		}
+		b := d.buf[d.pos]
+		d.pos++
+		u |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			break
+		}
+	}
+	return int64(u>>1) ^ -int64(u&1), nil
+}
+
+func (d *decoder) fixed(n int) ([]byte, error) {
+	if n < 0 || n > len(d.buf)-d.pos {
+		return nil, errShortData
+	}
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *decoder) bytes() ([]byte, error) {
+	n, err := d.long()
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 || n > int64(len(d.buf)-d.pos) {
+		return nil, errShortData
+	}
+	b, err := d.fixed(int(n))
+	return append([]byte(nil), b...), err
+}
+
+// blocks reads the blocks of an array or map; negative item counts are
+// followed by the block size in bytes
+func (d *decoder) blocks(item func() error) error {
+	for {
+		n, err := d.long()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return nil
+		}
+		if n < 0 {
+			n = -n
+			if _, err := d.long(); err != nil {
+				return err
+			}
+		}
+		for ; n > 0; n-- {
+			if err := item(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// decodeDecimal converts a big-endian two's-complement unscaled value
+func decodeDecimal(b []byte, scale int) *Decimal {
+	unscaled := new(big.Int).SetBytes(b)
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		unscaled.Sub(unscaled, new(big.Int).Lsh(big.NewInt(1), uint(8*len(b))))
+	}
+	denom := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)
+	return &Decimal{Value: new(big.Rat).SetFrac(unscaled, denom), Scale: scale}
+}
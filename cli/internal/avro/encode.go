@@ -0,0 +1,436 @@
+package avro
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// Encode encodes a value in Avro binary format. It accepts the types
+// produced by Decode as well as values decoded from plain JSON (use
+// json.Decoder.UseNumber to keep long values exact):
+//
+//   - records as map[string]interface{}; missing fields take their default
+//   - unions as the bare value, matched against the branches in order
+//   - dates as "2006-01-02" and timestamps as RFC 3339 strings or epoch numbers
+//   - decimals as numbers or numeric strings
+//   - bytes and fixed as strings
+func Encode(s *Schema, value interface{}) ([]byte, error) {
+	e := &encoder{}
+	if err := e.value(s, value); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) long(n int64) {
+	u := uint64((n << 1) ^ (n >> 63))
+	for u >= 0x80 {
+		e.buf = append(e.buf, byte(u)|0x80)
+		u >>= 7
+	}
+	e.buf = append(e.buf, byte(u))
+}
+
+func (e *encoder) bytes(b []byte) {
+	e.long(int64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) value(s *Schema, v interface{}) error {
+	switch s.Type {
+	case Null:
+		if v != nil {
+			return fmt.Errorf("expected null, got %T", v)
+		}
+		return nil
+
+	case Boolean:
+		b, ok := v.(bool)
+		if !ok {
+			return fmt.Errorf("expected boolean, got %T", v)
+		}
+		if b {
+			e.buf = append(e.buf, 1)
+		} else {
+			e.buf = append(e.buf, 0)
+		}
+		return nil
+
+	case Int:
+		if s.LogicalType == "date" {
+			days, err := toDays(v)
+			if err != nil {
+				return err
+			}
+			e.long(days)
+			return nil
+		}
+		n, err := toInt64(v)
+		if err != nil {
+			return err
+		}
+		if n < math.MinInt32 || n > math.MaxInt32 {
+			return fmt.Errorf("value %d overflows an int", n)
+		}
+		e.long(n)
+		return nil
+
+	case Long:
+		if s.LogicalType == "timestamp-millis" || s.LogicalType == "timestamp-micros" {
+			n, err := toTimestamp(v, s.LogicalType == "timestamp-micros")
+			if err != nil {
+				return err
+			}
+			e.long(n)
+			return nil
+		}
+		n, err := toInt64(v)
+		if err != nil {
+			return err
+		}
+		e.long(n)
+		return nil
+
+	case Float:
+		f, err := toFloat64(v)
+		if err != nil {
+			return err
+		}
+		e.buf = binary.LittleEndian.AppendUint32(e.buf, math.Float32bits(float32(f)))
+		return nil
+
+	case Double:
+		f, err := toFloat64(v)
+		if err != nil {
+			return err
+		}
+		e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(f))
+		return nil
+
+	case Bytes:
+		if s.LogicalType == "decimal" {
+			b, err := decimalBytes(v, s.Scale, 0)
+			if err != nil {
+				return err
+			}
+			e.bytes(b)
+			return nil
+		}
+		b, err := toBytes(v)
+		if err != nil {
+			return err
+		}
+		e.bytes(b)
+		return nil
+
+	case String:
+		str, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("expected string, got %T", v)
+		}
+		e.bytes([]byte(str))
+		return nil
+
+	case Fixed:
+		var b []byte
+		var err error
+		if s.LogicalType == "decimal" {
+			b, err = decimalBytes(v, s.Scale, s.Size)
+		} else {
+			b, err = toBytes(v)
+		}
+		if err != nil {
+			return err
+		}
+		if len(b) != s.Size {
+			return fmt.Errorf("fixed %s: expected %d bytes, got %d", s.FullName(), s.Size, len(b))
+		}
+		e.buf = append(e.buf, b...)
+		return nil
+
+	case Enum:
+		symbol, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("expected enum symbol, got %T", v)
+		}
+		for i, sym := range s.Symbols {
+			if sym == symbol {
+				e.long(int64(i))
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not a symbol of enum %s", symbol, s.FullName())
+
+	case Array:
+		items, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("expected array, got %T", v)
+		}
+		if len(items) > 0 {
+			e.long(int64(len(items)))
+			for i, item := range items {
+				if err := e.value(s.Items, item); err != nil {
+					return fmt.Errorf("item %d: %w", i, err)
+				}
+			}
+		}
+		e.long(0)
+		return nil
+
+	case Map:
+		values, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected map, got %T", v)
+		}
+		if len(values) > 0 {
+			keys := make([]string, 0, len(values))
+			for k := range values {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+
+			e.long(int64(len(values)))
+			for _, k := range keys {
+				e.bytes([]byte(k))
+				if err := e.value(s.Values, values[k]); err != nil {
+					return fmt.Errorf("key %s: %w", k, err)
+				}
+			}
+		}
+		e.long(0)
+		return nil
+
+	case Union:
+		// Try branches in order, rolling back partial writes
+		mark := len(e.buf)
+		var firstErr error
+		for i, branch := range s.Branches {
+			if (v == nil) != (branch.Type == Null) {
+				continue
+			}
+			e.long(int64(i))
+			err := e.value(branch, v)
+			if err == nil {
+				return nil
+			}
+			e.buf = e.buf[:mark]
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		if firstErr != nil {
+			return firstErr
+		}
+		return fmt.Errorf("value of type %T matches no union branch", v)
+
+	case Record:
+		return e.record(s, v)
+	}
+
+	return fmt.Errorf("unsupported schema type %s", s.Type)
+}
+
+func (e *encoder) record(s *Schema, v interface{}) error {
+	if r, ok := v.(*RecordValue); ok {
+		if r.Schema != s && r.Schema.FullName() != s.FullName() {
+			return fmt.Errorf("expected record %s, got %s", s.FullName(), r.Schema.FullName())
+		}
+		for i, f := range s.Fields {
+			if err := e.value(f.Type, r.Values[i]); err != nil {
+				return fmt.Errorf("field %s: %w", f.Name, err)
+			}
+		}
+		return nil
+	}
+
+	values, ok := v.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("expected record %s, got %T", s.FullName(), v)
+	}
+
+	for _, f := range s.Fields {
+		fv, present := values[f.Name]
+		if !present {
+			switch {
+			case f.HasDefault:
+				if err := json.Unmarshal(f.Default, &fv); err != nil {
+					return fmt.Errorf("field %s: invalid default: %w", f.Name, err)
+				}
+			case f.Type.Nullable():
+				fv = nil
+			default:
+				return fmt.Errorf("missing required field %s", f.Name)
+			}
+		}
+		if err := e.value(f.Type, fv); err != nil {
+			return fmt.Errorf("field %s: %w", f.Name, err)
+		}
+	}
+	return nil
+}
+
+func toInt64(v interface{}) (int64, error) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), nil
+	case int32:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case json.Number:
+		return n.Int64()
+	case float64:
+		if n != math.Trunc(n) {
+			return 0, fmt.Errorf("expected an integer, got %v", n)
+		}
+		return int64(n), nil
+	}
+	return 0, fmt.Errorf("expected an integer, got %T", v)
+}
+
+func toFloat64(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case float32:
+		return float64(n), nil
+	case float64:
+		return n, nil
+	case int, int32, int64:
+		i, _ := toInt64(n)
+		return float64(i), nil
+	case json.Number:
+		return n.Float64()
+	}
+	return 0, fmt.Errorf("expected a number, got %T", v)
+}
+
+func toBytes(v interface{}) ([]byte, error) {
+	switch b := v.(type) {
+	case []byte:
+		return b, nil
+	case string:
+		return []byte(b), nil
+	}
+	return nil, fmt.Errorf("expected bytes, got %T", v)
+}
+
+func toDays(v interface{}) (int64, error) {
+	var t time.Time
+	switch d := v.(type) {
+	case Date:
+		t = d.Time
+	case time.Time:
+		t = d
+	case string:
+		parsed, err := time.Parse("2006-01-02", d)
+		if err != nil {
+			return 0, fmt.Errorf("invalid date %q", d)
+		}
+		t = parsed
+	default:
+		return toInt64(v)
+	}
+	y, m, day := t.Date()
+	return time.Date(y, m, day, 0, 0, 0, 0, time.UTC).Unix() / 86400, nil
+}
+
+func toTimestamp(v interface{}, micros bool) (int64, error) {
+	var t time.Time
+	switch ts := v.(type) {
+	case time.Time:
+		t = ts
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, ts)
+		if err != nil {
+			return 0, fmt.Errorf("invalid timestamp %q", ts)
+		}
+		t = parsed
+	default:
+		return toInt64(v)
+	}
+	if micros {
+		return t.UnixMicro(), nil
+	}
+	return t.UnixMilli(), nil
+}
+
+// decimalBytes returns the big-endian two's-complement unscaled value,
+// sign-extended to size bytes when size > 0
+func decimalBytes(v interface{}, scale, size int) ([]byte, error) {
+	var r *big.Rat
+	switch d := v.(type) {
+	case *Decimal:
+		r = d.Value
+	case *big.Rat:
+		r = d
+	case string, json.Number:
+		var ok bool
+		if r, ok = new(big.Rat).SetString(fmt.Sprint(d)); !ok {
+			return nil, fmt.Errorf("invalid decimal %q", d)
+		}
+	case float64:
+		r = new(big.Rat).SetFloat64(d)
+		if r == nil {
+			return nil, fmt.Errorf("invalid decimal %v", d)
+		}
+		// Round binary floats to the scale
+		var err error
+		if r, err = roundRat(r, scale); err != nil {
+			return nil, err
+		}
+	default:
+		if n, err := toInt64(v); err == nil {
+			r = new(big.Rat).SetInt64(n)
+		} else {
+			return nil, fmt.Errorf("expected a decimal, got %T", v)
+		}
+	}
+
+	scaled := new(big.Rat).Mul(r, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)))
+	if !scaled.IsInt() {
+		return nil, fmt.Errorf("%s does not fit scale %d", r.FloatString(scale+2), scale)
+	}
+	unscaled := scaled.Num()
+
+	var b []byte
+	if unscaled.Sign() >= 0 {
+		b = unscaled.Bytes()
+		if len(b) == 0 || b[0]&0x80 != 0 {
+			b = append([]byte{0}, b...)
+		}
+	} else {
+		n := (unscaled.BitLen() + 8) / 8
+		mod := new(big.Int).Lsh(big.NewInt(1), uint(8*n))
+		b = new(big.Int).Add(mod, unscaled).Bytes()
+	}
+
+	if size > 0 {
+		if len(b) > size {
+			return nil, fmt.Errorf("decimal does not fit in %d bytes", size)
+		}
+		pad := byte(0)
+		if unscaled.Sign() < 0 {
+			pad = 0xff
+		}
+		for len(b) < size {
+			b = append([]byte{pad}, b...)
+		}
+	}
+	return b, nil
+}
+
+func roundRat(r *big.Rat, scale int) (*big.Rat, error) {
+	rounded, ok := new(big.Rat).SetString(r.FloatString(scale))
+	if !ok {
+		return nil, fmt.Errorf("invalid decimal %s", r.String())
+	}
+	return rounded, nil
+}
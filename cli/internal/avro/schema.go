@@ -0,0 +1,403 @@
+package avro
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Type is an Avro schema type name
+type Type string
+
+const (
+	Null    Type = "null"
+	Boolean Type = "boolean"
+	Int     Type = "int"
+	Long    Type = "long"
+	Float   Type = "float"
+	Double  Type = "double"
+	Bytes   Type = "bytes"
+	String  Type = "string"
+	Record  Type = "record"
+	Enum    Type = "enum"
+	Array   Type = "array"
+	Map     Type = "map"
+	Union   Type = "union"
+	Fixed   Type = "fixed"
+)
+
+// Schema is a parsed Avro schema node. Named types (record, enum, fixed)
+// are parsed once and shared by every reference to them.
+type Schema struct {
+	Type        Type
+	Name        string // short name of a named type
+	Namespace   string
+	Doc         string
+	Aliases     []string
+	LogicalType string
+	Precision   int
+	Scale       int
+
+	Fields   []*Field  // record
+	Symbols  []string  // enum
+	Default  string    // enum default symbol
+	Items    *Schema   // array
+	Values   *Schema   // map
+	Branches []*Schema // union
+	Size     int       // fixed
+
+	// Path is the JSON pointer of the node in the source document
+	Path string
+}
+
+// Field is a single field of a record schema
+type Field struct {
+	Name       string
+	Doc        string
+	Type       *Schema
+	Default    json.RawMessage
+	HasDefault bool
+	Aliases    []string
+	Order      string
+
+	// Path is the JSON pointer of the field definition in the source document
+	Path string
+}
+
+// FullName returns the namespace-qualified name of a named type
+func (s *Schema) FullName() string {
+	if s.Namespace == "" {
+		return s.Name
+	}
+	return s.Namespace + "." + s.Name
+}
+
+// IsNamed reports whether the schema is a named type
+func (s *Schema) IsNamed() bool {
+	return s.Type == Record || s.Type == Enum || s.Type == Fixed
+}
+
+// Nullable reports whether the schema is a union containing null
+func (s *Schema) Nullable() bool {
+	if s.Type != Union {
+		return false
+	}
+	for _, b := range s.Branches {
+		if b.Type == Null {
+			return true
+		}
+	}
+	return false
+}
+
+// NonNull returns the single non-null branch of an optional union
+// ("[null, T]" or "[T, null]"), or nil for any other schema
+func (s *Schema) NonNull() *Schema {
+	if s.Type != Union || len(s.Branches) != 2 {
+		return nil
+	}
+	switch {
+	case s.Branches[0].Type == Null && s.Branches[1].Type != Null:
+		return s.Branches[1]
+	case s.Branches[1].Type == Null && s.Branches[0].Type != Null:
+		return s.Branches[0]
+	}
+	return nil
+}
+
+// Field returns the record field with the given name
+func (s *Schema) Field(name string) *Field {
+	for _, f := range s.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// ParseFile reads and parses an Avro schema file (.avsc)
+func ParseFile(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema file: %w", err)
+	}
+	schema, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return schema, nil
+}
+
+// Parse parses an Avro schema from its JSON representation
+func Parse(data []byte) (*Schema, error) {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid schema JSON: %w", err)
+	}
+
+	p := &parser{names: make(map[string]*Schema)}
+	return p.parse(raw, "", "")
+}
+
+type parser struct {
+	names map[string]*Schema
+}
+
+func (p *parser) parse(raw interface{}, namespace, path string) (*Schema, error) {
+	switch v := raw.(type) {
+	case string:
+		return p.resolve(v, namespace, path)
+	case []interface{}:
+		union := &Schema{Type: Union, Path: path}
+		for i, branch := range v {
+			s, err := p.parse(branch, namespace, path+"/"+strconv.Itoa(i))
+			if err != nil {
+				return nil, err
+			}
+			if s.Type == Union {
+				return nil, fmt.Errorf("%s: unions may not immediately contain other unions", path)
+			}
+			union.Branches = append(union.Branches, s)
+		}
+		return union, nil
+	case map[string]interface{}:
+		return p.parseObject(v, namespace, path)
+	default:
+		return nil, fmt.Errorf("%s: unexpected schema value %v", pathOrRoot(path), raw)
+	}
+}
+
+func (p *parser) resolve(name, namespace, path string) (*Schema, error) {
+	switch Type(name) {
+	case Null, Boolean, Int, Long, Float, Double, Bytes, String:
+		return &Schema{Type: Type(name), Path: path}, nil
+	}
+
+	if !strings.Contains(name, ".") && namespace != "" {
+		if s, ok := p.names[namespace+"."+name]; ok {
+			return s, nil
+		}
+	}
+	if s, ok := p.names[name]; ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf("%s: unknown type %q", pathOrRoot(path), name)
+}
+
+func (p *parser) parseObject(obj map[string]interface{}, namespace, path string) (*Schema, error) {
+	typ, ok := obj["type"]
+	if !ok {
+		return nil, fmt.Errorf("%s: schema object has no type", pathOrRoot(path))
+	}
+
+	typeName, ok := typ.(string)
+	if !ok {
+		// {"type": {...}} or {"type": [...]} wraps another schema
+		return p.parse(typ, namespace, path+"/type")
+	}
+
+	s := &Schema{Type: Type(typeName), Path: path}
+	s.Doc, _ = obj["doc"].(string)
+	s.LogicalType, _ = obj["logicalType"].(string)
+	if precision, ok := obj["precision"].(float64); ok {
+		s.Precision = int(precision)
+	}
+	if scale, ok := obj["scale"].(float64); ok {
+		s.Scale = int(scale)
+	}
+
+	switch s.Type {
+	case Null, Boolean, Int, Long, Float, Double, Bytes, String:
+		return s, nil
+
+	case Record, "error", Enum, Fixed:
+		if s.Type == "error" {
+			s.Type = Record
+		}
+		if err := p.define(s, obj, namespace); err != nil {
+			return nil, err
+		}
+		switch s.Type {
+		case Record:
+			return s, p.parseFields(s, obj)
+		case Enum:
+			return s, parseSymbols(s, obj)
+		default:
+			size, ok := obj["size"].(float64)
+			if !ok {
+				return nil, fmt.Errorf("%s: fixed type %s has no size", pathOrRoot(path), s.FullName())
+			}
+			s.Size = int(size)
+			return s, nil
+		}
+
+	case Array:
+		items, ok := obj["items"]
+		if !ok {
+			return nil, fmt.Errorf("%s: array has no items", pathOrRoot(path))
+		}
+		var err error
+		s.Items, err = p.parse(items, namespace, path+"/items")
+		return s, err
+
+	case Map:
+		values, ok := obj["values"]
+		if !ok {
+			return nil, fmt.Errorf("%s: map has no values", pathOrRoot(path))
+		}
+		var err error
+		s.Values, err = p.parse(values, namespace, path+"/values")
+		return s, err
+
+	default:
+		// A named type reference written in object form
+		ref, err := p.resolve(typeName, namespace, path)
+		if err != nil {
+			return nil, err
+		}
+		return ref, nil
+	}
+}
+
+// define registers a named type before its body is parsed so that
+// recursive references resolve to the same node
+func (p *parser) define(s *Schema, obj map[string]interface{}, namespace string) error {
+	name, _ := obj["name"].(string)
+	if name == "" {
+		return fmt.Errorf("%s: %s type has no name", pathOrRoot(s.Path), s.Type)
+	}
+
+	if ns, ok := obj["namespace"].(string); ok {
+		namespace = ns
+	}
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		namespace, name = name[:i], name[i+1:]
+	}
+	s.Name = name
+	s.Namespace = namespace
+
+	if aliases, ok := obj["aliases"].([]interface{}); ok {
+		for _, a := range aliases {
+			if alias, ok := a.(string); ok {
+				s.Aliases = append(s.Aliases, alias)
+			}
+		}
+	}
+
+	if _, exists := p.names[s.FullName()]; exists {
+		return fmt.Errorf("%s: type %s is defined more than once", pathOrRoot(s.Path), s.FullName())
+	}
+	p.names[s.FullName()] = s
+	return nil
+}
+
+func (p *parser) parseFields(s *Schema, obj map[string]interface{}) error {
+	rawFields, ok := obj["fields"].([]interface{})
+	if !ok {
+		return fmt.Errorf("%s: record %s has no fields", pathOrRoot(s.Path), s.FullName())
+	}
+
+	seen := make(map[string]bool)
+	for i, rf := range rawFields {
+		fieldPath := fmt.Sprintf("%s/fields/%d", s.Path, i)
+		fobj, ok := rf.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: field must be an object", fieldPath)
+		}
+
+		field := &Field{Path: fieldPath}
+		field.Name, _ = fobj["name"].(string)
+		if field.Name == "" {
+			return fmt.Errorf("%s: field has no name", fieldPath)
+		}
+		if seen[field.Name] {
+			return fmt.Errorf("%s: duplicate field %q in record %s", fieldPath, field.Name, s.FullName())
+		}
+		seen[field.Name] = true
+
+		field.Doc, _ = fobj["doc"].(string)
+		field.Order, _ = fobj["order"].(string)
+		if aliases, ok := fobj["aliases"].([]interface{}); ok {
+			for _, a := range aliases {
+				if alias, ok := a.(string); ok {
+					field.Aliases = append(field.Aliases, alias)
+				}
+			}
+		}
+
+		rawType, ok := fobj["type"]
+		if !ok {
+			return fmt.Errorf("%s: field %q has no type", fieldPath, field.Name)
+		}
+		fieldType, err := p.parse(rawType, s.Namespace, fieldPath+"/type")
+		if err != nil {
+			return err
+		}
+		field.Type = fieldType
+
+		if def, ok := fobj["default"]; ok {
+			field.HasDefault = true
+			field.Default, _ = json.Marshal(def)
+		}
+
+		s.Fields = append(s.Fields, field)
+	}
+
+	return nil
+}
+
+func parseSymbols(s *Schema, obj map[string]interface{}) error {
+	rawSymbols, ok := obj["symbols"].([]interface{})
+	if !ok {
+		return fmt.Errorf("%s: enum %s has no symbols", pathOrRoot(s.Path), s.FullName())
+	}
+	for _, rs := range rawSymbols {
+		symbol, ok := rs.(string)
+		if !ok {
+			return fmt.Errorf("%s: enum %s has a non-string symbol", pathOrRoot(s.Path), s.FullName())
+		}
+		s.Symbols = append(s.Symbols, symbol)
+	}
+	s.Default, _ = obj["default"].(string)
+	return nil
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// Walk calls fn for s and every schema reachable from it. Each named type
+// is visited once, so recursive schemas terminate.
+func Walk(s *Schema, fn func(*Schema)) {
+	walk(s, fn, make(map[*Schema]bool))
+}
+
+func walk(s *Schema, fn func(*Schema), seen map[*Schema]bool) {
+	if s == nil || seen[s] {
+		return
+	}
+	if s.IsNamed() {
+		seen[s] = true
+	}
+	fn(s)
+
+	switch s.Type {
+	case Record:
+		for _, f := range s.Fields {
+			walk(f.Type, fn, seen)
+		}
+	case Array:
+		walk(s.Items, fn, seen)
+	case Map:
+		walk(s.Values, fn, seen)
+	case Union:
+		for _, b := range s.Branches {
+			walk(b, fn, seen)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package avro
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Generator produces random values that are valid for a schema. Values
+// use the same Go types as Decode. Strings are chosen from the field name
+// where it suggests a kind of value (ids, names, currencies, ...).
+type Generator struct {
+	// NullRate is the probability that an optional field is null
+	NullRate float64
+	// Now is the reference time for timestamps and dates
+	Now func() time.Time
+
+	rand *rand.Rand
+}
+
+var (
+	syntheticWords      = []string{"Apollo", "Falcon", "Harbor", "Summit", "Atlas", "Beacon", "Cedar", "Delta", "Orion", "Juniper"}
+	syntheticCurrencies = []string{"USD", "EUR", "GBP", "CAD"}
+	syntheticCities     = []string{"Arlington", "Denver", "Austin", "Seattle", "Columbus"}
+	syntheticStates     = []string{"VA", "CO", "TX", "WA", "OH"}
+)
+
+func NewGenerator(seed int64) *Generator {
+	return &Generator{
+		NullRate: 0.2,
+		Now:      time.Now,
+		rand:     rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Generate returns a random value for the schema
+func (g *Generator) Generate(s *Schema) interface{} {
+	return g.value(s, "")
+}
+
+func (g *Generator) value(s *Schema, field string) interface{} {
+	name := strings.ToLower(field)
+
+	switch s.Type {
+	case Null:
+		return nil
+
+	case Boolean:
+		return g.rand.Intn(2) == 1
+
+	case Int:
+		if s.LogicalType == "date" {
+			days := g.rand.Intn(361) - 180
+			return Date{g.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, days)}
+		}
+		return int32(g.rand.Intn(1000))
+
+	case Long:
+		switch s.LogicalType {
+		case "timestamp-millis", "timestamp-micros":
+			return g.timestamp(name)
+		}
+		return int64(g.rand.Intn(100000))
+
+	case Float:
+		return float32(g.rand.Intn(100000)) / 100
+
+	case Double:
+		return float64(g.rand.Intn(10000000)) / 100
+
+	case Bytes:
+		if s.LogicalType == "decimal" {
+			return g.decimal(s)
+		}
+		return g.bytes(8)
+
+	case Fixed:
+		if s.LogicalType == "decimal" {
+			return g.decimal(s)
+		}
+		return g.bytes(s.Size)
+
+	case String:
+		return g.str(name, s.LogicalType)
+
+	case Enum:
+		return s.Symbols[g.rand.Intn(len(s.Symbols))]
+
+	case Array:
+		items := make([]interface{}, g.rand.Intn(4))
+		for i := range items {
+			items[i] = g.value(s.Items, field)
+		}
+		return items
+
+	case Map:
+		values := make(map[string]interface{})
+		for i := g.rand.Intn(4); i > 0; i-- {
+			values[fmt.Sprintf("key%d", i)] = g.value(s.Values, field)
+		}
+		return values
+
+	case Union:
+		var nonNull []*Schema
+		for _, b := range s.Branches {
+			if b.Type != Null {
+				nonNull = append(nonNull, b)
+			}
+		}
+		if len(nonNull) == 0 || (len(nonNull) < len(s.Branches) && g.rand.Float64() < g.NullRate) {
+			return nil
+		}
+		return g.value(nonNull[g.rand.Intn(len(nonNull))], field)
+
+	case Record:
+		r := &RecordValue{Schema: s, Values: make([]interface{}, len(s.Fields))}
+		for i, f := range s.Fields {
+			r.Values[i] = g.value(f.Type, f.Name)
+		}
+		return r
+	}
+
+	return nil
+}
+
+// timestamp returns the current time for event times and a time in the
+// past year for creation times
+func (g *Generator) timestamp(field string) time.Time {
+	now := g.Now().UTC().Truncate(time.Millisecond)
+	if strings.Contains(field, "created") {
+		return now.Add(-time.Duration(g.rand.Int63n(int64(365 * 24 * time.Hour)))).Truncate(time.Millisecond)
+	}
+	return now
+}
+
+func (g *Generator) str(field, logicalType string) string {
+	switch {
+	case logicalType == "uuid" || field == "id" || strings.HasSuffix(field, "_id"):
+		return g.uuid()
+	case strings.Contains(field, "email"):
+		return fmt.Sprintf("user%d@example.com", g.rand.Intn(1000))
+	case strings.Contains(field, "currency"):
+		return syntheticCurrencies[g.rand.Intn(len(syntheticCurrencies))]
+	case strings.Contains(field, "country"):
+		return "US"
+	case strings.Contains(field, "city"):
+		return syntheticCities[g.rand.Intn(len(syntheticCities))]
+	case field == "state":
+		return syntheticStates[g.rand.Intn(len(syntheticStates))]
+	case strings.Contains(field, "zip") || strings.Contains(field, "postal"):
+		return fmt.Sprintf("%05d", g.rand.Intn(100000))
+	case strings.Contains(field, "address"):
+		return fmt.Sprintf("%d %s St", 1+g.rand.Intn(9999), syntheticWords[g.rand.Intn(len(syntheticWords))])
+	case strings.Contains(field, "system"):
+		return "dmesh-synthetic"
+	case strings.Contains(field, "name"):
+		return syntheticWords[g.rand.Intn(len(syntheticWords))] + " " + syntheticWords[g.rand.Intn(len(syntheticWords))]
+	case field == "":
+		return fmt.Sprintf("value-%04d", g.rand.Intn(10000))
+	}
+	return fmt.Sprintf("%s-%04d", field, g.rand.Intn(10000))
+}
+
+func (g *Generator) uuid() string {
+	b := g.bytes(16)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func (g *Generator) bytes(n int) []byte {
+	b := make([]byte, n)
+	g.rand.Read(b)
+	return b
+}
+
+// decimal returns a random value that fits the precision and, for fixed
+// schemas, the size
+func (g *Generator) decimal(s *Schema) *Decimal {
+	digits := s.Precision
+	if digits <= 0 || digits > 18 {
+		digits = 18
+	}
+	if s.Type == Fixed {
+		// Two's complement of n bytes holds 8n-1 bits, about 2.4 digits per byte
+		if max := (8*s.Size - 1) * 3 / 10; max < digits {
+			digits = max
+		}
+	}
+
+	limit := int64(1)
+	for i := 0; i < digits; i++ {
+		limit *= 10
+	}
+	unscaled := big.NewInt(g.rand.Int63n(limit))
+	denom := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(s.Scale)), nil)
+	return &Decimal{Value: new(big.Rat).SetFrac(unscaled, denom), Scale: s.Scale}
+}
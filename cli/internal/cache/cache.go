@@ -0,0 +1,305 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/config"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/logging"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/manifest"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/securefile"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/security"
+)
+
+// Table is the table holding the product data in a cached database
+const Table = "data"
+
+// States of cache entries
+const (
+	// Fresh entries are younger than the TTL and used by queries
+	Fresh = "fresh"
+	// Stale entries are kept until the retention period ends
+	Stale = "stale"
+	// Expired entries are past retention and deleted on next use
+	Expired = "expired"
+)
+
+// Cache keeps products pulled from S3 in encrypted DuckDB database files
+type Cache struct {
+	dir       string
+	ttl       time.Duration
+	retention time.Duration
+	log       *logging.Logger
+	store     *securefile.Store
+}
+
+// Entry describes a cached product
+type Entry struct {
+	Product  string `json:"product"`
+	Format   string `json:"format"`
+	Location string `json:"location"`
+	// SnapshotID is the Iceberg snapshot or Delta version that was pulled,
+	// when known
+	SnapshotID *int64 `json:"snapshot_id,omitempty"`
+	// Where is the filter of a subset
+	Where    string    `json:"where,omitempty"`
+	Rows     int64     `json:"rows"`
+	Size     int64     `json:"size"`
+	PulledAt time.Time `json:"pulled_at"`
+	// Classification is the product's classification when it was pulled
+	Classification string `json:"classification,omitempty"`
+
+	// name is the base name of the entry's files
+	name string
+}
+
+func New(cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) (*Cache, error) {
+	ttl, err := manifest.ParseDuration(cfg.CacheTTL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cache_ttl: %w", err)
+	}
+	retention, err := manifest.ParseDuration(cfg.CacheRetention)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cache_retention: %w", err)
+	}
+	if retention < ttl {
+		return nil, fmt.Errorf("cache_retention (%s) must not be shorter than cache_ttl (%s)", cfg.CacheRetention, cfg.CacheTTL)
+	}
+
+	store, err := securefile.NewStore(cfg, secCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := cfg.CacheDir
+	if dir == "" {
+		configDir, err := config.Dir()
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.Join(configDir, "cache")
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "tmp"), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	return &Cache{
+		dir:       dir,
+		ttl:       ttl,
+		retention: retention,
+		log:       log,
+		store:     store,
+	}, nil
+}
+
+// Dir returns the cache directory
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// State returns whether an entry is fresh, stale or expired
+func (c *Cache) State(e *Entry, now time.Time) string {
+	age := now.Sub(e.PulledAt)
+	switch {
+	case age >= c.retention:
+		return Expired
+	case age >= c.ttl:
+		return Stale
+	}
+	return Fresh
+}
+
+// Expires returns when an entry stops being used by queries and when it
+// is deleted
+func (c *Cache) Expires(e *Entry) (stale, expired time.Time) {
+	return e.PulledAt.Add(c.ttl), e.PulledAt.Add(c.retention)
+}
+
+// List returns the cached products, sorted by name
+func (c *Cache) List() ([]*Entry, error) {
+	files, err := filepath.Glob(filepath.Join(c.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cache entry: %w", err)
+		}
+		var e Entry
+		if err := json.Unmarshal(data, &e); err != nil {
+			c.log.Errorf("Ignoring invalid cache entry %s: %v", file, err)
+			continue
+		}
+		e.name = strings.TrimSuffix(filepath.Base(file), ".json")
+		entries = append(entries, &e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Product < entries[j].Product })
+	return entries, nil
+}
+
+// Lookup returns the fresh cache entry of a product, or nil. Expired
+// entries are deleted first, so data is never used or kept past
+// retention. The entry may hold a subset or an older snapshot; see
+// Complete.
+func (c *Cache) Lookup(product string) (*Entry, error) {
+	if _, err := c.Prune(func(e *Entry) bool { return false }); err != nil {
+		return nil, err
+	}
+
+	entries, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.Product == product && c.State(e, time.Now()) == Fresh {
+			return e, nil
+		}
+	}
+	return nil, nil
+}
+
+// Complete reports whether an entry holds all rows of its product at the
+// given snapshot, nil for tables without snapshots
+func (e *Entry) Complete(snapshotID *int64) bool {
+	if e.Where != "" {
+		return false
+	}
+	if e.SnapshotID == nil || snapshotID == nil {
+		return e.SnapshotID == nil && snapshotID == nil
+	}
+	return *e.SnapshotID == *snapshotID
+}
+
+// Prune deletes expired entries and the entries selected by remove, and
+// returns the deleted entries
+func (c *Cache) Prune(remove func(e *Entry) bool) ([]*Entry, error) {
+	entries, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var removed []*Entry
+	for _, e := range entries {
+		if c.State(e, now) != Expired && !remove(e) {
+			continue
+		}
+		if err := c.remove(e); err != nil {
+			return removed, err
+		}
+		removed = append(removed, e)
+	}
+
+	// Decrypted copies are removed when a query ends; clean up after
+	// runs that were killed
+	tmp, _ := filepath.Glob(filepath.Join(c.dir, "tmp", "*"))
+	for _, file := range tmp {
+		if info, err := os.Stat(file); err == nil && now.Sub(info.ModTime()) > 24*time.Hour {
+			os.RemoveAll(file)
+		}
+	}
+	return removed, nil
+}
+
+// TempFile returns a path for a plaintext database in the private
+// temporary directory of the cache, and a function that removes it
+func (c *Cache) TempFile() (string, func(), error) {
+	dir, err := os.MkdirTemp(filepath.Join(c.dir, "tmp"), "db-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	return filepath.Join(dir, "data.duckdb"), func() { os.RemoveAll(dir) }, nil
+}
+
+// Store encrypts a database file into the cache as the entry's product,
+// replacing an earlier pull
+func (c *Cache) Store(e *Entry, database string) error {
+	e.name = entryName(e.Product)
+	file := c.dataFile(e)
+	if err := c.store.EncryptFile(file, database); err != nil {
+		return fmt.Errorf("failed to encrypt cache file: %w", err)
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	e.Size = info.Size()
+
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(c.dir, e.name+".json"), data, 0600); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+
+	// Earlier versions named files without the hash
+	entries, err := c.List()
+	if err != nil {
+		return err
+	}
+	for _, old := range entries {
+		if old.Product == e.Product && old.name != e.name {
+			if err := c.remove(old); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Open decrypts an entry's database to a temporary file and returns its
+// path and a function that removes it
+func (c *Cache) Open(e *Entry) (string, func(), error) {
+	key, err := c.store.Key()
+	if err != nil {
+		return "", nil, err
+	}
+
+	path, cleanup, err := c.TempFile()
+	if err != nil {
+		return "", nil, err
+	}
+	if err := securefile.DecryptFile(path, c.dataFile(e), key); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to decrypt cache of %s (dmesh cache prune %s deletes it): %w", e.Product, e.Product, err)
+	}
+	return path, cleanup, nil
+}
+
+func (c *Cache) remove(e *Entry) error {
+	if err := os.Remove(c.dataFile(e)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete cache of %s: %w", e.Product, err)
+	}
+	if err := os.Remove(filepath.Join(c.dir, e.name+".json")); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete cache entry of %s: %w", e.Product, err)
+	}
+	return nil
+}
+
+func (c *Cache) dataFile(e *Entry) string {
+	return filepath.Join(c.dir, e.name+".duckdb.enc")
+}
+
+// entryName turns a product name into a file name. A hash of the name
+// keeps products apart whose names only differ in replaced characters.
+func entryName(product string) string {
+	sum := sha256.Sum256([]byte(product))
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, product) + "-" + hex.EncodeToString(sum[:4])
+}
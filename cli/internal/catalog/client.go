@@ -4,12 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/glue"
 	"github.com/frocore/fedramp-data-mesh/cli/internal/config"
 	"github.com/frocore/fedramp-data-mesh/cli/internal/logging"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/marking"
 	"github.com/frocore/fedramp-data-mesh/cli/internal/security"
 )
 
@@ -32,6 +34,13 @@ type DataProduct struct {
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 	Tags         map[string]string
+	
+	// Columns are the names of the columns, partition keys last
+	Columns []string
+	
+	// Classification is the security classification, such as
+	// CONTROLLED_UNCLASSIFIED, or empty when the product has none
+	Classification string
 }
 
 func NewClient(cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) (*Client, error) {
@@ -53,9 +62,21 @@ func NewClient(cfg *config.Config, secCtx *security.SecurityContext, log *loggin
 }
 
 func (c *Client) ListDataProducts(domainFilter string) ([]string, error) {
-	// Get databases (domains)
 	var productNames []string
+	err := c.eachDataProductTable(domainFilter, func(domain string, table *glue.TableData) {
+		// Format as domain.product
+		productNames = append(productNames, fmt.Sprintf("%s.%s", domain, *table.Name))
+	})
+	if err != nil {
+		return nil, err
+	}
 	
+	return productNames, nil
+}
+
+// eachDataProductTable calls fn with the Glue table of every data product
+// and its domain, or of those in one domain
+func (c *Client) eachDataProductTable(domainFilter string, fn func(domain string, table *glue.TableData)) error {
 	// First get all databases (represents domains)
 	dbInput := &glue.GetDatabasesInput{}
 	err := c.glueClient.GetDatabasesPages(dbInput, func(page *glue.GetDatabasesOutput, lastPage bool) bool {
@@ -73,15 +94,11 @@ func (c *Client) ListDataProducts(domainFilter string) ([]string, error) {
 			err := c.glueClient.GetTablesPages(tableInput, func(tablePage *glue.GetTablesOutput, tableLastPage bool) bool {
 				for _, table := range tablePage.TableList {
 					// Check if this is a data product by looking for specific tags
-					isDataProduct := false
-					if table.Parameters != nil {
-						_, isDataProduct = table.Parameters["data_product"]
+					if table.Parameters == nil {
+						continue
 					}
-					
-					if isDataProduct {
-						// Format as domain.product
-						productName := fmt.Sprintf("%s.%s", *db.Name, *table.Name)
-						productNames = append(productNames, productName)
+					if _, ok := table.Parameters["data_product"]; ok {
+						fn(*db.Name, table)
 					}
 				}
 				return true // Continue pagination
@@ -95,10 +112,9 @@ func (c *Client) ListDataProducts(domainFilter string) ([]string, error) {
 	})
 	
 	if err != nil {
-		return nil, fmt.Errorf("failed to list data products: %w", err)
+		return fmt.Errorf("failed to list data products: %w", err)
 	}
-	
-	return productNames, nil
+	return nil
 }
 
 func (c *Client) GetDataProduct(name string) (*DataProduct, error) {
@@ -139,15 +155,63 @@ func (c *Client) GetDataProduct(name string) (*DataProduct, error) {
 		return nil, fmt.Errorf("table is not marked as a data product: %s", name)
 	}
 	
-	// Build data product object
+	product := productFromTable(domain, table)
+	c.addTags(product)
+	
+	return product, nil
+}
+
+// tagRequests bounds the GetTags requests made at once when listing the
+// details of data products
+const tagRequests = 8
+
+// ListDataProductDetails returns the data products of the catalog like
+// ListDataProducts, with their metadata and tags
+func (c *Client) ListDataProductDetails(domainFilter string) ([]*DataProduct, error) {
+	var products []*DataProduct
+	err := c.eachDataProductTable(domainFilter, func(domain string, table *glue.TableData) {
+		products = append(products, productFromTable(domain, table))
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	// Tags take a request per product
+	var wg sync.WaitGroup
+	requests := make(chan struct{}, tagRequests)
+	for _, product := range products {
+		wg.Add(1)
+		requests <- struct{}{}
+		go func(product *DataProduct) {
+			defer wg.Done()
+			c.addTags(product)
+			<-requests
+		}(product)
+	}
+	wg.Wait()
+	return products, nil
+}
+
+// productFromTable builds a data product from its Glue table, without
+// tags
+func productFromTable(domain string, table *glue.TableData) *DataProduct {
 	product := &DataProduct{
-		Name:        name,
+		Name:        fmt.Sprintf("%s.%s", domain, aws.StringValue(table.Name)),
 		Domain:      domain,
 		Description: aws.StringValue(table.Description),
-		Location:    aws.StringValue(table.StorageDescriptor.Location),
 		Tags:        make(map[string]string),
 	}
 	
+	if table.StorageDescriptor != nil {
+		product.Location = aws.StringValue(table.StorageDescriptor.Location)
+		for _, col := range table.StorageDescriptor.Columns {
+			product.Columns = append(product.Columns, aws.StringValue(col.Name))
+		}
+	}
+	for _, col := range table.PartitionKeys {
+		product.Columns = append(product.Columns, aws.StringValue(col.Name))
+	}
+	
 	// Extract additional metadata from parameters
 	if table.Parameters != nil {
 		if format, ok := table.Parameters["table_format"]; ok {
@@ -164,6 +228,10 @@ func (c *Client) GetDataProduct(name string) (*DataProduct, error) {
 		if owner, ok := table.Parameters["owner"]; ok {
 			product.Owner = *owner
 		}
+		
+		if classification, ok := table.Parameters["security_classification"]; ok {
+			product.Classification = marking.Normalize(*classification)
+		}
 	}
 	
 	// Set timestamps
@@ -175,20 +243,36 @@ func (c *Client) GetDataProduct(name string) (*DataProduct, error) {
 		product.UpdatedAt = *table.UpdateTime
 	}
 	
-	// Get tags if any
+	return product
+}
+
+// addTags adds the Glue tags of a data product, which may also set its
+// classification
+func (c *Client) addTags(product *DataProduct) {
 	tagsInput := &glue.GetTagsInput{
 		ResourceArn: aws.String(fmt.Sprintf("arn:aws:glue:%s:%s:table/%s/%s", 
-			c.cfg.AWSRegion, c.cfg.AWSAccountID, domain, productName)),
+			c.cfg.AWSRegion, c.cfg.AWSAccountID, product.Domain, strings.TrimPrefix(product.Name, product.Domain+"."))),
 	}
 	
 	tagsOutput, err := c.glueClient.GetTags(tagsInput)
-	if err == nil && tagsOutput.Tags != nil {
+	if err != nil {
+		// A classification only set as a tag is then unknown
+		c.log.Debugf("Failed to get tags of %s: %v", product.Name, err)
+	} else if tagsOutput.Tags != nil {
 		for k, v := range tagsOutput.Tags {
 			product.Tags[k] = *v
 		}
 	}
 	
-	return product, nil
+	// The classification may also be set as a tag
+	if product.Classification == "" {
+		for _, key := range []string{"security_classification", "SecurityClassification"} {
+			if classification, ok := product.Tags[key]; ok {
+				product.Classification = marking.Normalize(classification)
+				break
+			}
+		}
+	}
 }
 
 func (c *Client) GetDataProductPath(name string) (string, error) {
@@ -201,58 +285,89 @@ func (c *Client) GetDataProductPath(name string) (string, error) {
 }
 
 func (c *Client) GetDataProductSchema(name string) (string, error) {
+	tableSchema, err := c.GetDataProductTableSchema(name)
+	if err != nil {
+		return "", err
+	}
+	
+	// Convert the typed schema to JSON
+	schema := map[string]interface{}{
+		"type": "struct",
+		"fields": tableSchema.Columns,
+	}
+	
+	schemaBytes, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal schema to JSON: %w", err)
+	}
+	
+	return string(schemaBytes), nil
+}
+
+// GetDataProductTableSchema returns the typed schema of a data product with
+// nested column types parsed. Partition keys follow the data columns, as
+// they do in query results.
+func (c *Client) GetDataProductTableSchema(name string) (*TableSchema, error) {
 	// Parse domain and product name
 	parts := strings.Split(name, ".")
 	if len(parts) != 2 {
-		return "", fmt.Errorf("invalid data product name format, expected domain.product: %s", name)
+		return nil, fmt.Errorf("invalid data product name format, expected domain.product: %s", name)
 	}
 	
 	domain := parts[0]
 	productName := parts[1]
 	
 	// Get table details from Glue
-	tableInput := &glue.GetTableInput{
+	tableOutput, err := c.glueClient.GetTable(&glue.GetTableInput{
 		DatabaseName: aws.String(domain),
 		Name:         aws.String(productName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data product schema: %w", err)
 	}
 	
-	tableOutput, err := c.glueClient.GetTable(tableInput)
-	if err != nil {
-		return "", fmt.Errorf("failed to get data product schema: %w", err)
+	table := tableOutput.Table
+	if table == nil || (table.StorageDescriptor == nil && len(table.PartitionKeys) == 0) {
+		return nil, fmt.Errorf("data product schema not found: %s", name)
 	}
 	
-	if tableOutput.Table == nil || tableOutput.Table.StorageDescriptor == nil {
-		return "", fmt.Errorf("data product schema not found: %s", name)
+	schema := &TableSchema{
+		Product:     name,
+		Domain:      domain,
+		Table:       productName,
+		Description: aws.StringValue(table.Description),
+		Columns:     []*Column{},
 	}
 	
-	// Convert Glue schema to JSON
-	columns := tableOutput.Table.StorageDescriptor.Columns
-	schema := map[string]interface{}{
-		"type": "struct",
-		"fields": []map[string]interface{}{},
+	var columns []*glue.Column
+	if table.StorageDescriptor != nil {
+		columns = table.StorageDescriptor.Columns
 	}
 	
-	fields := []map[string]interface{}{}
 	for _, col := range columns {
-		field := map[string]interface{}{
-			"name": *col.Name,
-			"type": *col.Type,
-		}
-		
-		if col.Comment != nil {
-			field["comment"] = *col.Comment
-		}
-		
-		fields = append(fields, field)
+		schema.Columns = append(schema.Columns, parseColumn(col))
 	}
 	
-	schema["fields"] = fields
+	for _, col := range table.PartitionKeys {
+		column := parseColumn(col)
+		column.PartitionKey = true
+		schema.Columns = append(schema.Columns, column)
+	}
 	
-	// Convert to JSON
-	schemaBytes, err := json.MarshalIndent(schema, "", "  ")
+	return schema, nil
+}
+
+// parseColumn types a Glue column. Types the parser doesn't know are kept
+// as they are, for the formats that can show them without a mapping.
+func parseColumn(col *glue.Column) *Column {
+	dataType, err := ParseType(aws.StringValue(col.Type))
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal schema to JSON: %w", err)
+		dataType = &DataType{Kind: KindUnknown, Name: aws.StringValue(col.Type)}
 	}
 	
-	return string(schemaBytes), nil
+	return &Column{
+		Name:    aws.StringValue(col.Name),
+		Type:    dataType,
+		Comment: aws.StringValue(col.Comment),
+	}
 }
@@ -0,0 +1,320 @@
+package catalog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// TypeKind classifies a Glue/Hive column type
+type TypeKind string
+
+const (
+	KindPrimitive TypeKind = "primitive"
+	KindDecimal   TypeKind = "decimal"
+	KindStruct    TypeKind = "struct"
+	KindArray     TypeKind = "array"
+	KindMap       TypeKind = "map"
+	// KindUnknown is a type the parser doesn't know, such as uniontype;
+	// Name holds the Glue type string as it is
+	KindUnknown TypeKind = "unknown"
+)
+
+// DataType is a parsed Glue/Hive column type such as
+// struct<amount:double,currency:string>
+type DataType struct {
+	Kind TypeKind `json:"kind"`
+
+	// Name is the lower-case primitive type name (string, bigint, varchar, ...)
+	Name   string `json:"name,omitempty"`
+	Length int    `json:"length,omitempty"` // char and varchar
+
+	Precision int `json:"precision,omitempty"` // decimal
+	Scale     int `json:"scale,omitempty"`     // decimal
+
+	Fields  []*StructField `json:"fields,omitempty"`  // struct
+	Element *DataType      `json:"element,omitempty"` // array
+	Key     *DataType      `json:"key,omitempty"`     // map
+	Value   *DataType      `json:"value,omitempty"`   // map
+}
+
+// StructField is a named member of a struct type
+type StructField struct {
+	Name string    `json:"name"`
+	Type *DataType `json:"type"`
+}
+
+// Column is a typed column of a data product table
+type Column struct {
+	Name         string    `json:"name"`
+	Type         *DataType `json:"type"`
+	Comment      string    `json:"comment,omitempty"`
+	PartitionKey bool      `json:"partition_key,omitempty"`
+}
+
+// TableSchema is the typed schema of a data product table
+type TableSchema struct {
+	Product     string    `json:"product"`
+	Domain      string    `json:"domain"`
+	Table       string    `json:"table"`
+	Description string    `json:"description,omitempty"`
+	Columns     []*Column `json:"columns"`
+}
+
+var primitiveTypes = map[string]bool{
+	"tinyint": true, "smallint": true, "int": true, "integer": true, "bigint": true,
+	"float": true, "double": true, "boolean": true, "string": true,
+	"varchar": true, "char": true, "binary": true, "date": true, "timestamp": true,
+}
+
+// ParseType parses a Glue/Hive type string into a type tree
+func ParseType(s string) (*DataType, error) {
+	p := &typeParser{input: s}
+	t, err := p.parseType()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos != len(p.input) {
+		return nil, p.errorf("unexpected %q", p.input[p.pos:])
+	}
+	return t, nil
+}
+
+// String returns the canonical Hive representation of the type
+func (t *DataType) String() string {
+	switch t.Kind {
+	case KindDecimal:
+		return fmt.Sprintf("decimal(%d,%d)", t.Precision, t.Scale)
+	case KindStruct:
+		parts := make([]string, len(t.Fields))
+		for i, f := range t.Fields {
+			parts[i] = quoteFieldName(f.Name) + ":" + f.Type.String()
+		}
+		return "struct<" + strings.Join(parts, ",") + ">"
+	case KindArray:
+		return "array<" + t.Element.String() + ">"
+	case KindMap:
+		return "map<" + t.Key.String() + "," + t.Value.String() + ">"
+	default:
+		if t.Length > 0 {
+			return fmt.Sprintf("%s(%d)", t.Name, t.Length)
+		}
+		return t.Name
+	}
+}
+
+// quoteFieldName backtick-quotes names that are not plain identifiers
+func quoteFieldName(name string) string {
+	for _, c := range name {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' {
+			return "`" + name + "`"
+		}
+	}
+	return name
+}
+
+type typeParser struct {
+	input string
+	pos   int
+}
+
+func (p *typeParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid type %q at position %d: %s", p.input, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *typeParser) skipSpace() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *typeParser) peek() byte {
+	p.skipSpace()
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *typeParser) expect(c byte) error {
+	if p.peek() != c {
+		if p.pos >= len(p.input) {
+			return p.errorf("expected %q, got end of input", c)
+		}
+		return p.errorf("expected %q, got %q", c, p.input[p.pos])
+	}
+	p.pos++
+	return nil
+}
+
+func (p *typeParser) ident() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.input) {
+		c := rune(p.input[p.pos])
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' {
+			break
+		}
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+func (p *typeParser) number() (int, error) {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.input) && p.input[p.pos] >= '0' && p.input[p.pos] <= '9' {
+		p.pos++
+	}
+	if start == p.pos {
+		return 0, p.errorf("expected a number")
+	}
+	return strconv.Atoi(p.input[start:p.pos])
+}
+
+func (p *typeParser) parseType() (*DataType, error) {
+	name := strings.ToLower(p.ident())
+	if name == "" {
+		return nil, p.errorf("expected a type name")
+	}
+
+	switch name {
+	case "array":
+		if err := p.expect('<'); err != nil {
+			return nil, err
+		}
+		elem, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		return &DataType{Kind: KindArray, Element: elem}, p.expect('>')
+
+	case "map":
+		if err := p.expect('<'); err != nil {
+			return nil, err
+		}
+		key, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(','); err != nil {
+			return nil, err
+		}
+		value, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		return &DataType{Kind: KindMap, Key: key, Value: value}, p.expect('>')
+
+	case "struct":
+		return p.parseStruct()
+
+	case "decimal", "numeric":
+		// Hive defaults to decimal(10,0)
+		t := &DataType{Kind: KindDecimal, Precision: 10}
+		if p.peek() == '(' {
+			p.pos++
+			var err error
+			if t.Precision, err = p.number(); err != nil {
+				return nil, err
+			}
+			if p.peek() == ',' {
+				p.pos++
+				if t.Scale, err = p.number(); err != nil {
+					return nil, err
+				}
+			}
+			if err := p.expect(')'); err != nil {
+				return nil, err
+			}
+		}
+		return t, nil
+	}
+
+	if !primitiveTypes[name] {
+		return nil, p.errorf("unknown type %q", name)
+	}
+	if name == "integer" {
+		name = "int"
+	}
+
+	t := &DataType{Kind: KindPrimitive, Name: name}
+	if (name == "varchar" || name == "char") && p.peek() == '(' {
+		p.pos++
+		var err error
+		if t.Length, err = p.number(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(')'); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+func (p *typeParser) parseStruct() (*DataType, error) {
+	if err := p.expect('<'); err != nil {
+		return nil, err
+	}
+
+	t := &DataType{Kind: KindStruct}
+	if p.peek() == '>' {
+		p.pos++
+		return t, nil
+	}
+
+	for {
+		name, err := p.fieldName()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(':'); err != nil {
+			return nil, err
+		}
+		fieldType, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		t.Fields = append(t.Fields, &StructField{Name: name, Type: fieldType})
+
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '>':
+			p.pos++
+			return t, nil
+		default:
+			return nil, p.errorf("expected ',' or '>' in struct")
+		}
+	}
+}
+
+// fieldName reads a struct field name, which may be quoted with backticks.
+// Crawled JSON data produces unquoted names such as first-name or @type,
+// so anything up to the ':' is accepted.
+func (p *typeParser) fieldName() (string, error) {
+	if p.peek() == '`' {
+		p.pos++
+		end := strings.IndexByte(p.input[p.pos:], '`')
+		if end < 0 {
+			return "", p.errorf("unterminated quoted field name")
+		}
+		name := p.input[p.pos : p.pos+end]
+		p.pos += end + 1
+		return name, nil
+	}
+
+	start := p.pos
+	end := strings.IndexAny(p.input[start:], ":,<>")
+	if end < 0 || p.input[start+end] != ':' {
+		return "", p.errorf("expected a field name followed by ':'")
+	}
+	name := strings.TrimSpace(p.input[start : start+end])
+	if name == "" {
+		return "", p.errorf("expected a field name")
+	}
+	p.pos = start + end
+	return name, nil
+}
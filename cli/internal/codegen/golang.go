@@ -0,0 +1,704 @@
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/avro"
+)
+
+// GoOptions controls Go code generation
+type GoOptions struct {
+	Package string // package clause of the generated file
+	Source  string // where the schema came from, recorded in the header
+	Schema  string // schema JSON embedded as a constant for producers
+}
+
+// GenerateGo generates Go types and binary Avro codecs for a record schema.
+//
+// Records become structs and enums become int32-based types with symbol
+// constants. Optional unions ([null, T]) become pointers, except for
+// slices, maps and bytes where nil stands for null. Other unions become
+// interface{} fields. timestamp-* and date logical types map to time.Time,
+// time-* to time.Duration and decimal to *big.Rat.
+func GenerateGo(schema *avro.Schema, opts GoOptions) ([]byte, error) {
+	if schema.Type != avro.Record {
+		return nil, fmt.Errorf("top-level schema must be a record, got %s", schema.Type)
+	}
+	if opts.Package == "" {
+		opts.Package = "events"
+	}
+
+	g := &goGen{typeNames: make(map[string]*avro.Schema)}
+	if err := g.collectTypes(schema); err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	for _, s := range g.types {
+		var err error
+		switch s.Type {
+		case avro.Enum:
+			g.writeEnum(&body, s)
+		case avro.Fixed:
+			g.writeFixed(&body, s)
+		case avro.Record:
+			err = g.writeRecord(&body, s, s == schema, opts)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	g.writeRuntime(&body)
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by dmesh schema codegen. DO NOT EDIT.\n")
+	if opts.Source != "" {
+		fmt.Fprintf(&out, "// Source: %s\n", opts.Source)
+	}
+	fmt.Fprintf(&out, "\npackage %s\n\n", opts.Package)
+	writeImports(&out, body.String())
+	out.Write(body.Bytes())
+
+	formatted, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated code does not compile: %w", err)
+	}
+	return formatted, nil
+}
+
+type goGen struct {
+	types     []*avro.Schema          // named types in definition order
+	typeNames map[string]*avro.Schema // Go type name -> schema
+	tmp       int
+
+	usesDecimal bool
+	usesMaps    bool
+}
+
+func (g *goGen) collectTypes(schema *avro.Schema) error {
+	var err error
+	avro.Walk(schema, func(s *avro.Schema) {
+		if err != nil || !s.IsNamed() {
+			return
+		}
+		name := goName(s.Name)
+		if other, ok := g.typeNames[name]; ok {
+			err = fmt.Errorf("types %s and %s both map to Go type %s", other.FullName(), s.FullName(), name)
+			return
+		}
+		g.typeNames[name] = s
+		g.types = append(g.types, s)
+	})
+	return err
+}
+
+// goType returns the Go type used for values of s
+func (g *goGen) goType(s *avro.Schema) (string, error) {
+	switch s.Type {
+	case avro.Null:
+		return "interface{}", nil
+	case avro.Boolean:
+		return "bool", nil
+	case avro.Int:
+		switch s.LogicalType {
+		case "date":
+			return "time.Time", nil
+		case "time-millis":
+			return "time.Duration", nil
+		}
+		return "int32", nil
+	case avro.Long:
+		switch s.LogicalType {
+		case "timestamp-millis", "timestamp-micros", "local-timestamp-millis", "local-timestamp-micros":
+			return "time.Time", nil
+		case "time-micros":
+			return "time.Duration", nil
+		}
+		return "int64", nil
+	case avro.Float:
+		return "float32", nil
+	case avro.Double:
+		return "float64", nil
+	case avro.Bytes:
+		if s.LogicalType == "decimal" {
+			g.usesDecimal = true
+			return "*big.Rat", nil
+		}
+		return "[]byte", nil
+	case avro.String:
+		return "string", nil
+	case avro.Fixed:
+		if s.LogicalType == "decimal" {
+			g.usesDecimal = true
+			return "*big.Rat", nil
+		}
+		return goName(s.Name), nil
+	case avro.Enum, avro.Record:
+		return goName(s.Name), nil
+	case avro.Array:
+		item, err := g.goType(s.Items)
+		return "[]" + item, err
+	case avro.Map:
+		g.usesMaps = true
+		value, err := g.goType(s.Values)
+		return "map[string]" + value, err
+	case avro.Union:
+		if inner := s.NonNull(); inner != nil {
+			t, err := g.goType(inner)
+			if err != nil || nilable(t) {
+				return t, err
+			}
+			return "*" + t, nil
+		}
+		return "interface{}", g.checkUnion(s)
+	}
+	return "", fmt.Errorf("unsupported Avro type %s", s.Type)
+}
+
+// checkUnion verifies that the branches of a general union can be told
+// apart by their Go type
+func (g *goGen) checkUnion(s *avro.Schema) error {
+	seen := make(map[string]bool)
+	for _, b := range s.Branches {
+		if b.Type == avro.Null {
+			continue
+		}
+		t, err := g.unionBranchType(b)
+		if err != nil {
+			return err
+		}
+		if seen[t] {
+			return fmt.Errorf("%s: union has more than one branch of Go type %s", s.Path, t)
+		}
+		seen[t] = true
+	}
+	return nil
+}
+
+// unionBranchType is the dynamic type stored in an interface{} union field
+func (g *goGen) unionBranchType(s *avro.Schema) (string, error) {
+	t, err := g.goType(s)
+	if err != nil {
+		return "", err
+	}
+	if s.Type == avro.Record {
+		return "*" + t, nil
+	}
+	return t, nil
+}
+
+func nilable(goType string) bool {
+	return strings.HasPrefix(goType, "[]") || strings.HasPrefix(goType, "map[") || strings.HasPrefix(goType, "*")
+}
+
+func (g *goGen) writeEnum(w *bytes.Buffer, s *avro.Schema) {
+	name := goName(s.Name)
+	symbolsVar := lowerFirst(name) + "Symbols"
+
+	writeDoc(w, name, fmt.Sprintf("is the Avro enum %s.", s.FullName()), s.Doc)
+	fmt.Fprintf(w, "type %s int32\n\n", name)
+
+	fmt.Fprintf(w, "const (\n")
+	for i, symbol := range s.Symbols {
+		if i == 0 {
+			fmt.Fprintf(w, "\t%s%s %s = iota\n", name, goName(symbol), name)
+		} else {
+			fmt.Fprintf(w, "\t%s%s\n", name, goName(symbol))
+		}
+	}
+	fmt.Fprintf(w, ")\n\n")
+
+	quoted := make([]string, len(s.Symbols))
+	for i, symbol := range s.Symbols {
+		quoted[i] = strconv.Quote(symbol)
+	}
+	fmt.Fprintf(w, "var %s = []string{%s}\n\n", symbolsVar, strings.Join(quoted, ", "))
+
+	fmt.Fprintf(w, `// String returns the Avro symbol of the enum value
+func (e %[1]s) String() string {
+	if e < 0 || int(e) >= len(%[2]s) {
+		return fmt.Sprintf("%[1]s(%%d)", int32(e))
+	}
+	return %[2]s[e]
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (e %[1]s) MarshalText() ([]byte, error) {
+	if e < 0 || int(e) >= len(%[2]s) {
+		return nil, fmt.Errorf("invalid %[1]s value %%d", int32(e))
+	}
+	return []byte(%[2]s[e]), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (e *%[1]s) UnmarshalText(text []byte) error {
+	v, err := Parse%[1]s(string(text))
+	if err != nil {
+		return err
+	}
+	*e = v
+	return nil
+}
+
+// Parse%[1]s returns the enum value for an Avro symbol
+func Parse%[1]s(symbol string) (%[1]s, error) {
+	for i, s := range %[2]s {
+		if s == symbol {
+			return %[1]s(i), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid %[1]s symbol %%q", symbol)
+}
+
+`, name, symbolsVar)
+}
+
+func (g *goGen) writeFixed(w *bytes.Buffer, s *avro.Schema) {
+	if s.LogicalType == "decimal" {
+		// Decimals are represented as *big.Rat rather than a named type
+		return
+	}
+	name := goName(s.Name)
+	writeDoc(w, name, fmt.Sprintf("is the Avro fixed type %s.", s.FullName()), s.Doc)
+	fmt.Fprintf(w, "type %s [%d]byte\n\n", name, s.Size)
+}
+
+func (g *goGen) writeRecord(w *bytes.Buffer, s *avro.Schema, topLevel bool, opts GoOptions) error {
+	name := goName(s.Name)
+
+	// Struct definition
+	writeDoc(w, name, fmt.Sprintf("is the Avro record %s.", s.FullName()), s.Doc)
+	fmt.Fprintf(w, "type %s struct {\n", name)
+	fieldNames := make(map[string]string)
+	for _, f := range s.Fields {
+		fieldName := goName(f.Name)
+		if other, ok := fieldNames[fieldName]; ok {
+			return fmt.Errorf("record %s: fields %s and %s both map to Go field %s", s.FullName(), other, f.Name, fieldName)
+		}
+		fieldNames[fieldName] = f.Name
+
+		t, err := g.goType(f.Type)
+		if err != nil {
+			return fmt.Errorf("record %s field %s: %w", s.FullName(), f.Name, err)
+		}
+		if f.Doc != "" {
+			fmt.Fprintf(w, "\t// %s\n", oneLine(f.Doc))
+		}
+		fmt.Fprintf(w, "\t%s %s `avro:%q json:%q`\n", fieldName, t, f.Name, f.Name)
+	}
+	fmt.Fprintf(w, "}\n\n")
+
+	if topLevel && opts.Schema != "" {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, []byte(opts.Schema)); err != nil {
+			return fmt.Errorf("invalid schema JSON: %w", err)
+		}
+		fmt.Fprintf(w, "// %sSchema is the Avro schema the code was generated from\n", name)
+		literal := "`" + compact.String() + "`"
+		if strings.Contains(compact.String(), "`") {
+			literal = strconv.Quote(compact.String())
+		}
+		fmt.Fprintf(w, "const %sSchema = %s\n\n", name, literal)
+	}
+
+	// Public codec methods
+	fmt.Fprintf(w, `// MarshalAvro encodes the record in Avro binary encoding
+func (r *%[1]s) MarshalAvro() ([]byte, error) {
+	e := &avroEncoder{}
+	r.encodeAvro(e)
+	return e.buf, e.err
+}
+
+// UnmarshalAvro decodes the record from Avro binary encoding
+func (r *%[1]s) UnmarshalAvro(data []byte) error {
+	d := &avroDecoder{buf: data}
+	*r = %[1]s{}
+	r.decodeAvro(d)
+	if d.err == nil && d.pos != len(d.buf) {
+		d.err = fmt.Errorf("%%d trailing bytes after %[1]s", len(d.buf)-d.pos)
+	}
+	return d.err
+}
+
+`, name)
+
+	if topLevel {
+		fmt.Fprintf(w, `// MarshalConfluent encodes the record with the Confluent wire-format
+// header (magic byte 0 followed by the big-endian schema ID)
+func (r *%[1]s) MarshalConfluent(schemaID int32) ([]byte, error) {
+	e := &avroEncoder{buf: make([]byte, 5, 256)}
+	binary.BigEndian.PutUint32(e.buf[1:5], uint32(schemaID))
+	r.encodeAvro(e)
+	return e.buf, e.err
+}
+
+// UnmarshalConfluent decodes a Confluent wire-format message and returns
+// the schema ID from its header
+func (r *%[1]s) UnmarshalConfluent(data []byte) (int32, error) {
+	if len(data) < 5 || data[0] != 0 {
+		return 0, errors.New("not a Confluent wire-format message")
+	}
+	schemaID := int32(binary.BigEndian.Uint32(data[1:5]))
+	return schemaID, r.UnmarshalAvro(data[5:])
+}
+
+`, name)
+	}
+
+	// Encoder
+	fmt.Fprintf(w, "func (r *%s) encodeAvro(e *avroEncoder) {\n", name)
+	for _, f := range s.Fields {
+		if err := g.writeEncode(w, f.Type, "r."+goName(f.Name), f.Name); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(w, "}\n\n")
+
+	// Decoder
+	fmt.Fprintf(w, "func (r *%s) decodeAvro(d *avroDecoder) {\n", name)
+	for _, f := range s.Fields {
+		if err := g.writeDecode(w, f.Type, "r."+goName(f.Name), f.Name); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(w, "}\n\n")
+
+	return nil
+}
+
+func (g *goGen) nextVar(prefix string) string {
+	g.tmp++
+	return fmt.Sprintf("%s%d", prefix, g.tmp)
+}
+
+// writeEncode writes statements that encode the Go expression expr
+func (g *goGen) writeEncode(w *bytes.Buffer, s *avro.Schema, expr, field string) error {
+	switch s.Type {
+	case avro.Null:
+	case avro.Boolean:
+		fmt.Fprintf(w, "e.writeBool(%s)\n", expr)
+	case avro.Int:
+		switch s.LogicalType {
+		case "date":
+			fmt.Fprintf(w, "e.writeLong(avroDays(%s))\n", expr)
+		case "time-millis":
+			fmt.Fprintf(w, "e.writeLong(int64(%s / time.Millisecond))\n", expr)
+		default:
+			fmt.Fprintf(w, "e.writeLong(int64(%s))\n", expr)
+		}
+	case avro.Long:
+		switch s.LogicalType {
+		case "timestamp-millis", "local-timestamp-millis":
+			fmt.Fprintf(w, "e.writeLong(%s.UnixMilli())\n", recv(expr))
+		case "timestamp-micros", "local-timestamp-micros":
+			fmt.Fprintf(w, "e.writeLong(%s.UnixMicro())\n", recv(expr))
+		case "time-micros":
+			fmt.Fprintf(w, "e.writeLong(int64(%s / time.Microsecond))\n", expr)
+		default:
+			fmt.Fprintf(w, "e.writeLong(%s)\n", expr)
+		}
+	case avro.Float:
+		fmt.Fprintf(w, "e.writeFloat(%s)\n", expr)
+	case avro.Double:
+		fmt.Fprintf(w, "e.writeDouble(%s)\n", expr)
+	case avro.Bytes:
+		if s.LogicalType == "decimal" {
+			fmt.Fprintf(w, "e.writeBytes(e.decimalBytes(%s, %d, 0, %q))\n", expr, s.Scale, field)
+		} else {
+			fmt.Fprintf(w, "e.writeBytes(%s)\n", expr)
+		}
+	case avro.String:
+		fmt.Fprintf(w, "e.writeString(%s)\n", expr)
+	case avro.Fixed:
+		if s.LogicalType == "decimal" {
+			fmt.Fprintf(w, "e.writeFixed(e.decimalBytes(%s, %d, %d, %q))\n", expr, s.Scale, s.Size, field)
+		} else {
+			fmt.Fprintf(w, "e.writeFixed(%s[:])\n", recv(expr))
+		}
+	case avro.Enum:
+		fmt.Fprintf(w, "e.writeLong(int64(%s))\n", expr)
+	case avro.Record:
+		fmt.Fprintf(w, "%s.encodeAvro(e)\n", recv(expr))
+	case avro.Array:
+		v := g.nextVar("v")
+		fmt.Fprintf(w, "if len(%s) > 0 {\ne.writeLong(int64(len(%s)))\nfor _, %s := range %s {\n", expr, expr, v, expr)
+		if err := g.writeEncode(w, s.Items, v, field); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "}\n}\ne.writeLong(0)\n")
+	case avro.Map:
+		k, v := g.nextVar("k"), g.nextVar("v")
+		fmt.Fprintf(w, "if len(%s) > 0 {\ne.writeLong(int64(len(%s)))\nfor _, %s := range avroSortedKeys(%s) {\n", expr, expr, k, expr)
+		fmt.Fprintf(w, "%s := %s[%s]\ne.writeString(%s)\n", v, expr, k, k)
+		if err := g.writeEncode(w, s.Values, v, field); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "}\n}\ne.writeLong(0)\n")
+	case avro.Union:
+		return g.writeUnionEncode(w, s, expr, field)
+	default:
+		return fmt.Errorf("field %s: unsupported Avro type %s", field, s.Type)
+	}
+	return nil
+}
+
+// recv parenthesizes a dereference used as a method receiver
+func recv(expr string) string {
+	if strings.HasPrefix(expr, "*") {
+		return "(" + expr + ")"
+	}
+	return expr
+}
+
+func (g *goGen) writeUnionEncode(w *bytes.Buffer, s *avro.Schema, expr, field string) error {
+	if inner := s.NonNull(); inner != nil {
+		nullIdx, valIdx := 0, 1
+		if s.Branches[1].Type == avro.Null {
+			nullIdx, valIdx = 1, 0
+		}
+		t, err := g.goType(inner)
+		if err != nil {
+			return err
+		}
+		value := expr
+		if !nilable(t) && inner.Type != avro.Record {
+			value = "*" + expr
+		}
+		fmt.Fprintf(w, "if %s == nil {\ne.writeLong(%d)\n} else {\ne.writeLong(%d)\n", expr, nullIdx, valIdx)
+		if err := g.writeEncode(w, inner, value, field); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "}\n")
+		return nil
+	}
+
+	v := g.nextVar("u")
+	fmt.Fprintf(w, "switch %s := %s.(type) {\n", v, expr)
+	for i, b := range s.Branches {
+		if b.Type == avro.Null {
+			fmt.Fprintf(w, "case nil:\ne.writeLong(%d)\n", i)
+			continue
+		}
+		t, err := g.unionBranchType(b)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "case %s:\ne.writeLong(%d)\n", t, i)
+		if err := g.writeEncode(w, b, v, field); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(w, "default:\ne.fail(fmt.Errorf(\"field %s: unsupported union value of type %%T\", %s))\n}\n", field, v)
+	return nil
+}
+
+// writeDecode writes statements that decode a value into the Go lvalue target
+func (g *goGen) writeDecode(w *bytes.Buffer, s *avro.Schema, target, field string) error {
+	switch s.Type {
+	case avro.Null:
+	case avro.Boolean:
+		fmt.Fprintf(w, "%s = d.readBool()\n", target)
+	case avro.Int:
+		switch s.LogicalType {
+		case "date":
+			fmt.Fprintf(w, "%s = avroDate(d.readLong())\n", target)
+		case "time-millis":
+			fmt.Fprintf(w, "%s = time.Duration(d.readLong()) * time.Millisecond\n", target)
+		default:
+			fmt.Fprintf(w, "%s = int32(d.readLong())\n", target)
+		}
+	case avro.Long:
+		switch s.LogicalType {
+		case "timestamp-millis", "local-timestamp-millis":
+			fmt.Fprintf(w, "%s = time.UnixMilli(d.readLong()).UTC()\n", target)
+		case "timestamp-micros", "local-timestamp-micros":
+			fmt.Fprintf(w, "%s = time.UnixMicro(d.readLong()).UTC()\n", target)
+		case "time-micros":
+			fmt.Fprintf(w, "%s = time.Duration(d.readLong()) * time.Microsecond\n", target)
+		default:
+			fmt.Fprintf(w, "%s = d.readLong()\n", target)
+		}
+	case avro.Float:
+		fmt.Fprintf(w, "%s = d.readFloat()\n", target)
+	case avro.Double:
+		fmt.Fprintf(w, "%s = d.readDouble()\n", target)
+	case avro.Bytes:
+		if s.LogicalType == "decimal" {
+			fmt.Fprintf(w, "%s = avroDecimal(d.readBytes(), %d)\n", target, s.Scale)
+		} else {
+			fmt.Fprintf(w, "%s = d.readBytes()\n", target)
+		}
+	case avro.String:
+		fmt.Fprintf(w, "%s = d.readString()\n", target)
+	case avro.Fixed:
+		if s.LogicalType == "decimal" {
+			fmt.Fprintf(w, "%s = avroDecimal(d.readFixed(%d), %d)\n", target, s.Size, s.Scale)
+		} else {
+			fmt.Fprintf(w, "copy(%s[:], d.readFixed(%d))\n", target, s.Size)
+		}
+	case avro.Enum:
+		fmt.Fprintf(w, "%s = %s(d.readEnum(%d, %q))\n", target, goName(s.Name), len(s.Symbols), field)
+	case avro.Record:
+		fmt.Fprintf(w, "%s.decodeAvro(d)\n", target)
+	case avro.Array:
+		t, err := g.goType(s.Items)
+		if err != nil {
+			return err
+		}
+		n, i, v := g.nextVar("n"), g.nextVar("i"), g.nextVar("v")
+		fmt.Fprintf(w, "%s = make([]%s, 0)\n", target, t)
+		fmt.Fprintf(w, "for %s := d.readBlockCount(); %s > 0; %s = d.readBlockCount() {\n", n, n, n)
+		fmt.Fprintf(w, "for %s := int64(0); %s < %s && d.err == nil; %s++ {\nvar %s %s\n", i, i, n, i, v, t)
+		if err := g.writeDecode(w, s.Items, v, field); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s = append(%s, %s)\n}\n}\n", target, target, v)
+	case avro.Map:
+		t, err := g.goType(s.Values)
+		if err != nil {
+			return err
+		}
+		n, i, k, v := g.nextVar("n"), g.nextVar("i"), g.nextVar("k"), g.nextVar("v")
+		fmt.Fprintf(w, "%s = make(map[string]%s)\n", target, t)
+		fmt.Fprintf(w, "for %s := d.readBlockCount(); %s > 0; %s = d.readBlockCount() {\n", n, n, n)
+		fmt.Fprintf(w, "for %s := int64(0); %s < %s && d.err == nil; %s++ {\n%s := d.readString()\nvar %s %s\n", i, i, n, i, k, v, t)
+		if err := g.writeDecode(w, s.Values, v, field); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s[%s] = %s\n}\n}\n", target, k, v)
+	case avro.Union:
+		return g.writeUnionDecode(w, s, target, field)
+	default:
+		return fmt.Errorf("field %s: unsupported Avro type %s", field, s.Type)
+	}
+	return nil
+}
+
+func (g *goGen) writeUnionDecode(w *bytes.Buffer, s *avro.Schema, target, field string) error {
+	optional := s.NonNull() != nil
+
+	fmt.Fprintf(w, "switch d.readLong() {\n")
+	for i, b := range s.Branches {
+		fmt.Fprintf(w, "case %d:\n", i)
+		if b.Type == avro.Null {
+			fmt.Fprintf(w, "%s = nil\n", target)
+			continue
+		}
+
+		t, err := g.goType(b)
+		if err != nil {
+			return err
+		}
+		v := g.nextVar("v")
+		fmt.Fprintf(w, "var %s %s\n", v, t)
+		if err := g.writeDecode(w, b, v, field); err != nil {
+			return err
+		}
+		switch {
+		case b.Type == avro.Record:
+			fmt.Fprintf(w, "%s = &%s\n", target, v)
+		case optional && !nilable(t):
+			fmt.Fprintf(w, "%s = &%s\n", target, v)
+		default:
+			fmt.Fprintf(w, "%s = %s\n", target, v)
+		}
+	}
+	fmt.Fprintf(w, "default:\nd.fail(errors.New(\"field %s: invalid union branch\"))\n}\n", field)
+	return nil
+}
+
+func (g *goGen) writeRuntime(w *bytes.Buffer) {
+	w.WriteString(runtimeBase)
+	if g.usesMaps {
+		w.WriteString(runtimeMaps)
+	}
+	if g.usesDecimal {
+		w.WriteString(runtimeDecimal)
+	}
+}
+
+// writeImports emits the imports referenced by the generated body
+func writeImports(w *bytes.Buffer, body string) {
+	candidates := []struct{ path, ident string }{
+		{"encoding/binary", "binary."},
+		{"errors", "errors."},
+		{"fmt", "fmt."},
+		{"math", "math."},
+		{"math/big", "big."},
+		{"sort", "sort."},
+		{"time", "time."},
+	}
+
+	var imports []string
+	for _, c := range candidates {
+		if strings.Contains(body, c.ident) {
+			imports = append(imports, c.path)
+		}
+	}
+	sort.Strings(imports)
+
+	w.WriteString("import (\n")
+	for _, imp := range imports {
+		fmt.Fprintf(w, "\t%q\n", imp)
+	}
+	w.WriteString(")\n\n")
+}
+
+func writeDoc(w *bytes.Buffer, name, summary, doc string) {
+	fmt.Fprintf(w, "// %s %s\n", name, summary)
+	if doc != "" {
+		fmt.Fprintf(w, "// %s\n", oneLine(doc))
+	}
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// commonInitialisms are kept upper case in generated identifiers
+var commonInitialisms = map[string]bool{
+	"API": true, "CUI": true, "HTTP": true, "ID": true, "IP": true, "JSON": true,
+	"SLA": true, "SQL": true, "URI": true, "URL": true, "UTC": true, "UUID": true,
+}
+
+// goName converts an Avro name (snake_case, UPPER_CASE or camelCase) to an
+// exported Go identifier
+func goName(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var b strings.Builder
+	for _, word := range words {
+		upper := strings.ToUpper(word)
+		if commonInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		if word == upper || word == strings.ToLower(word) {
+			word = strings.ToLower(word)
+		}
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+
+	result := b.String()
+	if result == "" || unicode.IsDigit([]rune(result)[0]) {
+		result = "X" + result
+	}
+	return result
+}
+
+func lowerFirst(s string) string {
+	runes := []rune(s)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
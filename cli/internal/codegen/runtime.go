@@ -0,0 +1,243 @@
+package codegen
+
+// Helpers emitted into every generated file so that the output has no
+// dependency on this module
+
+const runtimeBase = `
+// avroEncoder appends Avro binary encoded values to a buffer; the first
+// error is kept and later writes are ignored
+type avroEncoder struct {
+	buf []byte
+	err error
+}
+
+func (e *avroEncoder) fail(err error) {
+	if e.err == nil {
+		e.err = err
+	}
+}
+
+func (e *avroEncoder) writeLong(v int64) {
+	u := uint64((v << 1) ^ (v >> 63))
+	for u >= 0x80 {
+		e.buf = append(e.buf, byte(u)|0x80)
+		u >>= 7
+	}
+	e.buf = append(e.buf, byte(u))
+}
+
+func (e *avroEncoder) writeBool(v bool) {
+	if v {
+		e.buf = append(e.buf, 1)
+	} else {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+func (e *avroEncoder) writeFloat(v float32) {
+	e.buf = binary.LittleEndian.AppendUint32(e.buf, math.Float32bits(v))
+}
+
+func (e *avroEncoder) writeDouble(v float64) {
+	e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(v))
+}
+
+func (e *avroEncoder) writeBytes(v []byte) {
+	e.writeLong(int64(len(v)))
+	e.buf = append(e.buf, v...)
+}
+
+func (e *avroEncoder) writeString(v string) {
+	e.writeLong(int64(len(v)))
+	e.buf = append(e.buf, v...)
+}
+
+func (e *avroEncoder) writeFixed(v []byte) {
+	e.buf = append(e.buf, v...)
+}
+
+// avroDecoder reads Avro binary encoded values; after the first error all
+// reads return zero values
+type avroDecoder struct {
+	buf []byte
+	pos int
+	err error
+}
+
+func (d *avroDecoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+func (d *avroDecoder) readLong() int64 {
+	var u uint64
+	for shift := uint(0); d.err == nil; shift += 7 {
+		if d.pos >= len(d.buf) {
+			d.fail(errors.New("unexpected end of Avro data"))
+			return 0
+		}
+		if shift >= 64 {
+			d.fail(errors.New("Avro varint overflows a long"))
+			return 0
+		}
+		b := d.buf[d.pos]
+		d.pos++
+		u |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			break
+		}
+	}
+	return int64(u>>1) ^ -int64(u&1)
+}
+
+func (d *avroDecoder) readBool() bool {
+	b := d.readFixed(1)
+	return len(b) == 1 && b[0] != 0
+}
+
+func (d *avroDecoder) readFloat() float32 {
+	b := d.readFixed(4)
+	if len(b) < 4 {
+		return 0
+	}
+	return math.Float32frombits(binary.LittleEndian.Uint32(b))
+}
+
+func (d *avroDecoder) readDouble() float64 {
+	b := d.readFixed(8)
+	if len(b) < 8 {
+		return 0
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(b))
+}
+
+func (d *avroDecoder) readFixed(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(d.buf)-d.pos {
+		d.fail(errors.New("unexpected end of Avro data"))
+		return nil
+	}
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return b
+}
+
+func (d *avroDecoder) readBytes() []byte {
+	n := d.readLong()
+	if n < 0 || n > int64(len(d.buf)-d.pos) {
+		d.fail(errors.New("invalid Avro bytes length"))
+		return nil
+	}
+	return append([]byte(nil), d.readFixed(int(n))...)
+}
+
+func (d *avroDecoder) readString() string {
+	n := d.readLong()
+	if n < 0 || n > int64(len(d.buf)-d.pos) {
+		d.fail(errors.New("invalid Avro string length"))
+		return ""
+	}
+	return string(d.readFixed(int(n)))
+}
+
+func (d *avroDecoder) readEnum(symbols int, field string) int32 {
+	i := d.readLong()
+	if i < 0 || i >= int64(symbols) {
+		d.fail(fmt.Errorf("field %s: invalid enum index %d", field, i))
+		return 0
+	}
+	return int32(i)
+}
+
+// readBlockCount returns the item count of the next array or map block;
+// negative counts are followed by the block size in bytes
+func (d *avroDecoder) readBlockCount() int64 {
+	n := d.readLong()
+	if n < 0 {
+		n = -n
+		d.readLong()
+	}
+	if d.err != nil {
+		return 0
+	}
+	return n
+}
+
+func avroDays(t time.Time) int64 {
+	y, m, day := t.Date()
+	return time.Date(y, m, day, 0, 0, 0, 0, time.UTC).Unix() / 86400
+}
+
+func avroDate(days int64) time.Time {
+	return time.Unix(days*86400, 0).UTC()
+}
+`
+
+const runtimeMaps = `
+// avroSortedKeys makes map encoding deterministic
+func avroSortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+`
+
+const runtimeDecimal = `
+// decimalBytes returns the big-endian two's-complement unscaled value of
+// r at the given scale, sign-extended to size bytes when size > 0
+func (e *avroEncoder) decimalBytes(r *big.Rat, scale, size int, field string) []byte {
+	if r == nil {
+		e.fail(fmt.Errorf("field %s: nil decimal", field))
+		return make([]byte, size)
+	}
+	scaled := new(big.Rat).Mul(r, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)))
+	if !scaled.IsInt() {
+		e.fail(fmt.Errorf("field %s: %s does not fit scale %d", field, r.RatString(), scale))
+		return make([]byte, size)
+	}
+	unscaled := scaled.Num()
+
+	var b []byte
+	if unscaled.Sign() >= 0 {
+		b = unscaled.Bytes()
+		if len(b) == 0 || b[0]&0x80 != 0 {
+			b = append([]byte{0}, b...)
+		}
+	} else {
+		// Two's complement of a negative value: 2^(8n) + v
+		n := (unscaled.BitLen() + 8) / 8
+		mod := new(big.Int).Lsh(big.NewInt(1), uint(8*n))
+		b = new(big.Int).Add(mod, unscaled).Bytes()
+	}
+
+	if size > 0 {
+		if len(b) > size {
+			e.fail(fmt.Errorf("field %s: decimal does not fit in %d bytes", field, size))
+			return make([]byte, size)
+		}
+		pad := byte(0)
+		if unscaled.Sign() < 0 {
+			pad = 0xff
+		}
+		for len(b) < size {
+			b = append([]byte{pad}, b...)
+		}
+	}
+	return b
+}
+
+func avroDecimal(b []byte, scale int) *big.Rat {
+	unscaled := new(big.Int).SetBytes(b)
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		unscaled.Sub(unscaled, new(big.Int).Lsh(big.NewInt(1), uint(8*len(b))))
+	}
+	denom := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)
+	return new(big.Rat).SetFrac(unscaled, denom)
+}
+`
@@ -17,23 +17,72 @@ type Config struct {
 	CatalogURL    string `mapstructure:"catalog_url"`
 	S3DataLake    string `mapstructure:"s3_data_lake"`
 	SchemaRegistry string `mapstructure:"schema_registry_url"`
+	ManifestDir   string `mapstructure:"manifest_dir"`
+	
+	// Kafka connection; KafkaAuth is one of none, plain, scram-sha-256,
+	// scram-sha-512 or aws-msk-iam
+	KafkaBrokers  string `mapstructure:"kafka_brokers"`
+	KafkaAuth     string `mapstructure:"kafka_auth"`
+	KafkaUsername string `mapstructure:"kafka_username"`
+	KafkaPassword string `mapstructure:"kafka_password"`
+	KafkaTLS      bool   `mapstructure:"kafka_tls"`
+	
+	// Local cache of product data; CacheTTL is how long a pulled product
+	// is used instead of S3 and CacheRetention when it is deleted
+	CacheDir       string `mapstructure:"cache_dir"`
+	CacheTTL       string `mapstructure:"cache_ttl"`
+	CacheRetention string `mapstructure:"cache_retention"`
+	
+	// Key that local files are encrypted with: keyring (the OS keyring),
+	// kms (a data key under KMSKeyID) or file (a key file in the config
+	// directory, for hosts without a keyring)
+	EncryptionKey string `mapstructure:"encryption_key"`
+	KMSKeyID      string `mapstructure:"kms_key_id"`
+	
+	// Rendering of query results: NullDisplay is shown for NULLs in tables,
+	// TimestampFormat is a Go time layout or rfc3339, TimeZone is where
+	// timestamps are shown (UTC, Local or an IANA name) and DecimalPlaces
+	// the digits after the point, -1 for all
+	NullDisplay     string `mapstructure:"null_display"`
+	TimestampFormat string `mapstructure:"timestamp_format"`
+	TimeZone        string `mapstructure:"time_zone"`
+	DecimalPlaces   int    `mapstructure:"decimal_places"`
+	
+	// Number of queries kept in the query TUI's history, 0 to keep none
+	QueryHistorySize int `mapstructure:"query_history_size"`
+	
+	// Highest classification of results the query TUI copies to the
+	// clipboard
+	ClipboardClassification string `mapstructure:"clipboard_classification"`
+}
+
+// Dir returns the directory holding the configuration and local state
+func Dir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not find home directory: %w", err)
+	}
+	return filepath.Join(home, ".fedramp-data-mesh"), nil
 }
 
 // LoadConfig loads the configuration from the config file and environment variables
 func LoadConfig() (*Config, error) {
-	// Find home directory
-	home, err := os.UserHomeDir()
+	// Find config directory in home directory
+	configDir, err := Dir()
 	if err != nil {
-		return nil, fmt.Errorf("could not find home directory: %w", err)
+		return nil, err
 	}
 
-	// Create config directory if it doesn't exist
-	configDir := filepath.Join(home, ".fedramp-data-mesh")
+	// Create config directory if it doesn't exist; it holds credentials,
+	// logs and cached data, so only the user may access it
 	if _, err := os.Stat(configDir); os.IsNotExist(err) {
-		if err := os.MkdirAll(configDir, 0755); err != nil {
+		if err := os.MkdirAll(configDir, 0700); err != nil {
 			return nil, fmt.Errorf("could not create config directory: %w", err)
 		}
 	}
+	if err := os.Chmod(configDir, 0700); err != nil {
+		return nil, fmt.Errorf("could not restrict config directory: %w", err)
+	}
 
 	configName := "config"
 	configType := "yaml"
@@ -43,6 +92,7 @@ func LoadConfig() (*Config, error) {
 	viper.SetConfigName(configName)
 	viper.SetConfigType(configType)
 	viper.AddConfigPath(configPath)
+	viper.SetConfigPermissions(0600)
 
 	// Set default values
 	viper.SetDefault("aws_region", "us-east-1")
@@ -52,6 +102,23 @@ func LoadConfig() (*Config, error) {
 	viper.SetDefault("catalog_url", "")
 	viper.SetDefault("s3_data_lake", "")
 	viper.SetDefault("schema_registry_url", "")
+	viper.SetDefault("manifest_dir", ".")
+	viper.SetDefault("kafka_brokers", "")
+	viper.SetDefault("kafka_auth", "none")
+	viper.SetDefault("kafka_username", "")
+	viper.SetDefault("kafka_password", "")
+	viper.SetDefault("kafka_tls", false)
+	viper.SetDefault("cache_dir", "")
+	viper.SetDefault("cache_ttl", "1h")
+	viper.SetDefault("cache_retention", "7d")
+	viper.SetDefault("encryption_key", "keyring")
+	viper.SetDefault("kms_key_id", "")
+	viper.SetDefault("null_display", "NULL")
+	viper.SetDefault("timestamp_format", "")
+	viper.SetDefault("time_zone", "UTC")
+	viper.SetDefault("decimal_places", -1)
+	viper.SetDefault("query_history_size", 1000)
+	viper.SetDefault("clipboard_classification", "CONTROLLED_UNCLASSIFIED")
 
 	// Read from environment variables
 	viper.AutomaticEnv()
@@ -64,6 +131,9 @@ func LoadConfig() (*Config, error) {
 			return nil, fmt.Errorf("could not write default config file: %w", err)
 		}
 	}
+	if err := os.Chmod(configFile, 0600); err != nil {
+		return nil, fmt.Errorf("could not restrict config file: %w", err)
+	}
 
 	// Read the config file
 	if err := viper.ReadInConfig(); err != nil {
@@ -89,6 +159,23 @@ func SaveConfig(config *Config) error {
 	viper.Set("catalog_url", config.CatalogURL)
 	viper.Set("s3_data_lake", config.S3DataLake)
 	viper.Set("schema_registry_url", config.SchemaRegistry)
+	viper.Set("manifest_dir", config.ManifestDir)
+	viper.Set("kafka_brokers", config.KafkaBrokers)
+	viper.Set("kafka_auth", config.KafkaAuth)
+	viper.Set("kafka_username", config.KafkaUsername)
+	viper.Set("kafka_password", config.KafkaPassword)
+	viper.Set("kafka_tls", config.KafkaTLS)
+	viper.Set("cache_dir", config.CacheDir)
+	viper.Set("cache_ttl", config.CacheTTL)
+	viper.Set("cache_retention", config.CacheRetention)
+	viper.Set("encryption_key", config.EncryptionKey)
+	viper.Set("kms_key_id", config.KMSKeyID)
+	viper.Set("null_display", config.NullDisplay)
+	viper.Set("timestamp_format", config.TimestampFormat)
+	viper.Set("time_zone", config.TimeZone)
+	viper.Set("decimal_places", config.DecimalPlaces)
+	viper.Set("query_history_size", config.QueryHistorySize)
+	viper.Set("clipboard_classification", config.ClipboardClassification)
 
 	// Write the config file
 	if err := viper.WriteConfig(); err != nil {
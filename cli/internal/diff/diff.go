@@ -0,0 +1,367 @@
+package diff
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/duckdb"
+	godb "github.com/marcboeker/go-duckdb"
+)
+
+// Kinds of row changes
+const (
+	Inserted = "inserted"
+	Deleted  = "deleted"
+	Updated  = "updated"
+)
+
+// changesTable holds the changed rows after Compare, for export
+const changesTable = "__dmesh_diff"
+
+// DB is the part of duckdb.Connection the comparison needs
+type DB interface {
+	ExecuteQuery(query string) (*duckdb.QueryResult, error)
+}
+
+// Result summarizes the differences between two versions of a table
+type Result struct {
+	From     string   `json:"from"`
+	To       string   `json:"to"`
+	Key      []string `json:"key"`
+	FromRows int64    `json:"from_rows"`
+	ToRows   int64    `json:"to_rows"`
+	Inserted int64    `json:"inserted"`
+	Deleted  int64    `json:"deleted"`
+	Updated  int64    `json:"updated"`
+	// Columns counts the updated rows per changed column
+	Columns []ColumnChanges `json:"columns,omitempty"`
+	// AddedColumns and RemovedColumns are schema differences; they are
+	// not compared
+	AddedColumns   []string `json:"added_columns,omitempty"`
+	RemovedColumns []string `json:"removed_columns,omitempty"`
+	// Samples are some of the changed rows
+	Samples []Change `json:"samples,omitempty"`
+}
+
+// ColumnChanges counts the updated rows in which a column changed
+type ColumnChanges struct {
+	Column string `json:"column"`
+	Rows   int64  `json:"rows"`
+}
+
+// Change is a changed row: its key and, for updates, the changed values
+type Change struct {
+	Kind    string        `json:"kind"`
+	Key     []interface{} `json:"key"`
+	Changes []ValueChange `json:"changes,omitempty"`
+}
+
+// ValueChange is a column value before and after an update
+type ValueChange struct {
+	Column string      `json:"column"`
+	From   interface{} `json:"from"`
+	To     interface{} `json:"to"`
+}
+
+// Options control a comparison
+type Options struct {
+	// Samples is the number of changed rows kept in the result
+	Samples int
+}
+
+// Compare compares two registered views row by row on a key. Columns in
+// both views are compared with IS DISTINCT FROM, so nulls compare equal.
+// The changed rows are kept in a table for Export.
+func Compare(db DB, from, to string, key []string, opts Options) (*Result, error) {
+	if len(key) == 0 {
+		return nil, fmt.Errorf("a key is required to match rows")
+	}
+
+	fromColumns, err := columns(db, from)
+	if err != nil {
+		return nil, err
+	}
+	toColumns, err := columns(db, to)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{From: from, To: to, Key: key}
+	keys := make(map[string]bool, len(key))
+	for _, k := range key {
+		if !contains(fromColumns, k) || !contains(toColumns, k) {
+			return nil, fmt.Errorf("key column %s is not in both snapshots", k)
+		}
+		keys[k] = true
+	}
+
+	// Compare the columns both versions have
+	var compared []string
+	for _, c := range toColumns {
+		if !contains(fromColumns, c) {
+			result.AddedColumns = append(result.AddedColumns, c)
+		} else if !keys[c] {
+			compared = append(compared, c)
+		}
+	}
+	for _, c := range fromColumns {
+		if !contains(toColumns, c) {
+			result.RemovedColumns = append(result.RemovedColumns, c)
+		}
+	}
+
+	// Matching needs a unique key
+	for _, view := range []string{from, to} {
+		n, err := queryInt(db, fmt.Sprintf("SELECT count(*) FROM (SELECT 1 FROM %s GROUP BY %s HAVING count(*) > 1)",
+			quoteIdentifier(view), columnList(key)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to check key of %s: %w", view, err)
+		}
+		if n > 0 {
+			return nil, fmt.Errorf("key (%s) is not unique in %s: %d duplicated keys", strings.Join(key, ", "), view, n)
+		}
+	}
+
+	if result.FromRows, err = queryInt(db, "SELECT count(*) FROM "+quoteIdentifier(from)); err != nil {
+		return nil, err
+	}
+	if result.ToRows, err = queryInt(db, "SELECT count(*) FROM "+quoteIdentifier(to)); err != nil {
+		return nil, err
+	}
+
+	if _, err := db.ExecuteQuery(changesQuery(from, to, key, compared)); err != nil {
+		return nil, fmt.Errorf("failed to compare snapshots: %w", err)
+	}
+
+	// Counts per kind of change
+	counts, err := db.ExecuteQuery(fmt.Sprintf("SELECT change, count(*) FROM %s GROUP BY 1", quoteIdentifier(changesTable)))
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range counts.Rows {
+		n := toInt(row[1])
+		switch row[0] {
+		case Inserted:
+			result.Inserted = n
+		case Deleted:
+			result.Deleted = n
+		case Updated:
+			result.Updated = n
+		}
+	}
+
+	// Updated rows per column
+	if result.Updated > 0 {
+		perColumn, err := db.ExecuteQuery(fmt.Sprintf(
+			"SELECT c, count(*) AS n FROM (SELECT unnest(changed_columns) AS c FROM %s WHERE change = '%s') GROUP BY c ORDER BY n DESC, c",
+			quoteIdentifier(changesTable), Updated))
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range perColumn.Rows {
+			result.Columns = append(result.Columns, ColumnChanges{Column: fmt.Sprint(row[0]), Rows: toInt(row[1])})
+		}
+	}
+
+	if opts.Samples > 0 {
+		if result.Samples, err = samples(db, key, opts.Samples); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// changesQuery builds the table of changed rows: the key, the kind of
+// change, the changed columns and the values of each compared column in
+// both versions as from_<column> and to_<column>
+func changesQuery(from, to string, key, compared []string) string {
+	var join, selectKey []string
+	for _, k := range key {
+		c := quoteIdentifier(k)
+		join = append(join, fmt.Sprintf("f.%s IS NOT DISTINCT FROM t.%s", c, c))
+		selectKey = append(selectKey, fmt.Sprintf("coalesce(t.%s, f.%s) AS %s", c, c, c))
+	}
+
+	var changed, values []string
+	for _, name := range compared {
+		c := quoteIdentifier(name)
+		changed = append(changed, fmt.Sprintf("CASE WHEN f.%s IS DISTINCT FROM t.%s THEN %s END", c, c, quoteString(name)))
+		values = append(values,
+			fmt.Sprintf("f.%s AS %s", c, quoteIdentifier("from_"+name)),
+			fmt.Sprintf("t.%s AS %s", c, quoteIdentifier("to_"+name)))
+	}
+	changedList := "[]::VARCHAR[]"
+	if len(changed) > 0 {
+		changedList = fmt.Sprintf("list_filter([%s], x -> x IS NOT NULL)", strings.Join(changed, ", "))
+	}
+
+	columns := append(selectKey,
+		fmt.Sprintf("CASE WHEN f.__dmesh_present IS NULL THEN '%s' WHEN t.__dmesh_present IS NULL THEN '%s' ELSE '%s' END AS change",
+			Inserted, Deleted, Updated),
+		fmt.Sprintf("CASE WHEN f.__dmesh_present IS NOT NULL AND t.__dmesh_present IS NOT NULL THEN %s ELSE []::VARCHAR[] END AS changed_columns", changedList))
+	columns = append(columns, values...)
+
+	return fmt.Sprintf(`CREATE OR REPLACE TABLE %s AS
+SELECT * FROM (
+  SELECT %s
+  FROM (SELECT *, true AS __dmesh_present FROM %s) f
+  FULL OUTER JOIN (SELECT *, true AS __dmesh_present FROM %s) t ON %s
+) WHERE change <> '%s' OR len(changed_columns) > 0`,
+		quoteIdentifier(changesTable), strings.Join(columns, ",\n    "),
+		quoteIdentifier(from), quoteIdentifier(to), strings.Join(join, " AND "), Updated)
+}
+
+// samples reads some changed rows, updates first
+func samples(db DB, key []string, limit int) ([]Change, error) {
+	result, err := db.ExecuteQuery(fmt.Sprintf("SELECT * FROM %s ORDER BY change = '%s' DESC, change, %s LIMIT %d",
+		quoteIdentifier(changesTable), Updated, columnList(key), limit))
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]int, len(result.Columns))
+	for i, c := range result.Columns {
+		index[c] = i
+	}
+
+	var changes []Change
+	for _, row := range result.Rows {
+		change := Change{Kind: fmt.Sprint(row[index["change"]])}
+		for _, k := range key {
+			change.Key = append(change.Key, value(row[index[k]]))
+		}
+		if change.Kind == Updated {
+			changed, _ := row[index["changed_columns"]].([]interface{})
+			for _, c := range changed {
+				name := fmt.Sprint(c)
+				change.Changes = append(change.Changes, ValueChange{
+					Column: name,
+					From:   value(row[index["from_"+name]]),
+					To:     value(row[index["to_"+name]]),
+				})
+			}
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// Export writes the changed rows of the last comparison to a CSV or
+// Parquet file
+func Export(db DB, key []string, path, format string) error {
+	var options string
+	switch strings.ToLower(format) {
+	case "csv":
+		options = "FORMAT csv, HEADER"
+	case "parquet":
+		options = "FORMAT parquet"
+	default:
+		return fmt.Errorf("unsupported export format: %s", format)
+	}
+
+	_, err := db.ExecuteQuery(fmt.Sprintf("COPY (SELECT * FROM %s ORDER BY change, %s) TO %s (%s)",
+		quoteIdentifier(changesTable), columnList(key), quoteString(path), options))
+	if err != nil {
+		return fmt.Errorf("failed to export changes: %w", err)
+	}
+	return nil
+}
+
+// columns lists the columns of a view
+func columns(db DB, view string) ([]string, error) {
+	result, err := db.ExecuteQuery("DESCRIBE SELECT * FROM " + quoteIdentifier(view))
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe %s: %w", view, err)
+	}
+	var names []string
+	for _, row := range result.Rows {
+		names = append(names, fmt.Sprint(row[0]))
+	}
+	return names, nil
+}
+
+func queryInt(db DB, query string) (int64, error) {
+	result, err := db.ExecuteQuery(query)
+	if err != nil {
+		return 0, err
+	}
+	if len(result.Rows) != 1 || len(result.Rows[0]) != 1 {
+		return 0, fmt.Errorf("unexpected result of %s", query)
+	}
+	return toInt(result.Rows[0][0]), nil
+}
+
+func toInt(v interface{}) int64 {
+	switch v := v.(type) {
+	case int64:
+		return v
+	case int32:
+		return int64(v)
+	case uint64:
+		return int64(v)
+	case *big.Int:
+		return v.Int64()
+	}
+	return 0
+}
+
+// value converts values to types that encode naturally as JSON and text
+func value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case []byte:
+		return string(v)
+	case *big.Int:
+		return v.String()
+	case godb.Decimal:
+		scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(v.Scale)), nil)
+		return new(big.Rat).SetFrac(v.Value, scale).FloatString(int(v.Scale))
+	case godb.Map:
+		entries := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			entries[fmt.Sprint(k)] = value(e)
+		}
+		return entries
+	case map[string]interface{}:
+		fields := make(map[string]interface{}, len(v))
+		for k, f := range v {
+			fields[k] = value(f)
+		}
+		return fields
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = value(item)
+		}
+		return items
+	}
+	return v
+}
+
+func columnList(names []string) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = quoteIdentifier(n)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func quoteString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,88 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// WriteReport writes a comparison in the given format (text, json)
+func WriteReport(w io.Writer, result *Result, format string) error {
+	switch strings.ToLower(format) {
+	case "text", "":
+		return writeText(w, result)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	default:
+		return fmt.Errorf("unsupported diff format: %s", format)
+	}
+}
+
+func writeText(w io.Writer, r *Result) error {
+	fmt.Fprintf(w, "Comparing %s (%d rows) to %s (%d rows) on %s\n\n", r.From, r.FromRows, r.To, r.ToRows, strings.Join(r.Key, ", "))
+	fmt.Fprintf(w, "  Inserted: %d\n  Deleted:  %d\n  Updated:  %d\n", r.Inserted, r.Deleted, r.Updated)
+
+	if len(r.AddedColumns) > 0 {
+		fmt.Fprintf(w, "\nAdded columns (not compared): %s\n", strings.Join(r.AddedColumns, ", "))
+	}
+	if len(r.RemovedColumns) > 0 {
+		fmt.Fprintf(w, "\nRemoved columns (not compared): %s\n", strings.Join(r.RemovedColumns, ", "))
+	}
+
+	if len(r.Columns) > 0 {
+		fmt.Fprintln(w, "\nChanged columns:")
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, c := range r.Columns {
+			fmt.Fprintf(tw, "  %s\t%d rows\n", c.Column, c.Rows)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(r.Samples) > 0 {
+		fmt.Fprintln(w, "\nSample changes:")
+		for _, c := range r.Samples {
+			fmt.Fprintf(w, "  %-8s %s\n", c.Kind, keyText(r.Key, c.Key))
+			for _, v := range c.Changes {
+				fmt.Fprintf(w, "           %s: %s → %s\n", v.Column, valueText(v.From), valueText(v.To))
+			}
+		}
+	}
+	return nil
+}
+
+func keyText(names []string, values []interface{}) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = names[i] + "=" + valueText(v)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func valueText(v interface{}) string {
+	var s string
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		s = v
+	case []interface{}, map[string]interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		s = string(data)
+	default:
+		s = fmt.Sprint(v)
+	}
+	s = strings.ReplaceAll(s, "\n", " ")
+	if r := []rune(s); len(r) > 60 {
+		s = string(r[:59]) + "…"
+	}
+	return s
+}
@@ -1,12 +1,14 @@
 package duckdb
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
 
 	_ "github.com/marcboeker/go-duckdb"
 	"github.com/frocore/fedramp-data-mesh/cli/internal/config"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/lakehouse"
 	"github.com/frocore/fedramp-data-mesh/cli/internal/security"
 )
 
@@ -18,7 +20,9 @@ type Connection struct {
 
 type QueryResult struct {
 	Columns []string
-	Rows    [][]interface{}
+	// ColumnTypes are the DuckDB types of the columns, see ParseType
+	ColumnTypes []string
+	Rows        [][]interface{}
 }
 
 func NewConnection(cfg *config.Config, secCtx *security.SecurityContext) (*Connection, error) {
@@ -38,11 +42,11 @@ func NewConnection(cfg *config.Config, secCtx *security.SecurityContext) (*Conne
 	_, err = db.Exec(fmt.Sprintf(`
 		INSTALL httpfs;
 		LOAD httpfs;
-		SET s3_region='%s';
-		SET s3_access_key_id='%s';
-		SET s3_secret_access_key='%s';
-		SET s3_session_token='%s';
-	`, cfg.AWSRegion, awsAccessKey, awsSecretKey, awsSessionToken))
+		SET s3_region=%s;
+		SET s3_access_key_id=%s;
+		SET s3_secret_access_key=%s;
+		SET s3_session_token=%s;
+	`, quoteString(cfg.AWSRegion), quoteString(awsAccessKey), quoteString(awsSecretKey), quoteString(awsSessionToken)))
 	
 	if err != nil {
 		db.Close()
@@ -56,37 +60,266 @@ func NewConnection(cfg *config.Config, secCtx *security.SecurityContext) (*Conne
 	}, nil
 }
 
+// NewLocalConnection opens an in-memory DuckDB connection without S3
+// access, for data that is already local such as cached products
+func NewLocalConnection(cfg *config.Config, secCtx *security.SecurityContext) (*Connection, error) {
+	db, err := sql.Open("duckdb", "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DuckDB connection: %w", err)
+	}
+	
+	return &Connection{
+		db:     db,
+		secCtx: secCtx,
+		cfg:    cfg,
+	}, nil
+}
+
 func (c *Connection) Close() error {
 	return c.db.Close()
 }
 
 func (c *Connection) RegisterDataProduct(name, path string) error {
+	if err := c.createSchema(name); err != nil {
+		return err
+	}
+	
 	// Determine if this is Iceberg, Delta, or plain Parquet
 	if strings.Contains(path, "iceberg") {
 		// Iceberg table
 		_, err := c.db.Exec(fmt.Sprintf(`
 			INSTALL iceberg;
 			LOAD iceberg;
-			CREATE VIEW %s AS SELECT * FROM iceberg_scan('%s');
-		`, name, path))
+			CREATE VIEW %s AS SELECT * FROM iceberg_scan(%s);
+		`, QuoteName(name), quoteString(path)))
 		return err
 	} else if strings.Contains(path, "delta") {
 		// Delta Lake table
 		_, err := c.db.Exec(fmt.Sprintf(`
 			INSTALL delta;
 			LOAD delta;
-			CREATE VIEW %s AS SELECT * FROM delta_scan('%s');
-		`, name, path))
+			CREATE VIEW %s AS SELECT * FROM delta_scan(%s);
+		`, QuoteName(name), quoteString(path)))
 		return err
 	} else {
 		// Plain Parquet files
 		_, err := c.db.Exec(fmt.Sprintf(`
-			CREATE VIEW %s AS SELECT * FROM parquet_scan('%s/*.parquet');
-		`, name, path))
+			CREATE VIEW %s AS SELECT * FROM parquet_scan(%s);
+		`, QuoteName(name), quoteString(path+"/*.parquet")))
+		return err
+	}
+}
+
+// UnregisterDataProduct drops the view of a registered data product
+func (c *Connection) UnregisterDataProduct(name string) error {
+	if _, err := c.db.Exec(fmt.Sprintf("DROP VIEW IF EXISTS %s", QuoteName(name))); err != nil {
+		return fmt.Errorf("failed to unregister %s: %w", name, err)
+	}
+	return nil
+}
+
+// RegisterDataProductAsOf registers a data product like RegisterDataProduct,
+// pinned to a snapshot: Iceberg tables are scanned at the snapshot ID and
+// Delta versions as the Parquet files that made up the version
+func (c *Connection) RegisterDataProductAsOf(name string, version *lakehouse.TableVersion) error {
+	if err := c.createSchema(name); err != nil {
+		return err
+	}
+	
+	switch version.Format {
+	case "iceberg":
+		_, err := c.db.Exec(fmt.Sprintf(`
+			INSTALL iceberg;
+			LOAD iceberg;
+			CREATE VIEW %s AS SELECT * FROM iceberg_scan(%s, %d::UBIGINT);
+		`, QuoteName(name), quoteString(version.Location), version.Snapshot.ID))
+		return err
+	case "delta":
+		if len(version.DataFiles) == 0 {
+			return fmt.Errorf("delta version %d of %s has no data files", version.Snapshot.ID, name)
+		}
+		
+		files := make([]string, len(version.DataFiles))
+		for i, f := range version.DataFiles {
+			files[i] = quoteString(f)
+		}
+		
+		// Partition values are only encoded in the file paths
+		_, err := c.db.Exec(fmt.Sprintf(`
+			CREATE VIEW %s AS SELECT * FROM parquet_scan([%s], hive_partitioning = %t);
+		`, QuoteName(name), strings.Join(files, ", "), len(version.PartitionColumns) > 0))
 		return err
+	default:
+		return fmt.Errorf("time travel is not supported for %s tables", version.Format)
 	}
 }
 
+// RegisterTable creates a view over a table whose format is known, such as
+// a table declared in a data product manifest. For Iceberg the location
+// may also be a metadata file.
+func (c *Connection) RegisterTable(name, format, location string) error {
+	var err error
+	switch strings.ToLower(format) {
+	case "iceberg":
+		_, err = c.db.Exec(fmt.Sprintf(`
+			INSTALL iceberg;
+			LOAD iceberg;
+			CREATE OR REPLACE VIEW %s AS SELECT * FROM iceberg_scan(%s);
+		`, quoteIdentifier(name), quoteString(location)))
+	case "delta":
+		_, err = c.db.Exec(fmt.Sprintf(`
+			INSTALL delta;
+			LOAD delta;
+			CREATE OR REPLACE VIEW %s AS SELECT * FROM delta_scan(%s);
+		`, quoteIdentifier(name), quoteString(location)))
+	case "parquet", "":
+		// Plain Parquet files
+		_, err = c.db.Exec(fmt.Sprintf(`
+			CREATE OR REPLACE VIEW %s AS SELECT * FROM parquet_scan(%s);
+		`, quoteIdentifier(name), quoteString(strings.TrimSuffix(location, "/")+"/*.parquet")))
+	default:
+		return fmt.Errorf("unsupported table format: %s", format)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to register table %s: %w", name, err)
+	}
+	return nil
+}
+
+// CopyToDatabase runs a query and stores the result as a table in a new
+// DuckDB database file, returning the number of rows
+func (c *Connection) CopyToDatabase(path, table, query string) (int64, error) {
+	// Attach, copy and detach on one connection
+	conn, err := c.db.Conn(context.Background())
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	
+	if _, err := conn.ExecContext(context.Background(), fmt.Sprintf("ATTACH %s AS dmesh_export",
+		quoteString(path))); err != nil {
+		return 0, fmt.Errorf("failed to create database file: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "DETACH dmesh_export")
+	
+	if _, err := conn.ExecContext(context.Background(), fmt.Sprintf("CREATE TABLE dmesh_export.%s AS %s",
+		quoteIdentifier(table), query)); err != nil {
+		return 0, fmt.Errorf("failed to copy data: %w", err)
+	}
+	
+	var rows int64
+	err = conn.QueryRowContext(context.Background(), fmt.Sprintf("SELECT count(*) FROM dmesh_export.%s",
+		quoteIdentifier(table))).Scan(&rows)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count rows: %w", err)
+	}
+	return rows, nil
+}
+
+// CopyToParquet writes the results of a query to a Parquet file with
+// COPY, keeping DuckDB's types, and returns the number of rows
+func (c *Connection) CopyToParquet(path, query string) (int64, error) {
+	// The closing parenthesis goes on a line of its own, after any comment
+	// left in the query
+	result, err := c.db.Exec(fmt.Sprintf("COPY (%s\n) TO %s (FORMAT parquet)", trimStatement(query),
+		quoteString(path)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to write Parquet file: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return rows, nil
+}
+
+// trimStatement removes the comments, semicolons and space that end a
+// statement, so that it can be put inside another
+func trimStatement(query string) string {
+	end := 0
+	for i := 0; i < len(query); {
+		switch c := query[i]; {
+		case strings.HasPrefix(query[i:], "--"):
+			if n := strings.IndexByte(query[i:], '\n'); n >= 0 {
+				i += n + 1
+			} else {
+				i = len(query)
+			}
+		case strings.HasPrefix(query[i:], "/*"):
+			if n := strings.Index(query[i+2:], "*/"); n >= 0 {
+				i += n + 4
+			} else {
+				i = len(query)
+			}
+		case c == '\'' || c == '"':
+			// Doubled quotes inside are two strings in a row here
+			n := strings.IndexByte(query[i+1:], c)
+			if n < 0 {
+				return query
+			}
+			i += n + 2
+			end = i
+		case c == ';' || c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		default:
+			i++
+			end = i
+		}
+	}
+	return query[:end]
+}
+
+// RegisterDatabaseTable registers a table of a DuckDB database file like
+// RegisterDataProduct. The file is attached read-only.
+func (c *Connection) RegisterDatabaseTable(name, path, table string) error {
+	if err := c.createSchema(name); err != nil {
+		return err
+	}
+	
+	alias := quoteIdentifier("file_" + name)
+	_, err := c.db.Exec(fmt.Sprintf(`
+		ATTACH %s AS %s (READ_ONLY);
+		CREATE VIEW %s AS SELECT * FROM %s.%s;
+	`, quoteString(path), alias, QuoteName(name), alias, quoteIdentifier(table)))
+	if err != nil {
+		return fmt.Errorf("failed to register %s: %w", name, err)
+	}
+	return nil
+}
+
+// createSchema creates the schema of a qualified view name such as
+// domain.product, so that the view can be queried by that name
+func (c *Connection) createSchema(name string) error {
+	i := strings.LastIndex(name, ".")
+	if i <= 0 {
+		return nil
+	}
+	if _, err := c.db.Exec(fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", quoteIdentifier(name[:i]))); err != nil {
+		return fmt.Errorf("failed to create schema for %s: %w", name, err)
+	}
+	return nil
+}
+
+// quoteIdentifier quotes a view name for SQL
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// QuoteName quotes a view name qualified by its schema, such as
+// domain.product, as the schema and the view
+func QuoteName(name string) string {
+	i := strings.LastIndex(name, ".")
+	if i <= 0 {
+		return quoteIdentifier(name)
+	}
+	return quoteIdentifier(name[:i]) + "." + quoteIdentifier(name[i+1:])
+}
+
+// quoteString quotes a string, such as a location or path, for SQL
+func quoteString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
 func (c *Connection) ExecuteQuery(query string) (*QueryResult, error) {
 	// Execute the query
 	rows, err := c.db.Query(query)
@@ -101,10 +334,21 @@ func (c *Connection) ExecuteQuery(query string) (*QueryResult, error) {
 		return nil, fmt.Errorf("failed to get column info: %w", err)
 	}
 	
+	// Get column types
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get column info: %w", err)
+	}
+	typeNames := make([]string, len(columnTypes))
+	for i, ct := range columnTypes {
+		typeNames[i] = ct.DatabaseTypeName()
+	}
+	
 	// Prepare result container
 	result := &QueryResult{
-		Columns: columns,
-		Rows:    make([][]interface{}, 0),
+		Columns:     columns,
+		ColumnTypes: typeNames,
+		Rows:        make([][]interface{}, 0),
 	}
 	
 	// Prepare value containers
@@ -133,5 +377,113 @@ func (c *Connection) ExecuteQuery(query string) (*QueryResult, error) {
 		return nil, fmt.Errorf("error during row iteration: %w", err)
 	}
 	
+	c.describeZonedTypes(query, typeNames)
 	return result, nil
 }
+
+// describeZonedTypes replaces the types of columns with a time zone, which
+// the driver names like those without one, by the types DuckDB describes
+// the query with. Statements that can't be described keep their types.
+func (c *Connection) describeZonedTypes(query string, typeNames []string) {
+	timestamps := false
+	for _, name := range typeNames {
+		if strings.Contains(name, "TIMESTAMP") {
+			timestamps = true
+		}
+	}
+	if !timestamps {
+		return
+	}
+	
+	rows, err := c.db.Query("DESCRIBE " + query)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil || len(columns) < 2 {
+		return
+	}
+	
+	// Rows are a column's name and type, then what DuckDB knows about it
+	var described []string
+	values := make([]interface{}, len(columns))
+	scanArgs := make([]interface{}, len(columns))
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(scanArgs...); err != nil {
+			return
+		}
+		described = append(described, fmt.Sprint(values[1]))
+	}
+	if rows.Err() != nil || len(described) != len(typeNames) {
+		return
+	}
+	for i, name := range described {
+		if strings.Contains(name, "WITH TIME ZONE") {
+			typeNames[i] = name
+		}
+	}
+}
+
+// Table is a table or view and the names of its columns
+type Table struct {
+	Schema  string
+	Name    string
+	Columns []string
+}
+
+// Tables lists the tables and views that can be queried, such as
+// registered data products, with their columns
+func (c *Connection) Tables() ([]Table, error) {
+	rows, err := c.db.Query(`
+		SELECT table_schema, table_name, column_name
+		FROM information_schema.columns
+		WHERE table_schema NOT IN ('information_schema', 'pg_catalog')
+		ORDER BY table_schema, table_name, ordinal_position
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer rows.Close()
+	
+	var tables []Table
+	for rows.Next() {
+		var schema, name, column string
+		if err := rows.Scan(&schema, &name, &column); err != nil {
+			return nil, fmt.Errorf("failed to list tables: %w", err)
+		}
+		if n := len(tables); n == 0 || tables[n-1].Schema != schema || tables[n-1].Name != name {
+			tables = append(tables, Table{Schema: schema, Name: name})
+		}
+		tables[len(tables)-1].Columns = append(tables[len(tables)-1].Columns, column)
+	}
+	return tables, rows.Err()
+}
+
+// Functions lists the names of the functions and macros DuckDB has, other
+// than operators
+func (c *Connection) Functions() ([]string, error) {
+	rows, err := c.db.Query(`
+		SELECT DISTINCT function_name
+		FROM duckdb_functions()
+		WHERE regexp_full_match(function_name, '[a-z][a-z0-9_]*')
+		ORDER BY function_name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list functions: %w", err)
+	}
+	defer rows.Close()
+	
+	var functions []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to list functions: %w", err)
+		}
+		functions = append(functions, name)
+	}
+	return functions, rows.Err()
+}
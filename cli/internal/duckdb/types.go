@@ -0,0 +1,181 @@
+package duckdb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Type is a column type as DuckDB names it, such as DECIMAL(18,3),
+// VARCHAR[], STRUCT("a" INTEGER, "b" DATE) or MAP(VARCHAR, BIGINT)
+type Type struct {
+	// ID is the type without parameters: BOOLEAN, INTEGER, DECIMAL, LIST,
+	// STRUCT, MAP, TIMESTAMP WITH TIME ZONE, ...
+	ID string
+	// Width and Scale of decimals
+	Width int
+	Scale int
+	// Elem is the element type of lists
+	Elem *Type
+	// Fields of structs
+	Fields []StructField
+	// Key and Value types of maps
+	Key   *Type
+	Value *Type
+}
+
+// StructField is a field of a struct type
+type StructField struct {
+	Name string
+	Type *Type
+}
+
+// ParseType parses a type name as returned by DatabaseTypeName
+func ParseType(name string) (*Type, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("empty type name")
+	}
+
+	if strings.HasSuffix(name, "[]") {
+		elem, err := ParseType(name[:len(name)-2])
+		if err != nil {
+			return nil, err
+		}
+		return &Type{ID: "LIST", Elem: elem}, nil
+	}
+
+	open := strings.IndexByte(name, '(')
+	if open < 0 || !strings.HasSuffix(name, ")") {
+		return &Type{ID: strings.ToUpper(name)}, nil
+	}
+	id := strings.ToUpper(strings.TrimSpace(name[:open]))
+	args, err := splitTopLevel(name[open+1 : len(name)-1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid type %s: %w", name, err)
+	}
+
+	t := &Type{ID: id}
+	switch id {
+	case "DECIMAL", "NUMERIC":
+		t.ID = "DECIMAL"
+		if len(args) != 2 {
+			return nil, fmt.Errorf("invalid type %s", name)
+		}
+		if t.Width, err = strconv.Atoi(args[0]); err != nil {
+			return nil, fmt.Errorf("invalid type %s", name)
+		}
+		if t.Scale, err = strconv.Atoi(args[1]); err != nil {
+			return nil, fmt.Errorf("invalid type %s", name)
+		}
+	case "STRUCT":
+		for _, arg := range args {
+			fieldName, rest := splitFieldName(arg)
+			fieldType, err := ParseType(rest)
+			if err != nil {
+				return nil, err
+			}
+			t.Fields = append(t.Fields, StructField{Name: fieldName, Type: fieldType})
+		}
+	case "MAP":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("invalid type %s", name)
+		}
+		if t.Key, err = ParseType(args[0]); err != nil {
+			return nil, err
+		}
+		if t.Value, err = ParseType(args[1]); err != nil {
+			return nil, err
+		}
+	default:
+		// Parameters of other types, such as VARCHAR(10), don't matter here
+	}
+	return t, nil
+}
+
+// String names the type like DuckDB
+func (t *Type) String() string {
+	switch t.ID {
+	case "DECIMAL":
+		return fmt.Sprintf("DECIMAL(%d,%d)", t.Width, t.Scale)
+	case "LIST":
+		return t.Elem.String() + "[]"
+	case "STRUCT":
+		fields := make([]string, len(t.Fields))
+		for i, f := range t.Fields {
+			fields[i] = `"` + strings.ReplaceAll(f.Name, `"`, `""`) + `" ` + f.Type.String()
+		}
+		return "STRUCT(" + strings.Join(fields, ", ") + ")"
+	case "MAP":
+		return fmt.Sprintf("MAP(%s, %s)", t.Key, t.Value)
+	}
+	return t.ID
+}
+
+// Numeric reports whether values of the type are numbers
+func (t *Type) Numeric() bool {
+	switch t.ID {
+	case "TINYINT", "SMALLINT", "INTEGER", "BIGINT", "HUGEINT",
+		"UTINYINT", "USMALLINT", "UINTEGER", "UBIGINT",
+		"FLOAT", "DOUBLE", "DECIMAL":
+		return true
+	}
+	return false
+}
+
+// Zoned reports whether values of the type are instants in time rather
+// than wall clock times
+func (t *Type) Zoned() bool {
+	return t.ID == "TIMESTAMP WITH TIME ZONE" || t.ID == "TIMESTAMPTZ"
+}
+
+// splitTopLevel splits type arguments on commas outside parentheses and
+// quotes
+func splitTopLevel(s string) ([]string, error) {
+	var parts []string
+	depth, start := 0, 0
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unbalanced parentheses")
+			}
+		case c == ',' && depth == 0:
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if depth != 0 || quoted {
+		return nil, fmt.Errorf("unbalanced parentheses or quotes")
+	}
+	return append(parts, strings.TrimSpace(s[start:])), nil
+}
+
+// splitFieldName splits a struct field into its name, which may be
+// quoted with doubled quotes inside, and its type
+func splitFieldName(field string) (string, string) {
+	if !strings.HasPrefix(field, `"`) {
+		name, rest, _ := strings.Cut(field, " ")
+		return name, rest
+	}
+	var name strings.Builder
+	for i := 1; i < len(field); i++ {
+		if field[i] == '"' {
+			if i+1 < len(field) && field[i+1] == '"' {
+				name.WriteByte('"')
+				i++
+				continue
+			}
+			return name.String(), strings.TrimSpace(field[i+1:])
+		}
+		name.WriteByte(field[i])
+	}
+	return name.String(), ""
+}
@@ -0,0 +1,184 @@
+package history
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/config"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/securefile"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/security"
+)
+
+// Entry is a query that was run
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Product string    `json:"product,omitempty"`
+	Query   string    `json:"query"`
+}
+
+// History keeps the queries run in the query TUI in the config directory,
+// encrypted line by line like the logs since queries may hold data
+type History struct {
+	path    string
+	size    int
+	store   *securefile.Store
+	entries []Entry
+}
+
+// Open loads the history of the user, keeping the last query_history_size
+// queries. It returns nil when the history is disabled.
+func Open(cfg *config.Config, secCtx *security.SecurityContext) (*History, error) {
+	if cfg.QueryHistorySize <= 0 {
+		return nil, nil
+	}
+
+	store, err := securefile.NewStore(cfg, secCtx)
+	if err != nil {
+		return nil, err
+	}
+	configDir, err := config.Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	h := &History{
+		path:  filepath.Join(configDir, "query_history"),
+		size:  cfg.QueryHistorySize,
+		store: store,
+	}
+	if err := h.load(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Entries returns the queries, oldest first
+func (h *History) Entries() []Entry {
+	return h.entries
+}
+
+// Search returns the queries containing text, ignoring case, newest first
+// and without repeats
+func (h *History) Search(text string) []Entry {
+	text = strings.ToLower(text)
+	seen := make(map[string]bool)
+	var matches []Entry
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		e := h.entries[i]
+		if seen[e.Query] || !strings.Contains(strings.ToLower(e.Query), text) {
+			continue
+		}
+		seen[e.Query] = true
+		matches = append(matches, e)
+	}
+	return matches
+}
+
+// Add appends a query to the history, unless it repeats the last one
+func (h *History) Add(product, query string) error {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil
+	}
+	if n := len(h.entries); n > 0 && h.entries[n-1].Query == query {
+		return nil
+	}
+
+	e := Entry{Time: time.Now().UTC(), Product: product, Query: query}
+	line, err := h.encode(e)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open query history: %w", err)
+	}
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write query history: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write query history: %w", err)
+	}
+
+	h.entries = append(h.entries, e)
+	if len(h.entries) > h.size {
+		h.entries = h.entries[len(h.entries)-h.size:]
+	}
+	return nil
+}
+
+func (h *History) load() error {
+	if _, err := os.Stat(h.path); os.IsNotExist(err) {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := h.store.Decrypt(&buf, h.path); err != nil {
+		if errors.Is(err, securefile.ErrNotEncrypted) {
+			// Empty
+			return nil
+		}
+		return fmt.Errorf("failed to read query history: %w", err)
+	}
+
+	var lines int
+	for _, line := range bytes.Split(buf.Bytes(), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		lines++
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			// Lines that aren't entries are skipped rather than losing
+			// the rest of the history
+			continue
+		}
+		h.entries = append(h.entries, e)
+	}
+	if len(h.entries) > h.size {
+		h.entries = h.entries[len(h.entries)-h.size:]
+	}
+
+	// The file is appended to, so it is cut back to the entries kept once
+	// it holds twice as many
+	if lines > 2*h.size {
+		return h.rewrite()
+	}
+	return nil
+}
+
+// rewrite replaces the file with the entries kept
+func (h *History) rewrite() error {
+	var data []byte
+	for _, e := range h.entries {
+		line, err := h.encode(e)
+		if err != nil {
+			return err
+		}
+		data = append(data, line...)
+	}
+	if err := securefile.WritePrivateFile(h.path, data); err != nil {
+		return fmt.Errorf("failed to write query history: %w", err)
+	}
+	return nil
+}
+
+// encode encrypts an entry into a line of the file
+func (h *History) encode(e Entry) ([]byte, error) {
+	plain, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	line, err := h.store.EncryptLine(plain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt query history: %w", err)
+	}
+	return append(line, '\n'), nil
+}
@@ -0,0 +1,402 @@
+package lakehouse
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/config"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/logging"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/security"
+)
+
+// Client reads the commit history of Iceberg and Delta tables directly
+// from their metadata files, without a query engine
+type Client struct {
+	cfg    *config.Config
+	secCtx *security.SecurityContext
+	log    *logging.Logger
+	s3     *s3Storage
+}
+
+// Snapshot is an Iceberg snapshot or a Delta commit
+type Snapshot struct {
+	// ID is the Iceberg snapshot ID or the Delta version
+	ID        int64             `json:"id"`
+	ParentID  *int64            `json:"parent_id,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+	Operation string            `json:"operation,omitempty"`
+	Current   bool              `json:"current"`
+	Summary   map[string]string `json:"summary,omitempty"`
+	Stats     Stats             `json:"stats"`
+}
+
+// Stats counts the files and records a snapshot changed; nil when the
+// table metadata doesn't record them
+type Stats struct {
+	AddedFiles     *int64 `json:"added_files,omitempty"`
+	RemovedFiles   *int64 `json:"removed_files,omitempty"`
+	AddedRecords   *int64 `json:"added_records,omitempty"`
+	DeletedRecords *int64 `json:"deleted_records,omitempty"`
+	TotalRecords   *int64 `json:"total_records,omitempty"`
+}
+
+// History is the commit history of a table, oldest snapshot first
+type History struct {
+	Format   string `json:"format"`
+	Location string `json:"location"`
+	// MetadataFile is the Iceberg metadata file the history was read from
+	MetadataFile string     `json:"metadata_file,omitempty"`
+	Snapshots    []Snapshot `json:"snapshots"`
+}
+
+func NewClient(cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) *Client {
+	return &Client{
+		cfg:    cfg,
+		secCtx: secCtx,
+		log:    log,
+	}
+}
+
+// Current returns the current snapshot, or nil for an empty table
+func (h *History) Current() *Snapshot {
+	for i := range h.Snapshots {
+		if h.Snapshots[i].Current {
+			return &h.Snapshots[i]
+		}
+	}
+	return nil
+}
+
+// Latest returns the most recent snapshot, or nil for an empty table
+func (h *History) Latest() *Snapshot {
+	if len(h.Snapshots) == 0 {
+		return nil
+	}
+	return &h.Snapshots[len(h.Snapshots)-1]
+}
+
+// History reads the snapshots of a table in the given format (iceberg or
+// delta) at an s3:// URL or a local path
+func (c *Client) History(format, location string) (*History, error) {
+	store, location, err := c.storage(location)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(format) {
+	case "iceberg":
+		return c.icebergHistory(store, location)
+	case "delta":
+		return c.deltaHistory(store, location)
+	}
+	return nil, fmt.Errorf("format %q has no commit history; only iceberg and delta tables are supported", format)
+}
+
+func (c *Client) storage(location string) (storage, string, error) {
+	if !isRemote(location) {
+		return localStorage{}, strings.TrimPrefix(location, "file://"), nil
+	}
+	if _, _, err := splitS3URL(location); err != nil {
+		return nil, "", err
+	}
+
+	if c.s3 == nil {
+		// Get AWS session
+		sess, err := c.secCtx.GetAWSSession()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to create AWS session: %w", err)
+		}
+		c.s3 = &s3Storage{client: s3.New(sess)}
+	}
+	return c.s3, strings.TrimSuffix(location, "/"), nil
+}
+
+// icebergMetadata holds the parts of an Iceberg table metadata file that
+// describe snapshots
+type icebergMetadata struct {
+	CurrentSnapshotID *int64 `json:"current-snapshot-id"`
+	Snapshots         []struct {
+		SnapshotID       int64             `json:"snapshot-id"`
+		ParentSnapshotID *int64            `json:"parent-snapshot-id"`
+		TimestampMs      int64             `json:"timestamp-ms"`
+		Summary          map[string]string `json:"summary"`
+	} `json:"snapshots"`
+}
+
+func (c *Client) icebergHistory(store storage, location string) (*History, error) {
+	metadataDir := store.Join(location, "metadata")
+	file, err := currentIcebergMetadata(store, metadataDir)
+	if err != nil {
+		return nil, err
+	}
+	c.log.Debugf("Reading Iceberg metadata %s", file)
+
+	data, err := store.Read(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Iceberg metadata: %w", err)
+	}
+	var metadata icebergMetadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse Iceberg metadata %s: %w", file, err)
+	}
+
+	history := &History{Format: "iceberg", Location: location, MetadataFile: file}
+	for _, s := range metadata.Snapshots {
+		snapshot := Snapshot{
+			ID:        s.SnapshotID,
+			ParentID:  s.ParentSnapshotID,
+			Timestamp: time.UnixMilli(s.TimestampMs).UTC(),
+			Operation: s.Summary["operation"],
+			Current:   metadata.CurrentSnapshotID != nil && *metadata.CurrentSnapshotID == s.SnapshotID,
+			Summary:   s.Summary,
+		}
+		delete(snapshot.Summary, "operation")
+		snapshot.Stats = Stats{
+			AddedFiles:     summaryInt(s.Summary, "added-data-files"),
+			RemovedFiles:   summaryInt(s.Summary, "deleted-data-files"),
+			AddedRecords:   summaryInt(s.Summary, "added-records"),
+			DeletedRecords: summaryInt(s.Summary, "deleted-records"),
+			TotalRecords:   summaryInt(s.Summary, "total-records"),
+		}
+		history.Snapshots = append(history.Snapshots, snapshot)
+	}
+	sort.SliceStable(history.Snapshots, func(i, j int) bool {
+		return history.Snapshots[i].Timestamp.Before(history.Snapshots[j].Timestamp)
+	})
+	return history, nil
+}
+
+// currentIcebergMetadata finds the newest metadata file, from the version
+// hint written by Hadoop catalogs or else the highest version number
+// (vN.metadata.json or NNNNN-uuid.metadata.json)
+func currentIcebergMetadata(store storage, metadataDir string) (string, error) {
+	if hint, err := store.Read(store.Join(metadataDir, "version-hint.text")); err == nil {
+		if version := strings.TrimSpace(string(hint)); version != "" {
+			return store.Join(metadataDir, "v"+version+".metadata.json"), nil
+		}
+	}
+
+	names, err := store.List(metadataDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to list Iceberg metadata: %w", err)
+	}
+
+	best, bestVersion := "", int64(-1)
+	for _, name := range names {
+		if !strings.HasSuffix(name, ".metadata.json") {
+			continue
+		}
+		version := strings.TrimPrefix(name, "v")
+		if i := strings.IndexAny(version, "-."); i >= 0 {
+			version = version[:i]
+		}
+		n, err := strconv.ParseInt(version, 10, 64)
+		if err != nil {
+			continue
+		}
+		if n > bestVersion {
+			best, bestVersion = name, n
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("no Iceberg metadata files found in %s", metadataDir)
+	}
+	return store.Join(metadataDir, best), nil
+}
+
+// deltaCommitInfo is the commitInfo action of a Delta log entry
+type deltaCommitInfo struct {
+	Timestamp        int64                  `json:"timestamp"`
+	Operation        string                 `json:"operation"`
+	OperationMetrics map[string]interface{} `json:"operationMetrics"`
+}
+
+// deltaAdd is the add action of a Delta log entry
+type deltaAdd struct {
+	Path string `json:"path"`
+	// Stats is a JSON document holding numRecords among column statistics
+	Stats          string          `json:"stats"`
+	DeletionVector json.RawMessage `json:"deletionVector"`
+}
+
+// deltaCommit holds the actions of one Delta log file
+type deltaCommit struct {
+	Info    deltaCommitInfo
+	Added   []deltaAdd
+	Removed []string
+	// PartitionColumns is set when the commit has a metaData action
+	PartitionColumns []string
+	HasMetadata      bool
+}
+
+// deltaHistory reads the commits in _delta_log. Commits whose log files
+// have been cleaned up after a checkpoint are not listed.
+func (c *Client) deltaHistory(store storage, location string) (*History, error) {
+	logDir := store.Join(location, "_delta_log")
+	versions, err := deltaVersions(store, logDir)
+	if err != nil {
+		return nil, err
+	}
+
+	history := &History{Format: "delta", Location: location}
+	for _, version := range versions {
+		commit, err := readDeltaCommit(store, logDir, version)
+		if err != nil {
+			return nil, err
+		}
+		info := commit.Info
+
+		snapshot := Snapshot{ID: version, Operation: info.Operation}
+		if info.Timestamp > 0 {
+			snapshot.Timestamp = time.UnixMilli(info.Timestamp).UTC()
+		}
+		if version > 0 {
+			parent := version - 1
+			snapshot.ParentID = &parent
+		}
+		if len(info.OperationMetrics) > 0 {
+			snapshot.Summary = make(map[string]string, len(info.OperationMetrics))
+			for k, v := range info.OperationMetrics {
+				snapshot.Summary[k] = metricString(v)
+			}
+		}
+		snapshot.Stats = commit.stats()
+		history.Snapshots = append(history.Snapshots, snapshot)
+	}
+
+	if n := len(history.Snapshots); n > 0 {
+		history.Snapshots[n-1].Current = true
+	}
+	return history, nil
+}
+
+// deltaVersions lists the versions of the JSON commits in a Delta log,
+// oldest first
+func deltaVersions(store storage, logDir string) ([]int64, error) {
+	names, err := store.List(logDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Delta log: %w", err)
+	}
+
+	var versions []int64
+	for _, name := range names {
+		if !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		version, err := strconv.ParseInt(strings.TrimSuffix(name, ".json"), 10, 64)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions, nil
+}
+
+// readDeltaCommit reads a Delta log file, which holds one JSON action per
+// line
+func readDeltaCommit(store storage, logDir string, version int64) (*deltaCommit, error) {
+	name := fmt.Sprintf("%020d.json", version)
+	data, err := store.Read(store.Join(logDir, name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Delta log: %w", err)
+	}
+
+	commit := &deltaCommit{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var action struct {
+			CommitInfo *deltaCommitInfo `json:"commitInfo"`
+			Add        *deltaAdd        `json:"add"`
+			Remove     *struct {
+				Path string `json:"path"`
+			} `json:"remove"`
+			MetaData *struct {
+				PartitionColumns []string `json:"partitionColumns"`
+			} `json:"metaData"`
+		}
+		if err := json.Unmarshal(line, &action); err != nil {
+			return nil, fmt.Errorf("failed to parse Delta log %s: %w", name, err)
+		}
+		switch {
+		case action.CommitInfo != nil:
+			commit.Info = *action.CommitInfo
+		case action.Add != nil:
+			commit.Added = append(commit.Added, *action.Add)
+		case action.Remove != nil:
+			commit.Removed = append(commit.Removed, action.Remove.Path)
+		case action.MetaData != nil:
+			commit.PartitionColumns = action.MetaData.PartitionColumns
+			commit.HasMetadata = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to parse Delta log %s: %w", name, err)
+	}
+	return commit, nil
+}
+
+// stats counts the files of a commit and takes record counts from the
+// file statistics, or else from the operation metrics
+func (d *deltaCommit) stats() Stats {
+	added, removed := int64(len(d.Added)), int64(len(d.Removed))
+	stats := Stats{AddedFiles: &added, RemovedFiles: &removed}
+
+	var records int64
+	for _, add := range d.Added {
+		var fileStats struct {
+			NumRecords *int64 `json:"numRecords"`
+		}
+		if add.Stats == "" || json.Unmarshal([]byte(add.Stats), &fileStats) != nil || fileStats.NumRecords == nil {
+			records = -1
+			break
+		}
+		records += *fileStats.NumRecords
+	}
+	if records >= 0 {
+		stats.AddedRecords = &records
+	}
+
+	metrics := make(map[string]string, len(d.Info.OperationMetrics))
+	for k, v := range d.Info.OperationMetrics {
+		metrics[k] = metricString(v)
+	}
+	if stats.AddedRecords == nil {
+		stats.AddedRecords = summaryInt(metrics, "numOutputRows", "numTargetRowsInserted")
+	}
+	stats.DeletedRecords = summaryInt(metrics, "numDeletedRows", "numTargetRowsDeleted")
+	return stats
+}
+
+// metricString renders a metric decoded from JSON; numbers are decoded as
+// float64 and would otherwise print in exponent form
+func metricString(v interface{}) string {
+	if f, ok := v.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+// summaryInt returns the first of the keys holding an integer
+func summaryInt(summary map[string]string, keys ...string) *int64 {
+	for _, k := range keys {
+		if v, ok := summary[k]; ok {
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return &n
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package lakehouse
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// storage lists and reads the metadata files of a table location
+type storage interface {
+	// List returns the names of the files directly below dir, sorted
+	List(dir string) ([]string, error)
+	// Read returns the contents of a file
+	Read(file string) ([]byte, error)
+	// Join builds a path below a location
+	Join(elem ...string) string
+}
+
+// localStorage reads tables from the file system, such as tables written
+// by a local Spark session
+type localStorage struct{}
+
+func (localStorage) List(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (localStorage) Read(file string) ([]byte, error) {
+	return os.ReadFile(file)
+}
+
+func (localStorage) Join(elem ...string) string {
+	return filepath.Join(elem...)
+}
+
+// s3Storage reads tables from S3; paths are s3://bucket/key URLs
+type s3Storage struct {
+	client *s3.S3
+}
+
+func (s *s3Storage) List(dir string) ([]string, error) {
+	bucket, prefix, err := splitS3URL(dir)
+	if err != nil {
+		return nil, err
+	}
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	var names []string
+	input := &s3.ListObjectsV2Input{
+		Bucket:    aws.String(bucket),
+		Prefix:    aws.String(prefix),
+		Delimiter: aws.String("/"),
+	}
+	err = s.client.ListObjectsV2Pages(input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range page.Contents {
+			names = append(names, strings.TrimPrefix(aws.StringValue(obj.Key), prefix))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *s3Storage) Read(file string) ([]byte, error) {
+	bucket, key, err := splitS3URL(file)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := s.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	defer out.Body.Close()
+	return io.ReadAll(out.Body)
+}
+
+func (s *s3Storage) Join(elem ...string) string {
+	if len(elem) == 0 {
+		return ""
+	}
+	base := strings.TrimSuffix(elem[0], "/")
+	return base + "/" + path.Join(elem[1:]...)
+}
+
+// splitS3URL splits s3://bucket/key into bucket and key
+func splitS3URL(location string) (string, string, error) {
+	u, err := url.Parse(location)
+	if err != nil || (u.Scheme != "s3" && u.Scheme != "s3a") || u.Host == "" {
+		return "", "", fmt.Errorf("invalid S3 location %q", location)
+	}
+	return u.Host, strings.TrimPrefix(u.Path, "/"), nil
+}
+
+// isRemote reports whether a location is an object store URL rather than
+// a local path
+func isRemote(location string) bool {
+	return strings.Contains(location, "://") && !strings.HasPrefix(location, "file://")
+}
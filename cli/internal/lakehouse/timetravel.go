@@ -0,0 +1,186 @@
+package lakehouse
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AsOf selects a snapshot for time travel, by ID (the Iceberg snapshot ID
+// or the Delta version) or by time. The zero AsOf selects the current
+// snapshot.
+type AsOf struct {
+	SnapshotID *int64
+	Time       time.Time
+}
+
+// asOfLayouts are the timestamp formats accepted by ParseAsOf; times
+// without a zone are UTC
+var asOfLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ParseAsOf parses a snapshot ID or Delta version (an integer) or a
+// timestamp such as 2024-03-31 or 2024-03-31T17:00:00Z
+func ParseAsOf(s string) (AsOf, error) {
+	s = strings.TrimSpace(s)
+	if id, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return AsOf{SnapshotID: &id}, nil
+	}
+	for _, layout := range asOfLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return AsOf{Time: t.UTC()}, nil
+		}
+	}
+	return AsOf{}, fmt.Errorf("invalid --as-of %q: use a snapshot ID, a Delta version or a timestamp such as 2024-03-31T17:00:00Z", s)
+}
+
+func (a AsOf) String() string {
+	if a.SnapshotID != nil {
+		return strconv.FormatInt(*a.SnapshotID, 10)
+	}
+	if a.Time.IsZero() {
+		return "current"
+	}
+	return a.Time.Format(time.RFC3339)
+}
+
+// Resolve returns the snapshot with the given ID, or the snapshot that was
+// current at the given time: the latest one committed at or before it
+func (h *History) Resolve(asOf AsOf) (*Snapshot, error) {
+	if asOf.SnapshotID == nil && asOf.Time.IsZero() {
+		if current := h.Current(); current != nil {
+			return current, nil
+		}
+		return nil, fmt.Errorf("%s has no current snapshot", h.Location)
+	}
+	if asOf.SnapshotID != nil {
+		for i := range h.Snapshots {
+			if h.Snapshots[i].ID == *asOf.SnapshotID {
+				return &h.Snapshots[i], nil
+			}
+		}
+		return nil, fmt.Errorf("snapshot %d not found in %s", *asOf.SnapshotID, h.Location)
+	}
+
+	var found *Snapshot
+	for i := range h.Snapshots {
+		if h.Snapshots[i].Timestamp.After(asOf.Time) {
+			break
+		}
+		found = &h.Snapshots[i]
+	}
+	if found == nil {
+		if len(h.Snapshots) == 0 {
+			return nil, fmt.Errorf("%s has no snapshots", h.Location)
+		}
+		return nil, fmt.Errorf("%s has no snapshot at or before %s; the oldest is from %s",
+			h.Location, asOf.Time.Format(time.RFC3339), h.Snapshots[0].Timestamp.Format(time.RFC3339))
+	}
+	return found, nil
+}
+
+// TableVersion pins a table to a snapshot for time travel
+type TableVersion struct {
+	Format   string
+	Location string
+	Snapshot Snapshot
+	// DataFiles are the Parquet files of a Delta version, and
+	// PartitionColumns the columns encoded in their paths
+	DataFiles        []string
+	PartitionColumns []string
+}
+
+// TableVersion resolves a table in the given format (iceberg or delta) as
+// of a snapshot ID or time. Delta versions are reconstructed by replaying
+// the JSON commits of the log up to the version.
+func (c *Client) TableVersion(format, location string, asOf AsOf) (*TableVersion, error) {
+	history, err := c.History(format, location)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := history.Resolve(asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	version := &TableVersion{Format: history.Format, Location: location, Snapshot: *snapshot}
+	if history.Format == "delta" {
+		store, location, err := c.storage(location)
+		if err != nil {
+			return nil, err
+		}
+		version.DataFiles, version.PartitionColumns, err = deltaFiles(store, location, snapshot.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return version, nil
+}
+
+// deltaFiles replays the Delta log up to a version and returns the data
+// files that make up the table at that version
+func deltaFiles(store storage, location string, version int64) ([]string, []string, error) {
+	logDir := store.Join(location, "_delta_log")
+	versions, err := deltaVersions(store, logDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(versions) == 0 || versions[0] != 0 {
+		return nil, nil, fmt.Errorf("the Delta log of %s has been checkpointed; versions before the oldest JSON commit cannot be reconstructed", location)
+	}
+
+	files := make(map[string]deltaAdd)
+	seen := make(map[string]bool)
+	var order []string
+	var partitionColumns []string
+	for _, v := range versions {
+		if v > version {
+			break
+		}
+		commit, err := readDeltaCommit(store, logDir, v)
+		if err != nil {
+			return nil, nil, err
+		}
+		if commit.HasMetadata {
+			partitionColumns = commit.PartitionColumns
+		}
+		for _, path := range commit.Removed {
+			delete(files, path)
+		}
+		for _, add := range commit.Added {
+			if !seen[add.Path] {
+				seen[add.Path] = true
+				order = append(order, add.Path)
+			}
+			files[add.Path] = add
+		}
+	}
+
+	var paths []string
+	for _, path := range order {
+		add, ok := files[path]
+		if !ok {
+			continue
+		}
+		if len(add.DeletionVector) > 0 && string(add.DeletionVector) != "null" {
+			return nil, nil, fmt.Errorf("delta version %d uses deletion vectors, which time travel does not support", version)
+		}
+		// Paths are URIs, relative to the table unless absolute
+		if unescaped, err := url.PathUnescape(path); err == nil {
+			path = unescaped
+		}
+		if !strings.Contains(path, "://") && !strings.HasPrefix(path, "/") {
+			path = store.Join(location, path)
+		}
+		paths = append(paths, path)
+	}
+	return paths, partitionColumns, nil
+}
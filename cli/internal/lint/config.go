@@ -0,0 +1,105 @@
+package lint
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigFile is the rules file picked up from the working directory
+// when no rules file is given explicitly
+const DefaultConfigFile = ".dmesh-lint.yaml"
+
+// Config is the contents of a lint rules file:
+//
+//	rules:
+//	  field-doc:
+//	    severity: warning
+//	  required-event-fields:
+//	    options:
+//	      fields: [event_id, event_timestamp, security_classification]
+type Config struct {
+	Rules map[string]RuleConfig `yaml:"rules"`
+}
+
+// RuleConfig overrides the severity and options of a single rule
+type RuleConfig struct {
+	Severity Severity `yaml:"severity"`
+	Options  Options  `yaml:"options"`
+}
+
+// Options are rule-specific settings from the rules file
+type Options map[string]interface{}
+
+// String returns a string option or def when it is not set
+func (o Options) String(key, def string) string {
+	if v, ok := o[key].(string); ok && v != "" {
+		return v
+	}
+	return def
+}
+
+// Strings returns a list-of-strings option or def when it is not set
+func (o Options) Strings(key string, def []string) []string {
+	raw, ok := o[key].([]interface{})
+	if !ok {
+		return def
+	}
+	values := make([]string, 0, len(raw))
+	for _, v := range raw {
+		if s, ok := v.(string); ok {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
+// LoadConfig reads and validates a rules file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lint rules file: %w", err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse lint rules file %s: %w", path, err)
+	}
+
+	known := make(map[string]bool)
+	for _, rule := range BuiltinRules() {
+		known[rule.ID()] = true
+	}
+
+	for id, rc := range cfg.Rules {
+		if !known[id] {
+			return nil, fmt.Errorf("lint rules file %s: unknown rule %q", path, id)
+		}
+		switch rc.Severity {
+		case "", SeverityError, SeverityWarning, SeverityNote, SeverityOff:
+		default:
+			return nil, fmt.Errorf("lint rules file %s: invalid severity %q for rule %s", path, rc.Severity, id)
+		}
+	}
+
+	return &cfg, nil
+}
+
+// ruleSettings resolves the effective severity and options of a rule
+func (c *Config) ruleSettings(rule Rule) (Severity, Options) {
+	rc, ok := c.Rules[rule.ID()]
+	if !ok {
+		return rule.DefaultSeverity(), Options{}
+	}
+
+	severity := rc.Severity
+	if severity == "" {
+		severity = rule.DefaultSeverity()
+	}
+	opts := rc.Options
+	if opts == nil {
+		opts = Options{}
+	}
+	return severity, opts
+}
@@ -0,0 +1,155 @@
+package lint
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/avro"
+)
+
+// Severity of a rule violation
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityNote    Severity = "note"
+	SeverityOff     Severity = "off"
+)
+
+// Rule checks a parsed schema against one governance rule
+type Rule interface {
+	ID() string
+	Description() string
+	DefaultSeverity() Severity
+	Check(schema *avro.Schema, opts Options) []Finding
+}
+
+// Finding is a rule violation reported by a rule before severity and
+// source location are attached
+type Finding struct {
+	Path    string // JSON pointer into the schema document
+	Message string
+}
+
+// Violation is a finding attributed to a rule and a location in a file
+type Violation struct {
+	RuleID   string   `json:"ruleId"`
+	Severity Severity `json:"severity"`
+	File     string   `json:"file"`
+	Path     string   `json:"path"`
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Message  string   `json:"message"`
+}
+
+// Result holds the violations found across all linted files
+type Result struct {
+	Files      []string    `json:"files"`
+	Violations []Violation `json:"violations"`
+}
+
+// HasErrors reports whether any violation has error severity
+func (r *Result) HasErrors() bool {
+	for _, v := range r.Violations {
+		if v.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Counts returns the number of violations per severity
+func (r *Result) Counts() map[Severity]int {
+	counts := make(map[Severity]int)
+	for _, v := range r.Violations {
+		counts[v.Severity]++
+	}
+	return counts
+}
+
+// Linter runs the configured rule set against schema files
+type Linter struct {
+	rules  []Rule
+	config *Config
+}
+
+// NewLinter creates a linter with the built-in rules configured by cfg.
+// A nil cfg uses the default severity and options of every rule.
+func NewLinter(cfg *Config) *Linter {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	return &Linter{
+		rules:  BuiltinRules(),
+		config: cfg,
+	}
+}
+
+// Rules returns the rules known to the linter
+func (l *Linter) Rules() []Rule {
+	return l.rules
+}
+
+// LintFiles lints every file and collects the violations in file order
+func (l *Linter) LintFiles(paths []string) (*Result, error) {
+	result := &Result{Files: paths, Violations: []Violation{}}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema file: %w", err)
+		}
+		result.Violations = append(result.Violations, l.Lint(path, data)...)
+	}
+
+	return result, nil
+}
+
+// Lint checks a single schema document. A document that does not parse
+// is reported as a violation of the "valid-schema" pseudo-rule.
+func (l *Linter) Lint(file string, data []byte) []Violation {
+	schema, err := avro.Parse(data)
+	if err != nil {
+		return []Violation{{
+			RuleID:   "valid-schema",
+			Severity: SeverityError,
+			File:     file,
+			Line:     1,
+			Column:   1,
+			Message:  err.Error(),
+		}}
+	}
+
+	index := newLocationIndex(data)
+
+	var violations []Violation
+	for _, rule := range l.rules {
+		severity, opts := l.config.ruleSettings(rule)
+		if severity == SeverityOff {
+			continue
+		}
+		for _, f := range rule.Check(schema, opts) {
+			line, col := index.locate(f.Path)
+			violations = append(violations, Violation{
+				RuleID:   rule.ID(),
+				Severity: severity,
+				File:     file,
+				Path:     f.Path,
+				Line:     line,
+				Column:   col,
+				Message:  f.Message,
+			})
+		}
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool {
+		if violations[i].Line != violations[j].Line {
+			return violations[i].Line < violations[j].Line
+		}
+		return violations[i].Column < violations[j].Column
+	})
+
+	return violations
+}
@@ -0,0 +1,130 @@
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// locationIndex maps JSON pointers to line and column positions so that
+// violations can be annotated on the exact line of a pull request diff
+type locationIndex struct {
+	data    []byte
+	offsets map[string]int
+}
+
+type jsonFrame struct {
+	object  bool
+	wantKey bool
+	key     string
+	index   int
+}
+
+func newLocationIndex(data []byte) *locationIndex {
+	idx := &locationIndex{data: data, offsets: make(map[string]int)}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var stack []*jsonFrame
+
+	afterValue := func() {
+		if len(stack) == 0 {
+			return
+		}
+		top := stack[len(stack)-1]
+		if top.object {
+			top.wantKey = true
+		} else {
+			top.index++
+		}
+	}
+
+	for {
+		offset := int(dec.InputOffset())
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+
+		if len(stack) > 0 {
+			top := stack[len(stack)-1]
+			if top.object && top.wantKey {
+				if key, ok := tok.(string); ok {
+					top.key = key
+					top.wantKey = false
+					continue
+				}
+			}
+		}
+
+		if delim, ok := tok.(json.Delim); ok && (delim == '}' || delim == ']') {
+			stack = stack[:len(stack)-1]
+			afterValue()
+			continue
+		}
+
+		idx.offsets[pointerOf(stack)] = skipSeparators(data, offset)
+
+		switch tok {
+		case json.Delim('{'):
+			stack = append(stack, &jsonFrame{object: true, wantKey: true})
+		case json.Delim('['):
+			stack = append(stack, &jsonFrame{})
+		default:
+			afterValue()
+		}
+	}
+
+	return idx
+}
+
+// locate returns the 1-based line and column of the value at pointer,
+// falling back to its closest known ancestor
+func (idx *locationIndex) locate(pointer string) (int, int) {
+	for {
+		if offset, ok := idx.offsets[pointer]; ok {
+			return lineCol(idx.data, offset)
+		}
+		if pointer == "" {
+			return 1, 1
+		}
+		pointer = pointer[:strings.LastIndex(pointer, "/")]
+	}
+}
+
+func pointerOf(stack []*jsonFrame) string {
+	var b strings.Builder
+	for _, f := range stack {
+		b.WriteByte('/')
+		if f.object {
+			b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(f.key))
+		} else {
+			b.WriteString(strconv.Itoa(f.index))
+		}
+	}
+	return b.String()
+}
+
+// skipSeparators advances past the whitespace, commas and colons that the
+// decoder reports as part of the previous token
+func skipSeparators(data []byte, offset int) int {
+	for offset < len(data) {
+		switch data[offset] {
+		case ' ', '\t', '\r', '\n', ',', ':':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+func lineCol(data []byte, offset int) (int, int) {
+	if offset > len(data) {
+		offset = len(data)
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := offset - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
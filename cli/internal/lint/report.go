@@ -0,0 +1,169 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// WriteReport writes the lint result in the given format (text, json, sarif)
+func WriteReport(w io.Writer, result *Result, rules []Rule, format string) error {
+	switch strings.ToLower(format) {
+	case "text", "":
+		return writeText(w, result)
+	case "json":
+		return writeJSON(w, result)
+	case "sarif":
+		return writeSARIF(w, result, rules)
+	default:
+		return fmt.Errorf("unsupported lint output format: %s", format)
+	}
+}
+
+func writeText(w io.Writer, result *Result) error {
+	for _, v := range result.Violations {
+		if _, err := fmt.Fprintf(w, "%s:%d:%d: %s: %s [%s]\n", v.File, v.Line, v.Column, v.Severity, v.Message, v.RuleID); err != nil {
+			return err
+		}
+	}
+
+	counts := result.Counts()
+	_, err := fmt.Fprintf(w, "\n%d file(s) checked: %d error(s), %d warning(s), %d note(s)\n",
+		len(result.Files), counts[SeverityError], counts[SeverityWarning], counts[SeverityNote])
+	return err
+}
+
+func writeJSON(w io.Writer, result *Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
+
+// SARIF 2.1.0 subset understood by code scanning integrations
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri,omitempty"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string            `json:"id"`
+	ShortDescription     sarifMessage      `json:"shortDescription"`
+	DefaultConfiguration sarifRuleDefaults `json:"defaultConfiguration"`
+}
+
+type sarifRuleDefaults struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+}
+
+func writeSARIF(w io.Writer, result *Result, rules []Rule) error {
+	driver := sarifDriver{
+		Name:           "dmesh-schema-lint",
+		InformationURI: "https://github.com/frocore/fedramp-data-mesh",
+		Rules: []sarifRule{{
+			ID:                   "valid-schema",
+			ShortDescription:     sarifMessage{Text: "Schema files must be valid Avro"},
+			DefaultConfiguration: sarifRuleDefaults{Level: sarifLevel(SeverityError)},
+		}},
+	}
+	for _, rule := range rules {
+		driver.Rules = append(driver.Rules, sarifRule{
+			ID:                   rule.ID(),
+			ShortDescription:     sarifMessage{Text: rule.Description()},
+			DefaultConfiguration: sarifRuleDefaults{Level: sarifLevel(rule.DefaultSeverity())},
+		})
+	}
+
+	results := make([]sarifResult, 0, len(result.Violations))
+	for _, v := range result.Violations {
+		loc := sarifLocation{
+			PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(v.File)},
+				Region:           sarifRegion{StartLine: v.Line, StartColumn: v.Column},
+			},
+		}
+		if v.Path != "" {
+			loc.LogicalLocations = []sarifLogicalLocation{{FullyQualifiedName: v.Path}}
+		}
+		results = append(results, sarifResult{
+			RuleID:    v.RuleID,
+			Level:     sarifLevel(v.Severity),
+			Message:   sarifMessage{Text: v.Message},
+			Locations: []sarifLocation{loc},
+		})
+	}
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
+
+func sarifLevel(s Severity) string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityNote:
+		return "note"
+	default:
+		return "none"
+	}
+}
@@ -0,0 +1,192 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/avro"
+)
+
+// BuiltinRules returns the data mesh governance rule set
+func BuiltinRules() []Rule {
+	return []Rule{
+		fieldDocRule{},
+		requiredEventFieldsRule{},
+		enumSymbolCaseRule{},
+		nullFirstUnionRule{},
+	}
+}
+
+// fieldDocRule requires every record field to be documented
+type fieldDocRule struct{}
+
+func (fieldDocRule) ID() string                { return "field-doc" }
+func (fieldDocRule) DefaultSeverity() Severity { return SeverityError }
+func (fieldDocRule) Description() string {
+	return "Every record field must have a non-empty doc attribute"
+}
+
+func (fieldDocRule) Check(schema *avro.Schema, opts Options) []Finding {
+	var findings []Finding
+	for _, record := range records(schema) {
+		for _, f := range record.Fields {
+			if strings.TrimSpace(f.Doc) == "" {
+				findings = append(findings, Finding{
+					Path:    f.Path,
+					Message: fmt.Sprintf("field %s.%s has no doc", record.Name, f.Name),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// requiredEventFieldsRule requires event schemas to carry the envelope
+// fields every consumer relies on
+type requiredEventFieldsRule struct{}
+
+var defaultEventFields = []string{"event_id", "event_timestamp", "security_classification"}
+
+func (requiredEventFieldsRule) ID() string                { return "required-event-fields" }
+func (requiredEventFieldsRule) DefaultSeverity() Severity { return SeverityError }
+func (requiredEventFieldsRule) Description() string {
+	return "Event schemas must define event_id, event_timestamp and security_classification"
+}
+
+func (requiredEventFieldsRule) Check(schema *avro.Schema, opts Options) []Finding {
+	if schema.Type != avro.Record {
+		return nil
+	}
+
+	pattern, err := regexp.Compile(opts.String("eventSchemaPattern", "Event$"))
+	if err != nil {
+		return []Finding{{Message: fmt.Sprintf("invalid eventSchemaPattern option: %v", err)}}
+	}
+	if !pattern.MatchString(schema.Name) {
+		return nil
+	}
+
+	var findings []Finding
+	for _, name := range opts.Strings("fields", defaultEventFields) {
+		f := schema.Field(name)
+		if f == nil {
+			findings = append(findings, Finding{
+				Path:    schema.Path,
+				Message: fmt.Sprintf("event schema %s is missing required field %s", schema.Name, name),
+			})
+			continue
+		}
+		if msg := checkEnvelopeType(f); msg != "" {
+			findings = append(findings, Finding{Path: f.Path + "/type", Message: msg})
+		}
+	}
+	return findings
+}
+
+// checkEnvelopeType validates the types of the well-known envelope fields
+func checkEnvelopeType(f *avro.Field) string {
+	switch f.Name {
+	case "event_id":
+		if f.Type.Type != avro.String {
+			return "event_id must be a non-nullable string"
+		}
+	case "event_timestamp":
+		if f.Type.Type != avro.Long || !strings.HasPrefix(f.Type.LogicalType, "timestamp-") {
+			return "event_timestamp must be a non-nullable long with a timestamp-millis or timestamp-micros logical type"
+		}
+	case "security_classification":
+		if f.Type.Type != avro.Enum {
+			return "security_classification must be a non-nullable enum"
+		}
+	}
+	return ""
+}
+
+// enumSymbolCaseRule requires enum symbols to be UPPER_CASE
+type enumSymbolCaseRule struct{}
+
+func (enumSymbolCaseRule) ID() string                { return "enum-symbol-case" }
+func (enumSymbolCaseRule) DefaultSeverity() Severity { return SeverityError }
+func (enumSymbolCaseRule) Description() string {
+	return "Enum symbols must be UPPER_CASE"
+}
+
+func (enumSymbolCaseRule) Check(schema *avro.Schema, opts Options) []Finding {
+	pattern, err := regexp.Compile(opts.String("pattern", "^[A-Z][A-Z0-9_]*$"))
+	if err != nil {
+		return []Finding{{Message: fmt.Sprintf("invalid pattern option: %v", err)}}
+	}
+
+	var findings []Finding
+	avro.Walk(schema, func(s *avro.Schema) {
+		if s.Type != avro.Enum {
+			return
+		}
+		for i, symbol := range s.Symbols {
+			if !pattern.MatchString(symbol) {
+				findings = append(findings, Finding{
+					Path:    fmt.Sprintf("%s/symbols/%d", s.Path, i),
+					Message: fmt.Sprintf("enum %s symbol %q is not UPPER_CASE", s.Name, symbol),
+				})
+			}
+		}
+	})
+	return findings
+}
+
+// nullFirstUnionRule requires nullable unions to list null as the first
+// branch so that the field can default to null
+type nullFirstUnionRule struct{}
+
+func (nullFirstUnionRule) ID() string                { return "nullable-union-null-first" }
+func (nullFirstUnionRule) DefaultSeverity() Severity { return SeverityError }
+func (nullFirstUnionRule) Description() string {
+	return "Nullable unions must list null as the first branch"
+}
+
+func (nullFirstUnionRule) Check(schema *avro.Schema, opts Options) []Finding {
+	var findings []Finding
+	for _, record := range records(schema) {
+		for _, f := range record.Fields {
+			for _, union := range unionsOf(f.Type) {
+				if union.Nullable() && union.Branches[0].Type != avro.Null {
+					findings = append(findings, Finding{
+						Path:    union.Path,
+						Message: fmt.Sprintf("field %s.%s is a nullable union that does not list null first", record.Name, f.Name),
+					})
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// records returns every record type reachable from schema
+func records(schema *avro.Schema) []*avro.Schema {
+	var result []*avro.Schema
+	avro.Walk(schema, func(s *avro.Schema) {
+		if s.Type == avro.Record {
+			result = append(result, s)
+		}
+	})
+	return result
+}
+
+// unionsOf returns the unions in a field type without descending into
+// named types, which are checked as records of their own
+func unionsOf(s *avro.Schema) []*avro.Schema {
+	switch s.Type {
+	case avro.Union:
+		unions := []*avro.Schema{s}
+		for _, b := range s.Branches {
+			unions = append(unions, unionsOf(b)...)
+		}
+		return unions
+	case avro.Array:
+		return unionsOf(s.Items)
+	case avro.Map:
+		return unionsOf(s.Values)
+	}
+	return nil
+}
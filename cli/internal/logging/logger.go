@@ -1,10 +1,13 @@
 package logging
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"sync"
 )
 
 // Logger is a simple logger for the CLI
@@ -30,7 +33,7 @@ func NewLogger() *Logger {
 	}
 	
 	logsDir := filepath.Join(home, ".fedramp-data-mesh", "logs")
-	if err := os.MkdirAll(logsDir, 0755); err != nil {
+	if err := os.MkdirAll(logsDir, 0700); err != nil {
 		log.Printf("Could not create logs directory: %v", err)
 		return &Logger{
 			errorLog: log.New(os.Stderr, "ERROR: ", log.Ldate|log.Ltime|log.Lshortfile),
@@ -40,43 +43,87 @@ func NewLogger() *Logger {
 		}
 	}
 	
+	// Restrict logs created by earlier versions, which were readable by
+	// other users
+	os.Chmod(logsDir, 0700)
+	
 	// Open log files
-	errorFile, err := os.OpenFile(
-		filepath.Join(logsDir, "error.log"),
-		os.O_CREATE|os.O_WRONLY|os.O_APPEND,
-		0644,
-	)
-	if err != nil {
-		log.Printf("Could not open error log file: %v", err)
-		errorFile = os.Stderr
-	}
+	errorFile := openLogFile(logsDir, "error.log", os.Stderr)
+	infoFile := openLogFile(logsDir, "info.log", os.Stdout)
+	debugFile := openLogFile(logsDir, "debug.log", os.Stdout)
 	
-	infoFile, err := os.OpenFile(
-		filepath.Join(logsDir, "info.log"),
+	return &Logger{
+		errorLog: log.New(errorFile, "ERROR: ", log.Ldate|log.Ltime|log.Lshortfile),
+		infoLog:  log.New(infoFile, "INFO: ", log.Ldate|log.Ltime),
+		debugLog: log.New(debugFile, "DEBUG: ", log.Ldate|log.Ltime|log.Lshortfile),
+		verbose:  false,
+	}
+}
+
+// openLogFile opens a log file for appending, only accessible to the
+// user, or returns fallback if it can't be opened
+func openLogFile(logsDir, name string, fallback io.Writer) io.Writer {
+	f, err := os.OpenFile(
+		filepath.Join(logsDir, name),
 		os.O_CREATE|os.O_WRONLY|os.O_APPEND,
-		0644,
+		0600,
 	)
 	if err != nil {
-		log.Printf("Could not open info log file: %v", err)
-		infoFile = os.Stdout
+		log.Printf("Could not open %s: %v", name, err)
+		return fallback
+	}
+	if err := f.Chmod(0600); err != nil {
+		log.Printf("Could not restrict %s: %v", name, err)
+	}
+	return &logFile{file: f}
+}
+
+// EncryptFiles encrypts every entry written to the log files from now on
+// with encrypt, which turns an entry into a single line. Entries that
+// can't be encrypted are not written to the files.
+func (l *Logger) EncryptFiles(encrypt func(entry []byte) ([]byte, error)) {
+	for _, logger := range []*log.Logger{l.errorLog, l.infoLog, l.debugLog} {
+		if f, ok := logger.Writer().(*logFile); ok {
+			f.setEncrypt(encrypt)
+		}
 	}
+}
+
+// logFile is a log file whose entries may be encrypted
+type logFile struct {
+	mu       sync.Mutex
+	file     *os.File
+	encrypt  func(entry []byte) ([]byte, error)
+	reported bool
+}
+
+func (f *logFile) setEncrypt(encrypt func(entry []byte) ([]byte, error)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.encrypt = encrypt
+}
+
+// Write writes one log entry; log.Logger writes every entry at once
+func (f *logFile) Write(entry []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	
-	debugFile, err := os.OpenFile(
-		filepath.Join(logsDir, "debug.log"),
-		os.O_CREATE|os.O_WRONLY|os.O_APPEND,
-		0644,
-	)
+	if f.encrypt == nil {
+		return f.file.Write(entry)
+	}
+	line, err := f.encrypt(bytes.TrimRight(entry, "\n"))
 	if err != nil {
-		log.Printf("Could not open debug log file: %v", err)
-		debugFile = os.Stdout
+		// Never fall back to plaintext; say once why entries are missing
+		if !f.reported {
+			fmt.Fprintf(os.Stderr, "WARNING: not writing log entries to %s: %v\n", f.file.Name(), err)
+			f.reported = true
+		}
+		return len(entry), nil
 	}
-	
-	return &Logger{
-		errorLog: log.New(errorFile, "ERROR: ", log.Ldate|log.Ltime|log.Lshortfile),
-		infoLog:  log.New(infoFile, "INFO: ", log.Ldate|log.Ltime),
-		debugLog: log.New(debugFile, "DEBUG: ", log.Ldate|log.Ltime|log.Lshortfile),
-		verbose:  false,
+	if _, err := f.file.Write(append(line, '\n')); err != nil {
+		return 0, err
 	}
+	return len(entry), nil
 }
 
 // SetVerbose turns on verbose logging
@@ -0,0 +1,303 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/avro"
+	"gopkg.in/yaml.v3"
+)
+
+// Kind is the manifest kind of data product definitions
+const Kind = "DataProduct"
+
+// DataProduct is a data product manifest such as
+// domains/project-management/processors/spark/project_state.yaml
+type DataProduct struct {
+	Kind       string   `yaml:"kind"`
+	APIVersion string   `yaml:"apiVersion"`
+	Metadata   Metadata `yaml:"metadata"`
+	Spec       Spec     `yaml:"spec"`
+
+	// File is the path the manifest was loaded from
+	File string `yaml:"-"`
+	// Root is the repository root that absolute paths in the manifest,
+	// such as spec.schemaRef.path, are relative to
+	Root string `yaml:"-"`
+}
+
+type Metadata struct {
+	Name          string `yaml:"name"`
+	Domain        string `yaml:"domain"`
+	Owner         string `yaml:"owner"`
+	Description   string `yaml:"description"`
+	Documentation string `yaml:"documentation"`
+}
+
+type Spec struct {
+	SchemaRef              SchemaRef    `yaml:"schemaRef"`
+	EventStream            *EventStream `yaml:"eventStream"`
+	Tables                 []Table      `yaml:"tables"`
+	SLA                    SLA          `yaml:"sla"`
+	SecurityClassification string       `yaml:"securityClassification"`
+	Lineage                Lineage      `yaml:"lineage"`
+	Access                 Access       `yaml:"access"`
+}
+
+type SchemaRef struct {
+	Type string `yaml:"type"`
+	Path string `yaml:"path"`
+}
+
+type EventStream struct {
+	TopicName    string    `yaml:"topicName"`
+	PartitionKey string    `yaml:"partitionKey"`
+	Retention    Retention `yaml:"retention"`
+	Replication  int       `yaml:"replication"`
+}
+
+type Retention struct {
+	Time      string `yaml:"time"`
+	Snapshots int    `yaml:"snapshots"`
+}
+
+type Table struct {
+	Name         string      `yaml:"name"`
+	Catalog      string      `yaml:"catalog"`
+	Format       string      `yaml:"format"`
+	Location     string      `yaml:"location"`
+	Partitioning []Partition `yaml:"partitioning"`
+	Retention    *Retention  `yaml:"retention"`
+	// ConsumerGroup is the Kafka consumer group that loads the event
+	// stream into the table, for processors that commit their offsets
+	ConsumerGroup string `yaml:"consumerGroup"`
+}
+
+type Partition struct {
+	Name      string `yaml:"name"`
+	Transform string `yaml:"transform"`
+}
+
+type SLA struct {
+	Latency      string `yaml:"latency"`
+	Availability string `yaml:"availability"`
+}
+
+type Lineage struct {
+	Upstream []Upstream `yaml:"upstream"`
+}
+
+type Upstream struct {
+	Source string `yaml:"source"`
+	Type   string `yaml:"type"`
+}
+
+type Access struct {
+	Roles []Role `yaml:"roles"`
+}
+
+type Role struct {
+	Name        string   `yaml:"name"`
+	Permissions []string `yaml:"permissions"`
+}
+
+// ID returns the product identifier, domain.name
+func (p *DataProduct) ID() string {
+	return p.Metadata.Domain + "." + p.Metadata.Name
+}
+
+// QualifiedName returns the catalog name of a table, catalog.table
+func (t *Table) QualifiedName() string {
+	return t.Catalog + "." + t.Name
+}
+
+// Table returns the table with the given name or catalog.table name
+func (p *DataProduct) Table(name string) *Table {
+	for i := range p.Spec.Tables {
+		t := &p.Spec.Tables[i]
+		if t.Name == name || t.QualifiedName() == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// SchemaPath returns the local path of the product's schema file
+func (p *DataProduct) SchemaPath() string {
+	path := p.Spec.SchemaRef.Path
+	if path == "" || !strings.HasPrefix(path, "/") {
+		return filepath.Join(filepath.Dir(p.File), filepath.FromSlash(path))
+	}
+	return filepath.Join(p.Root, filepath.FromSlash(path))
+}
+
+// LoadSchema parses the product's Avro schema
+func (p *DataProduct) LoadSchema() (*avro.Schema, error) {
+	if p.Spec.SchemaRef.Path == "" {
+		return nil, fmt.Errorf("data product %s has no spec.schemaRef.path", p.ID())
+	}
+	if p.Spec.SchemaRef.Type != "" && p.Spec.SchemaRef.Type != "avro" {
+		return nil, fmt.Errorf("data product %s: unsupported schema type %s", p.ID(), p.Spec.SchemaRef.Type)
+	}
+	return avro.ParseFile(p.SchemaPath())
+}
+
+// Load reads a single manifest; root is the repository root
+func Load(path, root string) (*DataProduct, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var product DataProduct
+	if err := yaml.Unmarshal(data, &product); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+	if product.Kind != Kind {
+		return nil, fmt.Errorf("%s is not a %s manifest", path, Kind)
+	}
+	if product.Metadata.Name == "" || product.Metadata.Domain == "" {
+		return nil, fmt.Errorf("manifest %s: metadata.name and metadata.domain are required", path)
+	}
+
+	product.File = path
+	product.Root = root
+	return &product, nil
+}
+
+// Discover finds all data product manifests below root
+func Discover(root string) ([]*DataProduct, error) {
+	var products []*DataProduct
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != root && (strings.HasPrefix(name, ".") || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ext := filepath.Ext(path); ext != ".yaml" && ext != ".yml" {
+			return nil
+		}
+
+		// Cheap check before parsing; most YAML files are not manifests
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !bytes.Contains(data, []byte("kind: "+Kind)) {
+			return nil
+		}
+
+		product, err := Load(path, root)
+		if err != nil {
+			return err
+		}
+		products = append(products, product)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover data product manifests: %w", err)
+	}
+
+	sort.Slice(products, func(i, j int) bool {
+		return products[i].ID() < products[j].ID()
+	})
+	return products, nil
+}
+
+// Find returns the manifest of a data product by its domain.name ID or by
+// the catalog.table name of one of its tables
+func Find(root, name string) (*DataProduct, error) {
+	products, err := Discover(root)
+	if err != nil {
+		return nil, err
+	}
+	if p := Lookup(products, name); p != nil {
+		return p, nil
+	}
+	return nil, fmt.Errorf("no data product manifest found for %s under %s", name, root)
+}
+
+// Lookup returns the manifest of a data product among products like Find,
+// or nil
+func Lookup(products []*DataProduct, name string) *DataProduct {
+	for _, p := range products {
+		if p.ID() == name {
+			return p
+		}
+	}
+	for _, p := range products {
+		for _, t := range p.Spec.Tables {
+			if t.QualifiedName() == name {
+				return p
+			}
+		}
+	}
+	return nil
+}
+
+// ParseDuration parses manifest durations such as 1m, 12h, 30d or 2w
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if strings.HasSuffix(s, suffix) {
+			n, err := strconv.ParseFloat(strings.TrimSuffix(s, suffix), 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(n * float64(unit)), nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// FormatDuration formats a duration like ParseDuration's input, with at
+// most two units, such as 30d, 3d4h or 1m30s
+func FormatDuration(d time.Duration) string {
+	if d < 0 {
+		d = -d
+	}
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+
+	units := []struct {
+		suffix string
+		size   time.Duration
+	}{
+		{"d", 24 * time.Hour}, {"h", time.Hour}, {"m", time.Minute}, {"s", time.Second},
+	}
+	out := ""
+	parts := 0
+	for _, u := range units {
+		if d >= u.size || (parts > 0 && parts < 2) {
+			n := d / u.size
+			d -= n * u.size
+			if n > 0 {
+				out += fmt.Sprintf("%d%s", n, u.suffix)
+			}
+			parts++
+			if parts == 2 {
+				break
+			}
+		}
+	}
+	return out
+}
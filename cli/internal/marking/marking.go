@@ -0,0 +1,160 @@
+// Package marking derives the classification markings that output of
+// data products has to carry
+package marking
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Classifications of data products, lowest first, as in the
+// SecurityClassification enum of the event schemas
+const (
+	Unclassified           = "UNCLASSIFIED"
+	ControlledUnclassified = "CONTROLLED_UNCLASSIFIED"
+	Confidential           = "CONFIDENTIAL"
+)
+
+var levels = []string{Unclassified, ControlledUnclassified, Confidential}
+
+// Marking is the classification of output derived from some products: the
+// highest classification among them
+type Marking struct {
+	// Classification is empty when output comes from no products
+	Classification string
+	// Products are the products at that classification, or all of them
+	// when output is marked higher
+	Products []string
+
+	// sources are the classifications of all products, empty for those
+	// without one
+	sources map[string]string
+}
+
+// Normalize returns the canonical name of a classification, accepting
+// CUI and names in other cases or with spaces or hyphens
+func Normalize(classification string) string {
+	c := strings.ToUpper(strings.TrimSpace(classification))
+	c = strings.NewReplacer(" ", "_", "-", "_").Replace(c)
+	if c == "CUI" {
+		return ControlledUnclassified
+	}
+	return c
+}
+
+// rank orders classifications. Unknown classifications rank above all
+// known ones, so that they are never marked lower than they may be.
+func rank(classification string) int {
+	if classification == "" {
+		return -1
+	}
+	for i, level := range levels {
+		if level == classification {
+			return i
+		}
+	}
+	return len(levels)
+}
+
+// New derives the marking from the classifications of products, by
+// product name. Products without a classification may hold anything, so
+// they are marked at the highest level until Raise gives a
+// classification.
+func New(classifications map[string]string) Marking {
+	m := Marking{sources: make(map[string]string, len(classifications))}
+	for product, c := range classifications {
+		c = Normalize(c)
+		m.sources[product] = c
+		if c == "" {
+			c = levels[len(levels)-1]
+		}
+		switch r := rank(c); {
+		case r > rank(m.Classification):
+			m.Classification, m.Products = c, []string{product}
+		case c == m.Classification:
+			m.Products = append(m.Products, product)
+		}
+	}
+	sort.Strings(m.Products)
+	return m
+}
+
+// classified returns the marking of the products that have a
+// classification
+func (m Marking) classified() Marking {
+	known := make(map[string]string)
+	for product, c := range m.sources {
+		if c != "" {
+			known[product] = c
+		}
+	}
+	return New(known)
+}
+
+// Raise marks output higher than its products require, and gives the
+// classification of products without one. Lowering the marking below the
+// classification of any product is an error.
+func (m Marking) Raise(classification string) (Marking, error) {
+	c := Normalize(classification)
+	if c == "" {
+		return m, nil
+	}
+	if rank(c) == len(levels) {
+		return m, fmt.Errorf("unknown classification %s: must be one of %s", c, strings.Join(levels, ", "))
+	}
+	base := m.classified()
+	if rank(c) < rank(base.Classification) {
+		return m, fmt.Errorf("cannot mark output %s: %s is %s", c, strings.Join(base.Products, ", "), base.Classification)
+	}
+
+	// Products without a classification are given this one
+	raised := Marking{Classification: c, sources: m.sources}
+	for product, pc := range m.sources {
+		if pc == c || pc == "" || c != base.Classification {
+			raised.Products = append(raised.Products, product)
+		}
+	}
+	sort.Strings(raised.Products)
+	return raised, nil
+}
+
+// Within reports whether output so marked may go where output up to a
+// classification is allowed. Nothing is within an unknown classification,
+// and unmarked output, whose sources are unknown, is within none.
+func (m Marking) Within(classification string) bool {
+	limit := rank(Normalize(classification))
+	return m.Known() && limit < len(levels) && rank(m.Classification) <= limit
+}
+
+// Known reports whether the classification is known
+func (m Marking) Known() bool {
+	return m.Classification != ""
+}
+
+// Banner is the banner line for the top and bottom of output, such as
+// CUI for controlled unclassified information
+func (m Marking) Banner() string {
+	switch m.Classification {
+	case "":
+		return ""
+	case ControlledUnclassified:
+		return "CUI"
+	}
+	return strings.ReplaceAll(m.Classification, "_", " ")
+}
+
+// Metadata returns the marking as key-value metadata for files
+func (m Marking) Metadata() map[string]string {
+	if !m.Known() {
+		return nil
+	}
+	metadata := map[string]string{
+		"classification": m.Classification,
+		"marking":        m.Banner(),
+	}
+	if len(m.Products) > 0 {
+		metadata["classification_source"] = strings.Join(m.Products, ",")
+	}
+	return metadata
+}
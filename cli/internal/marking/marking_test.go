@@ -0,0 +1,157 @@
+package marking
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"cui":                     ControlledUnclassified,
+		" CUI ":                   ControlledUnclassified,
+		"controlled unclassified": ControlledUnclassified,
+		"Controlled-Unclassified": ControlledUnclassified,
+		"unclassified":            Unclassified,
+		"":                        "",
+		"secret":                  "SECRET",
+	}
+	for in, want := range tests {
+		if got := Normalize(in); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		sources  map[string]string
+		want     string
+		products []string
+	}{
+		{"no products", nil, "", nil},
+		{"one product", map[string]string{"sales.orders": "cui"}, ControlledUnclassified, []string{"sales.orders"}},
+		{"highest wins", map[string]string{"a": Unclassified, "b": ControlledUnclassified, "c": ControlledUnclassified},
+			ControlledUnclassified, []string{"b", "c"}},
+		// Products without a classification may hold anything
+		{"unknown is highest", map[string]string{"a": Unclassified, "b": ""}, Confidential, []string{"b"}},
+		{"unknown with highest", map[string]string{"a": Confidential, "b": ""}, Confidential, []string{"a", "b"}},
+		// Classifications the CLI doesn't know rank above all others
+		{"unrecognized", map[string]string{"a": Confidential, "b": "secret"}, "SECRET", []string{"b"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := New(test.sources)
+			if m.Classification != test.want || !reflect.DeepEqual(m.Products, test.products) {
+				t.Errorf("got %s from %v, want %s from %v", m.Classification, m.Products, test.want, test.products)
+			}
+			if m.Known() != (test.want != "") {
+				t.Errorf("Known() = %t", m.Known())
+			}
+		})
+	}
+}
+
+func TestRaise(t *testing.T) {
+	tests := []struct {
+		name     string
+		sources  map[string]string
+		raise    string
+		want     string
+		products []string
+		err      string
+	}{
+		{"nothing to raise to", map[string]string{"a": "cui"}, "", ControlledUnclassified, []string{"a"}, ""},
+		{"same level", map[string]string{"a": "cui", "b": Unclassified}, "cui", ControlledUnclassified, []string{"a"}, ""},
+		// The source products are kept when marked higher
+		{"higher", map[string]string{"a": "cui", "b": Unclassified}, Confidential, Confidential, []string{"a", "b"}, ""},
+		{"lower", map[string]string{"a": "cui"}, Unclassified, "", nil, "cannot mark output UNCLASSIFIED: a is CONTROLLED_UNCLASSIFIED"},
+		{"unknown level", map[string]string{"a": "cui"}, "secret", "", nil, "unknown classification SECRET"},
+		// Products without a classification are given the one raised to,
+		// which may be lower than the highest they were marked at
+		{"gives unknown", map[string]string{"a": Unclassified, "b": ""}, "cui", ControlledUnclassified, []string{"a", "b"}, ""},
+		{"gives unknown at level", map[string]string{"a": Unclassified, "b": ""}, Unclassified, Unclassified, []string{"a", "b"}, ""},
+		{"no products", nil, "cui", ControlledUnclassified, nil, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, err := New(test.sources).Raise(test.raise)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.Classification != test.want || !reflect.DeepEqual(m.Products, test.products) {
+				t.Errorf("got %s from %v, want %s from %v", m.Classification, m.Products, test.want, test.products)
+			}
+		})
+	}
+
+	// Raising again starts from the products, not the earlier raise
+	m, err := New(map[string]string{"a": Unclassified}).Raise(Confidential)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, err = m.Raise("cui"); err != nil || m.Classification != ControlledUnclassified {
+		t.Errorf("raised again to %s, %v, want %s", m.Classification, err, ControlledUnclassified)
+	}
+}
+
+func TestWithin(t *testing.T) {
+	tests := []struct {
+		marking string
+		limit   string
+		want    bool
+	}{
+		{Unclassified, Unclassified, true},
+		{Unclassified, "cui", true},
+		{ControlledUnclassified, "CUI", true},
+		{ControlledUnclassified, Unclassified, false},
+		{Confidential, "cui", false},
+		{Confidential, Confidential, true},
+		// Nothing is within an unknown limit, nor is unmarked output
+		// within any
+		{Unclassified, "secret", false},
+		{Unclassified, "", false},
+		{"", Confidential, false},
+		{"SECRET", Confidential, false},
+	}
+	for _, test := range tests {
+		m := Marking{Classification: test.marking}
+		if got := m.Within(test.limit); got != test.want {
+			t.Errorf("%q within %q = %t, want %t", test.marking, test.limit, got, test.want)
+		}
+	}
+}
+
+func TestBannerAndMetadata(t *testing.T) {
+	tests := []struct {
+		marking  Marking
+		banner   string
+		metadata map[string]string
+	}{
+		{Marking{}, "", nil},
+		{New(map[string]string{"b": "cui", "a": "cui"}), "CUI", map[string]string{
+			"classification": ControlledUnclassified, "marking": "CUI", "classification_source": "a,b",
+		}},
+		{New(map[string]string{"a": Unclassified}), "UNCLASSIFIED", map[string]string{
+			"classification": Unclassified, "marking": "UNCLASSIFIED", "classification_source": "a",
+		}},
+		{Marking{Classification: Confidential}, "CONFIDENTIAL", map[string]string{
+			"classification": Confidential, "marking": "CONFIDENTIAL",
+		}},
+	}
+	for _, test := range tests {
+		if got := test.marking.Banner(); got != test.banner {
+			t.Errorf("%s: banner %q, want %q", test.marking.Classification, got, test.banner)
+		}
+		if got := test.marking.Metadata(); !reflect.DeepEqual(got, test.metadata) {
+			t.Errorf("%s: metadata %v, want %v", test.marking.Classification, got, test.metadata)
+		}
+	}
+}
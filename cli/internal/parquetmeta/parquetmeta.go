@@ -0,0 +1,410 @@
+// Package parquetmeta sets key-value metadata in the footer of Parquet
+// files, for writers such as DuckDB's COPY that can't set it themselves.
+//
+// The footer is a FileMetaData struct in the Thrift compact protocol. Its
+// fields are copied as they are, except key_value_metadata, which is
+// rewritten with the new entries.
+package parquetmeta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+var magic = []byte("PAR1")
+
+// keyValueField is the id of key_value_metadata in FileMetaData
+const keyValueField = 5
+
+// Thrift compact protocol types
+const (
+	typeBoolTrue  = 1
+	typeBoolFalse = 2
+	typeByte      = 3
+	typeI16       = 4
+	typeI32       = 5
+	typeI64       = 6
+	typeDouble    = 7
+	typeBinary    = 8
+	typeList      = 9
+	typeSet       = 10
+	typeMap       = 11
+	typeStruct    = 12
+)
+
+var errCorrupt = errors.New("not a valid Parquet file")
+
+// Set adds key-value metadata to a Parquet file, replacing entries with
+// the same keys
+func Set(path string, metadata map[string]string) error {
+	if len(metadata) == 0 {
+		return nil
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+	if size < 12 {
+		return errCorrupt
+	}
+
+	head := make([]byte, 4)
+	if _, err := f.ReadAt(head, 0); err != nil {
+		return err
+	}
+	tail := make([]byte, 8)
+	if _, err := f.ReadAt(tail, size-8); err != nil {
+		return err
+	}
+	if !bytes.Equal(head, magic) || !bytes.Equal(tail[4:], magic) {
+		return errCorrupt
+	}
+	footerLen := int64(binary.LittleEndian.Uint32(tail))
+	footerStart := size - 8 - footerLen
+	if footerStart < 4 {
+		return errCorrupt
+	}
+	footer := make([]byte, footerLen)
+	if _, err := f.ReadAt(footer, footerStart); err != nil {
+		return err
+	}
+
+	updated, err := setKeyValues(footer, metadata)
+	if err != nil {
+		return fmt.Errorf("failed to update Parquet metadata: %w", err)
+	}
+	updated = binary.LittleEndian.AppendUint32(updated, uint32(len(updated)))
+	updated = append(updated, magic...)
+
+	if err := f.Truncate(footerStart); err != nil {
+		return err
+	}
+	if _, err := f.WriteAt(updated, footerStart); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// field is a field of the FileMetaData struct with its encoded value
+type field struct {
+	id    int16
+	typ   byte
+	value []byte
+}
+
+// setKeyValues rewrites a FileMetaData struct with merged key-value
+// metadata
+func setKeyValues(footer []byte, metadata map[string]string) ([]byte, error) {
+	r := &reader{buf: footer}
+	var fields []field
+	var lastID int16
+	for {
+		id, typ, err := r.fieldHeader(lastID)
+		if err != nil {
+			return nil, err
+		}
+		if typ == 0 {
+			break
+		}
+		start := r.pos
+		if err := r.skip(typ); err != nil {
+			return nil, err
+		}
+		fields = append(fields, field{id: id, typ: typ, value: footer[start:r.pos]})
+		lastID = id
+	}
+
+	// Merge with the existing entries, keeping their order
+	var entries []keyValue
+	for i, f := range fields {
+		if f.id != keyValueField {
+			continue
+		}
+		existing, err := readKeyValues(f.value)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range existing {
+			if _, ok := metadata[e.key]; !ok {
+				entries = append(entries, e)
+			}
+		}
+		fields = append(fields[:i], fields[i+1:]...)
+		break
+	}
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		entries = append(entries, keyValue{key: k, value: metadata[k], hasValue: true})
+	}
+	fields = append(fields, field{id: keyValueField, typ: typeList, value: writeKeyValues(entries)})
+	sort.SliceStable(fields, func(i, j int) bool { return fields[i].id < fields[j].id })
+
+	var out []byte
+	lastID = 0
+	for _, f := range fields {
+		if delta := f.id - lastID; delta > 0 && delta <= 15 {
+			out = append(out, byte(delta)<<4|f.typ)
+		} else {
+			out = append(out, f.typ)
+			out = appendVarint(out, zigzag(int64(f.id)))
+		}
+		out = append(out, f.value...)
+		lastID = f.id
+	}
+	return append(out, 0), nil
+}
+
+// keyValue is a KeyValue struct: field 1 is the key, field 2 the
+// optional value
+type keyValue struct {
+	key      string
+	value    string
+	hasValue bool
+}
+
+func readKeyValues(list []byte) ([]keyValue, error) {
+	r := &reader{buf: list}
+	size, elemType, err := r.listHeader()
+	if err != nil {
+		return nil, err
+	}
+	if elemType != typeStruct && size > 0 {
+		return nil, errCorrupt
+	}
+
+	entries := make([]keyValue, 0, size)
+	for i := 0; i < size; i++ {
+		var e keyValue
+		var lastID int16
+		for {
+			id, typ, err := r.fieldHeader(lastID)
+			if err != nil {
+				return nil, err
+			}
+			if typ == 0 {
+				break
+			}
+			if (id == 1 || id == 2) && typ == typeBinary {
+				s, err := r.binary()
+				if err != nil {
+					return nil, err
+				}
+				if id == 1 {
+					e.key = string(s)
+				} else {
+					e.value, e.hasValue = string(s), true
+				}
+			} else if err := r.skip(typ); err != nil {
+				return nil, err
+			}
+			lastID = id
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func writeKeyValues(entries []keyValue) []byte {
+	var out []byte
+	if len(entries) < 15 {
+		out = append(out, byte(len(entries))<<4|typeStruct)
+	} else {
+		out = append(out, 0xf0|typeStruct)
+		out = appendVarint(out, uint64(len(entries)))
+	}
+	for _, e := range entries {
+		out = append(out, 1<<4|typeBinary)
+		out = appendVarint(out, uint64(len(e.key)))
+		out = append(out, e.key...)
+		if e.hasValue {
+			out = append(out, 1<<4|typeBinary)
+			out = appendVarint(out, uint64(len(e.value)))
+			out = append(out, e.value...)
+		}
+		out = append(out, 0)
+	}
+	return out
+}
+
+// reader decodes the Thrift compact protocol
+type reader struct {
+	buf []byte
+	pos int
+}
+
+func (r *reader) byte() (byte, error) {
+	if r.pos >= len(r.buf) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	b := r.buf[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *reader) varint() (uint64, error) {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	if n <= 0 {
+		return 0, errCorrupt
+	}
+	r.pos += n
+	return v, nil
+}
+
+func (r *reader) advance(n uint64) error {
+	if n > uint64(len(r.buf)-r.pos) {
+		return io.ErrUnexpectedEOF
+	}
+	r.pos += int(n)
+	return nil
+}
+
+func (r *reader) binary() ([]byte, error) {
+	n, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	start := r.pos
+	if err := r.advance(n); err != nil {
+		return nil, err
+	}
+	return r.buf[start:r.pos], nil
+}
+
+// fieldHeader reads a field id and type; type 0 is the end of the struct
+func (r *reader) fieldHeader(lastID int16) (int16, byte, error) {
+	b, err := r.byte()
+	if err != nil {
+		return 0, 0, err
+	}
+	typ := b & 0x0f
+	if typ == 0 {
+		return 0, 0, nil
+	}
+	if delta := b >> 4; delta != 0 {
+		return lastID + int16(delta), typ, nil
+	}
+	v, err := r.varint()
+	if err != nil {
+		return 0, 0, err
+	}
+	return int16(unzigzag(v)), typ, nil
+}
+
+func (r *reader) listHeader() (int, byte, error) {
+	b, err := r.byte()
+	if err != nil {
+		return 0, 0, err
+	}
+	size := uint64(b >> 4)
+	if size == 15 {
+		if size, err = r.varint(); err != nil {
+			return 0, 0, err
+		}
+	}
+	if size > uint64(len(r.buf)) {
+		return 0, 0, errCorrupt
+	}
+	return int(size), b & 0x0f, nil
+}
+
+// skip reads past a value of a type
+func (r *reader) skip(typ byte) error {
+	switch typ {
+	case typeBoolTrue, typeBoolFalse:
+		// Struct fields carry booleans in the header
+		return nil
+	case typeByte:
+		return r.advance(1)
+	case typeI16, typeI32, typeI64:
+		_, err := r.varint()
+		return err
+	case typeDouble:
+		return r.advance(8)
+	case typeBinary:
+		_, err := r.binary()
+		return err
+	case typeList, typeSet:
+		size, elemType, err := r.listHeader()
+		if err != nil {
+			return err
+		}
+		for i := 0; i < size; i++ {
+			if err := r.skipElement(elemType); err != nil {
+				return err
+			}
+		}
+		return nil
+	case typeMap:
+		size, err := r.varint()
+		if err != nil || size == 0 {
+			return err
+		}
+		types, err := r.byte()
+		if err != nil {
+			return err
+		}
+		for i := uint64(0); i < size; i++ {
+			if err := r.skipElement(types >> 4); err != nil {
+				return err
+			}
+			if err := r.skipElement(types & 0x0f); err != nil {
+				return err
+			}
+		}
+		return nil
+	case typeStruct:
+		var lastID int16
+		for {
+			id, t, err := r.fieldHeader(lastID)
+			if err != nil {
+				return err
+			}
+			if t == 0 {
+				return nil
+			}
+			if err := r.skip(t); err != nil {
+				return err
+			}
+			lastID = id
+		}
+	}
+	return errCorrupt
+}
+
+// skipElement reads past an element of a list, set or map, in which
+// booleans take a byte
+func (r *reader) skipElement(typ byte) error {
+	if typ == typeBoolTrue || typ == typeBoolFalse {
+		return r.advance(1)
+	}
+	return r.skip(typ)
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	return binary.AppendUvarint(b, v)
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+func unzigzag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
@@ -0,0 +1,121 @@
+package parquetmeta
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow/go/v14/parquet/file"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/duckdb"
+)
+
+// testQuery has a row of values and a row of NULLs of the types exports
+// have to keep: decimals, timestamps and nested lists, structs and maps
+const testQuery = `SELECT * FROM (VALUES
+	(1, 'a', 12345.67::DECIMAL(18, 2), TIMESTAMP '2024-03-01 12:34:56.789', TIMESTAMPTZ '2024-03-01 12:34:56+00',
+		[1, 2, NULL], {'x': 1, 'y': 'b'}, MAP {'k': [1.5]}),
+	(2, NULL, NULL, NULL, NULL, NULL, NULL, NULL)
+) t(id, name, amount, at, at_tz, list, struct, map)`
+
+func TestSet(t *testing.T) {
+	db, err := duckdb.NewLocalConnection(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	path := filepath.Join(t.TempDir(), "results.parquet")
+	if _, err := db.CopyToParquet(path, testQuery); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set(path, map[string]string{"classification": "CONTROLLED_UNCLASSIFIED", "marking": "CUI"}); err != nil {
+		t.Fatal(err)
+	}
+	// Setting a key again replaces it
+	if err := Set(path, map[string]string{"marking": "CUI//SP-PRVCY", "classification_source": "orders"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Read the footer back with another Parquet implementation
+	reader, err := file.OpenParquetFile(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, kv := range reader.MetaData().KeyValueMetadata() {
+		got[kv.Key] = *kv.Value
+	}
+	reader.Close()
+	want := map[string]string{
+		"classification":        "CONTROLLED_UNCLASSIFIED",
+		"classification_source": "orders",
+		"marking":               "CUI//SP-PRVCY",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("metadata = %v, want %v", got, want)
+	}
+
+	// The data is untouched
+	expected, err := db.ExecuteQuery(testQuery + " ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := db.ExecuteQuery(fmt.Sprintf("SELECT * FROM read_parquet('%s') ORDER BY id", path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual.ColumnTypes, expected.ColumnTypes) {
+		t.Errorf("column types = %v, want %v", actual.ColumnTypes, expected.ColumnTypes)
+	}
+	if !reflect.DeepEqual(actual.Rows, expected.Rows) {
+		t.Errorf("rows = %v, want %v", actual.Rows, expected.Rows)
+	}
+}
+
+func TestCopyToParquetComments(t *testing.T) {
+	db, err := duckdb.NewLocalConnection(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// Queries are exported as they are written in the editor
+	queries := []string{
+		"SELECT 1 AS x",
+		"SELECT 1 AS x -- one row",
+		"SELECT 1 AS x; -- one row",
+		"SELECT 1 AS x;\n-- one row\n/* and no more */ ;\n",
+		"SELECT '--;' AS x /* a; comment */",
+		"SELECT 1 AS x -- an unterminated ' quote",
+	}
+	for i, query := range queries {
+		path := filepath.Join(t.TempDir(), fmt.Sprintf("results%d.parquet", i))
+		rows, err := db.CopyToParquet(path, query)
+		if err != nil {
+			t.Errorf("%q: %v", query, err)
+			continue
+		}
+		if rows != 1 {
+			t.Errorf("%q: %d rows, want 1", query, rows)
+		}
+	}
+}
+
+func TestSetNotParquet(t *testing.T) {
+	db, err := duckdb.NewLocalConnection(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	path := filepath.Join(t.TempDir(), "results.csv")
+	if _, err := db.ExecuteQuery(fmt.Sprintf("COPY (SELECT 1 AS id) TO '%s' (FORMAT csv)", path)); err != nil {
+		t.Fatal(err)
+	}
+	err = Set(path, map[string]string{"marking": "CUI"})
+	if err == nil || !strings.Contains(err.Error(), "Parquet") {
+		t.Errorf("Set of a CSV file = %v, want an error", err)
+	}
+}
@@ -0,0 +1,419 @@
+package profile
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/duckdb"
+	godb "github.com/marcboeker/go-duckdb"
+)
+
+// Defaults for the number of top values and histogram bins
+const (
+	DefaultTopK = 5
+	DefaultBins = 10
+)
+
+// sampleTable holds the sampled rows while a table is profiled
+const sampleTable = "__dmesh_profile_sample"
+
+// DB is the part of duckdb.Connection the profiler needs
+type DB interface {
+	ExecuteQuery(query string) (*duckdb.QueryResult, error)
+}
+
+// Options control how a table is profiled
+type Options struct {
+	// Sample is a percentage such as 1% or a row count such as 10000;
+	// empty profiles all rows
+	Sample string
+	// TopK is the number of most frequent values kept per column
+	TopK int
+	// Bins is the number of buckets of numeric and length histograms
+	Bins int
+}
+
+// Profile holds the statistics of a table
+type Profile struct {
+	Table     string          `json:"table"`
+	Sample    string          `json:"sample,omitempty"`
+	Rows      int64           `json:"rows"`
+	CreatedAt time.Time       `json:"created_at"`
+	Seconds   float64         `json:"seconds"`
+	Columns   []ColumnProfile `json:"columns"`
+}
+
+// ColumnProfile holds the statistics of one column. Min, Max and the
+// quartiles are DuckDB's text rendering of the values.
+type ColumnProfile struct {
+	Name      string  `json:"name"`
+	Type      string  `json:"type"`
+	Count     int64   `json:"count"`
+	Nulls     int64   `json:"nulls"`
+	NullRatio float64 `json:"null_ratio"`
+	Distinct  int64   `json:"distinct"`
+	// DistinctApprox is set when Distinct is DuckDB's HyperLogLog estimate
+	DistinctApprox bool         `json:"distinct_approx,omitempty"`
+	Min            string       `json:"min,omitempty"`
+	Max            string       `json:"max,omitempty"`
+	Avg            *float64     `json:"avg,omitempty"`
+	Std            *float64     `json:"std,omitempty"`
+	Q25            string       `json:"q25,omitempty"`
+	Q50            string       `json:"q50,omitempty"`
+	Q75            string       `json:"q75,omitempty"`
+	TopValues      []ValueCount `json:"top_values,omitempty"`
+	Histogram      []Bin        `json:"histogram,omitempty"`
+	Lengths        *LengthStats `json:"lengths,omitempty"`
+	// Errors lists the statistics that could not be computed
+	Errors []string `json:"errors,omitempty"`
+}
+
+// ValueCount is a value and the number of rows holding it
+type ValueCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// Bin is a histogram bucket. High is exclusive except for the last bin;
+// for integers both bounds are inclusive.
+type Bin struct {
+	Low   float64 `json:"low"`
+	High  float64 `json:"high"`
+	Count int64   `json:"count"`
+}
+
+// LengthStats describes the lengths of string values
+type LengthStats struct {
+	Min       int64   `json:"min"`
+	Max       int64   `json:"max"`
+	Avg       float64 `json:"avg"`
+	Histogram []Bin   `json:"histogram,omitempty"`
+}
+
+// Table profiles a registered table or view: DuckDB's SUMMARIZE plus
+// exact null and distinct counts, the most frequent values, histograms of
+// numeric columns and the length distribution of strings
+func Table(db DB, table string, opts Options) (*Profile, error) {
+	if opts.TopK <= 0 {
+		opts.TopK = DefaultTopK
+	}
+	if opts.Bins <= 0 {
+		opts.Bins = DefaultBins
+	}
+
+	start := time.Now()
+	p := &Profile{Table: table, Sample: opts.Sample, CreatedAt: start.UTC()}
+
+	// Materialize the sample so that every statistic sees the same rows.
+	// Not a temp table: the connection pool may run queries on different
+	// connections of the in-memory database.
+	src := quoteIdentifier(table)
+	if opts.Sample != "" {
+		clause, err := SampleClause(opts.Sample)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := db.ExecuteQuery(fmt.Sprintf("CREATE OR REPLACE TABLE %s AS SELECT * FROM %s USING SAMPLE %s",
+			quoteIdentifier(sampleTable), src, clause)); err != nil {
+			return nil, fmt.Errorf("failed to sample %s: %w", table, err)
+		}
+		defer db.ExecuteQuery("DROP TABLE IF EXISTS " + quoteIdentifier(sampleTable))
+		src = quoteIdentifier(sampleTable)
+	}
+
+	rows, err := queryInt(db, "SELECT count(*) FROM "+src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count rows of %s: %w", table, err)
+	}
+	p.Rows = rows
+
+	summary, err := db.ExecuteQuery("SUMMARIZE SELECT * FROM " + src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize %s: %w", table, err)
+	}
+	for _, row := range summary.Rows {
+		c := summaryColumn(summary.Columns, row)
+		profileColumn(db, src, &c, opts)
+		p.Columns = append(p.Columns, c)
+	}
+
+	p.Seconds = time.Since(start).Seconds()
+	return p, nil
+}
+
+// SampleClause turns 1% or 10000 (rows) into a DuckDB USING SAMPLE clause.
+// Percentages use Bernoulli sampling so that small tables still yield
+// rows; row counts use reservoir sampling.
+func SampleClause(sample string) (string, error) {
+	s := strings.ToLower(strings.TrimSpace(sample))
+	if strings.HasSuffix(s, "%") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(s, "%")), 64)
+		if err != nil || v <= 0 || v > 100 {
+			return "", fmt.Errorf("invalid sample %q: percentage must be between 0 and 100", sample)
+		}
+		return fmt.Sprintf("%s%% (bernoulli)", strconv.FormatFloat(v, 'f', -1, 64)), nil
+	}
+	s = strings.TrimSpace(strings.TrimSuffix(s, "rows"))
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 {
+		return "", fmt.Errorf("invalid sample %q: use a percentage such as 1%% or a row count", sample)
+	}
+	return fmt.Sprintf("%d ROWS", n), nil
+}
+
+// summaryColumn reads a row of SUMMARIZE output
+func summaryColumn(columns []string, row []interface{}) ColumnProfile {
+	var c ColumnProfile
+	for i, name := range columns {
+		v := row[i]
+		switch name {
+		case "column_name":
+			c.Name = text(v)
+		case "column_type":
+			c.Type = text(v)
+		case "min":
+			c.Min = text(v)
+		case "max":
+			c.Max = text(v)
+		case "approx_unique":
+			if n, ok := number(v); ok {
+				c.Distinct = int64(n)
+				c.DistinctApprox = true
+			}
+		case "avg":
+			if n, ok := number(v); ok {
+				c.Avg = &n
+			}
+		case "std":
+			if n, ok := number(v); ok {
+				c.Std = &n
+			}
+		case "q25":
+			c.Q25 = text(v)
+		case "q50":
+			c.Q50 = text(v)
+		case "q75":
+			c.Q75 = text(v)
+		case "count":
+			if n, ok := number(v); ok {
+				c.Count = int64(n)
+			}
+		case "null_percentage":
+			// Rendered as 12.5%; replaced by the exact count later
+			if n, ok := parseFloat(strings.TrimSuffix(text(v), "%")); ok {
+				c.NullRatio = n / 100
+			}
+		}
+	}
+	c.Nulls = int64(math.Round(c.NullRatio * float64(c.Count)))
+	return c
+}
+
+// profileColumn adds the statistics SUMMARIZE doesn't compute exactly
+func profileColumn(db DB, src string, c *ColumnProfile, opts Options) {
+	col := quoteIdentifier(c.Name)
+
+	// Exact null and distinct counts
+	counts, err := db.ExecuteQuery(fmt.Sprintf("SELECT count(*) - count(%s), count(DISTINCT %s) FROM %s", col, col, src))
+	if err != nil {
+		c.Errors = append(c.Errors, fmt.Sprintf("distinct: %v", err))
+	} else if len(counts.Rows) == 1 {
+		if n, ok := number(counts.Rows[0][0]); ok {
+			c.Nulls = int64(n)
+		}
+		if n, ok := number(counts.Rows[0][1]); ok {
+			c.Distinct = int64(n)
+			c.DistinctApprox = false
+		}
+	}
+	if c.Count > 0 {
+		c.NullRatio = float64(c.Nulls) / float64(c.Count)
+	}
+	if c.Count == c.Nulls {
+		return
+	}
+
+	// Most frequent values
+	top, err := db.ExecuteQuery(fmt.Sprintf(
+		"SELECT CAST(%s AS VARCHAR) AS value, count(*) AS n FROM %s WHERE %s IS NOT NULL GROUP BY 1 ORDER BY n DESC, value LIMIT %d",
+		col, src, col, opts.TopK))
+	if err != nil {
+		c.Errors = append(c.Errors, fmt.Sprintf("top values: %v", err))
+	} else {
+		for _, row := range top.Rows {
+			n, _ := number(row[1])
+			c.TopValues = append(c.TopValues, ValueCount{Value: text(row[0]), Count: int64(n)})
+		}
+	}
+
+	switch {
+	case isNumeric(c.Type):
+		low, lowOK := parseFloat(c.Min)
+		high, highOK := parseFloat(c.Max)
+		if !lowOK || !highOK {
+			return
+		}
+		bins, err := histogram(db, src, col+"::DOUBLE", low, high, opts.Bins, isInteger(c.Type))
+		if err != nil {
+			c.Errors = append(c.Errors, fmt.Sprintf("histogram: %v", err))
+			return
+		}
+		c.Histogram = bins
+
+	case c.Type == "VARCHAR":
+		length := fmt.Sprintf("length(%s)", col)
+		stats, err := db.ExecuteQuery(fmt.Sprintf("SELECT min(%s), max(%s), avg(%s) FROM %s", length, length, length, src))
+		if err != nil || len(stats.Rows) != 1 {
+			c.Errors = append(c.Errors, fmt.Sprintf("lengths: %v", err))
+			return
+		}
+		min, _ := number(stats.Rows[0][0])
+		max, _ := number(stats.Rows[0][1])
+		avg, _ := number(stats.Rows[0][2])
+		c.Lengths = &LengthStats{Min: int64(min), Max: int64(max), Avg: avg}
+
+		bins, err := histogram(db, src, length+"::DOUBLE", min, max, opts.Bins, true)
+		if err != nil {
+			c.Errors = append(c.Errors, fmt.Sprintf("length histogram: %v", err))
+			return
+		}
+		c.Lengths.Histogram = bins
+	}
+}
+
+// histogram counts the non-null values of expr in equal-width bins
+// between low and high. Integer bins have integer widths, so there may be
+// fewer of them.
+func histogram(db DB, src, expr string, low, high float64, bins int, integral bool) ([]Bin, error) {
+	if high <= low {
+		n, err := queryInt(db, fmt.Sprintf("SELECT count(%s) FROM %s", expr, src))
+		if err != nil {
+			return nil, err
+		}
+		return []Bin{{Low: low, High: high, Count: n}}, nil
+	}
+
+	width := (high - low) / float64(bins)
+	if integral {
+		width = math.Ceil((high - low + 1) / float64(bins))
+		bins = int(math.Ceil((high - low + 1) / width))
+	}
+	result, err := db.ExecuteQuery(fmt.Sprintf(
+		"SELECT least(CAST(floor((%s - %v) / %v) AS BIGINT), %d) AS bin, count(*) FROM %s WHERE %s IS NOT NULL GROUP BY 1 ORDER BY 1",
+		expr, low, width, bins-1, src, expr))
+	if err != nil {
+		return nil, err
+	}
+
+	histogram := make([]Bin, bins)
+	for i := range histogram {
+		histogram[i] = Bin{Low: low + float64(i)*width, High: low + float64(i+1)*width}
+		if integral {
+			histogram[i].High--
+		}
+	}
+	histogram[bins-1].High = high
+	for _, row := range result.Rows {
+		i, _ := number(row[0])
+		n, _ := number(row[1])
+		if int(i) >= 0 && int(i) < bins {
+			histogram[int(i)].Count += int64(n)
+		}
+	}
+	return histogram, nil
+}
+
+func queryInt(db DB, query string) (int64, error) {
+	result, err := db.ExecuteQuery(query)
+	if err != nil {
+		return 0, err
+	}
+	if len(result.Rows) != 1 || len(result.Rows[0]) != 1 {
+		return 0, fmt.Errorf("unexpected result of %s", query)
+	}
+	n, ok := number(result.Rows[0][0])
+	if !ok {
+		return 0, fmt.Errorf("unexpected result of %s: %v", query, result.Rows[0][0])
+	}
+	return int64(n), nil
+}
+
+// isNumeric reports whether a DuckDB type is numeric
+func isNumeric(typ string) bool {
+	switch typ {
+	case "TINYINT", "SMALLINT", "INTEGER", "BIGINT", "HUGEINT",
+		"UTINYINT", "USMALLINT", "UINTEGER", "UBIGINT", "FLOAT", "DOUBLE":
+		return true
+	}
+	return strings.HasPrefix(typ, "DECIMAL")
+}
+
+// isInteger reports whether a DuckDB type is an integer type
+func isInteger(typ string) bool {
+	return isNumeric(typ) && typ != "FLOAT" && typ != "DOUBLE" && !strings.HasPrefix(typ, "DECIMAL")
+}
+
+// number converts the numeric values DuckDB returns, and SUMMARIZE's
+// numbers rendered as text
+func number(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(v).Float64()
+		return f, true
+	case godb.Decimal:
+		scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(v.Scale)), nil)
+		f, _ := new(big.Rat).SetFrac(v.Value, scale).Float64()
+		return f, true
+	case string:
+		return parseFloat(v)
+	}
+	return 0, false
+}
+
+func parseFloat(s string) (float64, bool) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false
+	}
+	return f, true
+}
+
+func text(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return fmt.Sprint(v)
+}
+
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,207 @@
+package profile
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// barWidth is the width of the longest histogram bar
+const barWidth = 30
+
+// WriteReport writes a profile in the given format (table, json, markdown)
+func WriteReport(w io.Writer, p *Profile, format string) error {
+	switch strings.ToLower(format) {
+	case "table", "":
+		return writeTable(w, p)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(p)
+	case "markdown", "md":
+		return writeMarkdown(w, p)
+	default:
+		return fmt.Errorf("unsupported profile format: %s", format)
+	}
+}
+
+func writeTable(w io.Writer, p *Profile) error {
+	fmt.Fprintf(w, "Profile of %s: %d rows", p.Table, p.Rows)
+	if p.Sample != "" {
+		fmt.Fprintf(w, " (sample %s)", p.Sample)
+	}
+	fmt.Fprintf(w, ", %d columns\n\n", len(p.Columns))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "COLUMN\tTYPE\tNULLS\tDISTINCT\tMIN\tMAX\tAVG")
+	for _, c := range p.Columns {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", c.Name, truncate(c.Type, 24), percent(c.NullRatio),
+			distinct(c), truncate(c.Min, 24), truncate(c.Max, 24), float(c.Avg))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, c := range p.Columns {
+		if len(c.TopValues) == 0 && len(c.Histogram) == 0 && c.Lengths == nil && len(c.Errors) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s (%s)\n", c.Name, c.Type)
+
+		if len(c.TopValues) > 0 {
+			fmt.Fprintln(w, "  Top values:")
+			tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+			for _, v := range c.TopValues {
+				fmt.Fprintf(tw, "    %s\t%d\t%s\n", truncate(v.Value, 40), v.Count, percent(ratio(v.Count, c.Count)))
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+		}
+		if len(c.Histogram) > 0 {
+			fmt.Fprintln(w, "  Histogram:")
+			if err := writeBins(w, c.Histogram); err != nil {
+				return err
+			}
+		}
+		if c.Lengths != nil {
+			fmt.Fprintf(w, "  Length: min %d, max %d, avg %.1f\n", c.Lengths.Min, c.Lengths.Max, c.Lengths.Avg)
+			if len(c.Lengths.Histogram) > 0 {
+				if err := writeBins(w, c.Lengths.Histogram); err != nil {
+					return err
+				}
+			}
+		}
+		for _, e := range c.Errors {
+			fmt.Fprintf(w, "  Error: %s\n", e)
+		}
+	}
+	return nil
+}
+
+// writeBins draws a histogram as bars scaled to the largest bin
+func writeBins(w io.Writer, bins []Bin) error {
+	var max int64
+	for _, b := range bins {
+		if b.Count > max {
+			max = b.Count
+		}
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', tabwriter.AlignRight)
+	for _, b := range bins {
+		bar := 0
+		if max > 0 {
+			bar = int(b.Count * barWidth / max)
+		}
+		if bar == 0 && b.Count > 0 {
+			bar = 1
+		}
+		fmt.Fprintf(tw, "    %s\t%d\t %s\n", binRange(b, " - "), b.Count, strings.Repeat("█", bar))
+	}
+	return tw.Flush()
+}
+
+func writeMarkdown(w io.Writer, p *Profile) error {
+	fmt.Fprintf(w, "# Profile of `%s`\n\n", p.Table)
+	fmt.Fprintf(w, "- Rows: %d\n", p.Rows)
+	if p.Sample != "" {
+		fmt.Fprintf(w, "- Sample: %s\n", p.Sample)
+	}
+	fmt.Fprintf(w, "- Profiled at: %s\n\n", p.CreatedAt.Format("2006-01-02 15:04:05 UTC"))
+
+	fmt.Fprintln(w, "| Column | Type | Nulls | Distinct | Min | Max | Avg |")
+	fmt.Fprintln(w, "|---|---|---:|---:|---|---|---:|")
+	for _, c := range p.Columns {
+		fmt.Fprintf(w, "| %s | %s | %s | %s | %s | %s | %s |\n", markdownCell(c.Name), markdownCell(c.Type),
+			percent(c.NullRatio), distinct(c), markdownCell(c.Min), markdownCell(c.Max), float(c.Avg))
+	}
+
+	for _, c := range p.Columns {
+		if len(c.TopValues) == 0 && len(c.Histogram) == 0 && c.Lengths == nil && len(c.Errors) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n## %s\n", markdownCell(c.Name))
+
+		if len(c.TopValues) > 0 {
+			fmt.Fprintln(w, "\n| Value | Count | Share |")
+			fmt.Fprintln(w, "|---|---:|---:|")
+			for _, v := range c.TopValues {
+				fmt.Fprintf(w, "| %s | %d | %s |\n", markdownCell(v.Value), v.Count, percent(ratio(v.Count, c.Count)))
+			}
+		}
+		if len(c.Histogram) > 0 {
+			fmt.Fprintln(w, "\n| Range | Count |")
+			fmt.Fprintln(w, "|---|---:|")
+			for _, b := range c.Histogram {
+				fmt.Fprintf(w, "| %s | %d |\n", binRange(b, " – "), b.Count)
+			}
+		}
+		if c.Lengths != nil {
+			fmt.Fprintf(w, "\nLength: min %d, max %d, avg %.1f\n", c.Lengths.Min, c.Lengths.Max, c.Lengths.Avg)
+			if len(c.Lengths.Histogram) > 0 {
+				fmt.Fprintln(w, "\n| Length | Count |")
+				fmt.Fprintln(w, "|---|---:|")
+				for _, b := range c.Lengths.Histogram {
+					fmt.Fprintf(w, "| %s | %d |\n", binRange(b, " – "), b.Count)
+				}
+			}
+		}
+		for _, e := range c.Errors {
+			fmt.Fprintf(w, "\n> Error: %s\n", markdownCell(e))
+		}
+	}
+	return nil
+}
+
+func distinct(c ColumnProfile) string {
+	if c.DistinctApprox {
+		return fmt.Sprintf("~%d", c.Distinct)
+	}
+	return strconv.FormatInt(c.Distinct, 10)
+}
+
+func percent(r float64) string {
+	return strconv.FormatFloat(r*100, 'f', 1, 64) + "%"
+}
+
+func ratio(n, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+func float(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*f, 'g', 6, 64)
+}
+
+func bound(f float64) string {
+	return strconv.FormatFloat(f, 'g', 6, 64)
+}
+
+// binRange renders the bounds of a bin, or the single value of a bin
+// holding one integer
+func binRange(b Bin, sep string) string {
+	if b.Low == b.High {
+		return bound(b.Low)
+	}
+	return bound(b.Low) + sep + bound(b.High)
+}
+
+func truncate(s string, width int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	if r := []rune(s); len(r) > width {
+		return string(r[:width-1]) + "…"
+	}
+	return s
+}
+
+func markdownCell(s string) string {
+	return strings.ReplaceAll(truncate(s, 60), "|", `\|`)
+}
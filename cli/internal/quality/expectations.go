@@ -0,0 +1,211 @@
+package quality
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/manifest"
+	"gopkg.in/yaml.v3"
+)
+
+// Kind is the kind of expectations files
+const Kind = "Expectations"
+
+// Check types
+const (
+	CheckNotNull        = "not_null"
+	CheckUnique         = "unique"
+	CheckAcceptedValues = "accepted_values"
+	CheckRange          = "range"
+	CheckReferences     = "references"
+	CheckRowCountDelta  = "row_count_delta"
+)
+
+// Severities; warnings are reported but don't fail a run
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Suite is an expectations file, kept next to the product manifest as
+// <manifest>.quality.yaml:
+//
+//	kind: Expectations
+//	expectations:
+//	  - table: project_state_history
+//	    type: not_null
+//	    column: project_id
+//	  - table: project_state_history
+//	    type: accepted_values
+//	    column: status          # values default to the Avro enum symbols
+//	  - table: project_state_latest
+//	    type: unique
+//	    columns: [project_id]
+//	  - table: project_state_latest
+//	    type: row_count_delta
+//	    max_decrease: 5%
+type Suite struct {
+	Kind         string        `yaml:"kind"`
+	Expectations []Expectation `yaml:"expectations"`
+
+	// File is the path the suite was loaded from
+	File string `yaml:"-"`
+}
+
+// Expectation is a single declarative check on a table of the product
+type Expectation struct {
+	Name     string `yaml:"name"`
+	Table    string `yaml:"table"`
+	Type     string `yaml:"type"`
+	Severity string `yaml:"severity"`
+	// Where restricts the check to matching rows (a SQL condition)
+	Where string `yaml:"where"`
+
+	// Column is checked by not_null, accepted_values, range and references;
+	// nested fields are written as budget.currency
+	Column string `yaml:"column"`
+	// Columns is the key checked by unique
+	Columns []string `yaml:"columns"`
+
+	// Values accepted by accepted_values
+	Values []string `yaml:"values"`
+
+	// Min and Max bound range checks; either may be omitted
+	Min interface{} `yaml:"min"`
+	Max interface{} `yaml:"max"`
+
+	// References is the parent column of a references check
+	References *Reference `yaml:"references"`
+
+	// MaxIncrease and MaxDecrease bound row_count_delta, as percentages
+	// of the previous snapshot's row count such as 10%
+	MaxIncrease string `yaml:"max_increase"`
+	MaxDecrease string `yaml:"max_decrease"`
+}
+
+// Reference names a column of a table of another (or the same) product
+type Reference struct {
+	// Product is the domain.name ID; defaults to the checked product
+	Product string `yaml:"product"`
+	Table   string `yaml:"table"`
+	Column  string `yaml:"column"`
+}
+
+// SuitePath returns the expectations file of a product manifest, such as
+// project_state.quality.yaml next to project_state.yaml
+func SuitePath(product *manifest.DataProduct) string {
+	ext := filepath.Ext(product.File)
+	return strings.TrimSuffix(product.File, ext) + ".quality" + ext
+}
+
+// LoadSuite reads and validates an expectations file
+func LoadSuite(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read expectations file: %w", err)
+	}
+
+	var suite Suite
+	if err := yaml.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("failed to parse expectations file %s: %w", path, err)
+	}
+	if suite.Kind != "" && suite.Kind != Kind {
+		return nil, fmt.Errorf("%s is not an %s file", path, Kind)
+	}
+	if len(suite.Expectations) == 0 {
+		return nil, fmt.Errorf("%s declares no expectations", path)
+	}
+
+	for i := range suite.Expectations {
+		if err := suite.Expectations[i].validate(); err != nil {
+			return nil, fmt.Errorf("%s: expectation %d: %w", path, i+1, err)
+		}
+	}
+
+	suite.File = path
+	return &suite, nil
+}
+
+func (e *Expectation) validate() error {
+	if e.Table == "" {
+		return fmt.Errorf("table is required")
+	}
+	switch e.Severity {
+	case "":
+		e.Severity = SeverityError
+	case SeverityError, SeverityWarning:
+	default:
+		return fmt.Errorf("invalid severity %q", e.Severity)
+	}
+
+	switch e.Type {
+	case CheckNotNull, CheckAcceptedValues:
+		if e.Column == "" {
+			return fmt.Errorf("%s requires column", e.Type)
+		}
+	case CheckUnique:
+		if len(e.Columns) == 0 && e.Column != "" {
+			e.Columns = []string{e.Column}
+		}
+		if len(e.Columns) == 0 {
+			return fmt.Errorf("unique requires columns")
+		}
+	case CheckRange:
+		if e.Column == "" {
+			return fmt.Errorf("range requires column")
+		}
+		if e.Min == nil && e.Max == nil {
+			return fmt.Errorf("range requires min or max")
+		}
+	case CheckReferences:
+		if e.Column == "" || e.References == nil || e.References.Table == "" || e.References.Column == "" {
+			return fmt.Errorf("references requires column and references.table and references.column")
+		}
+	case CheckRowCountDelta:
+		if e.MaxIncrease == "" && e.MaxDecrease == "" {
+			return fmt.Errorf("row_count_delta requires max_increase or max_decrease")
+		}
+		for _, p := range []string{e.MaxIncrease, e.MaxDecrease} {
+			if _, err := parsePercent(p); err != nil {
+				return err
+			}
+		}
+	case "":
+		return fmt.Errorf("type is required")
+	default:
+		return fmt.Errorf("unknown expectation type %q", e.Type)
+	}
+
+	if e.Name == "" {
+		e.Name = e.defaultName()
+	}
+	return nil
+}
+
+func (e *Expectation) defaultName() string {
+	switch e.Type {
+	case CheckUnique:
+		return fmt.Sprintf("unique(%s)", strings.Join(e.Columns, ", "))
+	case CheckRowCountDelta:
+		return "row_count_delta"
+	case CheckReferences:
+		return fmt.Sprintf("%s references %s.%s", e.Column, e.References.Table, e.References.Column)
+	}
+	return fmt.Sprintf("%s(%s)", e.Type, e.Column)
+}
+
+// parsePercent parses 10% or 10 as 10 percent; empty means no limit
+// and returns -1
+func parsePercent(s string) (float64, error) {
+	if s == "" {
+		return -1, nil
+	}
+	v, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "%"), 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid percentage %q", s)
+	}
+	return v, nil
+}
@@ -0,0 +1,173 @@
+package quality
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// WriteReport writes the report in the given format (text, json, junit)
+func WriteReport(w io.Writer, report *Report, format string) error {
+	switch strings.ToLower(format) {
+	case "text", "":
+		return writeText(w, report)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case "junit":
+		return writeJUnit(w, report)
+	default:
+		return fmt.Errorf("unsupported quality report format: %s", format)
+	}
+}
+
+func writeText(w io.Writer, report *Report) error {
+	for _, r := range report.Results {
+		label := strings.ToUpper(string(r.Status))
+		if r.Status == StatusFail && r.Severity == SeverityWarning {
+			label = "WARN"
+		}
+		fmt.Fprintf(w, "%-5s %s: %s", label, r.Table, r.Name)
+		if r.Message != "" {
+			fmt.Fprintf(w, " - %s", r.Message)
+		}
+		fmt.Fprintln(w)
+
+		if r.Sample != nil && len(r.Sample.Rows) > 0 {
+			tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+			fmt.Fprintf(tw, "      %s\n", strings.Join(r.Sample.Columns, "\t"))
+			for _, row := range r.Sample.Rows {
+				cells := make([]string, len(row))
+				for i, v := range row {
+					cells[i] = sampleText(v)
+				}
+				fmt.Fprintf(tw, "      %s\n", strings.Join(cells, "\t"))
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "\n%s: %d expectation(s): %d passed, %d failed, %d warning(s), %d error(s)\n",
+		report.Product, len(report.Results), report.Passed, report.Failed, report.Warnings, report.Errors)
+	return err
+}
+
+func sampleText(v interface{}) string {
+	var s string
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case []interface{}, map[string]interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		s = string(data)
+	default:
+		s = strings.ReplaceAll(fmt.Sprint(v), "\n", " ")
+	}
+	if r := []rune(s); len(r) > 40 {
+		s = string(r[:39]) + "…"
+	}
+	return s
+}
+
+// JUnit XML as understood by CI test reporters
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+func writeJUnit(w io.Writer, report *Report) error {
+	suite := junitTestSuite{
+		Name:      report.Product,
+		Tests:     len(report.Results),
+		Failures:  report.Failed,
+		Errors:    report.Errors,
+		Timestamp: report.StartedAt.Format("2006-01-02T15:04:05"),
+	}
+
+	total := 0.0
+	for _, r := range report.Results {
+		total += r.Seconds
+		tc := junitTestCase{
+			Name:      r.Name,
+			ClassName: r.Table,
+			Time:      fmt.Sprintf("%.3f", r.Seconds),
+		}
+
+		switch {
+		case r.Status == StatusError:
+			tc.Error = &junitMessage{Message: r.Message, Type: r.Type}
+		case r.Status == StatusFail && r.Severity == SeverityWarning:
+			// Warnings don't fail the build; keep the details in the output
+			tc.SystemOut = "warning: " + r.Message + "\n" + sampleBody(r.Sample)
+		case r.Status == StatusFail:
+			tc.Failure = &junitMessage{Message: r.Message, Type: r.Type, Body: r.Query + "\n\n" + sampleBody(r.Sample)}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	suite.Time = fmt.Sprintf("%.3f", total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// sampleBody renders sample rows as tab-separated lines
+func sampleBody(sample *Sample) string {
+	if sample == nil || len(sample.Rows) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(strings.Join(sample.Columns, "\t"))
+	b.WriteString("\n")
+	for _, row := range sample.Rows {
+		cells := make([]string, len(row))
+		for i, v := range row {
+			cells[i] = sampleText(v)
+		}
+		b.WriteString(strings.Join(cells, "\t"))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,497 @@
+package quality
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/avro"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/duckdb"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/lakehouse"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/manifest"
+	godb "github.com/marcboeker/go-duckdb"
+)
+
+// Status is the outcome of an expectation
+type Status string
+
+const (
+	StatusPass  Status = "pass"
+	StatusFail  Status = "fail"
+	StatusError Status = "error"
+)
+
+// DB is the part of duckdb.Connection the runner needs
+type DB interface {
+	RegisterTable(name, format, location string) error
+	ExecuteQuery(query string) (*duckdb.QueryResult, error)
+}
+
+// Runner evaluates expectations as DuckDB queries over the product's
+// tables
+type Runner struct {
+	DB        DB
+	Lakehouse *lakehouse.Client
+	// Products resolves references to tables of other products
+	Products []*manifest.DataProduct
+	// Authorize is called once per product before its tables are read
+	Authorize func(product *manifest.DataProduct) error
+	// Samples is the number of failing rows kept per expectation
+	Samples int
+
+	registered map[string]error
+	authorized map[string]error
+}
+
+// Report holds the results of running a suite
+type Report struct {
+	Product   string    `json:"product"`
+	File      string    `json:"file"`
+	StartedAt time.Time `json:"started_at"`
+	Results   []Result  `json:"results"`
+	Passed    int       `json:"passed"`
+	Failed    int       `json:"failed"`
+	Warnings  int       `json:"warnings"`
+	Errors    int       `json:"errors"`
+}
+
+// Result is the outcome of one expectation. FailingRows counts the rows
+// violating it; for unique it counts duplicated keys.
+type Result struct {
+	Name        string  `json:"name"`
+	Table       string  `json:"table"`
+	Type        string  `json:"type"`
+	Severity    string  `json:"severity"`
+	Status      Status  `json:"status"`
+	FailingRows int64   `json:"failing_rows"`
+	Message     string  `json:"message,omitempty"`
+	Query       string  `json:"query,omitempty"`
+	Sample      *Sample `json:"sample,omitempty"`
+	Seconds     float64 `json:"seconds"`
+}
+
+// Sample holds some of the rows that violate an expectation
+type Sample struct {
+	Columns []string        `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+}
+
+// HasFailures reports whether an error-severity expectation failed or any
+// expectation could not be evaluated
+func (r *Report) HasFailures() bool {
+	return r.Failed > 0 || r.Errors > 0
+}
+
+// Run evaluates all expectations of a suite against a product
+func (r *Runner) Run(product *manifest.DataProduct, suite *Suite) *Report {
+	report := &Report{Product: product.ID(), File: suite.File, StartedAt: time.Now().UTC()}
+
+	for _, e := range suite.Expectations {
+		start := time.Now()
+		result := r.evaluate(product, e)
+		result.Name = e.Name
+		result.Type = e.Type
+		result.Severity = e.Severity
+		result.Seconds = time.Since(start).Seconds()
+
+		switch {
+		case result.Status == StatusPass:
+			report.Passed++
+		case result.Status == StatusError:
+			report.Errors++
+		case e.Severity == SeverityWarning:
+			report.Warnings++
+		default:
+			report.Failed++
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report
+}
+
+func (r *Runner) evaluate(product *manifest.DataProduct, e Expectation) Result {
+	result := Result{Table: e.Table}
+
+	table := product.Table(e.Table)
+	if table == nil {
+		return errorResult(result, fmt.Errorf("data product %s has no table %s", product.ID(), e.Table))
+	}
+	result.Table = table.QualifiedName()
+
+	view, err := r.register(product, table)
+	if err != nil {
+		return errorResult(result, err)
+	}
+
+	// Row conditions
+	var condition string
+	switch e.Type {
+	case CheckNotNull:
+		condition = column("t", e.Column) + " IS NULL"
+
+	case CheckAcceptedValues:
+		values := e.Values
+		if len(values) == 0 {
+			if values, err = enumSymbols(product, e.Column); err != nil {
+				return errorResult(result, err)
+			}
+		}
+		literals := make([]string, len(values))
+		for i, v := range values {
+			literals[i] = quoteString(v)
+		}
+		col := column("t", e.Column)
+		condition = fmt.Sprintf("%s IS NOT NULL AND CAST(%s AS VARCHAR) NOT IN (%s)", col, col, strings.Join(literals, ", "))
+
+	case CheckRange:
+		col := column("t", e.Column)
+		var bounds []string
+		if e.Min != nil {
+			bounds = append(bounds, fmt.Sprintf("%s < %s", col, literal(e.Min)))
+		}
+		if e.Max != nil {
+			bounds = append(bounds, fmt.Sprintf("%s > %s", col, literal(e.Max)))
+		}
+		condition = strings.Join(bounds, " OR ")
+
+	case CheckReferences:
+		parent, err := r.referencedView(product, e.References)
+		if err != nil {
+			return errorResult(result, err)
+		}
+		col := column("t", e.Column)
+		condition = fmt.Sprintf("%s IS NOT NULL AND NOT EXISTS (SELECT 1 FROM %s AS p WHERE %s = %s)",
+			col, parent, column("p", e.References.Column), col)
+
+	case CheckUnique:
+		return r.unique(result, view, e)
+
+	case CheckRowCountDelta:
+		return r.rowCountDelta(result, view, table, e)
+	}
+
+	if e.Where != "" {
+		condition = fmt.Sprintf("(%s) AND (%s)", condition, e.Where)
+	}
+	result.Query = fmt.Sprintf("SELECT * FROM %s AS t WHERE %s", view, condition)
+
+	failing, err := r.count(fmt.Sprintf("SELECT count(*) FROM %s AS t WHERE %s", view, condition))
+	if err != nil {
+		return errorResult(result, err)
+	}
+	result.FailingRows = failing
+	if failing == 0 {
+		result.Status = StatusPass
+		return result
+	}
+
+	result.Status = StatusFail
+	result.Message = fmt.Sprintf("%d row(s) violate the expectation", failing)
+	result.Sample = r.sample(result.Query)
+	return result
+}
+
+func (r *Runner) unique(result Result, view string, e Expectation) Result {
+	keys := make([]string, len(e.Columns))
+	for i, c := range e.Columns {
+		keys[i] = column("t", c)
+	}
+	where := ""
+	if e.Where != "" {
+		where = " WHERE " + e.Where
+	}
+	duplicates := fmt.Sprintf("SELECT %s, count(*) AS occurrences FROM %s AS t%s GROUP BY %s HAVING count(*) > 1",
+		strings.Join(keys, ", "), view, where, strings.Join(keys, ", "))
+	result.Query = duplicates + " ORDER BY occurrences DESC"
+
+	failing, err := r.count(fmt.Sprintf("SELECT count(*) FROM (%s)", duplicates))
+	if err != nil {
+		return errorResult(result, err)
+	}
+	result.FailingRows = failing
+	if failing == 0 {
+		result.Status = StatusPass
+		return result
+	}
+
+	result.Status = StatusFail
+	result.Message = fmt.Sprintf("%d duplicated key(s)", failing)
+	result.Sample = r.sample(result.Query)
+	return result
+}
+
+// rowCountDelta compares the row count with the total-records summary of
+// the previous Iceberg snapshot
+func (r *Runner) rowCountDelta(result Result, view string, table *manifest.Table, e Expectation) Result {
+	maxIncrease, _ := parsePercent(e.MaxIncrease)
+	maxDecrease, _ := parsePercent(e.MaxDecrease)
+
+	result.Query = fmt.Sprintf("SELECT count(*) FROM %s", view)
+	current, err := r.count(result.Query)
+	if err != nil {
+		return errorResult(result, err)
+	}
+
+	previous, err := r.previousRowCount(table)
+	if err != nil {
+		return errorResult(result, err)
+	}
+	if previous < 0 {
+		result.Status = StatusPass
+		result.Message = fmt.Sprintf("%d rows; no previous snapshot to compare with", current)
+		return result
+	}
+
+	change := 0.0
+	if previous > 0 {
+		change = float64(current-previous) / float64(previous) * 100
+	} else if current > 0 {
+		change = 100
+	}
+	result.Message = fmt.Sprintf("row count changed by %+.1f%% (%d to %d)", change, previous, current)
+
+	switch {
+	case maxIncrease >= 0 && change > maxIncrease,
+		maxDecrease >= 0 && -change > maxDecrease:
+		result.Status = StatusFail
+		delta := current - previous
+		if delta < 0 {
+			delta = -delta
+		}
+		result.FailingRows = delta
+	default:
+		result.Status = StatusPass
+	}
+	return result
+}
+
+// previousRowCount returns the total-records of the snapshot before the
+// current one, or -1 when there is none
+func (r *Runner) previousRowCount(table *manifest.Table) (int64, error) {
+	if !strings.EqualFold(table.Format, "iceberg") {
+		return 0, fmt.Errorf("row_count_delta needs Iceberg snapshot summaries; %s is a %s table", table.QualifiedName(), table.Format)
+	}
+	history, err := r.Lakehouse.History(table.Format, table.Location)
+	if err != nil {
+		return 0, err
+	}
+	current := history.Current()
+	if current == nil || current.ParentID == nil {
+		return -1, nil
+	}
+	for _, s := range history.Snapshots {
+		if s.ID != *current.ParentID {
+			continue
+		}
+		total, ok := s.Summary["total-records"]
+		if !ok {
+			return 0, fmt.Errorf("snapshot %d has no total-records summary", s.ID)
+		}
+		return strconv.ParseInt(total, 10, 64)
+	}
+	// The parent has been expired
+	return -1, nil
+}
+
+// register creates the view of a product table once, named by its
+// catalog.table name
+func (r *Runner) register(product *manifest.DataProduct, table *manifest.Table) (string, error) {
+	if r.registered == nil {
+		r.registered = make(map[string]error)
+		r.authorized = make(map[string]error)
+	}
+
+	if r.Authorize != nil {
+		err, checked := r.authorized[product.ID()]
+		if !checked {
+			err = r.Authorize(product)
+			r.authorized[product.ID()] = err
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	name := table.QualifiedName()
+	err, done := r.registered[name]
+	if !done {
+		err = r.DB.RegisterTable(name, table.Format, table.Location)
+		r.registered[name] = err
+	}
+	if err != nil {
+		return "", err
+	}
+	return quoteIdentifier(name), nil
+}
+
+func (r *Runner) referencedView(product *manifest.DataProduct, ref *Reference) (string, error) {
+	parent := product
+	if ref.Product != "" && ref.Product != product.ID() {
+		parent = nil
+		for _, p := range r.Products {
+			if p.ID() == ref.Product {
+				parent = p
+				break
+			}
+		}
+		if parent == nil {
+			return "", fmt.Errorf("referenced data product %s not found", ref.Product)
+		}
+	}
+
+	table := parent.Table(ref.Table)
+	if table == nil {
+		return "", fmt.Errorf("data product %s has no table %s", parent.ID(), ref.Table)
+	}
+	return r.register(parent, table)
+}
+
+func (r *Runner) count(query string) (int64, error) {
+	result, err := r.DB.ExecuteQuery(query)
+	if err != nil {
+		return 0, err
+	}
+	if len(result.Rows) != 1 || len(result.Rows[0]) != 1 {
+		return 0, fmt.Errorf("unexpected result of %s", query)
+	}
+	switch n := result.Rows[0][0].(type) {
+	case int64:
+		return n, nil
+	case int32:
+		return int64(n), nil
+	case *big.Int:
+		return n.Int64(), nil
+	}
+	return 0, fmt.Errorf("unexpected count type %T", result.Rows[0][0])
+}
+
+// sample returns the first failing rows; a failure to read them doesn't
+// change the outcome of the check
+func (r *Runner) sample(query string) *Sample {
+	if r.Samples <= 0 {
+		return nil
+	}
+	result, err := r.DB.ExecuteQuery(fmt.Sprintf("%s LIMIT %d", query, r.Samples))
+	if err != nil {
+		return nil
+	}
+
+	sample := &Sample{Columns: result.Columns, Rows: make([][]interface{}, len(result.Rows))}
+	for i, row := range result.Rows {
+		values := make([]interface{}, len(row))
+		for j, v := range row {
+			values[j] = sampleValue(v)
+		}
+		sample.Rows[i] = values
+	}
+	return sample
+}
+
+// sampleValue converts values to types that encode naturally as JSON and
+// text
+func sampleValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil, bool, string, int8, int16, int32, int64, uint8, uint16, uint32, uint64, float32, float64:
+		return v
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case []byte:
+		if utf8.Valid(v) {
+			return string(v)
+		}
+		return fmt.Sprintf("%x", v)
+	case *big.Int:
+		return v.String()
+	case godb.Decimal:
+		scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(v.Scale)), nil)
+		return new(big.Rat).SetFrac(v.Value, scale).FloatString(int(v.Scale))
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = sampleValue(item)
+		}
+		return items
+	case map[string]interface{}:
+		fields := make(map[string]interface{}, len(v))
+		for k, f := range v {
+			fields[k] = sampleValue(f)
+		}
+		return fields
+	case godb.Map:
+		entries := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			entries[fmt.Sprint(k)] = sampleValue(e)
+		}
+		return entries
+	}
+	return fmt.Sprint(v)
+}
+
+func errorResult(result Result, err error) Result {
+	result.Status = StatusError
+	result.Message = err.Error()
+	return result
+}
+
+// enumSymbols returns the symbols of the Avro enum of a column
+func enumSymbols(product *manifest.DataProduct, path string) ([]string, error) {
+	schema, err := product.LoadSchema()
+	if err != nil {
+		return nil, fmt.Errorf("accepted_values without values needs the Avro schema: %w", err)
+	}
+
+	s := schema
+	for _, name := range strings.Split(path, ".") {
+		if nonNull := s.NonNull(); nonNull != nil {
+			s = nonNull
+		}
+		if s.Type != avro.Record {
+			return nil, fmt.Errorf("column %s is not in the Avro schema", path)
+		}
+		f := s.Field(name)
+		if f == nil {
+			return nil, fmt.Errorf("column %s is not in the Avro schema", path)
+		}
+		s = f.Type
+	}
+	if nonNull := s.NonNull(); nonNull != nil {
+		s = nonNull
+	}
+	if s.Type != avro.Enum {
+		return nil, fmt.Errorf("column %s is not an Avro enum; accepted_values needs values", path)
+	}
+	return s.Symbols, nil
+}
+
+// column returns the SQL expression of a possibly nested column of a
+// table alias
+func column(alias, path string) string {
+	parts := strings.Split(path, ".")
+	for i, p := range parts {
+		parts[i] = quoteIdentifier(p)
+	}
+	return alias + "." + strings.Join(parts, ".")
+}
+
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func quoteString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// literal renders a YAML scalar as a SQL literal; strings such as dates
+// are cast implicitly by DuckDB
+func literal(v interface{}) string {
+	switch v := v.(type) {
+	case int, int64, float64:
+		return fmt.Sprint(v)
+	case time.Time:
+		return quoteString(v.UTC().Format("2006-01-02 15:04:05.999999"))
+	}
+	return quoteString(fmt.Sprint(v))
+}
@@ -0,0 +1,164 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/config"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/logging"
+)
+
+const contentType = "application/vnd.schemaregistry.v1+json"
+
+// Client talks to a Confluent-compatible schema registry
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	log        *logging.Logger
+}
+
+// Schema is a registered schema version
+type Schema struct {
+	Subject string `json:"subject,omitempty"`
+	Version int    `json:"version,omitempty"`
+	ID      int    `json:"id"`
+	Schema  string `json:"schema"`
+}
+
+// Error is an error response returned by the registry
+type Error struct {
+	StatusCode int
+	Code       int    `json:"error_code"`
+	Message    string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("schema registry error %d: %s", e.Code, e.Message)
+}
+
+func NewClient(cfg *config.Config, log *logging.Logger) (*Client, error) {
+	if cfg.SchemaRegistry == "" {
+		return nil, fmt.Errorf("schema_registry_url is not configured")
+	}
+
+	return &Client{
+		baseURL:    strings.TrimRight(cfg.SchemaRegistry, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		log:        log,
+	}, nil
+}
+
+// GetSchema returns a version of a subject; version may be "latest"
+func (c *Client) GetSchema(subject, version string) (*Schema, error) {
+	if version == "" {
+		version = "latest"
+	}
+
+	var schema Schema
+	path := fmt.Sprintf("/subjects/%s/versions/%s", url.PathEscape(subject), url.PathEscape(version))
+	if err := c.do(http.MethodGet, path, nil, &schema); err != nil {
+		return nil, fmt.Errorf("failed to get schema for subject %s: %w", subject, err)
+	}
+
+	return &schema, nil
+}
+
+// GetSchemaByID returns the schema registered under a global ID
+func (c *Client) GetSchemaByID(id int) (*Schema, error) {
+	var schema Schema
+	if err := c.do(http.MethodGet, fmt.Sprintf("/schemas/ids/%d", id), nil, &schema); err != nil {
+		return nil, fmt.Errorf("failed to get schema %d: %w", id, err)
+	}
+	schema.ID = id
+
+	return &schema, nil
+}
+
+// LookupSchema returns the version of a subject that holds the given schema
+func (c *Client) LookupSchema(subject, schema string) (*Schema, error) {
+	var registered Schema
+	path := fmt.Sprintf("/subjects/%s", url.PathEscape(subject))
+	if err := c.do(http.MethodPost, path, map[string]string{"schema": schema}, &registered); err != nil {
+		return nil, fmt.Errorf("failed to look up schema under subject %s: %w", subject, err)
+	}
+
+	return &registered, nil
+}
+
+// RegisterSchema registers a schema under a subject and returns its ID.
+// Registering a schema that already exists returns the existing ID.
+func (c *Client) RegisterSchema(subject, schema string) (int, error) {
+	var result struct {
+		ID int `json:"id"`
+	}
+	path := fmt.Sprintf("/subjects/%s/versions", url.PathEscape(subject))
+	if err := c.do(http.MethodPost, path, map[string]string{"schema": schema}, &result); err != nil {
+		return 0, fmt.Errorf("failed to register schema under subject %s: %w", subject, err)
+	}
+
+	return result.ID, nil
+}
+
+// IsNotFound reports whether err is a registry "not found" response, such
+// as an unknown subject or schema
+func IsNotFound(err error) bool {
+	var regErr *Error
+	return errors.As(err, &regErr) && regErr.StatusCode == http.StatusNotFound
+}
+
+func (c *Client) do(method, path string, body interface{}, out interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", contentType)
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	c.log.Debugf("Schema registry request: %s %s", method, path)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode >= 300 {
+		regErr := &Error{StatusCode: resp.StatusCode}
+		if json.Unmarshal(data, regErr) != nil || regErr.Message == "" {
+			regErr.Code = resp.StatusCode
+			regErr.Message = strings.TrimSpace(string(data))
+		}
+		return regErr
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
+	}
+
+	return nil
+}
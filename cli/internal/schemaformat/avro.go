@@ -0,0 +1,204 @@
+package schemaformat
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/catalog"
+)
+
+var invalidAvroName = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+var avroNullDefault = json.RawMessage("null")
+
+type avroRecord struct {
+	Type      string      `json:"type"`
+	Name      string      `json:"name"`
+	Namespace string      `json:"namespace,omitempty"`
+	Doc       string      `json:"doc,omitempty"`
+	Fields    []avroField `json:"fields"`
+}
+
+type avroField struct {
+	Name    string          `json:"name"`
+	Type    interface{}     `json:"type"`
+	Doc     string          `json:"doc,omitempty"`
+	Default json.RawMessage `json:"default,omitempty"`
+}
+
+type avroLogical struct {
+	Type        string `json:"type"`
+	LogicalType string `json:"logicalType"`
+	Precision   int    `json:"precision,omitempty"`
+	Scale       int    `json:"scale,omitempty"`
+}
+
+type avroArray struct {
+	Type  string      `json:"type"`
+	Items interface{} `json:"items"`
+}
+
+type avroMap struct {
+	Type   string      `json:"type"`
+	Values interface{} `json:"values"`
+}
+
+// avroWriter names nested records uniquely within one schema
+type avroWriter struct {
+	names map[string]bool
+}
+
+// toAvro renders the schema as an Avro record. Glue columns are nullable,
+// so every field is a ["null", T] union with a null default.
+func toAvro(schema *catalog.TableSchema) ([]byte, error) {
+	w := &avroWriter{names: make(map[string]bool)}
+
+	record, err := w.record(pascalCase(schema.Table), schema.Description, schema.Columns)
+	if err != nil {
+		return nil, err
+	}
+	record.Namespace = "com.frocore.datamesh." + schema.Domain
+
+	return marshalIndent(record)
+}
+
+func (w *avroWriter) record(name, doc string, columns []*catalog.Column) (*avroRecord, error) {
+	record := &avroRecord{Type: "record", Name: w.uniqueName(name), Doc: doc, Fields: []avroField{}}
+
+	names := fieldNames(columns)
+	for i, col := range columns {
+		t, err := w.avroType(col.Type, record.Name+pascalCase(col.Name))
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", col.Name, err)
+		}
+		doc := col.Comment
+		if names[i] != col.Name {
+			// Keep the name of columns renamed for Avro
+			doc = "Column " + col.Name
+			if col.Comment != "" {
+				doc += ". " + col.Comment
+			}
+		}
+		record.Fields = append(record.Fields, avroField{
+			Name:    names[i],
+			Type:    []interface{}{"null", t},
+			Doc:     doc,
+			Default: avroNullDefault,
+		})
+	}
+
+	return record, nil
+}
+
+// fieldNames gives the columns of a record distinct Avro field names.
+// Valid names are kept; others, such as a-b, are made valid and numbered
+// when that name is taken, as by a column a_b.
+func fieldNames(columns []*catalog.Column) []string {
+	names := make([]string, len(columns))
+	taken := make(map[string]bool, len(columns))
+	for i, col := range columns {
+		if avroName(col.Name) == col.Name && !taken[col.Name] {
+			names[i] = col.Name
+			taken[col.Name] = true
+		}
+	}
+	for i, col := range columns {
+		if names[i] != "" {
+			continue
+		}
+		name := avroName(col.Name)
+		candidate := name
+		for n := 2; taken[candidate]; n++ {
+			candidate = fmt.Sprintf("%s%d", name, n)
+		}
+		names[i] = candidate
+		taken[candidate] = true
+	}
+	return names
+}
+
+func (w *avroWriter) avroType(t *catalog.DataType, recordName string) (interface{}, error) {
+	switch t.Kind {
+	case catalog.KindDecimal:
+		return avroLogical{Type: "bytes", LogicalType: "decimal", Precision: t.Precision, Scale: t.Scale}, nil
+
+	case catalog.KindStruct:
+		columns := make([]*catalog.Column, len(t.Fields))
+		for i, f := range t.Fields {
+			columns[i] = &catalog.Column{Name: f.Name, Type: f.Type}
+		}
+		return w.record(recordName, "", columns)
+
+	case catalog.KindArray:
+		items, err := w.avroType(t.Element, recordName+"Item")
+		if err != nil {
+			return nil, err
+		}
+		return avroArray{Type: "array", Items: []interface{}{"null", items}}, nil
+
+	case catalog.KindMap:
+		value, err := w.avroType(t.Value, recordName+"Value")
+		if err != nil {
+			return nil, err
+		}
+		if t.Key.Kind == catalog.KindPrimitive && (t.Key.Name == "string" || t.Key.Name == "varchar" || t.Key.Name == "char") {
+			return avroMap{Type: "map", Values: []interface{}{"null", value}}, nil
+		}
+
+		// Avro maps only have string keys; other maps become key/value arrays
+		key, err := w.avroType(t.Key, recordName+"Key")
+		if err != nil {
+			return nil, err
+		}
+		entry := &avroRecord{
+			Type: "record",
+			Name: w.uniqueName(recordName + "Entry"),
+			Fields: []avroField{
+				{Name: "key", Type: key},
+				{Name: "value", Type: []interface{}{"null", value}, Default: avroNullDefault},
+			},
+		}
+		return avroArray{Type: "array", Items: entry}, nil
+	}
+
+	switch t.Name {
+	case "tinyint", "smallint", "int":
+		return "int", nil
+	case "bigint":
+		return "long", nil
+	case "float":
+		return "float", nil
+	case "double":
+		return "double", nil
+	case "boolean":
+		return "boolean", nil
+	case "string", "varchar", "char":
+		return "string", nil
+	case "binary":
+		return "bytes", nil
+	case "date":
+		return avroLogical{Type: "int", LogicalType: "date"}, nil
+	case "timestamp":
+		return avroLogical{Type: "long", LogicalType: "timestamp-micros"}, nil
+	}
+	return nil, fmt.Errorf("type %s has no Avro equivalent", t)
+}
+
+func (w *avroWriter) uniqueName(name string) string {
+	name = avroName(name)
+	candidate := name
+	for i := 2; w.names[candidate]; i++ {
+		candidate = fmt.Sprintf("%s%d", name, i)
+	}
+	w.names[candidate] = true
+	return candidate
+}
+
+func avroName(name string) string {
+	name = invalidAvroName.ReplaceAllString(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
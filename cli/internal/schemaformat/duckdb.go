@@ -0,0 +1,106 @@
+package schemaformat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/catalog"
+)
+
+// toDuckDB renders a CREATE TABLE statement for the schema
+func toDuckDB(schema *catalog.TableSchema) ([]byte, error) {
+	var b strings.Builder
+
+	if schema.Description != "" {
+		fmt.Fprintf(&b, "-- %s\n", oneLine(schema.Description))
+	}
+	fmt.Fprintf(&b, "CREATE TABLE %s.%s (\n", quoteIdent(schema.Domain), quoteIdent(schema.Table))
+
+	for i, col := range schema.Columns {
+		t, err := duckDBType(col.Type)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", col.Name, err)
+		}
+		fmt.Fprintf(&b, "    %s %s", quoteIdent(col.Name), t)
+		if i < len(schema.Columns)-1 {
+			b.WriteString(",")
+		}
+		if col.Comment != "" {
+			fmt.Fprintf(&b, " -- %s", oneLine(col.Comment))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(");\n")
+
+	return []byte(b.String()), nil
+}
+
+func duckDBType(t *catalog.DataType) (string, error) {
+	switch t.Kind {
+	case catalog.KindDecimal:
+		return fmt.Sprintf("DECIMAL(%d,%d)", t.Precision, t.Scale), nil
+
+	case catalog.KindStruct:
+		parts := make([]string, len(t.Fields))
+		for i, f := range t.Fields {
+			ft, err := duckDBType(f.Type)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = quoteIdent(f.Name) + " " + ft
+		}
+		return "STRUCT(" + strings.Join(parts, ", ") + ")", nil
+
+	case catalog.KindArray:
+		elem, err := duckDBType(t.Element)
+		if err != nil {
+			return "", err
+		}
+		return elem + "[]", nil
+
+	case catalog.KindMap:
+		key, err := duckDBType(t.Key)
+		if err != nil {
+			return "", err
+		}
+		value, err := duckDBType(t.Value)
+		if err != nil {
+			return "", err
+		}
+		return "MAP(" + key + ", " + value + ")", nil
+	}
+
+	switch t.Name {
+	case "tinyint":
+		return "TINYINT", nil
+	case "smallint":
+		return "SMALLINT", nil
+	case "int":
+		return "INTEGER", nil
+	case "bigint":
+		return "BIGINT", nil
+	case "float":
+		return "FLOAT", nil
+	case "double":
+		return "DOUBLE", nil
+	case "boolean":
+		return "BOOLEAN", nil
+	case "string", "varchar", "char":
+		return "VARCHAR", nil
+	case "binary":
+		return "BLOB", nil
+	case "date":
+		return "DATE", nil
+	case "timestamp":
+		return "TIMESTAMP", nil
+	}
+	return "", fmt.Errorf("type %s has no DuckDB equivalent", t)
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,62 @@
+package schemaformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/catalog"
+)
+
+// Formats lists the schema output formats in the order shown in help text
+var Formats = []string{"avro", "json-schema", "iceberg", "duckdb", "spark", "markdown"}
+
+// Render converts a data product table schema to the given format
+func Render(schema *catalog.TableSchema, format string) ([]byte, error) {
+	switch strings.ToLower(format) {
+	case "avro":
+		return toAvro(schema)
+	case "json-schema":
+		return toJSONSchema(schema)
+	case "iceberg":
+		return toIceberg(schema)
+	case "duckdb":
+		return toDuckDB(schema)
+	case "spark":
+		return toSpark(schema)
+	case "markdown", "md":
+		return toMarkdown(schema)
+	default:
+		return nil, fmt.Errorf("unsupported schema format: %s", format)
+	}
+}
+
+func marshalIndent(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// pascalCase turns snake_case names into PascalCase type names
+func pascalCase(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	for _, w := range words {
+		runes := []rune(w)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+	if b.Len() == 0 || unicode.IsDigit([]rune(b.String())[0]) {
+		return "T" + b.String()
+	}
+	return b.String()
+}
@@ -0,0 +1,132 @@
+package schemaformat
+
+import (
+	"fmt"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/catalog"
+)
+
+// Iceberg schema JSON as stored in table metadata
+type icebergStruct struct {
+	Type     string         `json:"type"`
+	SchemaID *int           `json:"schema-id,omitempty"`
+	Fields   []icebergField `json:"fields"`
+}
+
+type icebergField struct {
+	ID       int         `json:"id"`
+	Name     string      `json:"name"`
+	Required bool        `json:"required"`
+	Type     interface{} `json:"type"`
+	Doc      string      `json:"doc,omitempty"`
+}
+
+type icebergList struct {
+	Type            string      `json:"type"`
+	ElementID       int         `json:"element-id"`
+	Element         interface{} `json:"element"`
+	ElementRequired bool        `json:"element-required"`
+}
+
+type icebergMap struct {
+	Type          string      `json:"type"`
+	KeyID         int         `json:"key-id"`
+	Key           interface{} `json:"key"`
+	ValueID       int         `json:"value-id"`
+	Value         interface{} `json:"value"`
+	ValueRequired bool        `json:"value-required"`
+}
+
+// icebergWriter assigns field IDs the way Iceberg does for a fresh schema:
+// all fields of a struct are numbered before any of their nested fields
+type icebergWriter struct {
+	lastID int
+}
+
+// toIceberg renders the schema in the Iceberg table metadata JSON format
+func toIceberg(schema *catalog.TableSchema) ([]byte, error) {
+	w := &icebergWriter{}
+
+	fields := make([]*catalog.StructField, len(schema.Columns))
+	docs := make([]string, len(schema.Columns))
+	for i, col := range schema.Columns {
+		fields[i] = &catalog.StructField{Name: col.Name, Type: col.Type}
+		docs[i] = col.Comment
+	}
+
+	root, err := w.structType(fields, docs)
+	if err != nil {
+		return nil, err
+	}
+	schemaID := 0
+	root.SchemaID = &schemaID
+
+	return marshalIndent(root)
+}
+
+func (w *icebergWriter) structType(fields []*catalog.StructField, docs []string) (*icebergStruct, error) {
+	result := &icebergStruct{Type: "struct", Fields: make([]icebergField, len(fields))}
+	for i, f := range fields {
+		w.lastID++
+		result.Fields[i] = icebergField{ID: w.lastID, Name: f.Name}
+		if docs != nil {
+			result.Fields[i].Doc = docs[i]
+		}
+	}
+
+	for i, f := range fields {
+		t, err := w.icebergType(f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		result.Fields[i].Type = t
+	}
+
+	return result, nil
+}
+
+func (w *icebergWriter) icebergType(t *catalog.DataType) (interface{}, error) {
+	switch t.Kind {
+	case catalog.KindDecimal:
+		return fmt.Sprintf("decimal(%d,%d)", t.Precision, t.Scale), nil
+
+	case catalog.KindStruct:
+		return w.structType(t.Fields, nil)
+
+	case catalog.KindArray:
+		w.lastID++
+		list := icebergList{Type: "list", ElementID: w.lastID}
+		element, err := w.icebergType(t.Element)
+		if err != nil {
+			return nil, err
+		}
+		list.Element = element
+		return list, nil
+
+	case catalog.KindMap:
+		w.lastID += 2
+		m := icebergMap{Type: "map", KeyID: w.lastID - 1, ValueID: w.lastID}
+		key, err := w.icebergType(t.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := w.icebergType(t.Value)
+		if err != nil {
+			return nil, err
+		}
+		m.Key, m.Value = key, value
+		return m, nil
+	}
+
+	switch t.Name {
+	case "tinyint", "smallint", "int":
+		return "int", nil
+	case "bigint":
+		return "long", nil
+	case "float", "double", "boolean", "string", "binary", "date", "timestamp":
+		return t.Name, nil
+	case "varchar", "char":
+		return "string", nil
+	}
+	return nil, fmt.Errorf("type %s has no Iceberg equivalent", t)
+}
@@ -0,0 +1,116 @@
+package schemaformat
+
+import (
+	"fmt"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/catalog"
+)
+
+type jsonSchemaDocument struct {
+	Schema               string                 `json:"$schema"`
+	ID                   string                 `json:"$id"`
+	Title                string                 `json:"title"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 string                 `json:"type"`
+	Properties           map[string]interface{} `json:"properties"`
+	AdditionalProperties bool                   `json:"additionalProperties"`
+}
+
+// toJSONSchema renders the schema as a JSON Schema (draft 2020-12)
+// describing one row of the table
+func toJSONSchema(schema *catalog.TableSchema) ([]byte, error) {
+	properties, err := jsonSchemaProperties(schema.Columns)
+	if err != nil {
+		return nil, err
+	}
+
+	return marshalIndent(jsonSchemaDocument{
+		Schema:               "https://json-schema.org/draft/2020-12/schema",
+		ID:                   fmt.Sprintf("urn:frocore:datamesh:%s", schema.Product),
+		Title:                schema.Product,
+		Description:          schema.Description,
+		Type:                 "object",
+		Properties:           properties,
+		AdditionalProperties: false,
+	})
+}
+
+func jsonSchemaProperties(columns []*catalog.Column) (map[string]interface{}, error) {
+	properties := make(map[string]interface{}, len(columns))
+	for _, col := range columns {
+		prop, err := jsonSchemaType(col.Type)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", col.Name, err)
+		}
+		if col.Comment != "" {
+			prop["description"] = col.Comment
+		}
+		properties[col.Name] = prop
+	}
+	return properties, nil
+}
+
+// jsonSchemaType returns the schema of a nullable value of type t
+func jsonSchemaType(t *catalog.DataType) (map[string]interface{}, error) {
+	switch t.Kind {
+	case catalog.KindDecimal:
+		return map[string]interface{}{"type": []string{"number", "null"}}, nil
+
+	case catalog.KindStruct:
+		columns := make([]*catalog.Column, len(t.Fields))
+		for i, f := range t.Fields {
+			columns[i] = &catalog.Column{Name: f.Name, Type: f.Type}
+		}
+		properties, err := jsonSchemaProperties(columns)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"type":                 []string{"object", "null"},
+			"properties":           properties,
+			"additionalProperties": false,
+		}, nil
+
+	case catalog.KindArray:
+		items, err := jsonSchemaType(t.Element)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"type":  []string{"array", "null"},
+			"items": items,
+		}, nil
+
+	case catalog.KindMap:
+		values, err := jsonSchemaType(t.Value)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"type":                 []string{"object", "null"},
+			"additionalProperties": values,
+		}, nil
+	}
+
+	switch t.Name {
+	case "tinyint", "smallint", "int", "bigint":
+		return map[string]interface{}{"type": []string{"integer", "null"}}, nil
+	case "float", "double":
+		return map[string]interface{}{"type": []string{"number", "null"}}, nil
+	case "boolean":
+		return map[string]interface{}{"type": []string{"boolean", "null"}}, nil
+	case "string", "varchar", "char":
+		prop := map[string]interface{}{"type": []string{"string", "null"}}
+		if t.Length > 0 {
+			prop["maxLength"] = t.Length
+		}
+		return prop, nil
+	case "binary":
+		return map[string]interface{}{"type": []string{"string", "null"}, "contentEncoding": "base64"}, nil
+	case "date":
+		return map[string]interface{}{"type": []string{"string", "null"}, "format": "date"}, nil
+	case "timestamp":
+		return map[string]interface{}{"type": []string{"string", "null"}, "format": "date-time"}, nil
+	}
+	return nil, fmt.Errorf("type %s has no JSON Schema equivalent", t)
+}
@@ -0,0 +1,76 @@
+package schemaformat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/catalog"
+)
+
+// toMarkdown renders a data dictionary with one row per column and nested
+// field. Nested fields use dotted paths, with [] for array elements and
+// {} for map values.
+func toMarkdown(schema *catalog.TableSchema) ([]byte, error) {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n\n", schema.Product)
+	if schema.Description != "" {
+		fmt.Fprintf(&b, "%s\n\n", schema.Description)
+	}
+
+	b.WriteString("| Column | Type | Description |\n")
+	b.WriteString("|--------|------|-------------|\n")
+	for _, col := range schema.Columns {
+		comment := oneLine(col.Comment)
+		if col.PartitionKey {
+			comment = strings.TrimSpace("**Partition key.** " + comment)
+		}
+		writeMarkdownRow(&b, col.Name, col.Type, comment)
+	}
+
+	return []byte(b.String()), nil
+}
+
+func writeMarkdownRow(b *strings.Builder, path string, t *catalog.DataType, comment string) {
+	fmt.Fprintf(b, "| `%s` | `%s` | %s |\n", escapeMarkdown(path), markdownTypeName(t), escapeMarkdown(oneLine(comment)))
+
+	switch t.Kind {
+	case catalog.KindStruct:
+		for _, f := range t.Fields {
+			writeMarkdownRow(b, path+"."+f.Name, f.Type, "")
+		}
+	case catalog.KindArray:
+		if isNested(t.Element) {
+			writeMarkdownRow(b, path+"[]", t.Element, "")
+		}
+	case catalog.KindMap:
+		if isNested(t.Value) {
+			writeMarkdownRow(b, path+"{}", t.Value, "")
+		}
+	}
+}
+
+// markdownTypeName abbreviates nested types; their members get rows of their own
+func markdownTypeName(t *catalog.DataType) string {
+	switch t.Kind {
+	case catalog.KindStruct:
+		return "struct"
+	case catalog.KindArray:
+		if isNested(t.Element) {
+			return "array<" + markdownTypeName(t.Element) + ">"
+		}
+	case catalog.KindMap:
+		if isNested(t.Value) {
+			return "map<" + t.Key.String() + "," + markdownTypeName(t.Value) + ">"
+		}
+	}
+	return t.String()
+}
+
+func isNested(t *catalog.DataType) bool {
+	return t.Kind == catalog.KindStruct || t.Kind == catalog.KindArray || t.Kind == catalog.KindMap
+}
+
+func escapeMarkdown(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
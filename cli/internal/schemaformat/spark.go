@@ -0,0 +1,107 @@
+package schemaformat
+
+import (
+	"fmt"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/catalog"
+)
+
+// Spark DataType JSON, as produced by DataFrame.schema.json and accepted
+// by DataType.fromJson
+type sparkStruct struct {
+	Type   string       `json:"type"`
+	Fields []sparkField `json:"fields"`
+}
+
+type sparkField struct {
+	Name     string            `json:"name"`
+	Type     interface{}       `json:"type"`
+	Nullable bool              `json:"nullable"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+type sparkArray struct {
+	Type         string      `json:"type"`
+	ElementType  interface{} `json:"elementType"`
+	ContainsNull bool        `json:"containsNull"`
+}
+
+type sparkMap struct {
+	Type              string      `json:"type"`
+	KeyType           interface{} `json:"keyType"`
+	ValueType         interface{} `json:"valueType"`
+	ValueContainsNull bool        `json:"valueContainsNull"`
+}
+
+// toSpark renders the schema as Spark StructType JSON
+func toSpark(schema *catalog.TableSchema) ([]byte, error) {
+	root := sparkStruct{Type: "struct", Fields: []sparkField{}}
+	for _, col := range schema.Columns {
+		t, err := sparkType(col.Type)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", col.Name, err)
+		}
+		metadata := map[string]string{}
+		if col.Comment != "" {
+			metadata["comment"] = col.Comment
+		}
+		root.Fields = append(root.Fields, sparkField{Name: col.Name, Type: t, Nullable: true, Metadata: metadata})
+	}
+
+	return marshalIndent(root)
+}
+
+func sparkType(t *catalog.DataType) (interface{}, error) {
+	switch t.Kind {
+	case catalog.KindDecimal:
+		return fmt.Sprintf("decimal(%d,%d)", t.Precision, t.Scale), nil
+
+	case catalog.KindStruct:
+		s := sparkStruct{Type: "struct", Fields: []sparkField{}}
+		for _, f := range t.Fields {
+			ft, err := sparkType(f.Type)
+			if err != nil {
+				return nil, err
+			}
+			s.Fields = append(s.Fields, sparkField{Name: f.Name, Type: ft, Nullable: true, Metadata: map[string]string{}})
+		}
+		return s, nil
+
+	case catalog.KindArray:
+		elem, err := sparkType(t.Element)
+		if err != nil {
+			return nil, err
+		}
+		return sparkArray{Type: "array", ElementType: elem, ContainsNull: true}, nil
+
+	case catalog.KindMap:
+		key, err := sparkType(t.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := sparkType(t.Value)
+		if err != nil {
+			return nil, err
+		}
+		return sparkMap{Type: "map", KeyType: key, ValueType: value, ValueContainsNull: true}, nil
+	}
+
+	switch t.Name {
+	case "tinyint":
+		return "byte", nil
+	case "smallint":
+		return "short", nil
+	case "int":
+		return "integer", nil
+	case "bigint":
+		return "long", nil
+	case "float", "double", "boolean", "string", "binary", "date", "timestamp":
+		return t.Name, nil
+	case "varchar", "char":
+		if t.Length > 0 {
+			return fmt.Sprintf("%s(%d)", t.Name, t.Length), nil
+		}
+		return "string", nil
+	}
+	return nil, fmt.Errorf("type %s has no Spark equivalent", t)
+}
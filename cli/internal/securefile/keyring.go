@@ -0,0 +1,112 @@
+package securefile
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Entry of the key in the OS keyring
+const (
+	keyringService = "fedramp-data-mesh"
+	keyringAccount = "local-storage"
+	keyringLabel   = "dmesh local storage key"
+)
+
+// errNoKeyringKey is returned when the keyring has no key yet
+var errNoKeyringKey = errors.New("no key in keyring")
+
+// KeyringKey reads the key from the OS keyring, the macOS keychain or the
+// Secret Service through secret-tool on Linux, storing a random key on
+// first use
+func KeyringKey() ([]byte, error) {
+	key, err := readKeyring()
+	if err == nil {
+		return key, nil
+	}
+	if !errors.Is(err, errNoKeyringKey) {
+		return nil, err
+	}
+
+	key = make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	if err := writeKeyring(base64.StdEncoding.EncodeToString(key)); err != nil {
+		return nil, err
+	}
+
+	// Read the key back: when runs race, the key stored last wins
+	return readKeyring()
+}
+
+func readKeyring() ([]byte, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", keyringService, "-a", keyringAccount, "-w")
+	case "linux":
+		cmd = exec.Command("secret-tool", "lookup", "service", keyringService, "account", keyringAccount)
+	default:
+		return nil, fmt.Errorf("no OS keyring support on %s; set encryption_key to kms or file", runtime.GOOS)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && notFound(exitErr.ExitCode(), stderr.String()) {
+			return nil, errNoKeyringKey
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%v: %s", err, msg)
+		}
+		return nil, fmt.Errorf("failed to read key from keyring: %w (set encryption_key to kms or file on hosts without a keyring)", err)
+	}
+
+	encoded := strings.TrimSpace(string(out))
+	if encoded == "" {
+		return nil, errNoKeyringKey
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != keySize {
+		return nil, fmt.Errorf("keyring entry %s/%s is not a %d byte key", keyringService, keyringAccount, keySize)
+	}
+	return key, nil
+}
+
+// notFound tells a missing entry from a failure: security exits with 44
+// when there is no such item, secret-tool with 1 and no message
+func notFound(code int, stderr string) bool {
+	if runtime.GOOS == "darwin" {
+		return code == 44
+	}
+	return code == 1 && strings.TrimSpace(stderr) == ""
+}
+
+func writeKeyring(secret string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		// security only takes the password as an argument; give the
+		// command to its interactive mode on stdin so that the key does
+		// not show in the process list
+		cmd = exec.Command("security", "-i")
+		cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -l %q -w %s\n",
+			keyringService, keyringAccount, keyringLabel, secret))
+	default:
+		cmd = exec.Command("secret-tool", "store", "--label="+keyringLabel, "service", keyringService, "account", keyringAccount)
+		cmd.Stdin = strings.NewReader(secret)
+	}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to store key in keyring: %v %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
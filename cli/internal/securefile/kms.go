@@ -0,0 +1,83 @@
+package securefile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+// kmsContext is bound to the data key, so it can only be decrypted for
+// this purpose
+var kmsContext = map[string]*string{
+	"application": aws.String("fedramp-data-mesh"),
+	"purpose":     aws.String("local-storage"),
+}
+
+// KMSKey returns a data key of a KMS key. The data key is kept wrapped by
+// KMS in a file and unwrapped on every run, so reading local files needs
+// kms:Decrypt on the key. A data key is generated on first use.
+func KMSKey(sess *session.Session, keyID, path string) ([]byte, error) {
+	if keyID == "" {
+		return nil, fmt.Errorf("kms_key_id must be set to encrypt local files with KMS")
+	}
+	client := kms.New(sess)
+
+	wrapped, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		if wrapped, err = generateDataKey(client, keyID, path); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read data key file: %w", err)
+	}
+
+	out, err := client.Decrypt(&kms.DecryptInput{
+		CiphertextBlob:    wrapped,
+		KeyId:             aws.String(keyID),
+		EncryptionContext: kmsContext,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data key with KMS key %s: %w", keyID, err)
+	}
+	if len(out.Plaintext) != keySize {
+		return nil, fmt.Errorf("data key in %s is not a %d byte key", path, keySize)
+	}
+	return out.Plaintext, nil
+}
+
+// generateDataKey creates a data key and stores it wrapped in a 0600 file
+func generateDataKey(client *kms.KMS, keyID, path string) ([]byte, error) {
+	out, err := client.GenerateDataKeyWithoutPlaintext(&kms.GenerateDataKeyWithoutPlaintextInput{
+		KeyId:             aws.String(keyID),
+		KeySpec:           aws.String(kms.DataKeySpecAes256),
+		EncryptionContext: kmsContext,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate data key with KMS key %s: %w", keyID, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create key directory: %w", err)
+	}
+	// O_EXCL so that concurrent runs don't overwrite each other's key
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if os.IsExist(err) {
+		return os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create data key file: %w", err)
+	}
+	if _, err := f.Write(out.CiphertextBlob); err != nil {
+		f.Close()
+		os.Remove(path)
+		return nil, fmt.Errorf("failed to write data key file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	return out.CiphertextBlob, nil
+}
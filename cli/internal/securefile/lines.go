@@ -0,0 +1,83 @@
+package securefile
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+// linePrefix starts every encrypted line of a file that is appended to
+// line by line, such as a log
+const linePrefix = "DMESHLOG1:"
+
+// lineData is authenticated with every line so that a line cannot be
+// passed off as a chunk of an encrypted file
+var lineData = []byte("DMESHLOG1")
+
+// EncryptLine encrypts one line of text, without its newline, into a
+// single line: the prefix and the base64 of a random nonce and the
+// ciphertext
+func EncryptLine(line []byte, key []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	sealed := make([]byte, aead.NonceSize(), aead.NonceSize()+len(line)+gcmOverhead)
+	if _, err := rand.Read(sealed); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed = aead.Seal(sealed, sealed, line, lineData)
+
+	out := make([]byte, len(linePrefix)+base64.StdEncoding.EncodedLen(len(sealed)))
+	copy(out, linePrefix)
+	base64.StdEncoding.Encode(out[len(linePrefix):], sealed)
+	return out, nil
+}
+
+// DecryptLines copies src to dst line by line, decrypting the lines
+// written by EncryptLine. Other lines, such as those written before
+// encryption was enabled, are copied as they are.
+func DecryptLines(dst io.Writer, src io.Reader, key []byte) error {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+
+	in := bufio.NewScanner(src)
+	in.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for number := 1; in.Scan(); number++ {
+		line := in.Bytes()
+		if bytes.HasPrefix(line, []byte(linePrefix)) {
+			sealed, err := base64.StdEncoding.DecodeString(string(line[len(linePrefix):]))
+			if err != nil || len(sealed) < aead.NonceSize()+gcmOverhead {
+				return fmt.Errorf("line %d is corrupt", number)
+			}
+			if line, err = aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], lineData); err != nil {
+				return fmt.Errorf("failed to decrypt line %d: it is corrupt or was encrypted with another key", number)
+			}
+		}
+		if _, err := dst.Write(line); err != nil {
+			return err
+		}
+		if _, err := dst.Write([]byte{'\n'}); err != nil {
+			return err
+		}
+	}
+	return in.Err()
+}
+
+// HasEncryptedLines reports whether a file contains a line written by
+// EncryptLine
+func HasEncryptedLines(r io.Reader) (bool, error) {
+	in := bufio.NewScanner(r)
+	in.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for in.Scan() {
+		if bytes.HasPrefix(in.Bytes(), []byte(linePrefix)) {
+			return true, nil
+		}
+	}
+	return false, in.Err()
+}
@@ -0,0 +1,291 @@
+// Package securefile encrypts local files with AES-256-GCM.
+//
+// Files are encrypted in chunks so that large files such as DuckDB
+// databases can be streamed. Every chunk is sealed with a nonce made of a
+// random per-file prefix, the chunk number and a flag marking the last
+// chunk, so chunks can be neither reordered nor truncated unnoticed.
+// Files that are appended to, such as logs, are encrypted line by line.
+//
+// The key comes from the OS keyring, a KMS data key or a local key file,
+// as configured by encryption_key; see Store.
+package securefile
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// magic starts every encrypted file
+var magic = []byte("DMESHENC1")
+
+const (
+	chunkSize   = 64 * 1024
+	prefixSize  = 7
+	keySize     = 32
+	gcmOverhead = 16
+)
+
+// ErrNotEncrypted is returned when decrypting a file without the header
+// of an encrypted file
+var ErrNotEncrypted = errors.New("not an encrypted file")
+
+// Encrypt reads plaintext from src and writes it encrypted to dst
+func Encrypt(dst io.Writer, src io.Reader, key []byte) error {
+	w, err := NewWriter(dst, key)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, src); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// Writer encrypts what is written to it. Close must be called to write
+// the last chunk; it does not close the underlying writer.
+type Writer struct {
+	dst     io.Writer
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	buf     []byte
+	sealed  []byte
+	closed  bool
+}
+
+// NewWriter writes the header of an encrypted file to dst and returns a
+// Writer encrypting into it
+func NewWriter(dst io.Writer, key []byte) (*Writer, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := make([]byte, prefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	if _, err := dst.Write(append(append([]byte{}, magic...), prefix...)); err != nil {
+		return nil, err
+	}
+	return &Writer{dst: dst, aead: aead, prefix: prefix, buf: make([]byte, 0, 2*chunkSize)}, nil
+}
+
+// Write encrypts p. A full chunk is held back until more data or Close
+// shows whether it is the last one.
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, fmt.Errorf("write to closed encrypted file")
+	}
+	written := 0
+	for len(p) > 0 {
+		n := 2*chunkSize - len(w.buf)
+		if n > len(p) {
+			n = len(p)
+		}
+		w.buf = append(w.buf, p[:n]...)
+		p = p[n:]
+		written += n
+
+		if len(w.buf) > chunkSize {
+			if err := w.seal(w.buf[:chunkSize], false); err != nil {
+				return written, err
+			}
+			w.buf = append(w.buf[:0], w.buf[chunkSize:]...)
+		}
+	}
+	return written, nil
+}
+
+// Close encrypts the last chunk
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.seal(w.buf, true)
+}
+
+func (w *Writer) seal(chunk []byte, last bool) error {
+	if !last && w.counter == ^uint32(0) {
+		return fmt.Errorf("file too large to encrypt")
+	}
+	w.sealed = w.aead.Seal(w.sealed[:0], nonce(w.prefix, w.counter, last), chunk, nil)
+	if _, err := w.dst.Write(w.sealed); err != nil {
+		return err
+	}
+	w.counter++
+	return nil
+}
+
+// Decrypt reads an encrypted file from src and writes the plaintext to
+// dst. It fails if the file was modified or truncated; plaintext written
+// before such an error must be discarded.
+func Decrypt(dst io.Writer, src io.Reader, key []byte) error {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+
+	in := bufio.NewReaderSize(src, chunkSize+gcmOverhead)
+	header := make([]byte, len(magic)+prefixSize)
+	if _, err := io.ReadFull(in, header); err != nil || !bytes.Equal(header[:len(magic)], magic) {
+		return ErrNotEncrypted
+	}
+	prefix := header[len(magic):]
+
+	buf := make([]byte, chunkSize+gcmOverhead)
+	var plain []byte
+	for counter := uint32(0); ; counter++ {
+		n, err := io.ReadFull(in, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		last := n < len(buf)
+		if !last {
+			if _, err := in.Peek(1); err == io.EOF {
+				last = true
+			}
+		}
+
+		plain, err = aead.Open(plain[:0], nonce(prefix, counter, last), buf[:n], nil)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt: the file is corrupt or was encrypted with another key")
+		}
+		if _, err := dst.Write(plain); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// IsEncrypted reports whether a file starts with the encrypted file header
+func IsEncrypted(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	header := make([]byte, len(magic))
+	if _, err := io.ReadFull(f, header); err != nil {
+		return false, nil
+	}
+	return bytes.Equal(header, magic), nil
+}
+
+// EncryptFile encrypts the file src into dst, which is created with mode
+// 0600 and replaced atomically
+func EncryptFile(dst, src string, key []byte) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	return writeAtomic(dst, func(w io.Writer) error {
+		return Encrypt(w, in, key)
+	})
+}
+
+// DecryptFile decrypts the file src into dst, which is created with mode
+// 0600. dst is removed if decryption fails.
+func DecryptFile(dst, src string, key []byte) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := Decrypt(out, in, key); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
+
+// writeAtomic writes a 0600 file through a temporary file in the same
+// directory
+func writeAtomic(path string, write func(w io.Writer) error) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+
+	// CreateTemp already uses 0600; be explicit about it
+	if err := f.Chmod(0600); err != nil {
+		f.Close()
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if err := write(w); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// WriteFile writes data encrypted to a 0600 file, replacing it atomically
+func WriteFile(path string, data []byte, key []byte) error {
+	return writeAtomic(path, func(w io.Writer) error {
+		return Encrypt(w, bytes.NewReader(data), key)
+	})
+}
+
+// WritePrivateFile writes data unencrypted to a 0600 file, replacing it
+// atomically, for output that holds no data but should not be readable
+// by other users
+func WritePrivateFile(path string, data []byte) error {
+	return writeAtomic(path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != keySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes", keySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// nonce builds the nonce of a chunk: prefix, big-endian counter and the
+// last chunk flag
+func nonce(prefix []byte, counter uint32, last bool) []byte {
+	n := make([]byte, 12)
+	copy(n, prefix)
+	binary.BigEndian.PutUint32(n[prefixSize:], counter)
+	if last {
+		n[11] = 1
+	}
+	return n
+}
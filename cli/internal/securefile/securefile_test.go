@@ -0,0 +1,208 @@
+package securefile
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func encrypt(t *testing.T, plain, key []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Encrypt(&buf, bytes.NewReader(plain), key); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// sealedSize is the size of a file of n bytes: the header and the chunks
+// with their tags. A multiple of the chunk size ends in a full last chunk
+// rather than an empty one; an empty file has one empty chunk.
+func sealedSize(n int) int {
+	header := len(magic) + prefixSize
+	if n > 0 && n%chunkSize == 0 {
+		return header + n + (n/chunkSize)*gcmOverhead
+	}
+	return header + n + (n/chunkSize+1)*gcmOverhead
+}
+
+func TestRoundTrip(t *testing.T) {
+	key := testKey(t)
+	tests := []struct {
+		name string
+		size int
+	}{
+		{"empty", 0},
+		{"short", 100},
+		{"one chunk", chunkSize},
+		{"chunk and a byte", chunkSize + 1},
+		{"two chunks", 2 * chunkSize},
+		{"three and a half chunks", 3*chunkSize + chunkSize/2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			plain := make([]byte, test.size)
+			rand.Read(plain)
+
+			sealed := encrypt(t, plain, key)
+			if len(sealed) != sealedSize(test.size) {
+				t.Errorf("encrypted to %d bytes, want %d", len(sealed), sealedSize(test.size))
+			}
+			var out bytes.Buffer
+			if err := Decrypt(&out, bytes.NewReader(sealed), key); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out.Bytes(), plain) {
+				t.Errorf("decrypted %d bytes that differ from the %d written", out.Len(), len(plain))
+			}
+		})
+	}
+}
+
+func TestWriterSmallWrites(t *testing.T) {
+	key := testKey(t)
+	plain := make([]byte, 2*chunkSize+10)
+	rand.Read(plain)
+
+	// Writes that don't line up with chunks give the same chunks
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rest := plain; len(rest) > 0; {
+		n := 1000
+		if n > len(rest) {
+			n = len(rest)
+		}
+		if _, err := w.Write(rest[:n]); err != nil {
+			t.Fatal(err)
+		}
+		rest = rest[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("x")); err == nil {
+		t.Error("write after Close succeeded")
+	}
+
+	var out bytes.Buffer
+	if err := Decrypt(&out, &buf, key); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), plain) {
+		t.Error("decrypted data differs")
+	}
+}
+
+func TestDecryptTampered(t *testing.T) {
+	key := testKey(t)
+	plain := make([]byte, 2*chunkSize+100)
+	rand.Read(plain)
+	sealed := encrypt(t, plain, key)
+	header := len(magic) + prefixSize
+	chunk := chunkSize + gcmOverhead
+
+	tests := []struct {
+		name string
+		data []byte
+		key  []byte
+	}{
+		{"wrong key", sealed, testKey(t)},
+		// A file cut after a full chunk looks complete but that chunk
+		// isn't sealed as the last
+		{"truncated at chunk boundary", sealed[:header+chunk], key},
+		{"truncated at second chunk boundary", sealed[:header+2*chunk], key},
+		{"truncated in a chunk", sealed[:header+chunk+10], key},
+		{"only header", sealed[:header], key},
+		{"flipped bit", flip(sealed, header+chunk+5), key},
+		{"chunks swapped", append(append(append([]byte{}, sealed[:header]...), sealed[header+chunk:header+2*chunk]...),
+			append(append([]byte{}, sealed[header:header+chunk]...), sealed[header+2*chunk:]...)...), key},
+		{"trailing data", append(append([]byte{}, sealed...), 0), key},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Decrypt(&bytes.Buffer{}, bytes.NewReader(test.data), test.key)
+			if err == nil || !strings.Contains(err.Error(), "corrupt or was encrypted with another key") {
+				t.Errorf("error %v, want a failure to decrypt", err)
+			}
+		})
+	}
+
+	// An empty file is one empty chunk sealed as the last
+	empty := encrypt(t, nil, key)
+	if err := Decrypt(&bytes.Buffer{}, bytes.NewReader(empty[:header]), key); err == nil {
+		t.Error("decrypted an empty file without its chunk")
+	}
+	if err := Decrypt(&bytes.Buffer{}, bytes.NewReader(empty), testKey(t)); err == nil {
+		t.Error("decrypted an empty file with another key")
+	}
+}
+
+func flip(data []byte, i int) []byte {
+	data = append([]byte{}, data...)
+	data[i] ^= 1
+	return data
+}
+
+func TestDecryptNotEncrypted(t *testing.T) {
+	for _, data := range []string{"", "DMESH", "plain text that is long enough for a header"} {
+		if err := Decrypt(&bytes.Buffer{}, strings.NewReader(data), testKey(t)); !errors.Is(err, ErrNotEncrypted) {
+			t.Errorf("%q: error %v, want ErrNotEncrypted", data, err)
+		}
+	}
+}
+
+func TestInvalidKey(t *testing.T) {
+	if _, err := NewWriter(&bytes.Buffer{}, make([]byte, 16)); err == nil {
+		t.Error("encrypted with a 16 byte key")
+	}
+}
+
+func TestLines(t *testing.T) {
+	key := testKey(t)
+	var log bytes.Buffer
+	log.WriteString("written before encryption\n")
+	for _, line := range []string{"INFO: one", "", "ERROR: three"} {
+		sealed, err := EncryptLine([]byte(line), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.ContainsAny(sealed, "\r\n") {
+			t.Fatalf("encrypted line %q spans lines", line)
+		}
+		log.Write(append(sealed, '\n'))
+	}
+
+	if ok, err := HasEncryptedLines(bytes.NewReader(log.Bytes())); err != nil || !ok {
+		t.Errorf("HasEncryptedLines = %t, %v", ok, err)
+	}
+	var out bytes.Buffer
+	if err := DecryptLines(&out, bytes.NewReader(log.Bytes()), key); err != nil {
+		t.Fatal(err)
+	}
+	if want := "written before encryption\nINFO: one\n\nERROR: three\n"; out.String() != want {
+		t.Errorf("decrypted %q, want %q", out.String(), want)
+	}
+
+	if err := DecryptLines(&bytes.Buffer{}, bytes.NewReader(log.Bytes()), testKey(t)); err == nil {
+		t.Error("decrypted lines with another key")
+	}
+	// A line can't be passed off as a file and the other way round
+	line, _ := EncryptLine([]byte("x"), key)
+	if err := Decrypt(&bytes.Buffer{}, bytes.NewReader(line), key); err == nil {
+		t.Error("decrypted a line as a file")
+	}
+}
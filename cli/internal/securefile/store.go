@@ -0,0 +1,261 @@
+package securefile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/config"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/security"
+)
+
+// Sources of the key local files are encrypted with
+const (
+	KeySourceKeyring = "keyring"
+	KeySourceKMS     = "kms"
+	KeySourceFile    = "file"
+)
+
+// keyRetry is how long a failure to get the key is returned before the
+// keyring or KMS is asked again, such as after an SSO login
+const keyRetry = 30 * time.Second
+
+// keys caches the key per source for the life of the process, so the
+// keyring or KMS is asked once, and a failure to get it for keyRetry
+var keys struct {
+	sync.Mutex
+	byID map[string][]byte
+	errs map[string]keyError
+}
+
+type keyError struct {
+	err error
+	at  time.Time
+}
+
+// Store encrypts the files the CLI writes locally, such as exports, logs
+// and cached data, with the key configured by encryption_key
+type Store struct {
+	cfg    *config.Config
+	secCtx *security.SecurityContext
+}
+
+func NewStore(cfg *config.Config, secCtx *security.SecurityContext) (*Store, error) {
+	switch cfg.EncryptionKey {
+	case KeySourceKeyring, KeySourceFile:
+	case KeySourceKMS:
+		if cfg.KMSKeyID == "" {
+			return nil, fmt.Errorf("kms_key_id must be set when encryption_key is kms")
+		}
+	default:
+		return nil, fmt.Errorf("invalid encryption_key %q: must be keyring, kms or file", cfg.EncryptionKey)
+	}
+	return &Store{cfg: cfg, secCtx: secCtx}, nil
+}
+
+// Key returns the encryption key, loading or creating it on first use
+func (s *Store) Key() ([]byte, error) {
+	id := s.cfg.EncryptionKey + ":" + s.cfg.KMSKeyID
+
+	keys.Lock()
+	defer keys.Unlock()
+	if key, ok := keys.byID[id]; ok {
+		return key, nil
+	}
+	if failed, ok := keys.errs[id]; ok && time.Since(failed.at) < keyRetry {
+		return nil, failed.err
+	}
+
+	key, err := s.loadKey()
+	if err != nil {
+		if keys.errs == nil {
+			keys.errs = make(map[string]keyError)
+		}
+		keys.errs[id] = keyError{err: err, at: time.Now()}
+		return nil, err
+	}
+	delete(keys.errs, id)
+
+	if keys.byID == nil {
+		keys.byID = make(map[string][]byte)
+	}
+	keys.byID[id] = key
+	return key, nil
+}
+
+// loadKey gets the key from its source
+func (s *Store) loadKey() ([]byte, error) {
+	configDir, err := config.Dir()
+	if err != nil {
+		return nil, err
+	}
+	var key []byte
+	switch s.cfg.EncryptionKey {
+	case KeySourceKMS:
+		sess, err := s.secCtx.GetAWSSession()
+		if err != nil {
+			return nil, err
+		}
+		key, err = KMSKey(sess, s.cfg.KMSKeyID, filepath.Join(configDir, "keys", "data.key.kms"))
+		if err != nil {
+			return nil, err
+		}
+	case KeySourceFile:
+		if key, err = LocalKey(filepath.Join(configDir, "keys", "local.key")); err != nil {
+			return nil, err
+		}
+	default:
+		if key, err = KeyringKey(); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// WriteFile writes data to an encrypted 0600 file
+func (s *Store) WriteFile(path string, data []byte) error {
+	key, err := s.Key()
+	if err != nil {
+		return err
+	}
+	return WriteFile(path, data, key)
+}
+
+// Create creates an encrypted 0600 file. It is written to a temporary
+// file that replaces any file at path when the writer is closed.
+func (s *Store) Create(path string) (io.WriteCloser, error) {
+	key, err := s.Key()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Chmod(0600); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	buf := bufio.NewWriter(f)
+	w, err := NewWriter(buf, key)
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return &fileWriter{Writer: w, buf: buf, f: f, path: path}, nil
+}
+
+// EncryptFile encrypts the plaintext file src into the 0600 file dst
+func (s *Store) EncryptFile(dst, src string) error {
+	key, err := s.Key()
+	if err != nil {
+		return err
+	}
+	return EncryptFile(dst, src, key)
+}
+
+// TempDir creates a private directory for plaintext that is encrypted
+// once complete, such as files written by DuckDB, and returns a function
+// that removes it
+func (s *Store) TempDir() (string, func(), error) {
+	configDir, err := config.Dir()
+	if err != nil {
+		return "", nil, err
+	}
+	parent := filepath.Join(configDir, "tmp")
+	if err := os.MkdirAll(parent, 0700); err != nil {
+		return "", nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	dir, err := os.MkdirTemp(parent, "plain-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	return dir, func() { os.RemoveAll(dir) }, nil
+}
+
+// EncryptLine encrypts a line for a file that is appended to, see
+// EncryptLine
+func (s *Store) EncryptLine(line []byte) ([]byte, error) {
+	key, err := s.Key()
+	if err != nil {
+		return nil, err
+	}
+	return EncryptLine(line, key)
+}
+
+// Decrypt writes the plaintext of an encrypted file, or of the encrypted
+// lines of a log, to dst
+func (s *Store) Decrypt(dst io.Writer, path string) error {
+	encrypted, err := IsEncrypted(path)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if !encrypted {
+		hasLines, err := HasEncryptedLines(f)
+		if err != nil {
+			return err
+		}
+		if !hasLines {
+			return ErrNotEncrypted
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	}
+
+	key, err := s.Key()
+	if err != nil {
+		return err
+	}
+	if encrypted {
+		return Decrypt(dst, f, key)
+	}
+	return DecryptLines(dst, f, key)
+}
+
+// fileWriter encrypts into a temporary file that is renamed into place
+// on Close
+type fileWriter struct {
+	*Writer
+	buf    *bufio.Writer
+	f      *os.File
+	path   string
+	closed bool
+}
+
+func (w *fileWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	tmp := w.f.Name()
+	err := w.Writer.Close()
+	if err == nil {
+		err = w.buf.Flush()
+	}
+	if closeErr := w.f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, w.path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -16,27 +17,28 @@ import (
 )
 
 type SecurityContext struct {
-	cfg            *config.Config
+	cfg *config.Config
+	
+	// mu guards the credentials and role, which commands running in
+	// parallel, such as those of the TUIs, refresh and read
+	mu             sync.Mutex
 	awsCredentials *credentials.Credentials
 	lastRefresh    time.Time
 	sessionToken   string
 	role           string
 }
 
-func NewSecurityContext(cfg *config.Config) (*SecurityContext, error) {
-	ctx := &SecurityContext{
+// NewSecurityContext returns a security context for the configured role.
+// AWS credentials are looked up on first use, so that commands working
+// only on local files, such as schema lint, run without them.
+func NewSecurityContext(cfg *config.Config) *SecurityContext {
+	return &SecurityContext{
 		cfg:  cfg,
 		role: cfg.DefaultRole,
 	}
-	
-	// Initialize AWS credentials
-	if err := ctx.initAWSCredentials(); err != nil {
-		return nil, err
-	}
-	
-	return ctx, nil
 }
 
+// initAWSCredentials looks up credentials; called with mu held
 func (s *SecurityContext) initAWSCredentials() error {
 	// Check for profile in AWS config
 	if s.cfg.AWSProfile != "" {
@@ -59,6 +61,8 @@ func (s *SecurityContext) initAWSCredentials() error {
 	return nil
 }
 
+// refreshFromSSO gets credentials for the role; called with mu held once
+// the context is shared
 func (s *SecurityContext) refreshFromSSO() error {
 	// Check if SSO token exists and is valid
 	homeDir, err := os.UserHomeDir()
@@ -160,6 +164,16 @@ func (s *SecurityContext) refreshFromSSO() error {
 }
 
 func (s *SecurityContext) GetAWSCredentials() (string, string, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	// Look up credentials on first use, and again after a failure
+	if s.awsCredentials == nil {
+		if err := s.initAWSCredentials(); err != nil {
+			return "", "", "", err
+		}
+	}
+	
 	// Check if credentials need refresh
 	if time.Since(s.lastRefresh) > 55*time.Minute {
 		if err := s.refreshFromSSO(); err != nil {
@@ -195,6 +209,9 @@ func (s *SecurityContext) GetAWSSession() (*session.Session, error) {
 }
 
 func (s *SecurityContext) AssumeRole(role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
 	// Save current role
 	prevRole := s.role
 	s.role = role
@@ -211,6 +228,8 @@ func (s *SecurityContext) AssumeRole(role string) error {
 }
 
 func (s *SecurityContext) GetCurrentRole() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.role
 }
 
@@ -233,8 +252,9 @@ func (s *SecurityContext) CanAccessDataProduct(dataProduct string) (bool, error)
 	// to determine if the current user/role has access to the data product
 	// This is a simplified implementation that always returns true
 	
-	// Log the access attempt for audit purposes
-	fmt.Printf("User %s is requesting access to data product %s\n", *identity.Arn, dataProduct)
+	// Log the access attempt for audit purposes, on stderr so it doesn't
+	// mix with command output
+	fmt.Fprintf(os.Stderr, "User %s is requesting access to data product %s\n", *identity.Arn, dataProduct)
 	
 	return true, nil
 }
@@ -0,0 +1,227 @@
+package sla
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/lakehouse"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/manifest"
+)
+
+// Status is the outcome of a freshness check
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	StatusFail Status = "fail"
+	// StatusError means freshness could not be determined
+	StatusError Status = "error"
+	// StatusSkipped means the product declares no latency or no tables
+	StatusSkipped Status = "skipped"
+)
+
+// Freshness sources
+const (
+	SourceAuto           = "auto"
+	SourceSnapshot       = "snapshot"
+	SourceEventTimestamp = "event-timestamp"
+)
+
+// DefaultTimestampColumn is the event time column of data product tables
+const DefaultTimestampColumn = "event_timestamp"
+
+// EventTimeFunc returns the maximum of a timestamp column of a table, or
+// the zero time for an empty table
+type EventTimeFunc func(product *manifest.DataProduct, table *manifest.Table, column string) (time.Time, error)
+
+// Checker compares the freshness of data product tables with the latency
+// declared in their sla block
+type Checker struct {
+	Lakehouse *lakehouse.Client
+	// EventTime is used for the event-timestamp source; optional
+	EventTime EventTimeFunc
+	// Source is auto, snapshot or event-timestamp. Auto uses the latest
+	// snapshot and falls back to the event timestamp.
+	Source          string
+	TimestampColumn string
+	Now             func() time.Time
+}
+
+// Report is the result of checking a set of data products
+type Report struct {
+	CheckedAt time.Time       `json:"checked_at"`
+	Products  []ProductResult `json:"products"`
+	Passed    int             `json:"passed"`
+	Failed    int             `json:"failed"`
+	Errors    int             `json:"errors"`
+	Skipped   int             `json:"skipped"`
+}
+
+// ProductResult is the freshness of a data product; it passes when all
+// of its tables pass
+type ProductResult struct {
+	Product        string        `json:"product"`
+	Latency        string        `json:"latency,omitempty"`
+	LatencySeconds float64       `json:"latency_seconds,omitempty"`
+	Status         Status        `json:"status"`
+	Message        string        `json:"message,omitempty"`
+	Tables         []TableResult `json:"tables,omitempty"`
+}
+
+// TableResult is the freshness of one table
+type TableResult struct {
+	Table  string `json:"table"`
+	Source string `json:"source,omitempty"`
+	// LatestData is the latest snapshot time or event timestamp
+	LatestData       *time.Time `json:"latest_data,omitempty"`
+	FreshnessSeconds *float64   `json:"freshness_seconds,omitempty"`
+	Status           Status     `json:"status"`
+	Message          string     `json:"message,omitempty"`
+}
+
+// HasFailures reports whether any product failed or could not be checked
+func (r *Report) HasFailures() bool {
+	return r.Failed > 0 || r.Errors > 0
+}
+
+// Check checks the freshness of each product
+func (c *Checker) Check(products []*manifest.DataProduct) *Report {
+	report := &Report{CheckedAt: c.now()}
+	for _, p := range products {
+		result := c.checkProduct(p, report.CheckedAt)
+		switch result.Status {
+		case StatusPass:
+			report.Passed++
+		case StatusFail:
+			report.Failed++
+		case StatusError:
+			report.Errors++
+		case StatusSkipped:
+			report.Skipped++
+		}
+		report.Products = append(report.Products, result)
+	}
+	return report
+}
+
+func (c *Checker) now() time.Time {
+	if c.Now != nil {
+		return c.Now().UTC()
+	}
+	return time.Now().UTC()
+}
+
+func (c *Checker) checkProduct(p *manifest.DataProduct, now time.Time) ProductResult {
+	result := ProductResult{Product: p.ID(), Latency: p.Spec.SLA.Latency}
+	if result.Latency == "" {
+		result.Status = StatusSkipped
+		result.Message = "no sla.latency declared"
+		return result
+	}
+	latency, err := manifest.ParseDuration(result.Latency)
+	if err != nil {
+		result.Status = StatusError
+		result.Message = "sla.latency: " + err.Error()
+		return result
+	}
+	result.LatencySeconds = latency.Seconds()
+	if len(p.Spec.Tables) == 0 {
+		result.Status = StatusSkipped
+		result.Message = "no tables declared in spec.tables"
+		return result
+	}
+
+	// The worst table decides
+	result.Status = StatusPass
+	for i := range p.Spec.Tables {
+		table := c.checkTable(p, &p.Spec.Tables[i], latency, now)
+		switch {
+		case table.Status == StatusError && result.Status != StatusFail:
+			result.Status = StatusError
+		case table.Status == StatusFail:
+			result.Status = StatusFail
+		}
+		result.Tables = append(result.Tables, table)
+	}
+	return result
+}
+
+func (c *Checker) checkTable(p *manifest.DataProduct, t *manifest.Table, latency time.Duration, now time.Time) TableResult {
+	result := TableResult{Table: t.QualifiedName()}
+
+	latest, source, err := c.latestData(p, t)
+	if err != nil {
+		result.Status = StatusError
+		result.Message = err.Error()
+		return result
+	}
+	result.Source = source
+	if latest.IsZero() {
+		result.Status = StatusFail
+		result.Message = "table has no data"
+		return result
+	}
+
+	freshness := now.Sub(latest)
+	if freshness < 0 {
+		freshness = 0
+	}
+	seconds := freshness.Seconds()
+	result.LatestData = &latest
+	result.FreshnessSeconds = &seconds
+
+	if freshness <= latency {
+		result.Status = StatusPass
+	} else {
+		result.Status = StatusFail
+		result.Message = fmt.Sprintf("data is %s old, exceeding the %s latency", manifest.FormatDuration(freshness), p.Spec.SLA.Latency)
+	}
+	return result
+}
+
+// latestData returns the time of the newest data in a table and the
+// source it was read from
+func (c *Checker) latestData(p *manifest.DataProduct, t *manifest.Table) (time.Time, string, error) {
+	source := c.Source
+	if source == "" {
+		source = SourceAuto
+	}
+
+	var snapshotErr error
+	if source == SourceAuto || source == SourceSnapshot {
+		history, err := c.Lakehouse.History(t.Format, t.Location)
+		if err == nil {
+			if latest := history.Latest(); latest != nil {
+				return latest.Timestamp.UTC(), SourceSnapshot, nil
+			}
+			err = fmt.Errorf("table has no snapshots")
+		}
+		if source == SourceSnapshot {
+			return time.Time{}, "", err
+		}
+		snapshotErr = err
+	}
+
+	if source != SourceAuto && source != SourceEventTimestamp {
+		return time.Time{}, "", fmt.Errorf("unsupported freshness source: %s", source)
+	}
+	if c.EventTime == nil {
+		if snapshotErr != nil {
+			return time.Time{}, "", snapshotErr
+		}
+		return time.Time{}, "", fmt.Errorf("event timestamps are not available")
+	}
+
+	column := c.TimestampColumn
+	if column == "" {
+		column = DefaultTimestampColumn
+	}
+	latest, err := c.EventTime(p, t, column)
+	if err != nil {
+		if snapshotErr != nil {
+			return time.Time{}, "", fmt.Errorf("%v; max(%s): %w", snapshotErr, column, err)
+		}
+		return time.Time{}, "", fmt.Errorf("max(%s): %w", column, err)
+	}
+	return latest.UTC(), SourceEventTimestamp, nil
+}
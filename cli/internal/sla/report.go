@@ -0,0 +1,64 @@
+package sla
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/manifest"
+)
+
+// WriteReport writes the report in the given format (text, json)
+func WriteReport(w io.Writer, report *Report, format string) error {
+	switch strings.ToLower(format) {
+	case "text", "":
+		return writeText(w, report)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	default:
+		return fmt.Errorf("unsupported SLA report format: %s", format)
+	}
+}
+
+func writeText(w io.Writer, report *Report) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATUS\tDATA PRODUCT\tTABLE\tLATENCY\tFRESHNESS\tSOURCE\tDETAILS")
+	for _, p := range report.Products {
+		if len(p.Tables) == 0 {
+			fmt.Fprintf(tw, "%s\t%s\t-\t%s\t-\t-\t%s\n", statusLabel(p.Status), p.Product, orDash(p.Latency), p.Message)
+			continue
+		}
+		for _, t := range p.Tables {
+			freshness := "-"
+			if t.FreshnessSeconds != nil {
+				freshness = manifest.FormatDuration(time.Duration(*t.FreshnessSeconds * float64(time.Second)))
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", statusLabel(t.Status), p.Product, t.Table,
+				p.Latency, freshness, orDash(t.Source), t.Message)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "\n%d product(s) checked at %s: %d passed, %d failed, %d error(s), %d skipped\n",
+		len(report.Products), report.CheckedAt.Local().Format("2006-01-02 15:04:05"),
+		report.Passed, report.Failed, report.Errors, report.Skipped)
+	return err
+}
+
+func statusLabel(s Status) string {
+	return strings.ToUpper(string(s))
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,245 @@
+package stream
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/config"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/logging"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/security"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+// ErrStop can be returned by a message handler to end a tail without error
+var ErrStop = errors.New("stop tailing")
+
+// Client reads from and writes to the Kafka event streams of data products
+type Client struct {
+	cfg     *config.Config
+	log     *logging.Logger
+	brokers []string
+	dialer  *kafka.Dialer
+}
+
+// TailOptions select where reading starts
+type TailOptions struct {
+	Topic string
+	// FromBeginning starts at the earliest retained offset
+	FromBeginning bool
+	// Since starts at the first message at or after the time; ignored when zero
+	Since time.Time
+}
+
+func NewClient(cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger) (*Client, error) {
+	var brokers []string
+	for _, b := range strings.Split(cfg.KafkaBrokers, ",") {
+		if b = strings.TrimSpace(b); b != "" {
+			brokers = append(brokers, b)
+		}
+	}
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("kafka_brokers is not configured")
+	}
+
+	dialer := &kafka.Dialer{
+		Timeout:   10 * time.Second,
+		DualStack: true,
+	}
+
+	// Configure authentication
+	var mechanism sasl.Mechanism
+	switch cfg.KafkaAuth {
+	case "", "none":
+	case "plain":
+		mechanism = plain.Mechanism{Username: cfg.KafkaUsername, Password: cfg.KafkaPassword}
+	case "scram-sha-256", "scram-sha-512":
+		algo := scram.SHA256
+		if cfg.KafkaAuth == "scram-sha-512" {
+			algo = scram.SHA512
+		}
+		m, err := scram.Mechanism(algo, cfg.KafkaUsername, cfg.KafkaPassword)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure SCRAM authentication: %w", err)
+		}
+		mechanism = m
+	case "aws-msk-iam":
+		// Fail now rather than on the first connection
+		if _, _, _, err := secCtx.GetAWSCredentials(); err != nil {
+			return nil, fmt.Errorf("failed to get AWS credentials: %w", err)
+		}
+		mechanism = newMSKIAMMechanism(secCtx, cfg.AWSRegion)
+	default:
+		return nil, fmt.Errorf("unsupported kafka_auth: %s", cfg.KafkaAuth)
+	}
+	dialer.SASLMechanism = mechanism
+
+	// MSK only accepts IAM authentication over TLS
+	if cfg.KafkaTLS || cfg.KafkaAuth == "aws-msk-iam" {
+		dialer.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	return &Client{
+		cfg:     cfg,
+		log:     log,
+		brokers: brokers,
+		dialer:  dialer,
+	}, nil
+}
+
+// Brokers returns the configured bootstrap brokers
+func (c *Client) Brokers() []string {
+	return c.brokers
+}
+
+// IsLocal reports whether all brokers run on this machine, such as a
+// broker started with docker compose for testing
+func (c *Client) IsLocal() bool {
+	for _, b := range c.brokers {
+		if !IsLocalBroker(b) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsLocalBroker reports whether a host:port address is a loopback address
+func IsLocalBroker(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || host == "host.docker.internal" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// NewWriter returns a writer for a topic. Keys are partitioned with
+// murmur2 like the Java client's default partitioner, so events land on
+// the same partitions as those of the producing services.
+func (c *Client) NewWriter(topic string) *kafka.Writer {
+	return &kafka.Writer{
+		Addr:         kafka.TCP(c.brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Murmur2Balancer{},
+		RequiredAcks: kafka.RequireAll,
+		BatchTimeout: 10 * time.Millisecond,
+		Transport:    c.transport(),
+		// Local test brokers start without topics
+		AllowAutoTopicCreation: c.IsLocal(),
+	}
+}
+
+// Partitions returns the sorted partition IDs of a topic
+func (c *Client) Partitions(ctx context.Context, topic string) ([]int, error) {
+	partitions, err := c.dialer.LookupPartitions(ctx, "tcp", c.brokers[0], topic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up partitions of %s: %w", topic, err)
+	}
+	if len(partitions) == 0 {
+		return nil, fmt.Errorf("topic %s has no partitions", topic)
+	}
+
+	ids := make([]int, len(partitions))
+	for i, p := range partitions {
+		ids[i] = p.ID
+	}
+	sort.Ints(ids)
+	return ids, nil
+}
+
+// Tail reads all partitions of a topic without joining a consumer group
+// and calls handle for every message until the context is cancelled or
+// handle returns an error. Messages of one partition arrive in order;
+// partitions are interleaved as they are read.
+func (c *Client) Tail(ctx context.Context, opts TailOptions, handle func(kafka.Message) error) error {
+	partitions, err := c.Partitions(ctx, opts.Topic)
+	if err != nil {
+		return err
+	}
+
+	// Readers are stopped by cancelling the context; wait for them after
+	messages := make(chan kafka.Message)
+	errs := make(chan error, len(partitions))
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for _, partition := range partitions {
+		reader := kafka.NewReader(kafka.ReaderConfig{
+			Brokers:   c.brokers,
+			Topic:     opts.Topic,
+			Partition: partition,
+			Dialer:    c.dialer,
+			MinBytes:  1,
+			MaxBytes:  10 << 20,
+			MaxWait:   500 * time.Millisecond,
+		})
+
+		// Position the reader
+		switch {
+		case !opts.Since.IsZero():
+			// A time after the last message yields the end of the partition
+			err = reader.SetOffsetAt(ctx, opts.Since)
+		case opts.FromBeginning:
+			err = reader.SetOffset(kafka.FirstOffset)
+		default:
+			err = reader.SetOffset(kafka.LastOffset)
+		}
+		if err != nil {
+			reader.Close()
+			return fmt.Errorf("failed to set offset of partition %d: %w", partition, err)
+		}
+
+		wg.Add(1)
+		go func(partition int, reader *kafka.Reader) {
+			defer wg.Done()
+			defer reader.Close()
+			for {
+				msg, err := reader.ReadMessage(ctx)
+				if err != nil {
+					if ctx.Err() == nil {
+						errs <- fmt.Errorf("failed to read partition %d: %w", partition, err)
+					}
+					return
+				}
+				select {
+				case messages <- msg:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(partition, reader)
+	}
+
+	c.log.Debugf("Tailing %s: %d partitions", opts.Topic, len(partitions))
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errs:
+			return err
+		case msg := <-messages:
+			if err := handle(msg); err != nil {
+				if errors.Is(err, ErrStop) {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+}
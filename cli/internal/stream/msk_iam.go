@@ -0,0 +1,108 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/security"
+	"github.com/segmentio/kafka-go/sasl"
+)
+
+const (
+	mskIAMVersion   = "2020_10_22"
+	mskIAMService   = "kafka-cluster"
+	mskIAMAction    = "kafka-cluster:Connect"
+	mskIAMExpiry    = 5 * time.Minute
+	mskIAMUserAgent = "dmesh"
+)
+
+// mskIAMMechanism implements the AWS_MSK_IAM SASL mechanism: the client
+// sends a SigV4 presigned kafka-cluster:Connect request as a JSON object
+type mskIAMMechanism struct {
+	signer *v4.Signer
+	region string
+}
+
+// newMSKIAMMechanism signs with the credentials of the security context,
+// got again for every connection so that reconnects after they are
+// refreshed don't use expired ones
+func newMSKIAMMechanism(secCtx *security.SecurityContext, region string) *mskIAMMechanism {
+	creds := credentials.NewCredentials(contextProvider{secCtx: secCtx})
+	return &mskIAMMechanism{signer: v4.NewSigner(creds), region: region}
+}
+
+// contextProvider provides the credentials of a security context, which
+// refreshes them itself
+type contextProvider struct {
+	secCtx *security.SecurityContext
+}
+
+func (p contextProvider) Retrieve() (credentials.Value, error) {
+	accessKey, secretKey, sessionToken, err := p.secCtx.GetAWSCredentials()
+	if err != nil {
+		return credentials.Value{}, err
+	}
+	return credentials.Value{
+		AccessKeyID:     accessKey,
+		SecretAccessKey: secretKey,
+		SessionToken:    sessionToken,
+		ProviderName:    "SecurityContext",
+	}, nil
+}
+
+// IsExpired is always true so that every signature asks the context
+func (p contextProvider) IsExpired() bool {
+	return true
+}
+
+func (m *mskIAMMechanism) Name() string {
+	return "AWS_MSK_IAM"
+}
+
+func (m *mskIAMMechanism) Start(ctx context.Context) (sasl.StateMachine, []byte, error) {
+	metadata := sasl.MetadataFromContext(ctx)
+	if metadata == nil {
+		return nil, nil, fmt.Errorf("missing broker metadata for MSK IAM authentication")
+	}
+
+	query := url.Values{"Action": {mskIAMAction}}
+	signURL := url.URL{Scheme: "kafka", Host: metadata.Host, Path: "/", RawQuery: query.Encode()}
+	req, err := http.NewRequest(http.MethodGet, signURL.String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header, err := m.signer.Presign(req, nil, mskIAMService, m.region, mskIAMExpiry, time.Now())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to sign MSK IAM request: %w", err)
+	}
+
+	// The broker expects lower-case keys
+	payload := map[string]string{
+		"version":    mskIAMVersion,
+		"host":       signURL.Host,
+		"user-agent": mskIAMUserAgent,
+		"action":     mskIAMAction,
+	}
+	for key, values := range header {
+		payload[strings.ToLower(key)] = values[0]
+	}
+	for key, values := range req.URL.Query() {
+		payload[strings.ToLower(key)] = values[0]
+	}
+
+	data, err := json.Marshal(payload)
+	return m, data, err
+}
+
+func (m *mskIAMMechanism) Next(ctx context.Context, challenge []byte) (bool, []byte, error) {
+	// The broker answers with the session details; nothing more to send
+	return true, nil, nil
+}
@@ -0,0 +1,282 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// TopicStatus describes the partitions and retention of a topic
+type TopicStatus struct {
+	Topic             string            `json:"topic"`
+	ReplicationFactor int               `json:"replication_factor"`
+	Partitions        []PartitionStatus `json:"partitions"`
+	// Retention is the topic's retention.ms; negative for unlimited and
+	// zero when the broker doesn't expose it
+	Retention time.Duration `json:"-"`
+}
+
+// PartitionStatus holds the offsets of a partition. LatestTime is the
+// timestamp of the last message and is zero for an empty partition.
+type PartitionStatus struct {
+	Partition     int       `json:"partition"`
+	FirstOffset   int64     `json:"first_offset"`
+	HighWatermark int64     `json:"high_watermark"`
+	LatestTime    time.Time `json:"latest_timestamp,omitempty"`
+}
+
+// GroupStatus holds the committed offsets and lag of a consumer group on
+// one topic
+type GroupStatus struct {
+	Group      string         `json:"group"`
+	State      string         `json:"state"`
+	Members    int            `json:"members"`
+	Partitions []PartitionLag `json:"partitions"`
+	Lag        int64          `json:"lag"`
+}
+
+// PartitionLag is a group's position on a partition. Committed is -1 when
+// the group has not committed an offset for the partition, in which case
+// the whole retained partition counts as lag.
+type PartitionLag struct {
+	Partition int   `json:"partition"`
+	Committed int64 `json:"committed"`
+	Lag       int64 `json:"lag"`
+}
+
+// Messages returns the number of retained messages
+func (t *TopicStatus) Messages() int64 {
+	var n int64
+	for _, p := range t.Partitions {
+		n += p.HighWatermark - p.FirstOffset
+	}
+	return n
+}
+
+// LatestTime returns the newest message timestamp across partitions
+func (t *TopicStatus) LatestTime() time.Time {
+	var latest time.Time
+	for _, p := range t.Partitions {
+		if p.LatestTime.After(latest) {
+			latest = p.LatestTime
+		}
+	}
+	return latest
+}
+
+func (c *Client) transport() *kafka.Transport {
+	return &kafka.Transport{
+		SASL: c.dialer.SASLMechanism,
+		TLS:  c.dialer.TLS,
+	}
+}
+
+func (c *Client) adminClient() *kafka.Client {
+	return &kafka.Client{
+		Addr:      kafka.TCP(c.brokers...),
+		Timeout:   10 * time.Second,
+		Transport: c.transport(),
+	}
+}
+
+// TopicStatus reads the partitions, offsets, retention and the timestamp
+// of the last message of each partition
+func (c *Client) TopicStatus(ctx context.Context, topic string) (*TopicStatus, error) {
+	client := c.adminClient()
+
+	// Partitions and replicas
+	metadata, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata of %s: %w", topic, err)
+	}
+	if len(metadata.Topics) == 0 {
+		return nil, fmt.Errorf("topic %s not found", topic)
+	}
+	if err := metadata.Topics[0].Error; err != nil {
+		return nil, fmt.Errorf("failed to read metadata of %s: %w", topic, err)
+	}
+
+	status := &TopicStatus{Topic: topic}
+	var requests []kafka.OffsetRequest
+	for _, p := range metadata.Topics[0].Partitions {
+		if len(p.Replicas) > status.ReplicationFactor {
+			status.ReplicationFactor = len(p.Replicas)
+		}
+		requests = append(requests, kafka.FirstOffsetOf(p.ID), kafka.LastOffsetOf(p.ID))
+	}
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("topic %s has no partitions", topic)
+	}
+
+	// Offsets
+	offsets, err := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{topic: requests},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list offsets of %s: %w", topic, err)
+	}
+	for _, p := range offsets.Topics[topic] {
+		if p.Error != nil {
+			return nil, fmt.Errorf("failed to list offsets of %s partition %d: %w", topic, p.Partition, p.Error)
+		}
+		status.Partitions = append(status.Partitions, PartitionStatus{
+			Partition:     p.Partition,
+			FirstOffset:   p.FirstOffset,
+			HighWatermark: p.LastOffset,
+		})
+	}
+	sort.Slice(status.Partitions, func(i, j int) bool {
+		return status.Partitions[i].Partition < status.Partitions[j].Partition
+	})
+
+	// Timestamp of the last message of each partition
+	for i := range status.Partitions {
+		p := &status.Partitions[i]
+		if p.HighWatermark <= p.FirstOffset {
+			continue
+		}
+		if p.LatestTime, err = c.messageTime(ctx, client, topic, p.Partition, p.HighWatermark-1); err != nil {
+			return nil, err
+		}
+	}
+
+	// Retention; brokers may refuse DescribeConfigs to clients without
+	// the permission, which only hides the retention
+	configs, err := client.DescribeConfigs(ctx, &kafka.DescribeConfigsRequest{
+		Resources: []kafka.DescribeConfigRequestResource{{
+			ResourceType: kafka.ResourceTypeTopic,
+			ResourceName: topic,
+			ConfigNames:  []string{"retention.ms"},
+		}},
+	})
+	if err != nil {
+		c.log.Debugf("Failed to describe configs of %s: %v", topic, err)
+		return status, nil
+	}
+	for _, resource := range configs.Resources {
+		if resource.Error != nil {
+			c.log.Debugf("Failed to describe configs of %s: %v", topic, resource.Error)
+			continue
+		}
+		for _, entry := range resource.ConfigEntries {
+			if entry.ConfigName != "retention.ms" {
+				continue
+			}
+			ms, err := strconv.ParseInt(entry.ConfigValue, 10, 64)
+			switch {
+			case err != nil:
+			case ms < 0:
+				status.Retention = -1
+			default:
+				status.Retention = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+
+	return status, nil
+}
+
+// messageTime returns the timestamp of the message at an offset
+func (c *Client) messageTime(ctx context.Context, client *kafka.Client, topic string, partition int, offset int64) (time.Time, error) {
+	res, err := client.Fetch(ctx, &kafka.FetchRequest{
+		Topic:     topic,
+		Partition: partition,
+		Offset:    offset,
+		MinBytes:  1,
+		MaxBytes:  1 << 20,
+		MaxWait:   time.Second,
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to fetch %s partition %d: %w", topic, partition, err)
+	}
+	if res.Error != nil {
+		return time.Time{}, fmt.Errorf("failed to fetch %s partition %d: %w", topic, partition, res.Error)
+	}
+	if res.Records == nil {
+		return time.Time{}, nil
+	}
+
+	// Batches may start before the requested offset; keep the last record
+	// up to it
+	var latest time.Time
+	for {
+		record, err := res.Records.ReadRecord()
+		if errors.Is(err, io.EOF) {
+			return latest, nil
+		}
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to read %s partition %d: %w", topic, partition, err)
+		}
+		if record.Offset <= offset {
+			latest = record.Time
+		}
+	}
+}
+
+// GroupStatus reads the committed offsets of a consumer group on a topic
+// and computes the lag against the topic's high-water marks
+func (c *Client) GroupStatus(ctx context.Context, group string, topic *TopicStatus) (*GroupStatus, error) {
+	client := c.adminClient()
+
+	partitions := make([]int, len(topic.Partitions))
+	for i, p := range topic.Partitions {
+		partitions[i] = p.Partition
+	}
+
+	fetched, err := client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{
+		GroupID: group,
+		Topics:  map[string][]int{topic.Topic: partitions},
+	})
+	if err == nil {
+		err = fetched.Error
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch offsets of group %s: %w", group, err)
+	}
+
+	committed := make(map[int]int64)
+	for _, p := range fetched.Topics[topic.Topic] {
+		if p.Error != nil {
+			return nil, fmt.Errorf("failed to fetch offsets of group %s partition %d: %w", group, p.Partition, p.Error)
+		}
+		committed[p.Partition] = p.CommittedOffset
+	}
+
+	status := &GroupStatus{Group: group}
+	for _, p := range topic.Partitions {
+		offset, ok := committed[p.Partition]
+		if !ok {
+			offset = -1
+		}
+		lag := p.HighWatermark - p.FirstOffset
+		if offset >= 0 {
+			lag = p.HighWatermark - offset
+			if lag < 0 {
+				lag = 0
+			}
+		}
+		status.Partitions = append(status.Partitions, PartitionLag{Partition: p.Partition, Committed: offset, Lag: lag})
+		status.Lag += lag
+	}
+
+	// Group state is informational
+	described, err := client.DescribeGroups(ctx, &kafka.DescribeGroupsRequest{GroupIDs: []string{group}})
+	if err != nil {
+		c.log.Debugf("Failed to describe group %s: %v", group, err)
+		return status, nil
+	}
+	for _, g := range described.Groups {
+		if g.Error == nil && g.GroupID == group {
+			status.State = g.GroupState
+			status.Members = len(g.Members)
+		}
+	}
+
+	return status, nil
+}
@@ -0,0 +1,458 @@
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/duckdb"
+	"github.com/mattn/go-runewidth"
+	"github.com/muesli/reflow/ansi"
+)
+
+// Kinds of completions
+const (
+	completeColumn   = "column"
+	completeTable    = "table"
+	completeFunction = "function"
+	completeKeyword  = "keyword"
+)
+
+// maxCompletions limits the completions offered for a word
+const maxCompletions = 100
+
+// completion is a name offered while writing SQL
+type completion struct {
+	// text is inserted, quoted if need be; label is shown
+	text  string
+	label string
+	kind  string
+}
+
+// tableKeywords are followed by table names
+var tableKeywords = map[string]bool{
+	"FROM": true, "JOIN": true, "INTO": true, "UPDATE": true,
+	"TABLE": true, "DESCRIBE": true, "SUMMARIZE": true,
+}
+
+// clauseKeywords start the parts of a statement; the last one before a
+// word decides whether it is a table or a column
+var clauseKeywords = map[string]bool{
+	"SELECT": true, "WHERE": true, "GROUP": true, "ORDER": true, "HAVING": true,
+	"QUALIFY": true, "ON": true, "USING": true, "SET": true, "VALUES": true,
+	"LIMIT": true, "WINDOW": true, "RETURNING": true,
+}
+
+// completer offers the names of tables, their columns, functions and
+// keywords, by where in a statement they are written
+type completer struct {
+	tables    []duckdb.Table
+	functions []string
+}
+
+// SetTable adds a table or replaces the one of the same name
+func (c *completer) SetTable(t duckdb.Table) {
+	for i := range c.tables {
+		if strings.EqualFold(c.tables[i].Schema, t.Schema) && strings.EqualFold(c.tables[i].Name, t.Name) {
+			c.tables[i] = t
+			return
+		}
+	}
+	c.tables = append(c.tables, t)
+}
+
+// RemoveTable stops offering a table, such as an unregistered product
+func (c *completer) RemoveTable(name string) {
+	for i, t := range c.tables {
+		if strings.EqualFold(tableName(t), name) || strings.EqualFold(t.Schema+"."+t.Name, name) {
+			c.tables = append(c.tables[:i], c.tables[i+1:]...)
+			return
+		}
+	}
+}
+
+// SetFunctions sets the function names offered
+func (c *completer) SetFunctions(names []string) {
+	c.functions = names
+}
+
+// tableName is how a table is named in queries: registered products are
+// domain.product and tables of the main schema go unqualified
+func tableName(t duckdb.Table) string {
+	if t.Schema == "" || t.Schema == "main" {
+		return t.Name
+	}
+	return t.Schema + "." + t.Name
+}
+
+// Complete returns the completions of the word before offset in src and
+// where the text they replace starts
+func (c *completer) Complete(src []rune, offset int) (int, []completion) {
+	start := offset
+	for start > 0 && (isWordRune(src[start-1]) || src[start-1] == '.') {
+		start--
+	}
+	word := string(src[start:offset])
+	qualifier, prefix := "", word
+	if i := strings.LastIndexByte(word, '.'); i >= 0 {
+		qualifier, prefix = word[:i], word[i+1:]
+		start += len([]rune(qualifier)) + 1
+	}
+
+	// The statement the word is in; there is nothing to complete in
+	// strings and comments
+	from, to := 0, len(src)
+	for _, t := range lexSQL(src) {
+		if (t.kind == tokenString || t.kind == tokenComment) && t.start < start && start < t.end {
+			return start, nil
+		}
+		if t.kind != tokenSemicolon {
+			continue
+		}
+		if t.end <= start {
+			from = t.end
+		} else if t.start >= offset {
+			to = t.start
+			break
+		}
+	}
+	words := sqlWords(src[from:to])
+	refs := tableRefs(words)
+
+	var items []completion
+	add := func(kind string, names ...string) {
+		for _, name := range names {
+			if len(items) >= maxCompletions {
+				return
+			}
+			if !strings.HasPrefix(strings.ToLower(name), strings.ToLower(prefix)) || strings.EqualFold(name, prefix) {
+				continue
+			}
+			text := name
+			switch kind {
+			case completeColumn:
+				text = quoteName(name)
+			case completeTable:
+				parts := strings.Split(name, ".")
+				for i, part := range parts {
+					parts[i] = quoteName(part)
+				}
+				text = strings.Join(parts, ".")
+			case completeKeyword:
+				if strings.ToLower(prefix) == prefix && prefix != "" {
+					text = strings.ToLower(name)
+				}
+			}
+			items = append(items, completion{text: text, label: name, kind: kind})
+		}
+	}
+
+	if qualifier != "" {
+		// alias.column, table.column or schema.table
+		for _, t := range c.refTables(refs, qualifier) {
+			add(completeColumn, t.Columns...)
+		}
+		for _, t := range c.tables {
+			if strings.EqualFold(t.Schema, qualifier) {
+				add(completeTable, t.Name)
+			}
+		}
+		return start, dedupe(items)
+	}
+
+	if clause := lastClause(words, start-from); tableKeywords[clause] {
+		for _, t := range c.tables {
+			add(completeTable, tableName(t))
+		}
+		return start, dedupe(items)
+	}
+
+	// Columns of the tables the statement reads, or of all of them
+	// before it names any
+	tables := c.tables
+	if len(refs) > 0 {
+		tables = nil
+		for _, ref := range refs {
+			if t, ok := c.lookup(ref.table); ok {
+				tables = append(tables, t)
+			}
+		}
+	}
+	for _, t := range tables {
+		add(completeColumn, t.Columns...)
+	}
+	for _, t := range c.tables {
+		add(completeTable, tableName(t))
+	}
+	add(completeFunction, c.functions...)
+	keywords := make([]string, 0, len(sqlKeywords))
+	for k := range sqlKeywords {
+		keywords = append(keywords, k)
+	}
+	sort.Strings(keywords)
+	add(completeKeyword, keywords...)
+	return start, dedupe(items)
+}
+
+// lookup returns the table of a name as a query writes it
+func (c *completer) lookup(name string) (duckdb.Table, bool) {
+	for _, t := range c.tables {
+		if strings.EqualFold(tableName(t), name) || strings.EqualFold(t.Schema+"."+t.Name, name) {
+			return t, true
+		}
+	}
+	return duckdb.Table{}, false
+}
+
+// refTables returns the tables a statement reads as name, which is an
+// alias or the name of the table, or otherwise the table of that name
+func (c *completer) refTables(refs []tableRef, name string) []duckdb.Table {
+	var tables []duckdb.Table
+	for _, ref := range refs {
+		unqualified := ref.table[strings.LastIndexByte(ref.table, '.')+1:]
+		if strings.EqualFold(ref.alias, name) ||
+			ref.alias == "" && (strings.EqualFold(ref.table, name) || strings.EqualFold(unqualified, name)) {
+			if t, ok := c.lookup(ref.table); ok {
+				tables = append(tables, t)
+			}
+		}
+	}
+	if len(tables) == 0 {
+		if t, ok := c.lookup(name); ok {
+			tables = append(tables, t)
+		}
+	}
+	return tables
+}
+
+func dedupe(items []completion) []completion {
+	seen := make(map[string]bool, len(items))
+	out := items[:0]
+	for _, item := range items {
+		if !seen[item.text] {
+			seen[item.text] = true
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+var plainName = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// quoteName quotes a name unless it can be written as it is
+func quoteName(name string) string {
+	if plainName.MatchString(name) && !sqlKeywords[strings.ToUpper(name)] {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// sqlWord is a name, keyword or punctuation of a statement, at an offset
+type sqlWord struct {
+	text   string
+	offset int
+	punct  bool
+}
+
+// sqlWords splits a statement into names, with quoted ones unquoted and
+// qualified ones whole, and the punctuation between them; strings and
+// comments are left out
+func sqlWords(src []rune) []sqlWord {
+	var words []sqlWord
+	skip := make(map[int]int)
+	for _, t := range lexSQL(src) {
+		if t.kind == tokenString || t.kind == tokenComment {
+			skip[t.start] = t.end
+		}
+	}
+	for i := 0; i < len(src); {
+		if end, ok := skip[i]; ok {
+			i = end
+			continue
+		}
+		r := src[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case isWordRune(r) || r == '"':
+			start := i
+			var name strings.Builder
+			for i < len(src) {
+				if src[i] == '"' {
+					// Quoted part, where "" is a quote
+					for i++; i < len(src); i++ {
+						if src[i] == '"' {
+							if i+1 < len(src) && src[i+1] == '"' {
+								i++
+							} else {
+								break
+							}
+						}
+						name.WriteRune(src[i])
+					}
+					i++
+				} else if isWordRune(src[i]) || src[i] == '.' {
+					name.WriteRune(src[i])
+					i++
+				} else {
+					break
+				}
+			}
+			words = append(words, sqlWord{text: name.String(), offset: start})
+		default:
+			words = append(words, sqlWord{text: string(r), offset: i, punct: true})
+			i++
+		}
+	}
+	return words
+}
+
+// tableRef is a table a statement reads and its alias
+type tableRef struct {
+	table string
+	alias string
+}
+
+// tableRefs finds the tables after FROM and JOIN, with their aliases
+func tableRefs(words []sqlWord) []tableRef {
+	var refs []tableRef
+	for i := 0; i < len(words); i++ {
+		keyword := strings.ToUpper(words[i].text)
+		if words[i].punct || keyword != "FROM" && keyword != "JOIN" {
+			continue
+		}
+		for i+1 < len(words) && !words[i+1].punct && !sqlKeywords[strings.ToUpper(words[i+1].text)] {
+			i++
+			ref := tableRef{table: words[i].text}
+			if i+1 < len(words) && strings.EqualFold(words[i+1].text, "AS") {
+				i++
+			}
+			if i+1 < len(words) && !words[i+1].punct && !sqlKeywords[strings.ToUpper(words[i+1].text)] {
+				i++
+				ref.alias = words[i].text
+			}
+			refs = append(refs, ref)
+
+			// FROM a, b
+			if keyword != "FROM" || i+1 >= len(words) || words[i+1].text != "," {
+				break
+			}
+			i++
+		}
+	}
+	return refs
+}
+
+// lastClause returns the last table or clause keyword before an offset
+func lastClause(words []sqlWord, offset int) string {
+	clause := ""
+	for _, w := range words {
+		if w.offset >= offset {
+			break
+		}
+		if k := strings.ToUpper(w.text); !w.punct && (tableKeywords[k] || clauseKeywords[k]) {
+			clause = k
+		}
+	}
+	return clause
+}
+
+// maxCompletionRows is the height of the completion popup
+const maxCompletionRows = 8
+
+// completionPopup renders completions as a list of names and kinds, with
+// the selected one highlighted
+func completionPopup(items []completion, selected int) []string {
+	first := 0
+	if selected >= maxCompletionRows {
+		first = selected - maxCompletionRows + 1
+	}
+	width := 0
+	for _, item := range items {
+		if n := runewidth.StringWidth(item.label); n > width {
+			width = n
+		}
+	}
+	if width > 40 {
+		width = 40
+	}
+
+	var lines []string
+	for i := first; i < len(items) && i < first+maxCompletionRows; i++ {
+		line := fmt.Sprintf(" %s  %-8s ", pad(items[i].label, width, false), items[i].kind)
+		if i == selected {
+			lines = append(lines, selectedItemStyle.Render(line))
+		} else {
+			lines = append(lines, completionStyle.Render(line))
+		}
+	}
+	return lines
+}
+
+// overlay draws lines over a rendered view, from a row and column, keeping
+// what is left and right of them
+func overlay(view string, lines []string, row, col int) string {
+	rows := strings.Split(view, "\n")
+	for i, line := range lines {
+		r := row + i
+		if r >= len(rows) {
+			break
+		}
+		left := cutRight(rows[r], col)
+		if n := ansi.PrintableRuneWidth(left); n < col {
+			left += strings.Repeat(" ", col-n)
+		}
+		right := cutLeft(rows[r], col+ansi.PrintableRuneWidth(line))
+		rows[r] = left + "\x1b[0m" + line + right
+	}
+	return strings.Join(rows, "\n")
+}
+
+// cutRight keeps the first cells of text with escape sequences
+func cutRight(s string, cells int) string {
+	var b strings.Builder
+	width, escape := 0, false
+	for _, r := range s {
+		switch {
+		case r == '\x1b':
+			escape = true
+			b.WriteRune(r)
+		case escape:
+			b.WriteRune(r)
+			escape = !ansi.IsTerminator(r)
+		default:
+			if width += runewidth.RuneWidth(r); width > cells {
+				return b.String()
+			}
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// cutLeft removes the first cells of text with escape sequences, keeping
+// the sequences so that the rest looks as it did
+func cutLeft(s string, cells int) string {
+	var b strings.Builder
+	width, escape := 0, false
+	for _, r := range s {
+		switch {
+		case r == '\x1b':
+			escape = true
+			b.WriteRune(r)
+		case escape:
+			b.WriteRune(r)
+			escape = !ansi.IsTerminator(r)
+		case width >= cells:
+			b.WriteRune(r)
+		default:
+			// A wide character cut in half leaves a space
+			if width += runewidth.RuneWidth(r); width > cells {
+				b.WriteRune(' ')
+			}
+		}
+	}
+	return b.String()
+}
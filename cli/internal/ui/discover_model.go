@@ -1,30 +1,85 @@
 package ui
 
 import (
+	"bytes"
 	"fmt"
+	"sort"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/frocore/fedramp-data-mesh/cli/internal/catalog"
 	"github.com/frocore/fedramp-data-mesh/cli/internal/config"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/duckdb"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/lakehouse"
 	"github.com/frocore/fedramp-data-mesh/cli/internal/logging"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/manifest"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/marking"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/profile"
 	"github.com/frocore/fedramp-data-mesh/cli/internal/security"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/sla"
+	"github.com/muesli/reflow/wordwrap"
 )
 
 // Item represents a data product in the list
 type Item struct {
 	name  string
 	desc  string
+	// columns are the columns matched by the search
+	columns []string
 }
 
 func (i Item) Title() string       { return i.name }
-func (i Item) Description() string { return i.desc }
 func (i Item) FilterValue() string { return i.name }
 
+func (i Item) Description() string {
+	if len(i.columns) == 0 {
+		return i.desc
+	}
+	return i.desc + " · columns: " + strings.Join(i.columns, ", ")
+}
+
+// Tabs of the details view
+const (
+	overviewTab = iota
+	schemaTab
+	sampleTab
+	profileTab
+	lineageTab
+	slaTab
+)
+
+var detailTabs = []string{"Overview", "Schema", "Sample", "Profile", "Lineage", "SLA"}
+
+// loadingText is shown while a tab loads
+var loadingText = map[int]string{
+	schemaTab:  "Loading schema...",
+	sampleTab:  "Reading sample rows...",
+	profileTab: "Profiling a sample of " + profileSample + "...",
+	lineageTab: "Reading manifests...",
+	slaTab:     "Checking freshness...",
+}
+
+// profileSample keeps profiling in the TUI quick on large products
+const profileSample = "10000 rows"
+
+// sampleRows is the number of rows of the sample tab
+const sampleRows = 20
+
+// tabContent is the content of a tab, loaded when the tab is first shown
+type tabContent struct {
+	loading bool
+	loaded  bool
+	text    string
+	err     error
+}
+
 // Model represents the UI state
 type DiscoverModel struct {
 	cfg           *config.Config
@@ -32,10 +87,27 @@ type DiscoverModel struct {
 	log           *logging.Logger
 	list          list.Model
 	domainFilter  string
+	values        ValueFormat
+	
+	// products are all products of the catalog, of which the list shows
+	// those passing the filter
+	products      []*catalog.DataProduct
+	filter        productFilter
+	search        textinput.Model
+	searching     bool
+	facets        facetMenu
+	choosingFacets bool
+	
 	selectedProduct *catalog.DataProduct
 	detailViewport viewport.Model
 	showingDetails bool
-	err           error
+	detailTab     int
+	tabs          []tabContent
+	
+	// queryProduct is the product to open in the query TUI on exit
+	queryProduct  string
+	// startup lists the products of the catalog, shown until it is done
+	startup       startup
 	width         int
 	height        int
 }
@@ -45,12 +117,25 @@ func NewDiscoverModel(cfg *config.Config, secCtx *security.SecurityContext, log
 	l := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
 	l.Title = "Available Data Products"
 	l.SetShowHelp(true)
+	// Searching and facets replace the list's filtering of names
+	l.SetFilteringEnabled(false)
+	l.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{
+			key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "search")),
+			key.NewBinding(key.WithKeys("f"), key.WithHelp("f", "filter")),
+			key.NewBinding(key.WithKeys("Q"), key.WithHelp("Q", "query")),
+		}
+	}
 	
 	// Create viewport for details
 	vp := viewport.New(80, 20)
 	vp.Style = lipgloss.NewStyle().
 		BorderStyle(lipgloss.RoundedBorder()).
 		BorderForeground(lipgloss.Color("#0077B6"))
+		
+	search := textinput.New()
+	search.Prompt = "Search: "
+	search.Placeholder = "names, descriptions, owners, tags and columns"
 	
 	return &DiscoverModel{
 		cfg:           cfg,
@@ -58,16 +143,31 @@ func NewDiscoverModel(cfg *config.Config, secCtx *security.SecurityContext, log
 		log:           log,
 		list:          l,
 		domainFilter:  domainFilter,
+		values:        DefaultValueFormat(),
+		filter:        newProductFilter(),
+		search:        search,
 		detailViewport: vp,
 		showingDetails: false,
+		startup:       newStartup("Listing data products"),
 		width:         80,
 		height:        24,
 	}
 }
 
+// SetValueFormat sets how values of sample rows are rendered
+func (m *DiscoverModel) SetValueFormat(vf ValueFormat) {
+	m.values = vf
+}
+
+// QueryProduct returns the data product chosen to be queried when the
+// TUI quit, or an empty string
+func (m *DiscoverModel) QueryProduct() string {
+	return m.queryProduct
+}
+
 // Init implements bubbletea.Model
 func (m *DiscoverModel) Init() tea.Cmd {
-	return m.loadDataProducts
+	return tea.Batch(m.startup.Start(0), m.loadDataProducts)
 }
 
 // Update implements bubbletea.Model
@@ -78,117 +178,559 @@ func (m *DiscoverModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
+		m.layout()
 		
-		if m.showingDetails {
-			m.detailViewport.Width = msg.Width
-			m.detailViewport.Height = msg.Height
-			vp, cmd := m.detailViewport.Update(msg)
-			m.detailViewport = vp
-			cmds = append(cmds, cmd)
-		} else {
-			m.list.SetSize(msg.Width, msg.Height)
-			l, cmd := m.list.Update(msg)
-			m.list = l
-			cmds = append(cmds, cmd)
+	case tea.KeyMsg:
+		switch {
+		case !m.startup.Done():
+			return m.updateStartup(msg)
+		case m.showingDetails:
+			return m.updateDetails(msg)
+		case m.searching:
+			return m.updateSearch(msg)
+		case m.choosingFacets:
+			return m.updateFacets(msg)
 		}
 		
-	case tea.KeyMsg:
-		if m.showingDetails {
-			switch {
-			case key.Matches(msg, key.NewBinding(key.WithKeys("esc", "backspace"))):
-				m.showingDetails = false
-				m.selectedProduct = nil
+		switch msg.String() {
+		case "enter":
+			if product := m.selected(); product != nil {
+				m.showDetails(product)
 				return m, nil
-			default:
-				vp, cmd := m.detailViewport.Update(msg)
-				m.detailViewport = vp
-				cmds = append(cmds, cmd)
 			}
-		} else {
-			switch {
-			case key.Matches(msg, key.NewBinding(key.WithKeys("enter"))):
-				if i, ok := m.list.SelectedItem().(Item); ok {
-					return m, m.loadDataProductDetails(i.name)
-				}
-			default:
-				l, cmd := m.list.Update(msg)
-				m.list = l
-				cmds = append(cmds, cmd)
+		case "/":
+			m.searching = true
+			m.search.SetValue(m.filter.query)
+			m.search.CursorEnd()
+			m.layout()
+			return m, m.search.Focus()
+		case "f":
+			m.choosingFacets = true
+			m.facets.Update(m.products, &m.filter)
+			return m, nil
+		case "F":
+			m.filter.ClearFacets()
+			m.filter.SetSearch("")
+			m.applyFilter()
+			return m, nil
+		case "Q":
+			if product := m.selected(); product != nil {
+				m.queryProduct = product.Name
+				return m, tea.Quit
 			}
+		default:
+			l, cmd := m.list.Update(msg)
+			m.list = l
+			cmds = append(cmds, cmd)
 		}
 		
 	case dataProductsLoadedMsg:
-		items := make([]list.Item, len(msg.products))
-		for i, product := range msg.products {
-			items[i] = Item{name: product, desc: fmt.Sprintf("Data product: %s", product)}
-		}
-		m.list.SetItems(items)
-		l, cmd := m.list.Update(nil)
-		m.list = l
-		cmds = append(cmds, cmd)
-		
-	case dataProductDetailsLoadedMsg:
+		m.startup.Finish(0, msg.err)
 		if msg.err != nil {
-			m.err = msg.err
 			return m, nil
 		}
-		m.selectedProduct = msg.product
-		m.showingDetails = true
-		m.detailViewport.SetContent(m.formatProductDetails())
-		vp, cmd := m.detailViewport.Update(nil)
-		m.detailViewport = vp
-		cmds = append(cmds, cmd)
+		m.products = msg.products
+		m.applyFilter()
+	
+	case spinner.TickMsg:
+		return m, m.startup.Update(msg)
+		
+	case tabLoadedMsg:
+		// Ignore tabs of a product that is no longer shown
+		if m.selectedProduct == nil || msg.name != m.selectedProduct.Name {
+			return m, nil
+		}
+		m.tabs[msg.tab] = tabContent{loaded: true, text: msg.text, err: msg.err}
+		if msg.tab == m.detailTab {
+			m.detailViewport.SetContent(m.detailContent())
+		}
 		
-	case errMsg:
-		m.err = msg.err
-		return m, nil
 	}
 	
 	return m, tea.Batch(cmds...)
 }
 
+// updateStartup handles keys while the products are listed: r lists them
+// again when that failed
+func (m *DiscoverModel) updateStartup(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "esc", "q":
+		return m, tea.Quit
+	case "r":
+		if !m.startup.Running() {
+			return m, tea.Batch(m.startup.Start(0), m.loadDataProducts)
+		}
+	}
+	return m, nil
+}
+
+// updateDetails handles keys of the details view
+func (m *DiscoverModel) updateDetails(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch s := msg.String(); s {
+	case "esc", "backspace":
+		m.showingDetails = false
+		m.selectedProduct = nil
+		m.tabs = nil
+		return m, nil
+	case "tab":
+		return m, m.showTab((m.detailTab + 1) % len(detailTabs))
+	case "shift+tab":
+		return m, m.showTab((m.detailTab + len(detailTabs) - 1) % len(detailTabs))
+	case "1", "2", "3", "4", "5", "6":
+		return m, m.showTab(int(s[0] - '1'))
+	case "r":
+		// Load the tab again: profile again, check freshness again
+		if m.detailTab != overviewTab && !m.tabs[m.detailTab].loading {
+			m.tabs[m.detailTab] = tabContent{}
+			return m, m.showTab(m.detailTab)
+		}
+		return m, nil
+	case "Q":
+		m.queryProduct = m.selectedProduct.Name
+		return m, tea.Quit
+	}
+	
+	vp, cmd := m.detailViewport.Update(msg)
+	m.detailViewport = vp
+	return m, cmd
+}
+
+// updateSearch handles keys while the search is edited; the list follows
+// the search as it is typed
+func (m *DiscoverModel) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		m.searching = false
+		m.search.Blur()
+		m.layout()
+		return m, nil
+	case "esc":
+		m.searching = false
+		m.search.Blur()
+		m.filter.SetSearch("")
+		m.applyFilter()
+		return m, nil
+	}
+	
+	var cmd tea.Cmd
+	m.search, cmd = m.search.Update(msg)
+	m.filter.SetSearch(m.search.Value())
+	m.applyFilter()
+	return m, cmd
+}
+
+// updateFacets handles keys of the facet menu
+func (m *DiscoverModel) updateFacets(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		m.facets.Move(-1)
+	case "down", "j":
+		m.facets.Move(1)
+	case "pgup":
+		m.facets.Move(-m.facets.visibleRows())
+	case "pgdown":
+		m.facets.Move(m.facets.visibleRows())
+	case " ", "enter":
+		if row, ok := m.facets.Selected(); ok {
+			m.filter.Toggle(row.facet, row.value)
+			m.applyFilter()
+			m.facets.Update(m.products, &m.filter)
+		}
+	case "F":
+		m.filter.ClearFacets()
+		m.applyFilter()
+		m.facets.Update(m.products, &m.filter)
+	case "esc", "f", "q":
+		m.choosingFacets = false
+		m.layout()
+	}
+	return m, nil
+}
+
+// selected returns the product under the cursor of the list
+func (m *DiscoverModel) selected() *catalog.DataProduct {
+	i, ok := m.list.SelectedItem().(Item)
+	if !ok {
+		return nil
+	}
+	for _, p := range m.products {
+		if p.Name == i.name {
+			return p
+		}
+	}
+	return nil
+}
+
+// applyFilter lists the products passing the search and facets
+func (m *DiscoverModel) applyFilter() {
+	var items []list.Item
+	for _, p := range m.products {
+		if ok, columns := m.filter.Match(p); ok {
+			items = append(items, Item{name: p.Name, desc: productSummary(p), columns: columns})
+		}
+	}
+	m.list.SetItems(items)
+	m.list.ResetSelected()
+	m.layout()
+}
+
+// productSummary describes a product in the list: its description,
+// owner and classification
+func productSummary(p *catalog.DataProduct) string {
+	parts := []string{p.Description}
+	if p.Description == "" {
+		parts[0] = "No description"
+	}
+	if p.Owner != "" {
+		parts = append(parts, p.Owner)
+	}
+	if p.Classification != "" {
+		parts = append(parts, p.Classification)
+	}
+	return strings.Join(parts, " · ")
+}
+
+// layout sizes the list below the search and filter lines, and the
+// details below their title and tabs
+func (m *DiscoverModel) layout() {
+	height := m.height
+	if m.searching {
+		height--
+	}
+	if m.filter.Active() {
+		height--
+	}
+	m.list.SetSize(m.width, height)
+	m.facets.height = m.height
+	m.facets.Move(0)
+	m.search.Width = m.width - len(m.search.Prompt) - 1
+	
+	// Title and tabs above, key hints below
+	m.detailViewport.Width = m.width
+	m.detailViewport.Height = m.height - 4
+}
+
+// showDetails shows the details of a product, starting at its overview
+func (m *DiscoverModel) showDetails(product *catalog.DataProduct) {
+	m.selectedProduct = product
+	m.showingDetails = true
+	m.tabs = make([]tabContent, len(detailTabs))
+	m.detailTab = overviewTab
+	m.detailViewport.SetContent(m.detailContent())
+	m.detailViewport.GotoTop()
+}
+
+// showTab switches to a tab, loading it when first shown
+func (m *DiscoverModel) showTab(tab int) tea.Cmd {
+	m.detailTab = tab
+	var cmd tea.Cmd
+	if tab != overviewTab && !m.tabs[tab].loaded && !m.tabs[tab].loading {
+		m.tabs[tab].loading = true
+		cmd = m.loadTab(m.selectedProduct, tab)
+	}
+	m.detailViewport.SetContent(m.detailContent())
+	m.detailViewport.GotoTop()
+	return cmd
+}
+
 // View implements bubbletea.Model
 func (m *DiscoverModel) View() string {
-	if m.err != nil {
-		return fmt.Sprintf("Error: %v\n\nPress any key to exit", m.err)
+	if !m.startup.Done() {
+		return titleStyle.Render("Available Data Products") + "\n\n" +
+			m.startup.View(m.width, "Press r to retry, q to quit")
 	}
 	
 	if m.showingDetails && m.selectedProduct != nil {
-		return fmt.Sprintf("Data Product Details: %s\n\n%s\n\nPress ESC to go back", 
-			m.selectedProduct.Name, m.detailViewport.View())
+		return fmt.Sprintf("Data Product Details: %s\n%s\n%s\n%s",
+			m.selectedProduct.Name, m.tabBar(), m.detailViewport.View(),
+			panelTypeStyle.Render("Tab or 1-6 switch view, r reload, Q query, Esc back"))
+	}
+	
+	if m.choosingFacets {
+		return m.facets.View(&m.filter, m.width)
 	}
 	
-	return m.list.View()
+	var lines []string
+	if m.searching {
+		lines = append(lines, m.search.View())
+	}
+	if m.filter.Active() {
+		lines = append(lines, panelTypeStyle.Render(truncate(fmt.Sprintf("Showing %d of %d: %s (F clears)",
+			len(m.list.Items()), len(m.products), m.filter.String()), m.width)))
+	}
+	return strings.Join(append(lines, m.list.View()), "\n")
 }
 
 func (m *DiscoverModel) loadDataProducts() tea.Msg {
 	catalogClient, err := catalog.NewClient(m.cfg, m.secCtx, m.log)
 	if err != nil {
-		return errMsg{err: fmt.Errorf("failed to create catalog client: %w", err)}
+		return dataProductsLoadedMsg{err: fmt.Errorf("failed to create catalog client: %w", err)}
 	}
 	
-	products, err := catalogClient.ListDataProducts(m.domainFilter)
+	products, err := catalogClient.ListDataProductDetails(m.domainFilter)
 	if err != nil {
-		return errMsg{err: fmt.Errorf("failed to list data products: %w", err)}
+		return dataProductsLoadedMsg{err: err}
 	}
 	
+	sort.Slice(products, func(i, j int) bool { return products[i].Name < products[j].Name })
 	return dataProductsLoadedMsg{products: products}
 }
 
-func (m *DiscoverModel) loadDataProductDetails(name string) tea.Cmd {
+// loadTab loads the content of a tab in the background
+func (m *DiscoverModel) loadTab(product *catalog.DataProduct, tab int) tea.Cmd {
+	name := product.Name
+	width := m.detailViewport.Width - m.detailViewport.Style.GetHorizontalFrameSize()
 	return func() tea.Msg {
-		catalogClient, err := catalog.NewClient(m.cfg, m.secCtx, m.log)
-		if err != nil {
-			return errMsg{err: fmt.Errorf("failed to create catalog client: %w", err)}
+		var text string
+		var err error
+		switch tab {
+		case schemaTab:
+			text, err = m.loadSchema(name)
+		case sampleTab:
+			text, err = m.loadSample(product, width)
+		case profileTab:
+			text, err = m.loadProfile(name)
+		case lineageTab:
+			text, err = m.loadLineage(name)
+		case slaTab:
+			text, err = m.loadSLA(name)
 		}
-		
-		product, err := catalogClient.GetDataProduct(name)
-		if err != nil {
-			return dataProductDetailsLoadedMsg{err: err}
+		return tabLoadedMsg{name: name, tab: tab, text: text, err: err}
+	}
+}
+
+func (m *DiscoverModel) loadSchema(name string) (string, error) {
+	catalogClient, err := catalog.NewClient(m.cfg, m.secCtx, m.log)
+	if err != nil {
+		return "", fmt.Errorf("failed to create catalog client: %w", err)
+	}
+	schema, err := catalogClient.GetDataProductTableSchema(name)
+	if err != nil {
+		return "", err
+	}
+	
+	var b bytes.Buffer
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "COLUMN\tTYPE\tNOTES")
+	for _, col := range schema.Columns {
+		notes := col.Comment
+		if col.PartitionKey {
+			notes = strings.TrimSuffix("partition key; "+notes, "; ")
 		}
-		
-		return dataProductDetailsLoadedMsg{product: product}
+		writeSchemaColumn(tw, col.Name, col.Type, notes, 0)
+	}
+	if err := tw.Flush(); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// writeSchemaColumn writes the line of a column or struct field and those
+// of its fields
+func writeSchemaColumn(w *tabwriter.Writer, name string, t *catalog.DataType, notes string, depth int) {
+	dataType := ""
+	if t != nil {
+		dataType = t.String()
+		if t.Kind == catalog.KindStruct {
+			dataType = "struct"
+		}
+	}
+	fmt.Fprintf(w, "%s%s\t%s\t%s\n", strings.Repeat("  ", depth), name, dataType, notes)
+	if t != nil && t.Kind == catalog.KindStruct {
+		for _, f := range t.Fields {
+			writeSchemaColumn(w, f.Name, f.Type, "", depth+1)
+		}
+	}
+}
+
+func (m *DiscoverModel) loadSample(product *catalog.DataProduct, width int) (string, error) {
+	db, err := m.openProduct(product.Name)
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+	
+	result, err := db.ExecuteQuery(fmt.Sprintf("SELECT * FROM %s LIMIT %d", quoteName(product.Name), sampleRows))
+	if err != nil {
+		return "", err
+	}
+	
+	mark := marking.New(map[string]string{product.Name: product.Classification})
+	var b bytes.Buffer
+	if err := WriteQueryResults(&b, result, "table", mark, m.values, TableLayout{Width: width}); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func (m *DiscoverModel) loadProfile(name string) (string, error) {
+	db, err := m.openProduct(name)
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+	
+	p, err := profile.Table(db, name, profile.Options{Sample: profileSample})
+	if err != nil {
+		return "", err
+	}
+	
+	var b bytes.Buffer
+	if err := profile.WriteReport(&b, p, "table"); err != nil {
+		return "", fmt.Errorf("failed to render profile: %w", err)
+	}
+	return b.String(), nil
+}
+
+// openProduct checks access to a product and returns a DuckDB connection
+// with it registered
+func (m *DiscoverModel) openProduct(name string) (*duckdb.Connection, error) {
+	canAccess, err := m.secCtx.CanAccessDataProduct(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check access: %w", err)
+	}
+	if !canAccess {
+		return nil, fmt.Errorf("access denied to data product: %s", name)
+	}
+	
+	catalogClient, err := catalog.NewClient(m.cfg, m.secCtx, m.log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create catalog client: %w", err)
+	}
+	product, err := catalogClient.GetDataProduct(name)
+	if err != nil {
+		return nil, err
+	}
+	
+	db, err := duckdb.NewConnection(m.cfg, m.secCtx)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.RegisterTable(name, product.Format, product.Location); err != nil {
+		db.Close()
+		return nil, err
 	}
+	return db, nil
+}
+
+// findManifest returns the manifest of a product and all manifests under
+// manifest_dir
+func (m *DiscoverModel) findManifest(name string) (*manifest.DataProduct, []*manifest.DataProduct, error) {
+	products, err := manifest.Discover(m.cfg.ManifestDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	found := manifest.Lookup(products, name)
+	if found == nil {
+		return nil, nil, fmt.Errorf("no data product manifest found for %s under %s", name, m.cfg.ManifestDir)
+	}
+	return found, products, nil
+}
+
+func (m *DiscoverModel) loadLineage(name string) (string, error) {
+	product, products, err := m.findManifest(name)
+	if err != nil {
+		return "", err
+	}
+	
+	// Downstream products name this one, or one of its tables, as a source
+	sources := map[string]bool{name: true, product.ID(): true}
+	for _, t := range product.Spec.Tables {
+		sources[t.QualifiedName()] = true
+	}
+	var downstream []string
+	for _, p := range products {
+		for _, u := range p.Spec.Lineage.Upstream {
+			if sources[u.Source] && p.ID() != product.ID() {
+				downstream = append(downstream, p.ID())
+				break
+			}
+		}
+	}
+	sort.Strings(downstream)
+	
+	var b strings.Builder
+	b.WriteString("Upstream:\n")
+	if len(product.Spec.Lineage.Upstream) == 0 {
+		b.WriteString("  none declared\n")
+	}
+	for _, u := range product.Spec.Lineage.Upstream {
+		if u.Type != "" {
+			b.WriteString(fmt.Sprintf("  %s (%s)\n", u.Source, u.Type))
+		} else {
+			b.WriteString(fmt.Sprintf("  %s\n", u.Source))
+		}
+	}
+	
+	b.WriteString("\nDownstream:\n")
+	if len(downstream) == 0 {
+		b.WriteString("  none found\n")
+	}
+	for _, d := range downstream {
+		b.WriteString(fmt.Sprintf("  %s\n", d))
+	}
+	
+	b.WriteString("\nTables:\n")
+	for _, t := range product.Spec.Tables {
+		b.WriteString(fmt.Sprintf("  %s (%s) %s\n", t.QualifiedName(), t.Format, t.Location))
+	}
+	b.WriteString(fmt.Sprintf("\nManifest: %s\n", product.File))
+	return b.String(), nil
+}
+
+// loadSLA shows the declared service levels and checks freshness against
+// the latest snapshots
+func (m *DiscoverModel) loadSLA(name string) (string, error) {
+	product, _, err := m.findManifest(name)
+	if err != nil {
+		return "", err
+	}
+	
+	var b bytes.Buffer
+	b.WriteString(fmt.Sprintf("Latency:      %s\n", orNone(product.Spec.SLA.Latency)))
+	b.WriteString(fmt.Sprintf("Availability: %s\n\n", orNone(product.Spec.SLA.Availability)))
+	
+	checker := &sla.Checker{
+		Lakehouse: lakehouse.NewClient(m.cfg, m.secCtx, m.log),
+		Source:    sla.SourceSnapshot,
+	}
+	if err := sla.WriteReport(&b, checker.Check([]*manifest.DataProduct{product}), "text"); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// detailContent renders the selected tab of the details view
+func (m *DiscoverModel) detailContent() string {
+	if m.detailTab == overviewTab {
+		return m.formatProductDetails()
+	}
+	
+	tab := m.tabs[m.detailTab]
+	switch {
+	case tab.loading && !tab.loaded:
+		return loadingText[m.detailTab]
+	case tab.err != nil:
+		width := m.detailViewport.Width - m.detailViewport.Style.GetHorizontalFrameSize()
+		return wordwrap.String(fmt.Sprintf("Failed to load %s: %v", strings.ToLower(detailTabs[m.detailTab]), tab.err), width) +
+			"\n\nPress r to retry"
+	}
+	return tab.text
+}
+
+func (m *DiscoverModel) tabBar() string {
+	active := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#0077B6")).Underline(true)
+	inactive := lipgloss.NewStyle().Foreground(lipgloss.Color("#888888"))
+	
+	tabs := make([]string, len(detailTabs))
+	for i, t := range detailTabs {
+		if i == m.detailTab {
+			tabs[i] = active.Render(t)
+		} else {
+			tabs[i] = inactive.Render(t)
+		}
+	}
+	return strings.Join(tabs, "  ")
 }
 
 func (m *DiscoverModel) formatProductDetails() string {
@@ -198,18 +740,21 @@ func (m *DiscoverModel) formatProductDetails() string {
 	
 	var b strings.Builder
 	
-	b.WriteString(fmt.Sprintf("Name:         %s\n", m.selectedProduct.Name))
-	b.WriteString(fmt.Sprintf("Domain:       %s\n", m.selectedProduct.Domain))
-	b.WriteString(fmt.Sprintf("Description:  %s\n", m.selectedProduct.Description))
-	b.WriteString(fmt.Sprintf("Type:         %s\n", m.selectedProduct.Type))
-	b.WriteString(fmt.Sprintf("Format:       %s\n", m.selectedProduct.Format))
-	b.WriteString(fmt.Sprintf("Location:     %s\n", m.selectedProduct.Location))
-	b.WriteString(fmt.Sprintf("Owner:        %s\n", m.selectedProduct.Owner))
-	b.WriteString(fmt.Sprintf("Created:      %s\n", m.selectedProduct.CreatedAt.Format("2006-01-02 15:04:05")))
-	b.WriteString(fmt.Sprintf("Last Updated: %s\n", m.selectedProduct.UpdatedAt.Format("2006-01-02 15:04:05")))
+	b.WriteString(fmt.Sprintf("Name:           %s\n", m.selectedProduct.Name))
+	b.WriteString(fmt.Sprintf("Domain:         %s\n", m.selectedProduct.Domain))
+	b.WriteString(fmt.Sprintf("Description:    %s\n", m.selectedProduct.Description))
+	b.WriteString(fmt.Sprintf("Type:           %s\n", m.selectedProduct.Type))
+	b.WriteString(fmt.Sprintf("Format:         %s\n", m.selectedProduct.Format))
+	b.WriteString(fmt.Sprintf("Classification: %s\n", orNone(m.selectedProduct.Classification)))
+	b.WriteString(fmt.Sprintf("Location:       %s\n", m.selectedProduct.Location))
+	b.WriteString(fmt.Sprintf("Owner:          %s\n", m.selectedProduct.Owner))
+	b.WriteString(fmt.Sprintf("Columns:        %d\n", len(m.selectedProduct.Columns)))
+	b.WriteString(fmt.Sprintf("Created:        %s\n", m.selectedProduct.CreatedAt.Format("2006-01-02 15:04:05")))
+	b.WriteString(fmt.Sprintf("Last Updated:   %s\n", m.selectedProduct.UpdatedAt.Format("2006-01-02 15:04:05")))
 	
 	b.WriteString("\nTags:\n")
-	for k, v := range m.selectedProduct.Tags {
+	for _, tag := range productTags(m.selectedProduct) {
+		k, v, _ := strings.Cut(tag, "=")
 		b.WriteString(fmt.Sprintf("  %s: %s\n", k, v))
 	}
 	
@@ -218,14 +763,14 @@ func (m *DiscoverModel) formatProductDetails() string {
 
 // Message types for Bubble Tea
 type dataProductsLoadedMsg struct {
-	products []string
-}
-
-type dataProductDetailsLoadedMsg struct {
-	product *catalog.DataProduct
-	err     error
+	products []*catalog.DataProduct
+	err      error
 }
 
-type errMsg struct {
-	err error
+// tabLoadedMsg is the content of a tab of the details of a product
+type tabLoadedMsg struct {
+	name string
+	tab  int
+	text string
+	err  error
 }
@@ -1,147 +1,275 @@
 package ui
 
 import (
+	"bytes"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"html"
+	"io"
 	"os"
 	"strings"
 
+	"github.com/frocore/fedramp-data-mesh/cli/internal/arrowipc"
 	"github.com/frocore/fedramp-data-mesh/cli/internal/duckdb"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/marking"
 )
 
-func DisplayQueryResults(result *duckdb.QueryResult, format string) error {
+// DisplayQueryResults prints query results, marked with the classification
+// of the products they were derived from and with values rendered by vf.
+// On a terminal, tables are fitted to its width and output longer than
+// the screen goes through $PAGER.
+func DisplayQueryResults(result *duckdb.QueryResult, format string, mark marking.Marking, vf ValueFormat, expanded bool) error {
+	if IsBinaryFormat(format) {
+		return fmt.Errorf("%s output must be written to a file with --out-file", format)
+	}
+	layout := TableLayout{Expanded: expanded}
+	width, height, ok := terminalSize()
+	if !ok {
+		return WriteQueryResults(os.Stdout, result, format, mark, vf, layout)
+	}
+	
+	layout.Width = width
+	var buf bytes.Buffer
+	if err := WriteQueryResults(&buf, result, format, mark, vf, layout); err != nil {
+		return err
+	}
+	return page(buf.Bytes(), height)
+}
+
+// IsBinaryFormat reports whether an output format can't be printed to a
+// terminal
+func IsBinaryFormat(format string) bool {
+	switch strings.ToLower(format) {
+	case "parquet", "arrow", "xlsx":
+		return true
+	}
+	return false
+}
+
+// WriteQueryResults writes query results in an output format. Parquet is
+// written by DuckDB itself, see Connection.CopyToParquet.
+func WriteQueryResults(w io.Writer, result *duckdb.QueryResult, format string, mark marking.Marking, vf ValueFormat, layout TableLayout) error {
+	f := NewFormatter(result, vf)
 	switch strings.ToLower(format) {
 	case "table":
-		return displayTable(result)
+		return displayTable(w, result, mark, f, layout)
 	case "csv":
-		return displayCSV(result)
+		// Tools reading CSV take an empty field for NULL, not the text
+		// shown on screen
+		f.Null = ""
+		return displayCSV(w, result, mark, f)
 	case "json":
-		return displayJSON(result)
+		return displayJSON(w, result, mark, f)
+	case "ndjson":
+		return displayNDJSON(w, result, mark, f)
+	case "markdown":
+		return displayMarkdown(w, result, mark, f)
+	case "html":
+		return displayHTML(w, result, mark, f)
+	case "arrow":
+		return arrowipc.Write(w, result, mark.Metadata())
+	case "xlsx":
+		return writeXLSX(w, result, mark, f)
 	default:
 		return fmt.Errorf("unsupported output format: %s", format)
 	}
 }
 
-func displayTable(result *duckdb.QueryResult) error {
+func displayCSV(out io.Writer, result *duckdb.QueryResult, mark marking.Marking, f *Formatter) error {
 	if result == nil || len(result.Columns) == 0 {
-		fmt.Println("No results to display")
+		fmt.Fprintln(out, "No results to display")
 		return nil
 	}
 	
-	// Calculate column widths
-	colWidths := make([]int, len(result.Columns))
-	for i, col := range result.Columns {
-		colWidths[i] = len(col)
+	// Banners as comment lines before the header and after the rows
+	if mark.Known() {
+		fmt.Fprintln(out, "# "+mark.Banner())
 	}
 	
-	// Check row data to ensure column width is sufficient
+	w := csv.NewWriter(out)
+	
+	// Write header
+	if err := w.Write(result.Columns); err != nil {
+		return fmt.Errorf("error writing CSV header: %w", err)
+	}
+	
+	// Write data rows
 	for _, row := range result.Rows {
+		// Convert row to []string
+		rowStrings := make([]string, len(row))
 		for i, val := range row {
-			if i < len(colWidths) {
-				valStr := fmt.Sprintf("%v", val)
-				if len(valStr) > colWidths[i] {
-					colWidths[i] = len(valStr)
-				}
-			}
+			rowStrings[i] = f.Text(i, val)
+		}
+		
+		if err := w.Write(rowStrings); err != nil {
+			return fmt.Errorf("error writing CSV row: %w", err)
 		}
 	}
 	
-	// Print header
-	for i, col := range result.Columns {
-		fmt.Printf("| %-*s ", colWidths[i], col)
+	w.Flush()
+	
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("error flushing CSV writer: %w", err)
 	}
-	fmt.Println("|")
 	
-	// Print separator
-	for i := range result.Columns {
-		fmt.Print("|")
-		for j := 0; j < colWidths[i]+2; j++ {
-			fmt.Print("-")
-		}
+	if mark.Known() {
+		fmt.Fprintln(out, "# "+mark.Banner())
 	}
-	fmt.Println("|")
 	
-	// Print data rows
-	for _, row := range result.Rows {
-		for i, val := range row {
-			if i < len(colWidths) {
-				valStr := fmt.Sprintf("%v", val)
-				fmt.Printf("| %-*s ", colWidths[i], valStr)
+	return nil
+}
+
+// jsonEnvelope carries the rows of JSON output with their marking
+type jsonEnvelope struct {
+	Classification string       `json:"classification,omitempty"`
+	Marking        string       `json:"marking,omitempty"`
+	Products       []string     `json:"classification_source,omitempty"`
+	Rows           []jsonObject `json:"rows"`
+}
+
+func displayJSON(w io.Writer, result *duckdb.QueryResult, mark marking.Marking, f *Formatter) error {
+	if result == nil || len(result.Columns) == 0 {
+		fmt.Fprintln(w, "No results to display")
+		return nil
+	}
+	
+	// Convert to list of objects, keeping the order of the columns
+	data := make([]jsonObject, len(result.Rows))
+	
+	for i, row := range result.Rows {
+		data[i] = f.Row(result.Columns, row)
+	}
+	
+	// Marshal to JSON
+	envelope := jsonEnvelope{
+		Classification: mark.Classification,
+		Marking:        mark.Banner(),
+		Products:       mark.Products,
+		Rows:           data,
+	}
+	jsonBytes, err := json.MarshalIndent(envelope, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling to JSON: %w", err)
+	}
+	
+	fmt.Fprintln(w, string(jsonBytes))
+	
+	return nil
+}
+
+// ndjsonMarkingField is the field of NDJSON lines holding their marking
+const ndjsonMarkingField = "_marking"
+
+func displayNDJSON(w io.Writer, result *duckdb.QueryResult, mark marking.Marking, f *Formatter) error {
+	if result == nil {
+		return nil
+	}
+	
+	// One object per line, each carrying the marking, since lines are
+	// often split from the file
+	if mark.Known() {
+		for _, col := range result.Columns {
+			if col == ndjsonMarkingField {
+				return fmt.Errorf("column %s would hide the marking of NDJSON lines: rename it in the query", col)
 			}
 		}
-		fmt.Println("|")
 	}
-	
-	// Print footer
-	fmt.Printf("\n%d rows returned\n", len(result.Rows))
+	enc := json.NewEncoder(w)
+	for _, row := range result.Rows {
+		item := f.Row(result.Columns, row)
+		if mark.Known() {
+			item = append(item, jsonField{ndjsonMarkingField, mark.Banner()})
+		}
+		if err := enc.Encode(item); err != nil {
+			return fmt.Errorf("error marshaling to JSON: %w", err)
+		}
+	}
 	
 	return nil
 }
 
-func displayCSV(result *duckdb.QueryResult) error {
+func displayMarkdown(w io.Writer, result *duckdb.QueryResult, mark marking.Marking, f *Formatter) error {
 	if result == nil || len(result.Columns) == 0 {
-		fmt.Println("No results to display")
+		fmt.Fprintln(w, "No results to display")
 		return nil
 	}
 	
-	w := csv.NewWriter(os.Stdout)
+	if mark.Known() {
+		fmt.Fprintf(w, "**%s**\n\n", mark.Banner())
+	}
 	
-	// Write header
-	if err := w.Write(result.Columns); err != nil {
-		return fmt.Errorf("error writing CSV header: %w", err)
+	cells := make([]string, len(result.Columns))
+	for i, col := range result.Columns {
+		cells[i] = markdownCell(col)
+	}
+	fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+	for i := range cells {
+		cells[i] = "---"
 	}
+	fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
 	
-	// Write data rows
 	for _, row := range result.Rows {
-		// Convert row to []string
-		rowStrings := make([]string, len(row))
-		for i, val := range row {
-			rowStrings[i] = fmt.Sprintf("%v", val)
-		}
-		
-		if err := w.Write(rowStrings); err != nil {
-			return fmt.Errorf("error writing CSV row: %w", err)
+		for i := range cells {
+			cells[i] = ""
+			if i < len(row) {
+				cells[i] = markdownCell(f.Text(i, row[i]))
+			}
 		}
+		fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
 	}
 	
-	w.Flush()
-	
-	if err := w.Error(); err != nil {
-		return fmt.Errorf("error flushing CSV writer: %w", err)
+	if mark.Known() {
+		fmt.Fprintf(w, "\n**%s**\n", mark.Banner())
 	}
 	
 	return nil
 }
 
-func displayJSON(result *duckdb.QueryResult) error {
+// markdownCell escapes pipes and line breaks, which would end the cell
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.NewReplacer("\r\n", "<br>", "\n", "<br>").Replace(s)
+}
+
+func displayHTML(w io.Writer, result *duckdb.QueryResult, mark marking.Marking, f *Formatter) error {
 	if result == nil || len(result.Columns) == 0 {
-		fmt.Println("No results to display")
+		fmt.Fprintln(w, "No results to display")
 		return nil
 	}
 	
-	// Convert to list of maps
-	data := make([]map[string]interface{}, len(result.Rows))
+	banner := ""
+	if mark.Known() {
+		banner = fmt.Sprintf("<div class=\"marking\" style=\"text-align:center;font-weight:bold\">%s</div>\n", html.EscapeString(mark.Banner()))
+	}
+	
+	fmt.Fprintln(w, "<!DOCTYPE html>")
+	fmt.Fprintln(w, "<html>")
+	fmt.Fprintln(w, "<head><meta charset=\"utf-8\"></head>")
+	fmt.Fprintln(w, "<body>")
+	fmt.Fprint(w, banner)
+	fmt.Fprintln(w, "<table>")
 	
-	for i, row := range result.Rows {
-		item := make(map[string]interface{})
-		
-		for j, val := range row {
-			if j < len(result.Columns) {
-				item[result.Columns[j]] = val
-			}
-		}
-		
-		data[i] = item
+	fmt.Fprint(w, "<thead><tr>")
+	for _, col := range result.Columns {
+		fmt.Fprintf(w, "<th>%s</th>", html.EscapeString(col))
 	}
+	fmt.Fprintln(w, "</tr></thead>")
 	
-	// Marshal to JSON
-	jsonBytes, err := json.MarshalIndent(data, "", "  ")
-	if err != nil {
-		return fmt.Errorf("error marshaling to JSON: %w", err)
+	fmt.Fprintln(w, "<tbody>")
+	for _, row := range result.Rows {
+		fmt.Fprint(w, "<tr>")
+		for i, val := range row {
+			fmt.Fprintf(w, "<td>%s</td>", html.EscapeString(f.Text(i, val)))
+		}
+		fmt.Fprintln(w, "</tr>")
 	}
+	fmt.Fprintln(w, "</tbody>")
 	
-	fmt.Println(string(jsonBytes))
+	fmt.Fprintln(w, "</table>")
+	fmt.Fprint(w, banner)
+	fmt.Fprintln(w, "</body>")
+	fmt.Fprintln(w, "</html>")
 	
 	return nil
 }
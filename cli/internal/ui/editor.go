@@ -0,0 +1,555 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+)
+
+// Styles of SQL tokens in the editor
+var (
+	sqlKeywordStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#0096C7")).
+			Bold(true)
+
+	sqlStringStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#2A9D8F"))
+
+	sqlNumberStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#E76F51"))
+
+	sqlCommentStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#808080")).
+			Italic(true)
+
+	lineNumberStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#808080"))
+)
+
+// sqlKeywords are highlighted in the editor
+var sqlKeywords = map[string]bool{}
+
+func init() {
+	for _, k := range strings.Fields(`
+		ALL AND ANTI ANY AS ASC ASOF BETWEEN BY CASE CAST COPY CREATE CROSS
+		CUBE DELETE DESC DESCRIBE DISTINCT DROP ELSE END EXCEPT EXCLUDE EXISTS
+		EXPLAIN FALSE FILTER FIRST FOLLOWING FROM FULL GROUP GROUPING HAVING
+		ILIKE IN INNER INSERT INTERSECT INTERVAL INTO IS JOIN LAST LATERAL LEFT
+		LIKE LIMIT NATURAL NOT NULL NULLS OFFSET ON OR ORDER OUTER OVER
+		PARTITION PIVOT POSITIONAL PRAGMA PRECEDING QUALIFY RANGE RECURSIVE
+		REPLACE RIGHT ROLLUP ROW ROWS SAMPLE SELECT SEMI SET SHOW SIMILAR
+		SUMMARIZE TABLE THEN TO TRUE UNBOUNDED UNION UNNEST UNPIVOT UPDATE
+		USING VALUES VIEW WHEN WHERE WINDOW WITH`) {
+		sqlKeywords[k] = true
+	}
+}
+
+// Kinds of SQL tokens
+const (
+	tokenText = iota
+	tokenKeyword
+	tokenString
+	tokenNumber
+	tokenComment
+	tokenSemicolon
+)
+
+type sqlToken struct {
+	kind       int
+	start, end int
+}
+
+// lexSQL splits SQL into the tokens that are highlighted and that separate
+// statements; other text, such as names and operators, is left out
+func lexSQL(src []rune) []sqlToken {
+	var tokens []sqlToken
+	for i := 0; i < len(src); {
+		start, r := i, src[i]
+		kind := tokenText
+		switch {
+		case r == '-' && i+1 < len(src) && src[i+1] == '-':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			kind = tokenComment
+		case r == '/' && i+1 < len(src) && src[i+1] == '*':
+			i += 2
+			for i < len(src) && !(src[i] == '*' && i+1 < len(src) && src[i+1] == '/') {
+				i++
+			}
+			if i += 2; i > len(src) {
+				i = len(src)
+			}
+			kind = tokenComment
+		case r == '\'' || r == '"':
+			// Strings and quoted names, where a doubled quote escapes it
+			for i++; i < len(src); i++ {
+				if src[i] == r {
+					if i+1 < len(src) && src[i+1] == r {
+						i++
+						continue
+					}
+					i++
+					break
+				}
+			}
+			if r == '\'' {
+				kind = tokenString
+			}
+		case unicode.IsDigit(r) || r == '.' && i+1 < len(src) && unicode.IsDigit(src[i+1]):
+			for i++; i < len(src); i++ {
+				c := src[i]
+				if (c == '+' || c == '-') && (src[i-1] == 'e' || src[i-1] == 'E') {
+					continue
+				}
+				if !unicode.IsDigit(c) && c != '.' && c != '_' && c != 'e' && c != 'E' {
+					break
+				}
+			}
+			kind = tokenNumber
+		case unicode.IsLetter(r) || r == '_':
+			for i < len(src) && (unicode.IsLetter(src[i]) || unicode.IsDigit(src[i]) || src[i] == '_') {
+				i++
+			}
+			if sqlKeywords[strings.ToUpper(string(src[start:i]))] {
+				kind = tokenKeyword
+			}
+		case r == ';':
+			i++
+			kind = tokenSemicolon
+		default:
+			i++
+		}
+		if kind != tokenText {
+			tokens = append(tokens, sqlToken{kind, start, i})
+		}
+	}
+	return tokens
+}
+
+// sqlStatement is the span of a statement in the editor, including its
+// semicolon
+type sqlStatement struct {
+	start, end int
+}
+
+// splitStatements finds the statements of SQL, leaving out those that are
+// empty or only comments
+func splitStatements(src []rune) []sqlStatement {
+	var statements []sqlStatement
+	start := 0
+	code := func(start, end int, tokens []sqlToken) bool {
+		for i := start; i < end; i++ {
+			if !unicode.IsSpace(src[i]) && !inComment(i, tokens) {
+				return true
+			}
+		}
+		return false
+	}
+	tokens := lexSQL(src)
+	for _, t := range tokens {
+		if t.kind == tokenSemicolon {
+			if code(start, t.start, tokens) {
+				statements = append(statements, trimStatement(src, start, t.end))
+			}
+			start = t.end
+		}
+	}
+	if code(start, len(src), tokens) {
+		statements = append(statements, trimStatement(src, start, len(src)))
+	}
+	return statements
+}
+
+// trimStatement leaves the white space around a statement out of its span
+func trimStatement(src []rune, start, end int) sqlStatement {
+	for start < end && unicode.IsSpace(src[start]) {
+		start++
+	}
+	for end > start && unicode.IsSpace(src[end-1]) {
+		end--
+	}
+	return sqlStatement{start, end}
+}
+
+func inComment(offset int, tokens []sqlToken) bool {
+	for _, t := range tokens {
+		if t.kind == tokenComment && offset >= t.start && offset < t.end {
+			return true
+		}
+	}
+	return false
+}
+
+// sqlEditor is a multi-line SQL editor with syntax highlighting
+type sqlEditor struct {
+	lines    [][]rune
+	row, col int
+	// top and left are the first line and column shown
+	top, left     int
+	width, height int
+	focused       bool
+}
+
+func newSQLEditor(width, height int) sqlEditor {
+	return sqlEditor{lines: [][]rune{nil}, width: width, height: height}
+}
+
+// Value returns the text of the editor
+func (e *sqlEditor) Value() string {
+	lines := make([]string, len(e.lines))
+	for i, line := range e.lines {
+		lines[i] = string(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// SetValue replaces the text of the editor, with the cursor at its end
+func (e *sqlEditor) SetValue(s string) {
+	s = strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(s)
+	e.lines = nil
+	for _, line := range strings.Split(s, "\n") {
+		e.lines = append(e.lines, []rune(line))
+	}
+	e.row = len(e.lines) - 1
+	e.col = len(e.lines[e.row])
+	e.scroll()
+}
+
+func (e *sqlEditor) Focus()        { e.focused = true }
+func (e *sqlEditor) Blur()         { e.focused = false }
+func (e *sqlEditor) Focused() bool { return e.focused }
+
+// SetSize sets the size of the editor, including its line numbers
+func (e *sqlEditor) SetSize(width, height int) {
+	if height < 1 {
+		height = 1
+	}
+	e.width, e.height = width, height
+	e.scroll()
+}
+
+// Statements returns the statements of the text and the index of the one
+// at the cursor: the first that doesn't end before it, or the last one.
+// The index is -1 when there are no statements.
+func (e *sqlEditor) Statements() ([]sqlStatement, int) {
+	statements := splitStatements([]rune(e.Value()))
+	offset := e.offset()
+	for i, s := range statements {
+		if offset <= s.end {
+			return statements, i
+		}
+	}
+	return statements, len(statements) - 1
+}
+
+// offset returns the position of the cursor in the text
+func (e *sqlEditor) offset() int {
+	offset := e.col
+	for _, line := range e.lines[:e.row] {
+		offset += len(line) + 1
+	}
+	return offset
+}
+
+// setOffset moves the cursor to a position in the text
+func (e *sqlEditor) setOffset(offset int) {
+	e.row, e.col = 0, 0
+	for e.row+1 < len(e.lines) && offset > len(e.lines[e.row]) {
+		offset -= len(e.lines[e.row]) + 1
+		e.row++
+	}
+	e.col = offset
+	if e.col > len(e.lines[e.row]) {
+		e.col = len(e.lines[e.row])
+	}
+	e.scroll()
+}
+
+// Replace replaces the text between two positions, leaving the cursor
+// after it
+func (e *sqlEditor) Replace(start, end int, text string) {
+	src := []rune(e.Value())
+	e.SetValue(string(src[:start]) + text + string(src[end:]))
+	e.setOffset(start + len([]rune(text)))
+}
+
+// Cursor returns where the cursor is on the screen, relative to the top
+// left of the editor
+func (e *sqlEditor) Cursor() (row, col int) {
+	line := e.lines[e.row]
+	col = gutterWidth
+	if e.left < e.col {
+		col += runewidth.StringWidth(string(line[e.left:e.col]))
+	}
+	return e.row - e.top, col
+}
+
+// Update edits the text for a key
+func (e *sqlEditor) Update(msg tea.KeyMsg) {
+	if !e.focused {
+		return
+	}
+	line := e.lines[e.row]
+	switch msg.String() {
+	case "enter":
+		// Keep the indentation of the line
+		indent := 0
+		for indent < e.col && (line[indent] == ' ' || line[indent] == '\t') {
+			indent++
+		}
+		rest := append(append([]rune{}, line[:indent]...), line[e.col:]...)
+		e.lines[e.row] = line[:e.col]
+		e.lines = append(e.lines[:e.row+1], append([][]rune{rest}, e.lines[e.row+1:]...)...)
+		e.row, e.col = e.row+1, indent
+	case "backspace", "ctrl+h":
+		if e.col > 0 {
+			e.lines[e.row] = append(line[:e.col-1], line[e.col:]...)
+			e.col--
+		} else if e.row > 0 {
+			e.col = len(e.lines[e.row-1])
+			e.lines[e.row-1] = append(e.lines[e.row-1], line...)
+			e.lines = append(e.lines[:e.row], e.lines[e.row+1:]...)
+			e.row--
+		}
+	case "delete", "ctrl+d":
+		if e.col < len(line) {
+			e.lines[e.row] = append(line[:e.col], line[e.col+1:]...)
+		} else if e.row+1 < len(e.lines) {
+			e.lines[e.row] = append(line, e.lines[e.row+1]...)
+			e.lines = append(e.lines[:e.row+1], e.lines[e.row+2:]...)
+		}
+	case "ctrl+w", "alt+backspace":
+		start := e.wordLeft()
+		e.lines[e.row] = append(line[:start], line[e.col:]...)
+		e.col = start
+	case "ctrl+k":
+		e.lines[e.row] = line[:e.col]
+	case "ctrl+u":
+		e.lines[e.row] = line[e.col:]
+		e.col = 0
+	case "left", "ctrl+b":
+		if e.col > 0 {
+			e.col--
+		} else if e.row > 0 {
+			e.row--
+			e.col = len(e.lines[e.row])
+		}
+	case "right", "ctrl+f":
+		if e.col < len(line) {
+			e.col++
+		} else if e.row+1 < len(e.lines) {
+			e.row, e.col = e.row+1, 0
+		}
+	case "ctrl+left", "alt+b":
+		e.col = e.wordLeft()
+	case "ctrl+right", "alt+f":
+		e.col = e.wordRight()
+	case "up":
+		e.moveRow(-1)
+	case "down":
+		e.moveRow(1)
+	case "pgup":
+		e.moveRow(-e.height)
+	case "pgdown":
+		e.moveRow(e.height)
+	case "home", "ctrl+a":
+		e.col = 0
+	case "end", "ctrl+e":
+		e.col = len(line)
+	case "ctrl+home":
+		e.row, e.col = 0, 0
+	case "ctrl+end":
+		e.row = len(e.lines) - 1
+		e.col = len(e.lines[e.row])
+	default:
+		if msg.Type == tea.KeyRunes && !msg.Alt || msg.Type == tea.KeySpace {
+			e.insert(msg.Runes)
+		}
+	}
+	e.scroll()
+}
+
+// insert inserts text at the cursor, which may be several lines when
+// pasted
+func (e *sqlEditor) insert(runes []rune) {
+	text := strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(string(runes))
+	for i, part := range strings.Split(text, "\n") {
+		if i > 0 {
+			line := e.lines[e.row]
+			rest := append([]rune{}, line[e.col:]...)
+			e.lines[e.row] = line[:e.col]
+			e.lines = append(e.lines[:e.row+1], append([][]rune{rest}, e.lines[e.row+1:]...)...)
+			e.row, e.col = e.row+1, 0
+		}
+		line := e.lines[e.row]
+		r := []rune(part)
+		e.lines[e.row] = append(append(append([]rune{}, line[:e.col]...), r...), line[e.col:]...)
+		e.col += len(r)
+	}
+}
+
+func (e *sqlEditor) moveRow(n int) {
+	e.row += n
+	if e.row >= len(e.lines) {
+		e.row = len(e.lines) - 1
+	}
+	if e.row < 0 {
+		e.row = 0
+	}
+	if e.col > len(e.lines[e.row]) {
+		e.col = len(e.lines[e.row])
+	}
+}
+
+// wordLeft returns the column of the start of the word before the cursor
+func (e *sqlEditor) wordLeft() int {
+	line, col := e.lines[e.row], e.col
+	for col > 0 && !isWordRune(line[col-1]) {
+		col--
+	}
+	for col > 0 && isWordRune(line[col-1]) {
+		col--
+	}
+	return col
+}
+
+// wordRight returns the column of the end of the word after the cursor
+func (e *sqlEditor) wordRight() int {
+	line, col := e.lines[e.row], e.col
+	for col < len(line) && !isWordRune(line[col]) {
+		col++
+	}
+	for col < len(line) && isWordRune(line[col]) {
+		col++
+	}
+	return col
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// gutterWidth is the width of the line numbers
+const gutterWidth = 5
+
+// textWidth is the width of the text next to the line numbers
+func (e *sqlEditor) textWidth() int {
+	if e.width <= gutterWidth {
+		return 1
+	}
+	return e.width - gutterWidth
+}
+
+// scroll keeps the cursor in view
+func (e *sqlEditor) scroll() {
+	if e.row < e.top {
+		e.top = e.row
+	} else if e.row >= e.top+e.height {
+		e.top = e.row - e.height + 1
+	}
+	textWidth := e.textWidth()
+	if e.col < e.left {
+		e.left = e.col
+	} else if e.col >= e.left+textWidth {
+		e.left = e.col - textWidth + 1
+	}
+}
+
+// View renders the visible lines, highlighting SQL and the selected
+// statement, if any
+func (e *sqlEditor) View(selection *sqlStatement, selectionStyle lipgloss.Style) string {
+	src := []rune(e.Value())
+	kinds := make([]int, len(src))
+	for _, t := range lexSQL(src) {
+		for i := t.start; i < t.end; i++ {
+			kinds[i] = t.kind
+		}
+	}
+
+	// How runes look: their token kind, whether they are selected and
+	// whether the cursor is on them
+	type look struct {
+		kind             int
+		selected, cursor bool
+	}
+	render := func(l look, text string) string {
+		style := lipgloss.NewStyle()
+		if l.selected {
+			style = selectionStyle.Copy()
+		}
+		switch l.kind {
+		case tokenKeyword:
+			style = style.Inherit(sqlKeywordStyle)
+		case tokenString:
+			style = style.Inherit(sqlStringStyle)
+		case tokenNumber:
+			style = style.Inherit(sqlNumberStyle)
+		case tokenComment:
+			style = style.Inherit(sqlCommentStyle)
+		}
+		if l.cursor {
+			style = style.Reverse(true)
+		}
+		return style.Render(text)
+	}
+
+	textWidth := e.textWidth()
+	offset := 0
+	for _, line := range e.lines[:e.top] {
+		offset += len(line) + 1
+	}
+
+	var b strings.Builder
+	for row := e.top; row < e.top+e.height; row++ {
+		if row > e.top {
+			b.WriteString("\n")
+		}
+		if row >= len(e.lines) {
+			b.WriteString(lineNumberStyle.Render(fmt.Sprintf("%*s ", gutterWidth-1, "~")))
+			continue
+		}
+		b.WriteString(lineNumberStyle.Render(fmt.Sprintf("%*d ", gutterWidth-1, row+1)))
+
+		// Runs of runes that look the same are rendered together
+		line := e.lines[row]
+		width := 0
+		var run []rune
+		var runLook look
+		for col := e.left; col <= len(line); col++ {
+			l := look{cursor: e.focused && row == e.row && col == e.col}
+			if col == len(line) && !l.cursor {
+				break
+			}
+			r := ' '
+			if col < len(line) {
+				r = line[col]
+				l.kind = kinds[offset+col]
+			}
+			if r == '\t' {
+				r = ' '
+			}
+			if width += runewidth.RuneWidth(r); width > textWidth {
+				break
+			}
+			at := offset + col
+			l.selected = selection != nil && at >= selection.start && at < selection.end
+
+			if len(run) > 0 && l != runLook {
+				b.WriteString(render(runLook, string(run)))
+				run = run[:0]
+			}
+			runLook = l
+			run = append(run, r)
+		}
+		if len(run) > 0 {
+			b.WriteString(render(runLook, string(run)))
+		}
+		offset += len(line) + 1
+	}
+	return b.String()
+}
@@ -0,0 +1,112 @@
+package ui
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/duckdb"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/logging"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/marking"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/parquetmeta"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/securefile"
+)
+
+// errUnmarked refuses to write results whose classification is unknown
+var errUnmarked = errors.New("results of unknown classification can't be exported unmarked")
+
+// ExportQuery runs a query and writes its results to an encrypted file,
+// marked with their classification, returning the number of rows.
+// Parquet is written by DuckDB so that it keeps DuckDB's types. Results
+// of unknown classification aren't exported.
+func ExportQuery(db *duckdb.Connection, store *securefile.Store, query, path, format string, mark marking.Marking, vf ValueFormat, layout TableLayout) (int64, error) {
+	if !mark.Known() {
+		return 0, errUnmarked
+	}
+	if strings.ToLower(format) != "parquet" {
+		result, err := db.ExecuteQuery(query)
+		if err != nil {
+			return 0, err
+		}
+		return ExportResult(store, result, path, format, mark, vf, layout)
+	}
+
+	dir, cleanup, err := store.TempDir()
+	if err != nil {
+		return 0, err
+	}
+	defer cleanup()
+
+	plain := filepath.Join(dir, "results.parquet")
+	rows, err := db.CopyToParquet(plain, query)
+	if err != nil {
+		return 0, err
+	}
+	if err := MarkExport(plain, "parquet", mark); err != nil {
+		return 0, err
+	}
+	if err := store.EncryptFile(path, plain); err != nil {
+		return 0, fmt.Errorf("failed to encrypt results: %w", err)
+	}
+	return rows, nil
+}
+
+// ExportResult writes results at hand to an encrypted file like
+// ExportQuery. Parquet needs the query.
+func ExportResult(store *securefile.Store, result *duckdb.QueryResult, path, format string, mark marking.Marking, vf ValueFormat, layout TableLayout) (int64, error) {
+	if !mark.Known() {
+		return 0, errUnmarked
+	}
+	if strings.ToLower(format) == "parquet" {
+		return 0, fmt.Errorf("parquet output is written by running the query")
+	}
+
+	// The results are in memory already; formatting them in full first
+	// leaves no partial file behind on errors
+	var buf bytes.Buffer
+	if err := WriteQueryResults(&buf, result, format, mark, vf, layout); err != nil {
+		return 0, err
+	}
+	if err := store.WriteFile(path, buf.Bytes()); err != nil {
+		return 0, fmt.Errorf("failed to write output file: %w", err)
+	}
+	return int64(len(result.Rows)), nil
+}
+
+// LogExport records an export in the audit log: what was written where,
+// how it was marked and the products and query it came from
+func LogExport(log *logging.Logger, products []string, query, path, format string, mark marking.Marking, rows int64) {
+	banner := mark.Banner()
+	source := strings.Join(products, ",")
+	if source == "" {
+		source = "no data product"
+	}
+	log.Infof("Exported %d rows to %s as %s, marked %s (from %s): %s",
+		rows, path, strings.ToLower(format), banner, source, strings.Join(strings.Fields(query), " "))
+}
+
+// MarkExport adds the classification to a file written by DuckDB: as
+// key-value metadata of Parquet files and as comment lines above and
+// below CSV files. Files of unknown classification are refused.
+func MarkExport(path, format string, mark marking.Marking) error {
+	if !mark.Known() {
+		return errUnmarked
+	}
+	if format == "parquet" {
+		if err := parquetmeta.Set(path, mark.Metadata()); err != nil {
+			return fmt.Errorf("failed to mark export: %w", err)
+		}
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	banner := "# " + mark.Banner() + "\n"
+	marked := append([]byte(banner), data...)
+	return os.WriteFile(path, append(marked, banner...), 0600)
+}
@@ -0,0 +1,307 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/catalog"
+)
+
+// noValue stands for an empty attribute among facet values
+const noValue = "(none)"
+
+// discoverFacet is an attribute data products are filtered by in the
+// discover TUI
+type discoverFacet struct {
+	name   string
+	values func(p *catalog.DataProduct) []string
+}
+
+var discoverFacets = []discoverFacet{
+	{"Domain", func(p *catalog.DataProduct) []string { return []string{orNone(p.Domain)} }},
+	{"Format", func(p *catalog.DataProduct) []string { return []string{orNone(p.Format)} }},
+	{"Classification", func(p *catalog.DataProduct) []string { return []string{orNone(p.Classification)} }},
+	{"Tags", productTags},
+	{"Owner", func(p *catalog.DataProduct) []string { return []string{orNone(p.Owner)} }},
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return noValue
+	}
+	return s
+}
+
+// productTags returns the tags of a product as sorted key=value pairs
+func productTags(p *catalog.DataProduct) []string {
+	tags := make([]string, 0, len(p.Tags))
+	for k, v := range p.Tags {
+		tags = append(tags, k+"="+v)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// productFilter selects data products by a full-text search and by facet
+// values. Values chosen in one facet are alternatives; facets and search
+// terms all have to match.
+type productFilter struct {
+	query    string
+	terms    []string
+	selected []map[string]bool
+}
+
+func newProductFilter() productFilter {
+	f := productFilter{selected: make([]map[string]bool, len(discoverFacets))}
+	for i := range f.selected {
+		f.selected[i] = make(map[string]bool)
+	}
+	return f
+}
+
+// SetSearch sets the search; every word has to appear, ignoring case, in
+// the name, description, owner, tags or a column name of a product
+func (f *productFilter) SetSearch(query string) {
+	f.query = strings.TrimSpace(query)
+	f.terms = strings.Fields(strings.ToLower(query))
+}
+
+// Toggle chooses a value of a facet or clears the choice
+func (f *productFilter) Toggle(facet int, value string) {
+	if f.selected[facet][value] {
+		delete(f.selected[facet], value)
+	} else {
+		f.selected[facet][value] = true
+	}
+}
+
+// ClearFacets clears the chosen facet values, keeping the search
+func (f *productFilter) ClearFacets() {
+	for i := range f.selected {
+		f.selected[i] = make(map[string]bool)
+	}
+}
+
+// Active reports whether the filter excludes anything
+func (f *productFilter) Active() bool {
+	if len(f.terms) > 0 {
+		return true
+	}
+	for _, values := range f.selected {
+		if len(values) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether a product passes the filter and returns the
+// columns whose names matched search terms
+func (f *productFilter) Match(p *catalog.DataProduct) (bool, []string) {
+	return f.match(p, -1)
+}
+
+// match is Match ignoring the values chosen in one facet, skip, which is
+// what the counts of that facet's values are based on
+func (f *productFilter) match(p *catalog.DataProduct, skip int) (bool, []string) {
+	for i, values := range f.selected {
+		if i == skip || len(values) == 0 {
+			continue
+		}
+		found := false
+		for _, v := range discoverFacets[i].values(p) {
+			if values[v] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, nil
+		}
+	}
+	return f.search(p)
+}
+
+func (f *productFilter) search(p *catalog.DataProduct) (bool, []string) {
+	if len(f.terms) == 0 {
+		return true, nil
+	}
+
+	text := strings.ToLower(strings.Join(append([]string{p.Name, p.Description, p.Owner}, productTags(p)...), "\n"))
+	var columns []string
+	for _, col := range p.Columns {
+		name := strings.ToLower(col)
+		for _, term := range f.terms {
+			if strings.Contains(name, term) {
+				columns = append(columns, col)
+				break
+			}
+		}
+	}
+
+	for _, term := range f.terms {
+		if strings.Contains(text, term) {
+			continue
+		}
+		found := false
+		for _, col := range columns {
+			if strings.Contains(strings.ToLower(col), term) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, nil
+		}
+	}
+	return true, columns
+}
+
+// String describes the filter, such as
+// search "orders" · Domain: finance, sales
+func (f *productFilter) String() string {
+	var parts []string
+	if f.query != "" {
+		parts = append(parts, fmt.Sprintf("search %q", f.query))
+	}
+	for i, values := range f.selected {
+		if len(values) == 0 {
+			continue
+		}
+		chosen := make([]string, 0, len(values))
+		for v := range values {
+			chosen = append(chosen, v)
+		}
+		sort.Strings(chosen)
+		parts = append(parts, discoverFacets[i].name+": "+strings.Join(chosen, ", "))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// facetRow is a line of the facet menu: the name of a facet when value is
+// empty, otherwise one of its values
+type facetRow struct {
+	facet int
+	value string
+	count int
+}
+
+// facetMenu lists the values of each facet with the number of products
+// that have them, given the search and the other facets
+type facetMenu struct {
+	rows   []facetRow
+	cursor int
+	top    int
+	height int
+}
+
+// Update recounts the values of the facets among products
+func (m *facetMenu) Update(products []*catalog.DataProduct, filter *productFilter) {
+	m.rows = m.rows[:0]
+	for i, facet := range discoverFacets {
+		counts := make(map[string]int)
+		for value := range filter.selected[i] {
+			counts[value] = 0
+		}
+		for _, p := range products {
+			if ok, _ := filter.match(p, i); !ok {
+				continue
+			}
+			for _, v := range facet.values(p) {
+				counts[v]++
+			}
+		}
+		if len(counts) == 0 {
+			continue
+		}
+
+		values := make([]string, 0, len(counts))
+		for v := range counts {
+			values = append(values, v)
+		}
+		sort.Strings(values)
+		m.rows = append(m.rows, facetRow{facet: i})
+		for _, v := range values {
+			m.rows = append(m.rows, facetRow{facet: i, value: v, count: counts[v]})
+		}
+	}
+	m.Move(0)
+}
+
+// Selected returns the value under the cursor
+func (m *facetMenu) Selected() (facetRow, bool) {
+	if m.cursor >= len(m.rows) || m.rows[m.cursor].value == "" {
+		return facetRow{}, false
+	}
+	return m.rows[m.cursor], true
+}
+
+// Move moves the cursor by n values, stepping over the names of facets
+func (m *facetMenu) Move(n int) {
+	if len(m.rows) == 0 {
+		m.cursor, m.top = 0, 0
+		return
+	}
+	step := 1
+	if n < 0 {
+		step = -1
+	}
+	m.cursor = clamp(m.cursor+n, 0, len(m.rows)-1)
+	// Every facet has values, so turning back at the ends finds one
+	for m.rows[m.cursor].value == "" {
+		if next := m.cursor + step; next >= 0 && next < len(m.rows) {
+			m.cursor = next
+		} else {
+			step = -step
+		}
+	}
+
+	if m.cursor-1 < m.top {
+		// Keep the name of the facet in view above its first value
+		m.top = m.cursor - 1
+	} else if n := m.visibleRows(); m.cursor >= m.top+n {
+		m.top = m.cursor - n + 1
+	}
+}
+
+// visibleRows is the number of rows shown below the title and above the
+// key hints
+func (m *facetMenu) visibleRows() int {
+	n := m.height - 3
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// View renders the menu
+func (m *facetMenu) View(filter *productFilter, width int) string {
+	lines := []string{titleStyle.Render("Filter Data Products")}
+	if len(m.rows) == 0 {
+		lines = append(lines, "No data products")
+	}
+
+	n := m.visibleRows()
+	for i := m.top; i < len(m.rows) && i < m.top+n; i++ {
+		row := m.rows[i]
+		if row.value == "" {
+			lines = append(lines, promptStyle.Render(truncate(discoverFacets[row.facet].name, width)))
+			continue
+		}
+		check := "[ ] "
+		if filter.selected[row.facet][row.value] {
+			check = "[x] "
+		}
+		line := truncate(fmt.Sprintf("  %s%s (%d)", check, row.value, row.count), width)
+		if i == m.cursor {
+			line = selectedItemStyle.Render(pad(line, width, false))
+		}
+		lines = append(lines, line)
+	}
+	for len(lines) < n+1 {
+		lines = append(lines, "")
+	}
+	lines = append(lines, panelTypeStyle.Render(truncate("Space choose, F clear, Esc done", width)))
+	return strings.Join(lines, "\n")
+}
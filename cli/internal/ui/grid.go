@@ -0,0 +1,606 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/duckdb"
+	godb "github.com/marcboeker/go-duckdb"
+	"github.com/mattn/go-runewidth"
+)
+
+// maxCellWidth is the widest a column of the grid is shown; the inspector
+// shows the rest
+const maxCellWidth = 40
+
+// gridSeparator goes between the columns of the grid
+const gridSeparator = " │ "
+
+var (
+	gridHeaderStyle = resultHeaderStyle.Copy().Padding(0)
+
+	gridStatusStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#888888"))
+)
+
+// resultGrid shows a query result as cells with a cursor. The header stays
+// in place while rows scroll, columns scroll sideways and can be sorted,
+// hidden and moved, and a block of cells can be selected.
+type resultGrid struct {
+	result *duckdb.QueryResult
+	f      *Formatter
+	// cells are the values as one line of text, and widths the widths of
+	// the columns, up to maxCellWidth
+	cells  [][]string
+	widths []int
+
+	// columns are those shown, in order, and rows the order of the rows
+	columns []int
+	rows    []int
+
+	// sortCol is the column sorted by, or -1; sorted in the query rather
+	// than here when pushed
+	sortCol  int
+	sortDesc bool
+	pushed   bool
+
+	// row and col are the cursor, as positions in rows and columns, and
+	// top and left the first row and column shown
+	row  int
+	col  int
+	top  int
+	left int
+	// selecting marks a block from the anchor to the cursor
+	selecting bool
+	anchorRow int
+	anchorCol int
+
+	width   int
+	height  int
+	focused bool
+}
+
+func newResultGrid() resultGrid {
+	return resultGrid{sortCol: -1, width: 80, height: 20}
+}
+
+// SetResult shows a result. The order and hiding of columns is kept when
+// the result has the same columns, as when it is sorted in the query.
+func (g *resultGrid) SetResult(result *duckdb.QueryResult, vf ValueFormat) {
+	same := g.result != nil && result != nil && reflect.DeepEqual(g.result.Columns, result.Columns)
+	g.result = result
+	g.f = NewFormatter(result, vf)
+	g.cells, g.widths = nil, nil
+	g.row, g.top, g.selecting = 0, 0, false
+	g.sortCol, g.sortDesc, g.pushed = -1, false, false
+	if result == nil {
+		g.columns, g.rows = nil, nil
+		return
+	}
+
+	g.widths = make([]int, len(result.Columns))
+	for i, col := range result.Columns {
+		g.widths[i] = runewidth.StringWidth(tableText(col))
+	}
+	g.cells = make([][]string, len(result.Rows))
+	for r, row := range result.Rows {
+		g.cells[r] = make([]string, len(result.Columns))
+		for i := range result.Columns {
+			if i < len(row) {
+				g.cells[r][i] = tableText(g.f.Text(i, row[i]))
+			}
+			if n := runewidth.StringWidth(g.cells[r][i]); n > g.widths[i] {
+				g.widths[i] = n
+			}
+		}
+	}
+	for i, n := range g.widths {
+		if n > maxCellWidth {
+			g.widths[i] = maxCellWidth
+		} else if n < minColumnWidth {
+			g.widths[i] = minColumnWidth
+		}
+	}
+
+	if !same {
+		g.columns = make([]int, len(result.Columns))
+		for i := range g.columns {
+			g.columns[i] = i
+		}
+		g.col, g.left = 0, 0
+	}
+	g.sortRows()
+}
+
+// SetSize sets the size of the grid, borders included
+func (g *resultGrid) SetSize(width, height int) {
+	g.width, g.height = width, height
+	g.scroll()
+}
+
+// Empty reports whether there are no cells to show
+func (g *resultGrid) Empty() bool {
+	return g.result == nil || len(g.columns) == 0 || len(g.rows) == 0
+}
+
+// Cursor returns the column and row of the result under the cursor
+func (g *resultGrid) Cursor() (col, row int, ok bool) {
+	if g.Empty() {
+		return 0, 0, false
+	}
+	return g.columns[g.col], g.rows[g.row], true
+}
+
+// value returns a value of the result
+func (g *resultGrid) value(col, row int) interface{} {
+	if values := g.result.Rows[row]; col < len(values) {
+		return values[col]
+	}
+	return nil
+}
+
+// Move moves the cursor by rows and columns
+func (g *resultGrid) Move(rows, cols int) {
+	g.row = clamp(g.row+rows, 0, len(g.rows)-1)
+	g.col = clamp(g.col+cols, 0, len(g.columns)-1)
+	g.scroll()
+}
+
+// Sort sorts the rows by the column under the cursor, ascending, then
+// descending, then in the order of the result
+func (g *resultGrid) Sort() {
+	col, _, ok := g.Cursor()
+	if !ok {
+		return
+	}
+	switch {
+	case g.sortCol != col || g.pushed:
+		g.sortCol, g.sortDesc = col, false
+	case !g.sortDesc:
+		g.sortDesc = true
+	default:
+		g.sortCol = -1
+	}
+	g.pushed = false
+	g.sortRows()
+}
+
+// NextPushedSort returns the column under the cursor and whether sorting
+// it in the query next is descending: it is once sorted ascending
+func (g *resultGrid) NextPushedSort() (string, bool, bool) {
+	col, _, ok := g.Cursor()
+	if !ok {
+		return "", false, false
+	}
+	return g.result.Columns[col], g.sortCol == col && !g.sortDesc, true
+}
+
+// SetPushedSort records that the result is sorted by a column in the query
+func (g *resultGrid) SetPushedSort(column string, desc bool) {
+	for i, name := range g.result.Columns {
+		if name == column {
+			g.sortCol, g.sortDesc, g.pushed = i, desc, true
+			return
+		}
+	}
+}
+
+func (g *resultGrid) sortRows() {
+	g.rows = make([]int, len(g.cells))
+	for i := range g.rows {
+		g.rows[i] = i
+	}
+	if g.sortCol >= 0 && !g.pushed {
+		col := g.sortCol
+		// Nulls last either way, like DuckDB
+		sort.SliceStable(g.rows, func(a, b int) bool {
+			x, y := g.value(col, g.rows[a]), g.value(col, g.rows[b])
+			if x == nil || y == nil {
+				return x != nil && y == nil
+			}
+			if g.sortDesc {
+				return compareValues(x, y) > 0
+			}
+			return compareValues(x, y) < 0
+		})
+	}
+	g.row = clamp(g.row, 0, len(g.rows)-1)
+	g.scroll()
+}
+
+// Hide hides the column under the cursor, unless it is the last one shown
+func (g *resultGrid) Hide() {
+	if len(g.columns) <= 1 {
+		return
+	}
+	g.columns = append(g.columns[:g.col], g.columns[g.col+1:]...)
+	g.selecting = false
+	g.Move(0, 0)
+}
+
+// ShowAll shows the hidden columns, where they were in the result
+func (g *resultGrid) ShowAll() {
+	if g.result == nil {
+		return
+	}
+	shown := make(map[int]bool)
+	for _, c := range g.columns {
+		shown[c] = true
+	}
+	for i := range g.result.Columns {
+		if shown[i] {
+			continue
+		}
+		// After the shown column that precedes it in the result
+		at := 0
+		for j, c := range g.columns {
+			if c < i {
+				at = j + 1
+			}
+		}
+		g.columns = append(g.columns[:at], append([]int{i}, g.columns[at:]...)...)
+		if at <= g.col {
+			g.col++
+		}
+	}
+	g.Move(0, 0)
+}
+
+// Hidden returns the number of hidden columns
+func (g *resultGrid) Hidden() int {
+	if g.result == nil {
+		return 0
+	}
+	return len(g.result.Columns) - len(g.columns)
+}
+
+// MoveColumn moves the column under the cursor left or right, with the
+// cursor
+func (g *resultGrid) MoveColumn(step int) {
+	to := g.col + step
+	if g.Empty() || to < 0 || to >= len(g.columns) {
+		return
+	}
+	g.columns[g.col], g.columns[to] = g.columns[to], g.columns[g.col]
+	g.col = to
+	g.scroll()
+}
+
+// ToggleSelection starts a selection at the cursor or drops it
+func (g *resultGrid) ToggleSelection() {
+	g.selecting = !g.selecting && !g.Empty()
+	g.anchorRow, g.anchorCol = g.row, g.col
+}
+
+// Selection returns the selected block as positions in rows and columns,
+// the cell under the cursor when nothing is selected
+func (g *resultGrid) Selection() (row1, col1, row2, col2 int) {
+	if !g.selecting {
+		return g.row, g.col, g.row, g.col
+	}
+	row1, row2 = g.anchorRow, g.row
+	if row1 > row2 {
+		row1, row2 = row2, row1
+	}
+	col1, col2 = g.anchorCol, g.col
+	if col1 > col2 {
+		col1, col2 = col2, col1
+	}
+	return row1, col1, row2, col2
+}
+
+// CopyText returns the selected cells, or those of the selected rows in
+// all columns shown with a header, as tab-separated lines
+func (g *resultGrid) CopyText(wholeRows bool) string {
+	if g.Empty() {
+		return ""
+	}
+	row1, col1, row2, col2 := g.Selection()
+	if !wholeRows && row1 == row2 && col1 == col2 {
+		// A single cell is copied as it is
+		c, r := g.columns[col1], g.rows[row1]
+		return g.f.Text(c, g.value(c, r))
+	}
+	if wholeRows {
+		col1, col2 = 0, len(g.columns)-1
+	}
+
+	var b strings.Builder
+	line := func(values []string) {
+		b.WriteString(strings.Join(values, "\t"))
+		b.WriteString("\n")
+	}
+	if wholeRows {
+		var names []string
+		for _, c := range g.columns[col1 : col2+1] {
+			names = append(names, tableText(g.result.Columns[c]))
+		}
+		line(names)
+	}
+	for _, r := range g.rows[row1 : row2+1] {
+		var values []string
+		for _, c := range g.columns[col1 : col2+1] {
+			values = append(values, g.cells[r][c])
+		}
+		line(values)
+	}
+	return b.String()
+}
+
+// Shown returns the result as shown: the columns not hidden, in order,
+// and the rows as sorted
+func (g *resultGrid) Shown() *duckdb.QueryResult {
+	if g.result == nil {
+		return nil
+	}
+	shown := &duckdb.QueryResult{Rows: make([][]interface{}, 0, len(g.rows))}
+	typed := len(g.result.ColumnTypes) == len(g.result.Columns)
+	for _, c := range g.columns {
+		shown.Columns = append(shown.Columns, g.result.Columns[c])
+		if typed {
+			shown.ColumnTypes = append(shown.ColumnTypes, g.result.ColumnTypes[c])
+		}
+	}
+	for _, r := range g.rows {
+		row := make([]interface{}, len(g.columns))
+		for i, c := range g.columns {
+			row[i] = g.value(c, r)
+		}
+		shown.Rows = append(shown.Rows, row)
+	}
+	return shown
+}
+
+// Inspect returns the name and type of the column under the cursor and
+// its value in full, with JSON indented
+func (g *resultGrid) Inspect() (title, text string, ok bool) {
+	col, row, ok := g.Cursor()
+	if !ok {
+		return "", "", false
+	}
+	t := g.f.Type(col)
+	title = fmt.Sprintf("%s %s, row %d", g.result.Columns[col], t, g.row+1)
+
+	v := g.value(col, row)
+	text = g.f.Text(col, v)
+	if v == nil {
+		return title, text, true
+	}
+	switch t.ID {
+	case "STRUCT", "LIST", "MAP", "JSON":
+	default:
+		if trimmed := strings.TrimSpace(text); !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+			return title, text, true
+		}
+	}
+	var indented bytes.Buffer
+	if json.Indent(&indented, []byte(text), "", "  ") == nil {
+		text = indented.String()
+	}
+	return title, text, true
+}
+
+// visibleRows is the number of rows below the header and above the status
+// line
+func (g *resultGrid) visibleRows() int {
+	n := g.height - 4
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// scroll keeps the cursor on the screen
+func (g *resultGrid) scroll() {
+	if g.row < g.top {
+		g.top = g.row
+	} else if n := g.visibleRows(); g.row >= g.top+n {
+		g.top = g.row - n + 1
+	}
+	if g.top < 0 {
+		g.top = 0
+	}
+
+	if g.col < g.left {
+		g.left = g.col
+	}
+	for g.left < g.col && g.lastVisible() < g.col {
+		g.left++
+	}
+}
+
+// lastVisible returns the last column shown whole from left
+func (g *resultGrid) lastVisible() int {
+	width := g.width - 2
+	last := g.left
+	for i := g.left; i < len(g.columns); i++ {
+		width -= g.widths[g.columns[i]]
+		if i > g.left {
+			width -= runewidth.StringWidth(gridSeparator)
+		}
+		if width < 0 {
+			break
+		}
+		last = i
+	}
+	return last
+}
+
+// View renders the grid
+func (g *resultGrid) View(style lipgloss.Style) string {
+	inner := g.width - 2
+	var lines []string
+	if g.Empty() {
+		lines = append(lines, "No rows returned")
+	} else {
+		row1, col1, row2, col2 := g.Selection()
+		line := func(text func(c int) string, cellStyle func(c int) *lipgloss.Style) string {
+			var b strings.Builder
+			room := inner
+			for i := g.left; i < len(g.columns) && room > 0; i++ {
+				if i > g.left {
+					sep := truncate(gridSeparator, room)
+					b.WriteString(sep)
+					room -= runewidth.StringWidth(sep)
+				}
+				c := g.columns[i]
+				width := g.widths[c]
+				if width > room {
+					width = room
+				}
+				cell := pad(text(c), width, g.f.Type(c).Numeric())
+				if s := cellStyle(i); s != nil {
+					cell = s.Render(cell)
+				}
+				b.WriteString(cell)
+				room -= width
+			}
+			return b.String()
+		}
+
+		lines = append(lines, line(func(c int) string {
+			name := tableText(g.result.Columns[c])
+			if c == g.sortCol {
+				name = sortArrow(g.sortDesc) + name
+			}
+			return name
+		}, func(i int) *lipgloss.Style {
+			return &gridHeaderStyle
+		}))
+		for r := g.top; r < len(g.rows) && r < g.top+g.visibleRows(); r++ {
+			cells := g.cells[g.rows[r]]
+			lines = append(lines, line(func(c int) string {
+				return cells[c]
+			}, func(i int) *lipgloss.Style {
+				switch {
+				case g.focused && r == g.row && i == g.col:
+					return &selectedItemStyle
+				case g.selecting && r >= row1 && r <= row2 && i >= col1 && i <= col2:
+					return &selectedStatementStyle
+				}
+				return nil
+			}))
+		}
+	}
+	for len(lines) < g.visibleRows()+1 {
+		lines = append(lines, "")
+	}
+	lines = append(lines, gridStatusStyle.Render(truncate(g.status(), inner)))
+
+	return style.Copy().Width(inner).Height(g.height - 2).Render(strings.Join(lines, "\n"))
+}
+
+// status describes the position, sorting and hidden columns, with the
+// keys when the grid has the focus
+func (g *resultGrid) status() string {
+	if g.result == nil {
+		return ""
+	}
+	parts := []string{fmt.Sprintf("%d rows", len(g.rows))}
+	if col, _, ok := g.Cursor(); ok {
+		parts[0] = fmt.Sprintf("Row %d/%d, %s", g.row+1, len(g.rows), g.result.Columns[col])
+	}
+	if g.sortCol >= 0 {
+		sorted := fmt.Sprintf("sorted by %s %s", g.result.Columns[g.sortCol], sortArrow(g.sortDesc))
+		if g.pushed {
+			sorted += " in the query"
+		}
+		parts = append(parts, sorted)
+	}
+	if n := g.Hidden(); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d hidden", n))
+	}
+	if g.focused {
+		parts = append(parts, "Enter inspect, s/S sort, -/+ hide/show, </> move, v select, y/Y copy")
+	}
+	return strings.Join(parts, " · ")
+}
+
+func sortArrow(desc bool) string {
+	if desc {
+		return "↓"
+	}
+	return "↑"
+}
+
+// compareValues orders two values of a column that aren't null
+func compareValues(a, b interface{}) int {
+	switch x := a.(type) {
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y)
+		}
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			switch {
+			case x.Before(y):
+				return -1
+			case x.After(y):
+				return 1
+			}
+			return 0
+		}
+	case bool:
+		if y, ok := b.(bool); ok && x != y {
+			if y {
+				return -1
+			}
+			return 1
+		}
+		return 0
+	case *big.Int:
+		if y, ok := b.(*big.Int); ok {
+			return x.Cmp(y)
+		}
+	}
+	if x, ok := number(a); ok {
+		if y, ok := number(b); ok {
+			return x.Cmp(y)
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// number returns a numeric value exactly, if it is one
+func number(v interface{}) (*big.Float, bool) {
+	switch value := v.(type) {
+	case godb.Decimal:
+		if value.Value == nil {
+			return nil, false
+		}
+		f := new(big.Float).SetInt(value.Value)
+		scale := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(value.Scale)), nil))
+		return f.Quo(f, scale), true
+	case *big.Int:
+		return new(big.Float).SetInt(value), true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return new(big.Float).SetInt64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Float).SetUint64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		if f := rv.Float(); f == f {
+			return big.NewFloat(f), true
+		}
+	}
+	return nil, false
+}
+
+func clamp(n, lo, hi int) int {
+	if n > hi {
+		n = hi
+	}
+	if n < lo {
+		n = lo
+	}
+	return n
+}
@@ -0,0 +1,242 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/catalog"
+	"github.com/mattn/go-runewidth"
+)
+
+// panelWidth is the width of the product panel, borders included
+const panelWidth = 40
+
+var (
+	panelStyle = lipgloss.NewStyle().
+			BorderStyle(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("#0077B6"))
+
+	panelTypeStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#888888"))
+)
+
+// panelRow is a line of the product panel: a product, or a column or
+// struct field of its schema
+type panelRow struct {
+	product string
+	// name is what inserting the row puts in the editor: the qualified
+	// name of the product, or the path of the column or field
+	name     string
+	label    string
+	dataType string
+	depth    int
+}
+
+// productPanel lists the data products of the catalog beside the editor,
+// with which are registered in the session and the schemas of those
+// expanded
+type productPanel struct {
+	products   []string
+	registered map[string]bool
+	// pending are products being registered or unregistered
+	pending  map[string]bool
+	expanded map[string]bool
+	schemas  map[string]*catalog.TableSchema
+	errors   map[string]string
+
+	cursor  int
+	top     int
+	width   int
+	height  int
+	open    bool
+	focused bool
+}
+
+func newProductPanel(products []string) productPanel {
+	return productPanel{
+		products:   products,
+		registered: make(map[string]bool),
+		pending:    make(map[string]bool),
+		expanded:   make(map[string]bool),
+		schemas:    make(map[string]*catalog.TableSchema),
+		errors:     make(map[string]string),
+		width:      panelWidth,
+	}
+}
+
+// SetHeight sets the height of the panel, borders included
+func (p *productPanel) SetHeight(height int) {
+	p.height = height
+	p.scroll()
+}
+
+// rows returns the lines of the panel
+func (p *productPanel) rows() []panelRow {
+	var rows []panelRow
+	for _, product := range p.products {
+		rows = append(rows, panelRow{product: product, name: qualifiedName(product), label: product})
+		if !p.expanded[product] {
+			continue
+		}
+		schema := p.schemas[product]
+		switch {
+		case p.errors[product] != "":
+			rows = append(rows, panelRow{product: product, label: p.errors[product], depth: 1})
+		case schema == nil:
+			rows = append(rows, panelRow{product: product, label: "loading…", depth: 1})
+		default:
+			for _, col := range schema.Columns {
+				rows = appendColumn(rows, product, "", col.Name, col.Type, 1)
+			}
+		}
+	}
+	return rows
+}
+
+// appendColumn adds the row of a column or field and those of its fields,
+// for structs
+func appendColumn(rows []panelRow, product, parent, name string, t *catalog.DataType, depth int) []panelRow {
+	path := quoteName(name)
+	if parent != "" {
+		path = parent + "." + path
+	}
+	row := panelRow{product: product, name: path, label: name, depth: depth}
+	if t == nil {
+		return append(rows, row)
+	}
+	if t.Kind == catalog.KindStruct {
+		row.dataType = "struct"
+		rows = append(rows, row)
+		for _, f := range t.Fields {
+			rows = appendColumn(rows, product, path, f.Name, f.Type, depth+1)
+		}
+		return rows
+	}
+	row.dataType = t.String()
+	return append(rows, row)
+}
+
+// qualifiedName quotes the parts of a domain.product name as needed
+func qualifiedName(product string) string {
+	parts := strings.Split(product, ".")
+	for i, part := range parts {
+		parts[i] = quoteName(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// Selected returns the row under the cursor
+func (p *productPanel) Selected() (panelRow, bool) {
+	rows := p.rows()
+	if p.cursor >= len(rows) {
+		return panelRow{}, false
+	}
+	return rows[p.cursor], true
+}
+
+// Move moves the cursor by n rows
+func (p *productPanel) Move(n int) {
+	p.cursor += n
+	if last := len(p.rows()) - 1; p.cursor > last {
+		p.cursor = last
+	}
+	if p.cursor < 0 {
+		p.cursor = 0
+	}
+	p.scroll()
+}
+
+// Toggle expands or collapses the product under the cursor, moving the
+// cursor to it, and reports whether it was expanded
+func (p *productPanel) Toggle() (string, bool) {
+	row, ok := p.Selected()
+	if !ok {
+		return "", false
+	}
+	p.expanded[row.product] = !p.expanded[row.product]
+	for i, r := range p.rows() {
+		if r.product == row.product {
+			p.cursor = i
+			break
+		}
+	}
+	p.scroll()
+	return row.product, p.expanded[row.product]
+}
+
+// visibleRows is the number of rows shown below the title and above the
+// key hints
+func (p *productPanel) visibleRows() int {
+	n := p.height - 4
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+func (p *productPanel) scroll() {
+	if p.cursor < p.top {
+		p.top = p.cursor
+	} else if n := p.visibleRows(); p.cursor >= p.top+n {
+		p.top = p.cursor - n + 1
+	}
+}
+
+// View renders the panel
+func (p *productPanel) View() string {
+	inner := p.width - 2
+	lines := []string{promptStyle.Render(truncate("Data Products", inner))}
+
+	rows := p.rows()
+	if len(rows) == 0 {
+		lines = append(lines, truncate("No data products", inner))
+	}
+	n := p.visibleRows()
+	for i := p.top; i < len(rows) && i < p.top+n; i++ {
+		lines = append(lines, p.rowView(rows[i], i == p.cursor, inner))
+	}
+	for len(lines) < n+1 {
+		lines = append(lines, "")
+	}
+	lines = append(lines, panelTypeStyle.Render(truncate("Space register, Enter expand, i insert", inner)))
+
+	return panelStyle.Copy().Width(inner).Height(p.height - 2).Render(strings.Join(lines, "\n"))
+}
+
+// rowView renders a row: products with whether they are registered and
+// expanded, columns with their types
+func (p *productPanel) rowView(row panelRow, selected bool, width int) string {
+	var text, dataType string
+	if row.depth == 0 {
+		arrow, state := "▸ ", "○ "
+		if p.expanded[row.product] {
+			arrow = "▾ "
+		}
+		switch {
+		case p.pending[row.product]:
+			state = "◌ "
+		case p.registered[row.product]:
+			state = "● "
+		}
+		text = truncate(arrow+state+row.label, width)
+	} else {
+		text = truncate(strings.Repeat("  ", row.depth)+row.label, width)
+		if row.dataType != "" {
+			if room := width - runewidth.StringWidth(text) - 1; room >= minColumnWidth {
+				dataType = truncate(row.dataType, room)
+			}
+		}
+	}
+
+	if selected && p.focused {
+		line := text
+		if dataType != "" {
+			line += " " + dataType
+		}
+		return selectedItemStyle.Render(pad(line, width, false))
+	}
+	if dataType != "" {
+		return text + " " + panelTypeStyle.Render(dataType)
+	}
+	return text
+}
@@ -2,8 +2,11 @@ package ui
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	"github.com/charmbracelet/bubbletea"
@@ -11,8 +14,12 @@ import (
 	"github.com/frocore/fedramp-data-mesh/cli/internal/catalog"
 	"github.com/frocore/fedramp-data-mesh/cli/internal/config"
 	"github.com/frocore/fedramp-data-mesh/cli/internal/duckdb"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/history"
 	"github.com/frocore/fedramp-data-mesh/cli/internal/logging"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/marking"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/securefile"
 	"github.com/frocore/fedramp-data-mesh/cli/internal/security"
+	"github.com/mattn/go-runewidth"
 )
 
 // Styles
@@ -40,120 +47,374 @@ var (
 	
 	resultCellStyle = lipgloss.NewStyle().
 		Padding(0, 1)
+	
+	bannerStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("#FFFFFF")).
+		Align(lipgloss.Center)
+	
+	selectedStatementStyle = lipgloss.NewStyle().
+		Background(lipgloss.Color("#1D3557"))
+	
+	selectedItemStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#FFFFFF")).
+		Background(lipgloss.Color("#0077B6"))
+	
+	completionStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#FFFFFF")).
+		Background(lipgloss.Color("#343A40"))
+	
+	noticeStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#2A9D8F")).
+		Padding(0, 1)
+	
+	resultStyle = lipgloss.NewStyle().
+		BorderStyle(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#0077B6"))
 )
 
+// editorHeight is the number of lines of the SQL editor
+const editorHeight = 8
+
+// exportFormats are the formats results are exported in, by Tab, and
+// exportExtensions the extensions of their files
+var (
+	exportFormats    = []string{"csv", "json", "ndjson", "markdown", "html", "table", "parquet", "arrow", "xlsx"}
+	exportExtensions = map[string]string{"markdown": ".md", "table": ".txt"}
+)
+
+// Steps of opening the query TUI; registering the first product is added
+// once the catalog lists it
+const (
+	startDuckDB = iota
+	startCatalog
+	startHistory
+	startNames
+)
+
+// bannerColors are the customary colors of classification banners
+var bannerColors = map[string]string{
+	marking.Unclassified:           "#007A33",
+	marking.ControlledUnclassified: "#502B85",
+	marking.Confidential:           "#0033A0",
+}
+
+// renderBanner renders the classification banner across the screen
+func renderBanner(mark marking.Marking, width int) string {
+	color, ok := bannerColors[mark.Classification]
+	if !ok {
+		color = "#C8102E"
+	}
+	return bannerStyle.Copy().Background(lipgloss.Color(color)).Width(width).Render(mark.Banner())
+}
+
 // Model represents the UI state
 type QueryModel struct {
 	cfg           *config.Config
 	secCtx        *security.SecurityContext
 	log           *logging.Logger
 	db            *duckdb.Connection
-	queryInput    textinput.Model
-	resultViewport viewport.Model
-	dataProducts  []string
-	selectedProduct string
+	editor        sqlEditor
+	grid          resultGrid
+	catalogClient *catalog.Client
+	marking       marking.Marking
+	values        ValueFormat
 	result        *duckdb.QueryResult
 	error         string
 	width         int
 	height        int
+	
+	// history of queries; recall is the entry shown by Ctrl+P and Ctrl+N,
+	// -1 for none, and draft the text it replaced
+	history       *history.History
+	recall        int
+	draft         string
+	
+	// Ctrl+R search of the history
+	searching     bool
+	search        textinput.Model
+	matches       []history.Entry
+	match         int
+	
+	// Completion of names while writing queries: the offered completions,
+	// the selected one and where the text they replace starts
+	completer     completer
+	completions   []completion
+	completion    int
+	completeFrom  int
+	
+	// Products registered in the session, by name, with their
+	// classifications; shown are those of the products the results on
+	// screen were read from, and raise the classification asked for
+	panel         productPanel
+	registered    map[string]string
+	shown         map[string]string
+	raise         string
+	
+	// The query the results are of, and the inspector of the cell under
+	// the grid's cursor
+	lastQuery     string
+	inspecting    bool
+	inspector     viewport.Model
+	inspectTitle  string
+	notice        string
+	
+	// Export of the results to a file: the results shown, or those of
+	// running the query again
+	exporting     bool
+	exportPath    textinput.Model
+	exportFormat  int
+	exportRerun   bool
+	
+	// Opening DuckDB, the catalog and the history in the background, and
+	// registering the product asked for, or else the first one
+	startup       startup
+	initialProduct string
+	startupProduct string
+	registerStep  int
 }
 
 func NewQueryModel(cfg *config.Config, secCtx *security.SecurityContext, log *logging.Logger, initialProduct string) *QueryModel {
-	// Create text input for SQL queries
-	ti := textinput.New()
-	ti.Placeholder = "Enter SQL query"
-	ti.Width = 80
-	ti.Focus()
-	
-	// Create viewport for results
-	vp := viewport.New(80, 20)
-	vp.Style = lipgloss.NewStyle().
-		BorderStyle(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("#0077B6"))
-	
-	// Create DB connection
-	db, err := duckdb.NewConnection(cfg, secCtx)
-	if err != nil {
-		log.Errorf("Failed to create DuckDB connection: %v", err)
-	}
+	// Create editor for SQL queries
+	editor := newSQLEditor(80, editorHeight)
+	editor.Focus()
 	
-	// Fetch available data products
-	catalogClient, err := catalog.NewClient(cfg, secCtx, log)
-	if err != nil {
-		log.Errorf("Failed to create catalog client: %v", err)
-	}
+	search := textinput.New()
+	search.Placeholder = "Search history"
+	search.Width = 60
 	
-	var products []string
-	if catalogClient != nil {
-		products, err = catalogClient.ListDataProducts("")
-		if err != nil {
-			log.Errorf("Failed to fetch data products: %v", err)
-		}
-	}
+	exportPath := textinput.New()
+	exportPath.Placeholder = "File to write"
+	exportPath.Width = 60
 	
-	selectedProduct := initialProduct
-	if selectedProduct == "" && len(products) > 0 {
-		selectedProduct = products[0]
-	}
+	// Create viewport for inspecting values of results
+	vp := viewport.New(80, 20)
+	vp.Style = resultStyle
 	
-	if db != nil && catalogClient != nil && selectedProduct != "" {
-		// Resolve product path
-		path, err := catalogClient.GetDataProductPath(selectedProduct)
-		if err != nil {
-			log.Errorf("Failed to resolve data product path: %v", err)
-		} else {
-			// Register the data product with DuckDB
-			if err := db.RegisterDataProduct(selectedProduct, path); err != nil {
-				log.Errorf("Failed to register data product: %v", err)
-			}
-		}
+	panel := newProductPanel(nil)
+	if initialProduct != "" {
+		panel.products = []string{initialProduct}
 	}
 	
-	return &QueryModel{
+	m := &QueryModel{
 		cfg:            cfg,
 		secCtx:         secCtx,
 		log:            log,
-		db:             db,
-		queryInput:     ti,
-		resultViewport: vp,
-		dataProducts:   products,
-		selectedProduct: selectedProduct,
+		editor:         editor,
+		grid:           newResultGrid(),
+		inspector:      vp,
+		values:         DefaultValueFormat(),
 		error:          "",
 		width:          80,
 		height:         24,
+		recall:         -1,
+		search:         search,
+		exportPath:     exportPath,
+		panel:          panel,
+		registered:     make(map[string]string),
+		startup:        newStartup("Opening DuckDB", "Listing data products", "Loading query history", "Loading names for completion"),
+		initialProduct: initialProduct,
+		registerStep:   -1,
 	}
+	m.updateMarking()
+	return m
+}
+
+// RaiseMarking marks results higher than the products' classification
+func (m *QueryModel) RaiseMarking(classification string) error {
+	mark, err := m.marking.Raise(classification)
+	if err != nil {
+		return err
+	}
+	m.marking = mark
+	m.raise = classification
+	return nil
+}
+
+// SetValueFormat sets how values of results are rendered
+func (m *QueryModel) SetValueFormat(vf ValueFormat) {
+	m.values = vf
 }
 
 // Init implements bubbletea.Model
 func (m *QueryModel) Init() tea.Cmd {
-	return textinput.Blink
+	return tea.Batch(textinput.Blink, m.runStep(startDuckDB), m.runStep(startCatalog), m.runStep(startHistory))
 }
 
-// Update implements bubbletea.Model
+// Update implements bubbletea.Model. Terminals send Ctrl+Enter as Ctrl+J,
+// if they tell it from Enter at all, so F5 runs the statement too.
 func (m *QueryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	var (
-		tiCmd  tea.Cmd
-		vpCmd  tea.Cmd
-	)
-	
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		m.notice = ""
+		if m.searching {
+			return m.updateSearch(msg)
+		}
+		if m.exporting {
+			return m.updateExport(msg)
+		}
+		if m.inspecting {
+			switch msg.String() {
+			case "ctrl+c":
+				return m, tea.Quit
+			case "esc", "enter", "q":
+				m.inspecting = false
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.inspector, cmd = m.inspector.Update(msg)
+			return m, cmd
+		}
+		if len(m.completions) > 0 {
+			switch msg.String() {
+			case "tab":
+				item := m.completions[m.completion]
+				m.editor.Replace(m.completeFrom, m.editor.offset(), item.text)
+				m.completions = nil
+				return m, nil
+			case "up", "ctrl+p":
+				if m.completion > 0 {
+					m.completion--
+				}
+				return m, nil
+			case "down", "ctrl+n":
+				if m.completion+1 < len(m.completions) {
+					m.completion++
+				}
+				return m, nil
+			case "esc":
+				m.completions = nil
+				return m, nil
+			}
+		}
+		
 		switch msg.String() {
 		case "ctrl+c", "esc":
 			return m, tea.Quit
 		
-		case "enter":
-			if m.queryInput.Value() != "" {
-				return m, m.executeQuery
+		case "ctrl+t":
+			// Retry the steps of opening that failed
+			if m.startup.Running() {
+				return m, nil
+			}
+			var cmds []tea.Cmd
+			for _, step := range m.startup.Failed() {
+				cmds = append(cmds, m.runStep(step))
+			}
+			return m, tea.Batch(cmds...)
+		
+		case "ctrl+j", "f5":
+			// The statement at the cursor
+			statements, current := m.editor.Statements()
+			if current < 0 {
+				return m, nil
+			}
+			return m, m.executeQuery(m.statementText(statements[current]))
+		
+		case "alt+enter":
+			// All statements in turn
+			statements, _ := m.editor.Statements()
+			if len(statements) == 0 {
+				return m, nil
 			}
+			queries := make([]string, len(statements))
+			for i, s := range statements {
+				queries[i] = m.statementText(s)
+			}
+			return m, m.executeQuery(queries...)
+		
+		case "ctrl+@":
+			// Ctrl+Space
+			if m.editor.Focused() {
+				m.updateCompletions(true)
+			}
+			return m, nil
+		
+		case "ctrl+r":
+			if m.history != nil {
+				m.searching = true
+				m.search.SetValue("")
+				m.search.Focus()
+				m.matches = m.history.Search("")
+				m.match = 0
+			}
+			return m, nil
+		
+		case "ctrl+p", "ctrl+n":
+			if m.history != nil && m.editor.Focused() {
+				if msg.String() == "ctrl+p" {
+					m.recallEntry(-1)
+				} else {
+					m.recallEntry(1)
+				}
+			}
+			return m, nil
 		
 		case "tab":
-			// Toggle between input and result view
-			if m.queryInput.Focused() {
-				m.queryInput.Blur()
+			// Cycle through the editor, the result view and the product
+			// panel when it is open
+			switch {
+			case m.editor.Focused():
+				m.editor.Blur()
 				// Allow scrolling in results
+			case !m.panel.focused && m.panel.open:
+				m.panel.focused = true
+			default:
+				m.panel.focused = false
+				m.editor.Focus()
+			}
+			return m, nil
+		
+		case "ctrl+s":
+			// Export the results
+			if m.result == nil && m.lastQuery == "" {
+				return m, nil
+			}
+			if !m.marking.Known() {
+				m.error = unmarkedMessage("exported")
+				return m, nil
+			}
+			m.exporting = true
+			m.exportRerun = m.result == nil
+			if m.exportPath.Value() == "" {
+				m.exportPath.SetValue("results" + exportExtension(exportFormats[m.exportFormat]))
+			}
+			m.exportPath.CursorEnd()
+			m.exportPath.Focus()
+			m.completions = nil
+			return m, nil
+		
+		case "ctrl+o", "f2":
+			// Open the product panel, or close it
+			m.panel.open = !m.panel.open
+			m.panel.focused = m.panel.open
+			if m.panel.open {
+				m.editor.Blur()
+			} else {
+				m.editor.Focus()
+			}
+			m.completions = nil
+			m.layout()
+			return m, nil
+		}
+		
+		if m.panel.focused {
+			return m.updatePanel(msg)
+		}
+		
+		if !m.editor.Focused() {
+			return m.updateGrid(msg)
+		}
+		
+		if m.editor.Focused() {
+			m.editor.Update(msg)
+			m.recall = -1
+			
+			// Completions follow the word being typed
+			if msg.Type == tea.KeyRunes || msg.Type == tea.KeyBackspace && len(m.completions) > 0 {
+				m.updateCompletions(false)
 			} else {
-				m.queryInput.Focus()
+				m.completions = nil
 			}
 			return m, nil
 		}
@@ -161,25 +422,713 @@ func (m *QueryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
-		m.resultViewport.Width = msg.Width
-		m.resultViewport.Height = msg.Height - 10 // Leave room for query input and headers
+		m.layout()
 		return m, nil
-		
+	
 	case queryResultMsg:
 		m.result = msg.result
 		m.error = ""
-		m.resultViewport.SetContent(m.formatResult())
+		m.grid.SetResult(msg.result, m.values)
+		if msg.sortColumn != "" {
+			m.grid.SetPushedSort(msg.sortColumn, msg.sortDesc)
+		}
+		m.shown = msg.classifications
+		m.updateMarking()
+		
+		// Queries may have created tables or views
+		m.refreshTables()
 		return m, nil
+	
+	case productRegisteredMsg:
+		delete(m.panel.pending, msg.product)
+		// Failing to register the product of the startup fails its step
+		// rather than showing an error
+		starting := m.registerStep >= 0 && msg.product == m.startupProduct && m.startup.State(m.registerStep) == stepRunning
+		if starting {
+			m.startup.Finish(m.registerStep, msg.err)
+		}
+		if msg.err != nil {
+			if !starting {
+				m.error = msg.err.Error()
+			}
+			return m, nil
+		}
+		m.panel.registered[msg.product] = msg.registered
+		if msg.registered {
+			m.registered[msg.product] = msg.classification
+			m.refreshTables()
+		} else {
+			delete(m.registered, msg.product)
+			m.completer.RemoveTable(msg.product)
+		}
+		m.updateMarking()
 		
+		// The catalog's schema stands in for a product DuckDB can't list
+		if _, ok := m.completer.lookup(msg.product); msg.registered && !ok && m.panel.schemas[msg.product] == nil {
+			return m, m.loadSchema(msg.product)
+		}
+		return m, nil
+	
+	case productSchemaMsg:
+		if msg.err != nil {
+			m.panel.errors[msg.product] = msg.err.Error()
+		} else {
+			m.panel.schemas[msg.product] = msg.schema
+			m.refreshTables()
+		}
+		return m, nil
+	
+	case duckDBOpenedMsg:
+		m.startup.Finish(startDuckDB, msg.err)
+		if msg.err != nil {
+			return m, nil
+		}
+		m.db = msg.db
+		return m, tea.Batch(m.runStep(startNames), m.startRegistration())
+	
+	case productsListedMsg:
+		if msg.client != nil {
+			m.catalogClient = msg.client
+		}
+		m.startup.Finish(startCatalog, msg.err)
+		if msg.err != nil {
+			return m, nil
+		}
+		products := msg.products
+		if m.initialProduct != "" && !containsString(products, m.initialProduct) {
+			products = append([]string{m.initialProduct}, products...)
+		}
+		m.panel.products = products
+		m.panel.Move(0)
+		return m, m.startRegistration()
+	
+	case historyLoadedMsg:
+		m.startup.Finish(startHistory, msg.err)
+		m.history = msg.history
+		return m, nil
+	
+	case namesLoadedMsg:
+		m.startup.Finish(startNames, msg.err)
+		for _, t := range msg.tables {
+			m.completer.SetTable(t)
+		}
+		m.completer.SetFunctions(msg.functions)
+		m.refreshTables()
+		return m, nil
+	
+	case spinner.TickMsg:
+		return m, m.startup.Update(msg)
+	
 	case queryErrorMsg:
 		m.error = msg.error
 		return m, nil
+	
+	case exportDoneMsg:
+		if msg.err != nil {
+			m.error = fmt.Sprintf("Export failed: %v", msg.err)
+			return m, nil
+		}
+		m.error = ""
+		m.notice = fmt.Sprintf("Wrote %d rows to %s, encrypted (dmesh decrypt %s to read it)", msg.rows, msg.path, msg.path)
+		return m, nil
+	}
+	
+	return m, nil
+}
+
+// updateExport handles keys while choosing where and how to export: Tab
+// changes the format, Ctrl+T whether the query runs again and Enter
+// writes the file
+func (m *QueryModel) updateExport(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	
+	case "esc":
+		m.exporting = false
+		return m, nil
+	
+	case "tab", "shift+tab":
+		step := 1
+		if msg.String() == "shift+tab" {
+			step = len(exportFormats) - 1
+		}
+		from := exportFormats[m.exportFormat]
+		m.exportFormat = (m.exportFormat + step) % len(exportFormats)
+		
+		// The file follows the format when it was named after the last one
+		path := m.exportPath.Value()
+		if old := exportExtension(from); strings.HasSuffix(path, old) {
+			m.exportPath.SetValue(strings.TrimSuffix(path, old) + exportExtension(exportFormats[m.exportFormat]))
+			m.exportPath.CursorEnd()
+		}
+		return m, nil
+	
+	case "ctrl+t":
+		if m.lastQuery != "" && m.result != nil {
+			m.exportRerun = !m.exportRerun
+		}
+		return m, nil
+	
+	case "enter":
+		path := strings.TrimSpace(m.exportPath.Value())
+		if path == "" {
+			return m, nil
+		}
+		m.exporting = false
+		return m, m.exportResults(path, exportFormats[m.exportFormat])
+	}
+	
+	var cmd tea.Cmd
+	m.exportPath, cmd = m.exportPath.Update(msg)
+	return m, cmd
+}
+
+// exportResults writes the results shown, or those of running the query
+// again, to an encrypted file like dmesh query --out-file: marked as the
+// results on screen, after checking access to the products and recorded
+// in the audit log
+func (m *QueryModel) exportResults(path, format string) tea.Cmd {
+	rerun := m.exportRerun || format == "parquet"
+	query, mark := m.lastQuery, m.marking
+	db, secCtx, values := m.db, m.secCtx, m.values
+	var products []string
+	var result *duckdb.QueryResult
+	if rerun {
+		products = m.productNames()
+	} else {
+		for name := range m.shown {
+			products = append(products, name)
+		}
+		sort.Strings(products)
+		result = m.grid.Shown()
+	}
+	
+	return func() tea.Msg {
+		for _, product := range products {
+			canAccess, err := secCtx.CanAccessDataProduct(product)
+			if err != nil {
+				return exportDoneMsg{err: fmt.Errorf("failed to check access: %w", err)}
+			}
+			if !canAccess {
+				return exportDoneMsg{err: fmt.Errorf("access denied to data product: %s", product)}
+			}
+		}
+		
+		store, err := securefile.NewStore(m.cfg, secCtx)
+		if err != nil {
+			return exportDoneMsg{err: err}
+		}
+		var rows int64
+		if rerun {
+			if db == nil {
+				return exportDoneMsg{err: fmt.Errorf("database connection not initialized")}
+			}
+			rows, err = ExportQuery(db, store, query, path, format, mark, values, TableLayout{})
+		} else {
+			rows, err = ExportResult(store, result, path, format, mark, values, TableLayout{})
+		}
+		if err != nil {
+			return exportDoneMsg{err: err}
+		}
+		LogExport(m.log, products, query, path, format, mark, rows)
+		return exportDoneMsg{path: path, rows: rows}
+	}
+}
+
+// exportView renders the choices of an export
+func (m *QueryModel) exportView() string {
+	var b strings.Builder
+	format := exportFormats[m.exportFormat]
+	b.WriteString("Export results to: ")
+	b.WriteString(m.exportPath.View())
+	b.WriteString("\n\n")
+	b.WriteString(fmt.Sprintf("Format: %s (Tab to change)\n", format))
+	switch {
+	case format == "parquet":
+		b.WriteString("Rows: all rows of the query, run again; Parquet is written by DuckDB\n")
+	case m.exportRerun:
+		b.WriteString("Rows: all rows of the query, run again (Ctrl+T for the rows shown)\n")
+	default:
+		b.WriteString(fmt.Sprintf("Rows: the %d rows shown, as sorted and without hidden columns (Ctrl+T to run the query again)\n",
+			len(m.grid.rows)))
+	}
+	if m.marking.Known() {
+		b.WriteString(fmt.Sprintf("Marked: %s\n", m.marking.Banner()))
+	}
+	b.WriteString("\nEnter export, Esc cancel")
+	return b.String()
+}
+
+// exportExtension returns the file extension of an export format
+func exportExtension(format string) string {
+	if ext, ok := exportExtensions[format]; ok {
+		return ext
+	}
+	return "." + format
+}
+
+// updateGrid handles keys in the result grid: moving the cursor, sorting
+// here or in the query, hiding and moving columns, selecting and copying
+// cells and inspecting the one under the cursor
+func (m *QueryModel) updateGrid(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		m.grid.Move(-1, 0)
+	case "down", "j":
+		m.grid.Move(1, 0)
+	case "left", "h":
+		m.grid.Move(0, -1)
+	case "right", "l":
+		m.grid.Move(0, 1)
+	case "pgup":
+		m.grid.Move(-m.grid.visibleRows(), 0)
+	case "pgdown":
+		m.grid.Move(m.grid.visibleRows(), 0)
+	case "home", "g":
+		m.grid.Move(-len(m.grid.rows), 0)
+	case "end", "G":
+		m.grid.Move(len(m.grid.rows), 0)
+	
+	case "s":
+		m.grid.Sort()
+	case "S":
+		return m, m.sortInQuery()
+	case "-":
+		m.grid.Hide()
+	case "+", "=":
+		m.grid.ShowAll()
+	case "<", "shift+left":
+		m.grid.MoveColumn(-1)
+	case ">", "shift+right":
+		m.grid.MoveColumn(1)
+	
+	case "v":
+		m.grid.ToggleSelection()
+	case "y":
+		m.copyCells(false)
+	case "Y":
+		m.copyCells(true)
+	
+	case "enter":
+		title, text, ok := m.grid.Inspect()
+		if !ok {
+			return m, nil
+		}
+		m.inspectTitle = title
+		m.inspector.SetContent(lipgloss.NewStyle().Width(m.inspector.Width - 2).Render(text))
+		m.inspector.GotoTop()
+		m.inspecting = true
+	}
+	return m, nil
+}
+
+// sortInQuery runs the query of the results again, sorted by the column
+// under the cursor in DuckDB rather than the rows at hand
+func (m *QueryModel) sortInQuery() tea.Cmd {
+	column, desc, ok := m.grid.NextPushedSort()
+	if !ok || m.lastQuery == "" {
+		return nil
+	}
+	order := "ASC"
+	if desc {
+		order = "DESC"
+	}
+	// The query may end in a comment, so the parenthesis is on a line of
+	// its own
+	query := fmt.Sprintf("SELECT * FROM (%s\n) ORDER BY %s %s NULLS LAST",
+		strings.TrimRight(strings.TrimSpace(m.lastQuery), ";"), quoteName(column), order)
+	run := m.runQueries(query)
+	return func() tea.Msg {
+		msg := run()
+		if result, ok := msg.(queryResultMsg); ok {
+			result.sortColumn, result.sortDesc = column, desc
+			return result
+		}
+		return msg
+	}
+}
+
+// unmarkedMessage explains why results of no registered product can't
+// leave the TUI
+func unmarkedMessage(action string) string {
+	return fmt.Sprintf("Unmarked results can't be %s: register the data products they come from with Ctrl+O, or mark them with --classification", action)
+}
+
+// copyCells copies the selected cells, or rows, to the clipboard when
+// the results are marked no higher than clipboard_classification. Results
+// of no registered product are unmarked and never copied, as their
+// sources are unknown. Text is copied between banners, like printed
+// results.
+func (m *QueryModel) copyCells(wholeRows bool) {
+	text := m.grid.CopyText(wholeRows)
+	if text == "" {
+		return
+	}
+	if !m.marking.Known() {
+		m.error = unmarkedMessage("copied to the clipboard")
+		return
+	}
+	if limit := m.cfg.ClipboardClassification; !m.marking.Within(limit) {
+		m.error = fmt.Sprintf("Results marked %s can't be copied to the clipboard: clipboard_classification is %s",
+			m.marking.Banner(), marking.Normalize(limit))
+		return
+	}
+	
+	lines := strings.Count(strings.TrimSuffix(text, "\n"), "\n") + 1
+	text = m.marking.Banner() + "\n" + strings.TrimSuffix(text, "\n") + "\n" + m.marking.Banner() + "\n"
+	if err := clipboard.WriteAll(text); err != nil {
+		m.error = fmt.Sprintf("Failed to copy to the clipboard: %v", err)
+		return
+	}
+	if lines == 1 && !wholeRows {
+		m.notice = "Copied to the clipboard"
+	} else {
+		m.notice = fmt.Sprintf("Copied %d lines to the clipboard", lines)
+	}
+}
+
+// updatePanel handles keys in the product panel: Space registers or
+// unregisters the product under the cursor, Enter shows or hides its
+// schema and i inserts the name under the cursor into the editor
+func (m *QueryModel) updatePanel(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		m.panel.Move(-1)
+	case "down", "j":
+		m.panel.Move(1)
+	case "pgup":
+		m.panel.Move(-m.panel.visibleRows())
+	case "pgdown":
+		m.panel.Move(m.panel.visibleRows())
+	case "home":
+		m.panel.Move(-len(m.panel.rows()))
+	case "end":
+		m.panel.Move(len(m.panel.rows()))
+	
+	case "enter", "right", "left":
+		product, expanded := m.panel.Toggle()
+		if expanded && m.panel.schemas[product] == nil {
+			delete(m.panel.errors, product)
+			return m, m.loadSchema(product)
+		}
+	
+	case " ":
+		row, ok := m.panel.Selected()
+		if !ok || m.panel.pending[row.product] {
+			return m, nil
+		}
+		m.panel.pending[row.product] = true
+		if m.panel.registered[row.product] {
+			return m, m.unregisterProduct(row.product)
+		}
+		return m, m.registerProduct(row.product)
+	
+	case "i":
+		row, ok := m.panel.Selected()
+		if !ok || row.name == "" {
+			return m, nil
+		}
+		offset := m.editor.offset()
+		m.editor.Replace(offset, offset, row.name)
+		m.panel.focused = false
+		m.editor.Focus()
+	}
+	return m, nil
+}
+
+// registerProduct registers a product in the session, once the user is
+// found to have access to it
+func (m *QueryModel) registerProduct(name string) tea.Cmd {
+	db, catalogClient, secCtx := m.db, m.catalogClient, m.secCtx
+	return func() tea.Msg {
+		if db == nil || catalogClient == nil {
+			return productRegisteredMsg{product: name, err: fmt.Errorf("catalog or database not initialized")}
+		}
+		canAccess, err := secCtx.CanAccessDataProduct(name)
+		if err != nil {
+			return productRegisteredMsg{product: name, err: fmt.Errorf("failed to check access: %w", err)}
+		}
+		if !canAccess {
+			return productRegisteredMsg{product: name, err: fmt.Errorf("access denied to data product: %s", name)}
+		}
+		
+		product, err := catalogClient.GetDataProduct(name)
+		if err != nil {
+			return productRegisteredMsg{product: name, err: fmt.Errorf("failed to resolve data product: %w", err)}
+		}
+		if err := db.RegisterDataProduct(name, product.Location); err != nil {
+			return productRegisteredMsg{product: name, err: fmt.Errorf("failed to register data product: %w", err)}
+		}
+		return productRegisteredMsg{product: name, classification: product.Classification, registered: true}
+	}
+}
+
+// runStep starts a step of opening the TUI
+func (m *QueryModel) runStep(step int) tea.Cmd {
+	var cmd tea.Cmd
+	switch step {
+	case startDuckDB:
+		cmd = m.openDuckDB
+	case startCatalog:
+		cmd = m.listProducts
+	case startHistory:
+		cmd = m.loadHistory
+	case startNames:
+		cmd = m.loadNames(m.db)
+	case m.registerStep:
+		m.panel.pending[m.startupProduct] = true
+		cmd = m.registerProduct(m.startupProduct)
+	}
+	return tea.Batch(m.startup.Start(step), cmd)
+}
+
+// startRegistration registers the product asked for, or else the first
+// of the catalog, once DuckDB and the catalog are open
+func (m *QueryModel) startRegistration() tea.Cmd {
+	if m.registerStep >= 0 || m.db == nil || m.catalogClient == nil {
+		return nil
+	}
+	product := m.initialProduct
+	if product == "" && len(m.panel.products) > 0 {
+		product = m.panel.products[0]
+	}
+	if product == "" {
+		return nil
+	}
+	m.startupProduct = product
+	m.registerStep = m.startup.Add("Registering " + product)
+	return m.runStep(m.registerStep)
+}
+
+func (m *QueryModel) openDuckDB() tea.Msg {
+	db, err := duckdb.NewConnection(m.cfg, m.secCtx)
+	if err != nil {
+		return duckDBOpenedMsg{err: fmt.Errorf("failed to create DuckDB connection: %w", err)}
+	}
+	return duckDBOpenedMsg{db: db}
+}
+
+func (m *QueryModel) listProducts() tea.Msg {
+	client, err := catalog.NewClient(m.cfg, m.secCtx, m.log)
+	if err != nil {
+		return productsListedMsg{err: fmt.Errorf("failed to create catalog client: %w", err)}
+	}
+	products, err := client.ListDataProducts("")
+	if err != nil {
+		return productsListedMsg{client: client, err: err}
+	}
+	return productsListedMsg{client: client, products: products}
+}
+
+func (m *QueryModel) loadHistory() tea.Msg {
+	hist, err := history.Open(m.cfg, m.secCtx)
+	if err != nil {
+		return historyLoadedMsg{err: fmt.Errorf("failed to load query history: %w", err)}
+	}
+	return historyLoadedMsg{history: hist}
+}
+
+// loadNames lists the tables and functions of the session for completion
+func (m *QueryModel) loadNames(db *duckdb.Connection) tea.Cmd {
+	return func() tea.Msg {
+		tables, err := db.Tables()
+		if err != nil {
+			return namesLoadedMsg{err: fmt.Errorf("failed to list tables: %w", err)}
+		}
+		functions, err := db.Functions()
+		if err != nil {
+			return namesLoadedMsg{tables: tables, err: fmt.Errorf("failed to list functions: %w", err)}
+		}
+		return namesLoadedMsg{tables: tables, functions: functions}
+	}
+}
+
+// unregisterProduct drops a product from the session
+func (m *QueryModel) unregisterProduct(name string) tea.Cmd {
+	db := m.db
+	return func() tea.Msg {
+		if db == nil {
+			return productRegisteredMsg{product: name, err: fmt.Errorf("database not initialized")}
+		}
+		if err := db.UnregisterDataProduct(name); err != nil {
+			return productRegisteredMsg{product: name, err: err}
+		}
+		return productRegisteredMsg{product: name}
+	}
+}
+
+// loadSchema fetches the schema of a product for the panel
+func (m *QueryModel) loadSchema(name string) tea.Cmd {
+	catalogClient := m.catalogClient
+	return func() tea.Msg {
+		if catalogClient == nil {
+			return productSchemaMsg{product: name, err: fmt.Errorf("catalog not initialized")}
+		}
+		schema, err := catalogClient.GetDataProductTableSchema(name)
+		return productSchemaMsg{product: name, schema: schema, err: err}
+	}
+}
+
+// refreshTables offers the tables and views of the session for completion,
+// with the catalog's schema standing in for registered products DuckDB
+// doesn't list
+func (m *QueryModel) refreshTables() {
+	if m.db != nil {
+		if tables, err := m.db.Tables(); err == nil {
+			for _, t := range tables {
+				m.completer.SetTable(t)
+			}
+		}
+	}
+	for name := range m.registered {
+		if _, ok := m.completer.lookup(name); !ok && m.panel.schemas[name] != nil {
+			m.completer.SetTable(schemaTable(m.panel.schemas[name]))
+		}
+	}
+}
+
+// updateMarking marks the screen for the registered products and those the
+// results shown were read from, raised as asked for
+func (m *QueryModel) updateMarking() {
+	classifications := make(map[string]string)
+	for name, c := range m.shown {
+		classifications[name] = c
+	}
+	for name, c := range m.registered {
+		classifications[name] = c
+	}
+	mark := marking.New(classifications)
+	if raised, err := mark.Raise(m.raise); err == nil {
+		mark = raised
+	}
+	m.marking = mark
+	m.layout()
+}
+
+// layout sizes the editor, results and product panel to the terminal
+func (m *QueryModel) layout() {
+	width := m.width
+	if m.panel.open {
+		width -= panelWidth
+	}
+	m.editor.SetSize(width, editorHeight)
+	height := m.height - 9 - editorHeight // Leave room for the editor and headers
+	m.panel.SetHeight(m.height - 4)
+	if m.marking.Known() {
+		height -= 2 // and the banners
+		m.panel.SetHeight(m.height - 6)
+	}
+	m.grid.SetSize(width, height)
+	m.inspector.Width = width
+	m.inspector.Height = height - 1 // below its title
+}
+
+// productNames returns the registered products, sorted
+func (m *QueryModel) productNames() []string {
+	names := make([]string, 0, len(m.registered))
+	for name := range m.registered {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// schemaTable is a table for completion from the catalog's schema of a
+// product
+func schemaTable(schema *catalog.TableSchema) duckdb.Table {
+	t := duckdb.Table{Schema: schema.Domain, Name: schema.Table}
+	for _, col := range schema.Columns {
+		t.Columns = append(t.Columns, col.Name)
+	}
+	return t
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
 	}
+	return false
+}
+
+// updateSearch handles keys while searching the history: typing narrows
+// the matches, Ctrl+R and the arrows move between them and Enter puts
+// one in the editor
+func (m *QueryModel) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
 	
-	m.queryInput, tiCmd = m.queryInput.Update(msg)
-	m.resultViewport, vpCmd = m.resultViewport.Update(msg)
+	case "esc", "ctrl+g":
+		m.searching = false
+		return m, nil
 	
-	return m, tea.Batch(tiCmd, vpCmd)
+	case "enter":
+		if m.match < len(m.matches) {
+			m.editor.SetValue(m.matches[m.match].Query)
+		}
+		m.searching = false
+		m.recall = -1
+		return m, nil
+	
+	case "ctrl+r", "down", "ctrl+n":
+		if m.match+1 < len(m.matches) {
+			m.match++
+		}
+		return m, nil
+	
+	case "up", "ctrl+p":
+		if m.match > 0 {
+			m.match--
+		}
+		return m, nil
+	}
+	
+	var cmd tea.Cmd
+	m.search, cmd = m.search.Update(msg)
+	m.matches = m.history.Search(m.search.Value())
+	m.match = 0
+	return m, cmd
+}
+
+// updateCompletions offers the completions of the word at the cursor:
+// always when asked for with Ctrl+Space, otherwise once part of a name or
+// a qualifier is typed
+func (m *QueryModel) updateCompletions(asked bool) {
+	src := []rune(m.editor.Value())
+	offset := m.editor.offset()
+	start, items := m.completer.Complete(src, offset)
+	if !asked && start == offset && (start == 0 || src[start-1] != '.') {
+		items = nil
+	}
+	m.completions, m.completion, m.completeFrom = items, 0, start
+}
+
+// recallEntry replaces the editor's text with an earlier or later query
+// of the history, and finally with what was being written
+func (m *QueryModel) recallEntry(step int) {
+	entries := m.history.Entries()
+	if m.recall < 0 {
+		m.recall = len(entries)
+		m.draft = m.editor.Value()
+	}
+	i := m.recall + step
+	if i < 0 || i > len(entries) {
+		return
+	}
+	m.recall = i
+	if i == len(entries) {
+		m.editor.SetValue(m.draft)
+	} else {
+		m.editor.SetValue(entries[i].Query)
+	}
+}
+
+// statementText returns the text of a statement in the editor
+func (m *QueryModel) statementText(s sqlStatement) string {
+	return string([]rune(m.editor.Value())[s.start:s.end])
 }
 
 // View implements bubbletea.Model
@@ -189,15 +1138,34 @@ func (m *QueryModel) View() string {
 	// Title bar
 	title := fmt.Sprintf(" FroCore Data Mesh CLI - Query Tool ")
 	b.WriteString(titleStyle.Render(title))
-	b.WriteString("\n\n")
+	b.WriteString("\n")
+	
+	// Classification banner; results on screen are marked like printed ones
+	if m.marking.Known() {
+		b.WriteString(renderBanner(m.marking, m.width))
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	header := b.String()
+	b.Reset()
 	
-	// Current data product
-	b.WriteString(fmt.Sprintf("Current Data Product: %s\n\n", m.selectedProduct))
+	// Registered data products
+	if products := m.productNames(); len(products) > 0 {
+		b.WriteString(fmt.Sprintf("Data Products: %s\n\n", strings.Join(products, ", ")))
+	} else {
+		b.WriteString("Data Products: none registered, press Ctrl+O to choose\n\n")
+	}
 	
-	// Query input
+	// Query editor, with the statement that runs highlighted when there
+	// are several
 	b.WriteString(promptStyle.Render("SQL Query: "))
 	b.WriteString("\n")
-	b.WriteString(m.queryInput.View())
+	editorTop := strings.Count(header, "\n") + strings.Count(b.String(), "\n")
+	var selected *sqlStatement
+	if statements, current := m.editor.Statements(); len(statements) > 1 && m.editor.Focused() {
+		selected = &statements[current]
+	}
+	b.WriteString(m.editor.View(selected, selectedStatementStyle))
 	b.WriteString("\n\n")
 	
 	// Error message (if any)
@@ -205,102 +1173,205 @@ func (m *QueryModel) View() string {
 		b.WriteString(errorStyle.Render("Error: " + m.error))
 		b.WriteString("\n\n")
 	}
+	if m.notice != "" {
+		b.WriteString(noticeStyle.Render(m.notice))
+		b.WriteString("\n\n")
+	}
 	
-	// Results
-	if m.result != nil {
+	// History search or results
+	if m.searching {
+		b.WriteString(m.searchView())
+	} else if m.exporting {
+		b.WriteString(m.exportView())
+	} else if m.inspecting {
+		b.WriteString(truncate("Inspect: "+m.inspectTitle+" (Esc to close)", m.grid.width))
+		b.WriteString("\n")
+		b.WriteString(m.inspector.View())
+	} else if m.result != nil {
 		b.WriteString("Results:\n")
-		b.WriteString(m.resultViewport.View())
+		m.grid.focused = !m.editor.Focused() && !m.panel.focused
+		b.WriteString(m.grid.View(resultStyle))
+	} else if !m.startup.Done() {
+		b.WriteString(m.startup.View(m.grid.width, "Press Ctrl+T to retry"))
+	} else {
+		b.WriteString("No results to display. Press Ctrl+Enter or F5 to execute query.")
+	}
+	
+	// Product panel beside the editor and results
+	body := b.String()
+	b.Reset()
+	b.WriteString(header)
+	if m.panel.open {
+		main := lipgloss.NewStyle().Width(m.width - panelWidth).Render(body)
+		b.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, main, m.panel.View()))
 	} else {
-		b.WriteString("No results to display. Press Enter to execute query.")
+		b.WriteString(body)
 	}
 	
 	// Help text
 	b.WriteString("\n\n")
-	b.WriteString("Press Tab to toggle focus, Enter to execute, Esc to quit")
+	b.WriteString("Ctrl+Enter/F5 run statement, Alt+Enter run all, Ctrl+Space complete, Ctrl+R search history, Ctrl+P/N previous/next query, Ctrl+O products, Ctrl+S export, Tab switch focus, Esc quit")
 	
-	return b.String()
-}
-
-func (m *QueryModel) executeQuery() tea.Msg {
-	if m.db == nil {
-		return queryErrorMsg{error: "Database connection not initialized"}
+	if m.marking.Known() {
+		b.WriteString("\n")
+		b.WriteString(renderBanner(m.marking, m.width))
 	}
 	
-	query := m.queryInput.Value()
-	
-	// Check for data product access
-	if m.selectedProduct != "" {
-		canAccess, err := m.secCtx.CanAccessDataProduct(m.selectedProduct)
-		if err != nil {
-			return queryErrorMsg{error: fmt.Sprintf("Failed to check access: %v", err)}
-		}
-		if !canAccess {
-			return queryErrorMsg{error: fmt.Sprintf("Access denied to data product: %s", m.selectedProduct)}
+	// Completions below the word being completed
+	if len(m.completions) > 0 && m.editor.Focused() {
+		row, col := m.editor.Cursor()
+		typed := []rune(m.editor.Value())[m.completeFrom:m.editor.offset()]
+		if col -= runewidth.StringWidth(string(typed)); col < 0 {
+			col = 0
 		}
+		return overlay(b.String(), completionPopup(m.completions, m.completion), editorTop+row+1, col)
 	}
 	
-	// Execute the query
-	result, err := m.db.ExecuteQuery(query)
-	if err != nil {
-		return queryErrorMsg{error: err.Error()}
-	}
-	
-	return queryResultMsg{result: result}
+	return b.String()
 }
 
-func (m *QueryModel) formatResult() string {
-	if m.result == nil || len(m.result.Columns) == 0 {
-		return "No results to display"
-	}
-	
+// searchView renders the history search and the queries matching it, one
+// line each
+func (m *QueryModel) searchView() string {
 	var b strings.Builder
+	b.WriteString("History search: ")
+	b.WriteString(m.search.View())
+	b.WriteString("\n")
 	
-	// Calculate column widths
-	colWidths := make([]int, len(m.result.Columns))
-	for i, col := range m.result.Columns {
-		colWidths[i] = len(col) + 2 // Add padding
+	rows := m.grid.height - 1
+	if rows < 1 {
+		rows = 1
 	}
-	
-	// Check row data to ensure column width is sufficient
-	for _, row := range m.result.Rows {
-		for i, val := range row {
-			if i < len(colWidths) {
-				valStr := fmt.Sprintf("%v", val)
-				if len(valStr)+2 > colWidths[i] {
-					colWidths[i] = len(valStr) + 2
-				}
+	first := 0
+	if m.match >= rows {
+		first = m.match - rows + 1
+	}
+	if len(m.matches) == 0 {
+		b.WriteString("No matching queries")
+	}
+	for i := first; i < len(m.matches) && i < first+rows; i++ {
+		e := m.matches[i]
+		line := fmt.Sprintf("%s  %s", e.Time.Local().Format("2006-01-02 15:04"), strings.Join(strings.Fields(e.Query), " "))
+		line = truncate(line, m.grid.width-2)
+		if i == m.match {
+			b.WriteString(selectedItemStyle.Render("> " + line))
+		} else {
+			b.WriteString("  " + line)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// executeQuery records queries in the history and runs them
+func (m *QueryModel) executeQuery(queries ...string) tea.Cmd {
+	if len(queries) > 0 {
+		m.lastQuery = queries[len(queries)-1]
+	}
+	if m.history != nil {
+		for _, query := range queries {
+			if err := m.history.Add(strings.Join(m.productNames(), ","), query); err != nil {
+				m.error = err.Error()
 			}
 		}
 	}
-	
-	// Header row
-	for i, col := range m.result.Columns {
-		b.WriteString(resultHeaderStyle.Width(colWidths[i]).Render(col))
+	return m.runQueries(queries...)
+}
+
+// runQueries runs queries in turn and shows the result of the last one
+func (m *QueryModel) runQueries(queries ...string) tea.Cmd {
+	products := m.productNames()
+	classifications := make(map[string]string, len(m.registered))
+	for name, c := range m.registered {
+		classifications[name] = c
 	}
-	b.WriteString("\n")
+	// Update sets the connection once DuckDB opens; the command gets the
+	// one of the moment rather than reading the model from its goroutine
+	db, secCtx := m.db, m.secCtx
 	
-	// Data rows
-	for _, row := range m.result.Rows {
-		for i, val := range row {
-			if i < len(colWidths) {
-				valStr := fmt.Sprintf("%v", val)
-				b.WriteString(resultCellStyle.Width(colWidths[i]).Render(valStr))
+	return func() tea.Msg {
+		if db == nil {
+			return queryErrorMsg{error: "Database connection not initialized"}
+		}
+		
+		// Check for data product access
+		for _, product := range products {
+			canAccess, err := secCtx.CanAccessDataProduct(product)
+			if err != nil {
+				return queryErrorMsg{error: fmt.Sprintf("Failed to check access: %v", err)}
+			}
+			if !canAccess {
+				return queryErrorMsg{error: fmt.Sprintf("Access denied to data product: %s", product)}
 			}
 		}
-		b.WriteString("\n")
+		
+		// Execute the queries
+		var result *duckdb.QueryResult
+		for i, query := range queries {
+			var err error
+			if result, err = db.ExecuteQuery(query); err != nil {
+				if len(queries) > 1 {
+					return queryErrorMsg{error: fmt.Sprintf("statement %d: %v", i+1, err)}
+				}
+				return queryErrorMsg{error: err.Error()}
+			}
+		}
+		
+		return queryResultMsg{result: result, classifications: classifications}
 	}
-	
-	// Summary
-	b.WriteString(fmt.Sprintf("\n%d rows returned", len(m.result.Rows)))
-	
-	return b.String()
 }
 
 // Message types for Bubble Tea
 type queryResultMsg struct {
 	result *duckdb.QueryResult
+	// classifications of the products registered when the query ran
+	classifications map[string]string
+	// sortColumn is the column the query was sorted by from the grid
+	sortColumn      string
+	sortDesc        bool
 }
 
 type queryErrorMsg struct {
 	error string
 }
+
+type productRegisteredMsg struct {
+	product        string
+	classification string
+	registered     bool
+	err            error
+}
+
+type exportDoneMsg struct {
+	path string
+	rows int64
+	err  error
+}
+
+type duckDBOpenedMsg struct {
+	db  *duckdb.Connection
+	err error
+}
+
+type productsListedMsg struct {
+	client   *catalog.Client
+	products []string
+	err      error
+}
+
+type historyLoadedMsg struct {
+	history *history.History
+	err     error
+}
+
+type namesLoadedMsg struct {
+	tables    []duckdb.Table
+	functions []string
+	err       error
+}
+
+type productSchemaMsg struct {
+	product string
+	schema  *catalog.TableSchema
+	err     error
+}
@@ -0,0 +1,152 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/wordwrap"
+)
+
+var (
+	spinnerStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#0077B6"))
+
+	stepDoneStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#007A33"))
+
+	stepFailedStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#FF0000"))
+)
+
+// States of a startup step
+const (
+	stepPending = iota
+	stepRunning
+	stepDone
+	stepFailed
+)
+
+// startupStep is something a TUI does in the background as it opens, such
+// as connecting to the catalog
+type startupStep struct {
+	name  string
+	state int
+	err   error
+}
+
+// startup shows the progress of the steps of opening a TUI, with a spinner
+// for those running and the errors of those that failed
+type startup struct {
+	steps   []startupStep
+	spinner spinner.Model
+}
+
+func newStartup(names ...string) startup {
+	s := spinner.New()
+	s.Spinner = spinner.Dot
+	s.Style = spinnerStyle
+
+	steps := make([]startupStep, len(names))
+	for i, name := range names {
+		steps[i] = startupStep{name: name}
+	}
+	return startup{steps: steps, spinner: s}
+}
+
+// Add adds a step, returning its index
+func (s *startup) Add(name string) int {
+	s.steps = append(s.steps, startupStep{name: name})
+	return len(s.steps) - 1
+}
+
+// Start marks a step running and returns the command that keeps the
+// spinner turning
+func (s *startup) Start(step int) tea.Cmd {
+	s.steps[step].state = stepRunning
+	s.steps[step].err = nil
+	return s.spinner.Tick
+}
+
+// Finish marks a step done, or failed with err
+func (s *startup) Finish(step int, err error) {
+	s.steps[step].state = stepDone
+	s.steps[step].err = err
+	if err != nil {
+		s.steps[step].state = stepFailed
+	}
+}
+
+// State returns the state of a step
+func (s *startup) State(step int) int {
+	return s.steps[step].state
+}
+
+// Failed returns the steps that failed
+func (s *startup) Failed() []int {
+	var failed []int
+	for i, step := range s.steps {
+		if step.state == stepFailed {
+			failed = append(failed, i)
+		}
+	}
+	return failed
+}
+
+// Running reports whether any step is running
+func (s *startup) Running() bool {
+	for _, step := range s.steps {
+		if step.state == stepRunning {
+			return true
+		}
+	}
+	return false
+}
+
+// Done reports whether every step is done
+func (s *startup) Done() bool {
+	for _, step := range s.steps {
+		if step.state != stepDone {
+			return false
+		}
+	}
+	return true
+}
+
+// Update turns the spinner while steps are running and lets it stop when
+// none are
+func (s *startup) Update(msg spinner.TickMsg) tea.Cmd {
+	if !s.Running() {
+		return nil
+	}
+	var cmd tea.Cmd
+	s.spinner, cmd = s.spinner.Update(msg)
+	return cmd
+}
+
+// View renders a line per step, with errors wrapped to width below the
+// steps that failed
+func (s *startup) View(width int, retry string) string {
+	var lines []string
+	for _, step := range s.steps {
+		switch step.state {
+		case stepPending:
+			lines = append(lines, panelTypeStyle.Render("· "+step.name))
+		case stepRunning:
+			lines = append(lines, s.spinner.View()+step.name+"...")
+		case stepDone:
+			lines = append(lines, stepDoneStyle.Render("✓ ")+step.name)
+		case stepFailed:
+			lines = append(lines, stepFailedStyle.Render("✗ ")+step.name)
+			msg := wordwrap.String(step.err.Error(), width-4)
+			for _, line := range strings.Split(msg, "\n") {
+				lines = append(lines, "    "+stepFailedStyle.Render(line))
+			}
+		}
+	}
+	if len(s.Failed()) > 0 && !s.Running() {
+		lines = append(lines, "", retry)
+	}
+	return strings.Join(lines, "\n")
+}
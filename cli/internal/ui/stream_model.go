@@ -0,0 +1,250 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/avro"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/stream"
+	"github.com/mattn/go-runewidth"
+)
+
+const (
+	streamMaxRows     = 1000
+	streamMaxColWidth = 28
+)
+
+// StreamEventMsg delivers a decoded event to the StreamModel
+type StreamEventMsg struct {
+	Event *stream.Event
+}
+
+// StreamErrorMsg reports a failure to read or decode the stream
+type StreamErrorMsg struct {
+	Err error
+}
+
+// StreamSkipMsg reports an event that was skipped as it can't be decoded
+type StreamSkipMsg struct {
+	Err error
+}
+
+// StreamModel shows the latest events of a topic as a live table
+type StreamModel struct {
+	product string
+	topic   string
+	columns []string
+	rows    []*stream.Event
+	count   int
+	paused  bool
+	err     error
+	// skipped counts the events that couldn't be decoded, the last for
+	// skipErr
+	skipped int
+	skipErr error
+	width   int
+	height  int
+}
+
+func NewStreamModel(product, topic string) *StreamModel {
+	return &StreamModel{
+		product: product,
+		topic:   topic,
+		width:   80,
+		height:  24,
+	}
+}
+
+// Init implements bubbletea.Model
+func (m *StreamModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update implements bubbletea.Model
+func (m *StreamModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "esc", "q":
+			return m, tea.Quit
+		case " ", "p":
+			m.paused = !m.paused
+		case "c":
+			m.rows = nil
+		}
+
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+
+	case StreamEventMsg:
+		m.count++
+		if m.columns == nil {
+			m.columns = eventColumns(msg.Event)
+		}
+		if !m.paused {
+			m.rows = append(m.rows, msg.Event)
+			if len(m.rows) > streamMaxRows {
+				m.rows = m.rows[len(m.rows)-streamMaxRows:]
+			}
+		}
+
+	case StreamErrorMsg:
+		m.err = msg.Err
+
+	case StreamSkipMsg:
+		m.skipped++
+		m.skipErr = msg.Err
+	}
+
+	return m, nil
+}
+
+// View implements bubbletea.Model
+func (m *StreamModel) View() string {
+	var b strings.Builder
+
+	// Title bar
+	b.WriteString(titleStyle.Width(m.width).Render(fmt.Sprintf(" FroCore Data Mesh CLI - Stream %s ", m.product)))
+	b.WriteString("\n")
+
+	status := fmt.Sprintf("Topic: %s   Events: %d", m.topic, m.count)
+	if m.paused {
+		status += "   [paused]"
+	}
+	b.WriteString(status)
+	b.WriteString("\n\n")
+
+	// Error message (if any)
+	lines := m.height - 7
+	if m.err != nil {
+		b.WriteString(errorStyle.Render("Error: " + m.err.Error()))
+		b.WriteString("\n")
+		lines--
+	}
+	if m.skipped > 0 {
+		b.WriteString(errorStyle.Render(fmt.Sprintf("Skipped %d events that can't be decoded, the last: %v", m.skipped, m.skipErr)))
+		b.WriteString("\n")
+		lines--
+	}
+
+	if len(m.rows) == 0 {
+		b.WriteString("Waiting for events...\n")
+	} else {
+		b.WriteString(m.renderTable(lines))
+	}
+
+	// Help text
+	b.WriteString("\n")
+	b.WriteString("Space to pause, c to clear, q to quit")
+
+	return b.String()
+}
+
+// renderTable renders the newest rows that fit, with as many columns as
+// the terminal width allows
+func (m *StreamModel) renderTable(lines int) string {
+	headers := append([]string{"time", "partition", "offset", "key"}, m.columns...)
+	rows := m.rows
+	if lines < 1 {
+		lines = 1
+	}
+	if len(rows) > lines {
+		rows = rows[len(rows)-lines:]
+	}
+
+	cells := make([][]string, len(rows))
+	for i, e := range rows {
+		row := []string{
+			e.Time.Local().Format("15:04:05.000"),
+			fmt.Sprint(e.Partition),
+			fmt.Sprint(e.Offset),
+			formatStreamValue(e.Key),
+		}
+		for _, col := range m.columns {
+			v, _ := e.Field(col)
+			row = append(row, formatStreamValue(v))
+		}
+		cells[i] = row
+	}
+
+	// Column widths, dropping columns that do not fit
+	widths := make([]int, 0, len(headers))
+	total := 0
+	for c, h := range headers {
+		w := len(h)
+		for _, row := range cells {
+			if len(row[c]) > w {
+				w = len(row[c])
+			}
+		}
+		if w > streamMaxColWidth {
+			w = streamMaxColWidth
+		}
+		if c > 0 && total+w+2 > m.width {
+			break
+		}
+		widths = append(widths, w)
+		total += w + 2
+	}
+
+	var b strings.Builder
+	for c, w := range widths {
+		b.WriteString(resultHeaderStyle.Width(w + 2).Render(truncate(headers[c], w)))
+	}
+	b.WriteString("\n")
+	for _, row := range cells {
+		for c, w := range widths {
+			b.WriteString(resultCellStyle.Width(w + 2).Render(truncate(row[c], w)))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// eventColumns returns the top-level field names of a record event
+func eventColumns(e *stream.Event) []string {
+	record, ok := e.Value.(*avro.RecordValue)
+	if !ok {
+		return []string{}
+	}
+	columns := make([]string, len(record.Schema.Fields))
+	for i, f := range record.Schema.Fields {
+		columns[i] = f.Name
+	}
+	return columns
+}
+
+func formatStreamValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case *avro.RecordValue, []interface{}, map[string]interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	}
+	return fmt.Sprint(v)
+}
+
+// truncate shortens text to a display width, ending it with an ellipsis;
+// wide characters such as CJK take two columns
+func truncate(s string, width int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	if runewidth.StringWidth(s) <= width {
+		return s
+	}
+	if width <= 1 {
+		return runewidth.Truncate(s, width, "")
+	}
+	return runewidth.Truncate(s, width, ellipsis)
+}
@@ -0,0 +1,264 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/duckdb"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/marking"
+	"github.com/mattn/go-runewidth"
+	"golang.org/x/term"
+)
+
+// TableLayout configures how table output fits the terminal
+type TableLayout struct {
+	// Width is the width rows are truncated to, or 0 for no limit
+	Width int
+	// Expanded prints each row as lines of column names and values, like
+	// psql's \x, for results with many or wide columns
+	Expanded bool
+}
+
+// minColumnWidth is the narrowest a column is truncated to
+const minColumnWidth = 3
+
+const ellipsis = "…"
+
+func displayTable(w io.Writer, result *duckdb.QueryResult, mark marking.Marking, f *Formatter, layout TableLayout) error {
+	if result == nil || len(result.Columns) == 0 {
+		fmt.Fprintln(w, "No results to display")
+		return nil
+	}
+
+	// Banner above the header
+	if mark.Known() {
+		fmt.Fprintln(w, mark.Banner())
+		fmt.Fprintln(w)
+	}
+
+	cells := make([][]string, len(result.Rows))
+	for r, row := range result.Rows {
+		cells[r] = make([]string, len(result.Columns))
+		for i := range result.Columns {
+			if i < len(row) {
+				cells[r][i] = tableText(f.Text(i, row[i]))
+			}
+		}
+	}
+	if layout.Expanded {
+		writeExpanded(w, result.Columns, cells, layout.Width)
+	} else {
+		writeTable(w, result.Columns, cells, f, layout.Width)
+	}
+
+	// Print footer
+	fmt.Fprintf(w, "\n%d rows returned\n", len(result.Rows))
+	if mark.Known() {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, mark.Banner())
+	}
+
+	return nil
+}
+
+// writeTable writes rows under a header, with numbers right-aligned and
+// the widest columns truncated until rows fit in width
+func writeTable(w io.Writer, columns []string, cells [][]string, f *Formatter, width int) {
+	widths := make([]int, len(columns))
+	headers := make([]string, len(columns))
+	for i, col := range columns {
+		headers[i] = tableText(col)
+		widths[i] = runewidth.StringWidth(headers[i])
+	}
+	for _, row := range cells {
+		for i, cell := range row {
+			if n := runewidth.StringWidth(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	if width > 0 {
+		// Each column has a bar and a space on either side
+		fitWidths(widths, width-3*len(columns)-1)
+	}
+
+	line := func(values []string) {
+		var b strings.Builder
+		for i, value := range values {
+			b.WriteString("| ")
+			b.WriteString(pad(value, widths[i], f.Type(i).Numeric()))
+			b.WriteString(" ")
+		}
+		b.WriteString("|")
+		fmt.Fprintln(w, b.String())
+	}
+
+	line(headers)
+	var sep strings.Builder
+	for _, n := range widths {
+		sep.WriteString("|")
+		sep.WriteString(strings.Repeat("-", n+2))
+	}
+	sep.WriteString("|")
+	fmt.Fprintln(w, sep.String())
+	for _, row := range cells {
+		line(row)
+	}
+}
+
+// writeExpanded writes each row as a record of column names and values,
+// with values truncated to fit in width
+func writeExpanded(w io.Writer, columns []string, cells [][]string, width int) {
+	names := make([]string, len(columns))
+	nameWidth := 0
+	for i, col := range columns {
+		names[i] = tableText(col)
+		if n := runewidth.StringWidth(names[i]); n > nameWidth {
+			nameWidth = n
+		}
+	}
+	valueWidth := 0
+	for _, row := range cells {
+		for _, cell := range row {
+			if n := runewidth.StringWidth(cell); n > valueWidth {
+				valueWidth = n
+			}
+		}
+	}
+	if width > 0 && nameWidth+3+valueWidth > width {
+		valueWidth = width - nameWidth - 3
+		if valueWidth < minColumnWidth {
+			valueWidth = minColumnWidth
+		}
+	}
+
+	for r, row := range cells {
+		// -[ RECORD 1 ]-+------, with the + above the bars
+		title := fmt.Sprintf("-[ RECORD %d ]", r+1)
+		if n := nameWidth + 1 - len(title); n > 0 {
+			title += strings.Repeat("-", n)
+		}
+		fmt.Fprintln(w, title+"+"+strings.Repeat("-", valueWidth+1))
+		for i, cell := range row {
+			fmt.Fprintln(w, pad(names[i], nameWidth, false)+" | "+truncate(cell, valueWidth))
+		}
+	}
+}
+
+// fitWidths narrows the widest columns until their widths add up to at
+// most total, but no column below minColumnWidth
+func fitWidths(widths []int, total int) {
+	sum, widest := 0, 0
+	for _, n := range widths {
+		sum += n
+		if n > widest {
+			widest = n
+		}
+	}
+	if sum <= total {
+		return
+	}
+
+	capped := func(limit int) int {
+		sum := 0
+		for _, n := range widths {
+			if n > limit {
+				n = limit
+			}
+			sum += n
+		}
+		return sum
+	}
+
+	// The largest limit on column widths that fits, by bisection
+	lo, hi := minColumnWidth, widest
+	if capped(lo) > total {
+		hi = lo
+	}
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if capped(mid) <= total {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	// Give what is left over to the first of the truncated columns
+	spare := total - capped(lo)
+	for i, n := range widths {
+		if n > lo {
+			widths[i] = lo
+			if spare > 0 {
+				widths[i]++
+				spare--
+			}
+		}
+	}
+}
+
+// pad truncates or pads text to a display width, aligned left or right
+func pad(s string, width int, right bool) string {
+	s = truncate(s, width)
+	fill := strings.Repeat(" ", width-runewidth.StringWidth(s))
+	if right {
+		return fill + s
+	}
+	return s + fill
+}
+
+// tableText puts text on one line and drops control characters, which
+// would break the layout or be interpreted by the terminal
+func tableText(s string) string {
+	s = strings.NewReplacer("\r\n", "↵", "\n", "↵", "\r", "↵", "\t", " ").Replace(s)
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// terminalSize returns the size of the terminal standard output is, if it
+// is one
+func terminalSize() (width, height int, ok bool) {
+	fd := int(os.Stdout.Fd())
+	if !term.IsTerminal(fd) {
+		return 0, 0, false
+	}
+	width, height, err := term.GetSize(fd)
+	if err != nil {
+		return 0, 0, false
+	}
+	return width, height, true
+}
+
+// page prints output through $PAGER when it has more lines than the
+// terminal, or directly when it fits or no pager is set
+func page(output []byte, height int) error {
+	pager := strings.Fields(os.Getenv("PAGER"))
+	if len(pager) == 0 || bytes.Count(output, []byte("\n")) < height {
+		_, err := os.Stdout.Write(output)
+		return err
+	}
+
+	cmd := exec.Command(pager[0], pager[1:]...)
+	cmd.Stdin = bytes.NewReader(output)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		// A missing pager shouldn't lose the results
+		fmt.Fprintf(os.Stderr, "Could not start pager %s: %v\n", pager[0], err)
+		_, err := os.Stdout.Write(output)
+		return err
+	}
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("pager %s failed: %w", pager[0], err)
+	}
+	return nil
+}
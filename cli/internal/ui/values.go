@@ -0,0 +1,411 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/duckdb"
+	godb "github.com/marcboeker/go-duckdb"
+)
+
+// DefaultTimestampLayout shows timestamps like DuckDB does, with as many
+// fractional digits as they have
+const DefaultTimestampLayout = "2006-01-02 15:04:05.999999"
+
+// ValueFormat configures how query results are rendered as text
+type ValueFormat struct {
+	// Null is shown for NULL values in tables, Markdown and HTML; CSV
+	// has empty fields, JSON null and XLSX empty cells
+	Null string
+	// TimestampLayout is the Go time layout of timestamps
+	TimestampLayout string
+	// Location is the time zone timestamps with a time zone are shown in;
+	// those without one are shown as they are
+	Location *time.Location
+	// Decimals is the number of digits after the point of decimal and
+	// floating point numbers, or -1 for all of them
+	Decimals int
+}
+
+// DefaultValueFormat renders values like DuckDB's shell, in UTC
+func DefaultValueFormat() ValueFormat {
+	return ValueFormat{Null: "NULL", TimestampLayout: DefaultTimestampLayout, Location: time.UTC, Decimals: -1}
+}
+
+// NewValueFormat returns a value format from settings as configured: the
+// layout is a Go time layout or rfc3339, and the time zone an IANA name,
+// UTC or Local. Timestamps outside UTC show their zone unless the layout
+// says otherwise.
+func NewValueFormat(null, timestampLayout, timeZone string, decimals int) (ValueFormat, error) {
+	vf := DefaultValueFormat()
+	vf.Null = null
+
+	switch strings.ToLower(timeZone) {
+	case "", "utc":
+	case "local":
+		vf.Location = time.Local
+	default:
+		loc, err := time.LoadLocation(timeZone)
+		if err != nil {
+			return vf, fmt.Errorf("invalid time zone %s: %w", timeZone, err)
+		}
+		vf.Location = loc
+	}
+
+	switch strings.ToLower(timestampLayout) {
+	case "":
+		if vf.Location != time.UTC {
+			vf.TimestampLayout = DefaultTimestampLayout + " MST"
+		}
+	case "rfc3339", "iso8601":
+		vf.TimestampLayout = time.RFC3339Nano
+	default:
+		vf.TimestampLayout = timestampLayout
+	}
+
+	if decimals < -1 {
+		return vf, fmt.Errorf("invalid number of decimals %d: must be -1 or more", decimals)
+	}
+	vf.Decimals = decimals
+	return vf, nil
+}
+
+// wallClockLayout is the layout of timestamps shown without a time zone:
+// those of a type without one only have a wall clock time, so the zone the
+// layout adds to others is left out
+func (vf ValueFormat) wallClockLayout(t *duckdb.Type) string {
+	if t.Zoned() {
+		return vf.TimestampLayout
+	}
+	switch vf.TimestampLayout {
+	case DefaultTimestampLayout + " MST":
+		return DefaultTimestampLayout
+	case time.RFC3339Nano:
+		return "2006-01-02T15:04:05.999999999"
+	}
+	return vf.TimestampLayout
+}
+
+// Formatter renders the values of a query result by the types of their
+// columns
+type Formatter struct {
+	ValueFormat
+	types []*duckdb.Type
+}
+
+// NewFormatter returns a formatter for the columns of a result. Columns
+// of unknown type are rendered by the type of their values.
+func NewFormatter(result *duckdb.QueryResult, vf ValueFormat) *Formatter {
+	f := &Formatter{ValueFormat: vf}
+	if result == nil {
+		return f
+	}
+	f.types = make([]*duckdb.Type, len(result.Columns))
+	for i := range f.types {
+		f.types[i] = &duckdb.Type{}
+		if i < len(result.ColumnTypes) {
+			if t, err := duckdb.ParseType(result.ColumnTypes[i]); err == nil {
+				f.types[i] = t
+			}
+		}
+	}
+	return f
+}
+
+// Type returns the type of a column
+func (f *Formatter) Type(col int) *duckdb.Type {
+	if col < len(f.types) {
+		return f.types[col]
+	}
+	return &duckdb.Type{}
+}
+
+// Text renders a value of a column as text
+func (f *Formatter) Text(col int, v interface{}) string {
+	return f.text(f.Type(col), v)
+}
+
+// JSON converts a value of a column into one that encodes to JSON without
+// losing precision or order: decimals keep their digits, structs the
+// order of their fields and times are rendered as text
+func (f *Formatter) JSON(col int, v interface{}) interface{} {
+	return f.json(f.Type(col), v)
+}
+
+// Row converts a row into a JSON object of its columns, in order
+func (f *Formatter) Row(columns []string, row []interface{}) jsonObject {
+	obj := make(jsonObject, 0, len(row))
+	for i, val := range row {
+		if i < len(columns) {
+			obj = append(obj, jsonField{columns[i], f.JSON(i, val)})
+		}
+	}
+	return obj
+}
+
+func (vf ValueFormat) text(t *duckdb.Type, v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return vf.Null
+	case string:
+		return value
+	case bool:
+		return strconv.FormatBool(value)
+	case float32:
+		return vf.float(float64(value), 32)
+	case float64:
+		return vf.float(value, 64)
+	case godb.Decimal:
+		return formatDecimal(vf.round(value))
+	case *big.Int:
+		return value.String()
+	case time.Time:
+		switch t.ID {
+		case "DATE":
+			return value.Format("2006-01-02")
+		case "TIME":
+			return value.Format("15:04:05.999999")
+		}
+		if !t.Zoned() || vf.Location == nil {
+			return value.UTC().Format(vf.wallClockLayout(t))
+		}
+		return value.In(vf.Location).Format(vf.TimestampLayout)
+	case godb.Interval:
+		return formatInterval(value)
+	case []byte:
+		if t.ID == "UUID" && len(value) == 16 {
+			return formatUUID(value)
+		}
+		return formatBlob(value)
+	case []interface{}, map[string]interface{}, godb.Map:
+		text, err := marshalJSON(vf.json(t, v))
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(text)
+	}
+	return fmt.Sprint(v)
+}
+
+func (vf ValueFormat) json(t *duckdb.Type, v interface{}) interface{} {
+	switch value := v.(type) {
+	case nil:
+		return nil
+	case godb.Decimal:
+		return json.Number(formatDecimal(vf.round(value)))
+	case *big.Int:
+		return json.Number(value.String())
+	case float32:
+		return vf.jsonFloat(float64(value), 32)
+	case float64:
+		return vf.jsonFloat(value, 64)
+	case time.Time, godb.Interval, []byte:
+		return vf.text(t, v)
+	case []interface{}:
+		elem := t.Elem
+		if elem == nil {
+			elem = &duckdb.Type{}
+		}
+		values := make([]interface{}, len(value))
+		for i, e := range value {
+			values[i] = vf.json(elem, e)
+		}
+		return values
+	case map[string]interface{}:
+		// Struct fields in the order of the type
+		obj := make(jsonObject, 0, len(value))
+		seen := make(map[string]bool, len(value))
+		for _, field := range t.Fields {
+			if e, ok := value[field.Name]; ok {
+				obj = append(obj, jsonField{field.Name, vf.json(field.Type, e)})
+				seen[field.Name] = true
+			}
+		}
+		var rest []string
+		for name := range value {
+			if !seen[name] {
+				rest = append(rest, name)
+			}
+		}
+		sort.Strings(rest)
+		for _, name := range rest {
+			obj = append(obj, jsonField{name, vf.json(&duckdb.Type{}, value[name])})
+		}
+		return obj
+	case godb.Map:
+		keyType, valueType := t.Key, t.Value
+		if keyType == nil || valueType == nil {
+			keyType, valueType = &duckdb.Type{}, &duckdb.Type{}
+		}
+		// Go maps have no order, so entries are sorted by key
+		obj := make(jsonObject, 0, len(value))
+		for k, e := range value {
+			obj = append(obj, jsonField{vf.text(keyType, k), vf.json(valueType, e)})
+		}
+		sort.Slice(obj, func(i, j int) bool { return obj[i].name < obj[j].name })
+		return obj
+	}
+	return v
+}
+
+// float formats a floating point number without an exponent unless it is
+// very large or small
+func (vf ValueFormat) float(f float64, bits int) string {
+	if vf.Decimals >= 0 {
+		return strconv.FormatFloat(f, 'f', vf.Decimals, bits)
+	}
+	if abs := math.Abs(f); abs != 0 && (abs >= 1e21 || abs < 1e-6) {
+		return strconv.FormatFloat(f, 'g', -1, bits)
+	}
+	return strconv.FormatFloat(f, 'f', -1, bits)
+}
+
+// jsonFloat returns a number as formatted; JSON has no NaN or infinities,
+// so they are text
+func (vf ValueFormat) jsonFloat(f float64, bits int) interface{} {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return vf.float(f, bits)
+	}
+	return json.Number(vf.float(f, bits))
+}
+
+// round rounds a decimal half away from zero to the configured number of
+// decimals, or pads it with zeros
+func (vf ValueFormat) round(d godb.Decimal) godb.Decimal {
+	places := vf.Decimals
+	if places < 0 || places == int(d.Scale) || d.Value == nil {
+		return d
+	}
+	value := new(big.Int)
+	if places > int(d.Scale) {
+		pow := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(places-int(d.Scale))), nil)
+		value.Mul(d.Value, pow)
+	} else {
+		pow := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(int(d.Scale)-places)), nil)
+		rem := new(big.Int)
+		value.QuoRem(new(big.Int).Abs(d.Value), pow, rem)
+		if rem.Mul(rem, big.NewInt(2)).Cmp(pow) >= 0 {
+			value.Add(value, big.NewInt(1))
+		}
+		if d.Value.Sign() < 0 {
+			value.Neg(value)
+		}
+	}
+	return godb.Decimal{Width: d.Width, Scale: uint8(places), Value: value}
+}
+
+// formatDecimal formats a decimal exactly, with all digits of its scale
+func formatDecimal(d godb.Decimal) string {
+	if d.Value == nil {
+		return ""
+	}
+	digits := new(big.Int).Abs(d.Value).String()
+	scale := int(d.Scale)
+	if scale > 0 {
+		if len(digits) <= scale {
+			digits = strings.Repeat("0", scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+	}
+	if d.Value.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// formatInterval formats an interval like DuckDB: 1 year 2 months 3 days
+// 04:05:06
+func formatInterval(iv godb.Interval) string {
+	var parts []string
+	unit := func(n int32, name string) {
+		if n == 1 || n == -1 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, name))
+		} else if n != 0 {
+			parts = append(parts, fmt.Sprintf("%d %ss", n, name))
+		}
+	}
+	unit(iv.Months/12, "year")
+	unit(iv.Months%12, "month")
+	unit(iv.Days, "day")
+
+	if iv.Micros != 0 || len(parts) == 0 {
+		micros, sign := iv.Micros, ""
+		if micros < 0 {
+			micros, sign = -micros, "-"
+		}
+		clock := fmt.Sprintf("%s%02d:%02d:%02d", sign, micros/3600e6, micros/60e6%60, micros/1e6%60)
+		if frac := micros % 1e6; frac != 0 {
+			clock += strings.TrimRight(fmt.Sprintf(".%06d", frac), "0")
+		}
+		parts = append(parts, clock)
+	}
+	return strings.Join(parts, " ")
+}
+
+func formatUUID(b []byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// formatBlob formats binary data like DuckDB: printable ASCII as it is and
+// other bytes as \xNN
+func formatBlob(b []byte) string {
+	var s strings.Builder
+	for _, c := range b {
+		if c >= 0x20 && c < 0x7f && c != '\\' {
+			s.WriteByte(c)
+		} else {
+			fmt.Fprintf(&s, `\x%02X`, c)
+		}
+	}
+	return s.String()
+}
+
+// jsonObject is a JSON object that keeps the order of its fields
+type jsonObject []jsonField
+
+type jsonField struct {
+	name  string
+	value interface{}
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, field := range o {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		name, err := marshalJSON(field.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := marshalJSON(field.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(name)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// marshalJSON encodes JSON without escaping <, > and &, which are only a
+// concern for JSON embedded in HTML
+func marshalJSON(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,51 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/duckdb"
+)
+
+func TestFormatterTimestamps(t *testing.T) {
+	db, err := duckdb.NewLocalConnection(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// A trailing comment or semicolon doesn't hide the time zones
+	result, err := db.ExecuteQuery(`SELECT TIMESTAMP '2024-03-01 17:00:00' AS at, TIMESTAMPTZ '2024-03-01 17:00:00+00' AS at_tz,
+		{'x': TIMESTAMPTZ '2024-03-01 17:00:00+00'} AS struct, [TIMESTAMP '2024-03-01 17:00:00'] AS list; -- one row`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		layout string
+		want   []string
+	}{
+		{"", []string{
+			"2024-03-01 17:00:00",
+			"2024-03-01 12:00:00 EST",
+			`{"x":"2024-03-01 12:00:00 EST"}`,
+			`["2024-03-01 17:00:00"]`,
+		}},
+		{"rfc3339", []string{
+			"2024-03-01T17:00:00",
+			"2024-03-01T12:00:00-05:00",
+			`{"x":"2024-03-01T12:00:00-05:00"}`,
+			`["2024-03-01T17:00:00"]`,
+		}},
+	}
+	for _, test := range tests {
+		vf, err := NewValueFormat("NULL", test.layout, "America/New_York", -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := NewFormatter(result, vf)
+		for i, want := range test.want {
+			if got := f.Text(i, result.Rows[0][i]); got != want {
+				t.Errorf("%q layout: %s = %q, want %q", test.layout, result.Columns[i], got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/duckdb"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/marking"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/xlsx"
+	godb "github.com/marcboeker/go-duckdb"
+)
+
+// maxExactDigits is the number of significant digits Excel keeps; numbers
+// with more are written as text so that no digits are lost
+const maxExactDigits = 15
+
+// writeXLSX writes query results as a workbook, with banner rows above and
+// below the results and the banner in the page header and footer
+func writeXLSX(w io.Writer, result *duckdb.QueryResult, mark marking.Marking, f *Formatter) error {
+	var rows [][]xlsx.Cell
+	if mark.Known() {
+		rows = append(rows, []xlsx.Cell{xlsx.String(mark.Banner())}, nil)
+	}
+
+	header := make([]xlsx.Cell, len(result.Columns))
+	for i, col := range result.Columns {
+		header[i] = xlsx.String(col)
+	}
+	rows = append(rows, header)
+
+	for _, row := range result.Rows {
+		cells := make([]xlsx.Cell, len(row))
+		for i, val := range row {
+			cells[i] = xlsxCell(f, i, val)
+		}
+		rows = append(rows, cells)
+	}
+
+	if mark.Known() {
+		rows = append(rows, nil, []xlsx.Cell{xlsx.String(mark.Banner())})
+	}
+
+	return xlsx.Write(w, xlsx.Sheet{
+		Name:         "Results",
+		Rows:         rows,
+		HeaderFooter: mark.Banner(),
+	})
+}
+
+// xlsxCell converts a value to a cell of the matching type. Numbers and
+// times keep their values, with the formatter's decimals and time zone;
+// other values are text as the formatter renders them.
+func xlsxCell(f *Formatter, col int, v interface{}) xlsx.Cell {
+	switch value := v.(type) {
+	case nil:
+		return xlsx.Cell{}
+	case bool:
+		return xlsx.Boolean(value)
+	case int8, int16, int32, int64, uint8, uint16, uint32, uint64:
+		s := fmt.Sprint(value)
+		if len(strings.TrimPrefix(s, "-")) > maxExactDigits {
+			return xlsx.String(s)
+		}
+		return xlsx.Cell{Type: xlsx.Number, Value: s}
+	case float32, float64:
+		cell := xlsx.Float(reflect.ValueOf(value).Float())
+		if cell.Type == xlsx.Number && f.Decimals >= 0 {
+			cell.Format = decimalFormat(f.Decimals)
+		}
+		return cell
+	case *big.Int:
+		if len(new(big.Int).Abs(value).String()) > maxExactDigits {
+			return xlsx.String(value.String())
+		}
+		return xlsx.Cell{Type: xlsx.Number, Value: value.String()}
+	case godb.Decimal:
+		if value.Value == nil || len(new(big.Int).Abs(value.Value).String()) > maxExactDigits {
+			return xlsx.String(f.Text(col, value))
+		}
+		places := int(value.Scale)
+		if f.Decimals >= 0 {
+			places = f.Decimals
+		}
+		return xlsx.Cell{Type: xlsx.Number, Value: formatDecimal(value), Format: decimalFormat(places)}
+	case time.Time:
+		t := f.Type(col)
+		switch t.ID {
+		case "DATE":
+			return xlsx.Time(value, "yyyy-mm-dd")
+		case "TIME":
+			midnight := time.Date(value.Year(), value.Month(), value.Day(), 0, 0, 0, 0, value.Location())
+			return xlsx.TimeOfDay(value.Sub(midnight), "hh:mm:ss")
+		}
+		if t.Zoned() && f.Location != nil {
+			value = value.In(f.Location)
+		} else {
+			value = value.UTC()
+		}
+		return xlsx.Time(value, "yyyy-mm-dd hh:mm:ss")
+	}
+
+	// Text, and lists, structs and maps as JSON
+	return xlsx.String(f.Text(col, v))
+}
+
+// decimalFormat is the number format for some digits after the point
+func decimalFormat(places int) string {
+	if places == 0 {
+		return "0"
+	}
+	return "0." + strings.Repeat("0", places)
+}
@@ -0,0 +1,61 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/frocore/fedramp-data-mesh/cli/internal/duckdb"
+	"github.com/frocore/fedramp-data-mesh/cli/internal/xlsx"
+)
+
+func TestXLSXCell(t *testing.T) {
+	db, err := duckdb.NewLocalConnection(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	result, err := db.ExecuteQuery(`SELECT * FROM (VALUES
+		(1, 12345.67::DECIMAL(18, 2), 1234567890123456.78::DECIMAL(38, 2), 12345678901234567890::HUGEINT,
+			DATE '2024-03-01', TIMESTAMP '2024-03-01 17:00:00', TIMESTAMPTZ '2024-03-01 17:00:00+00', TIME '06:00:00',
+			[1, NULL], {'x': 1.50::DECIMAL(3, 2), 'y': NULL}),
+		(NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL)
+	) t(id, amount, big, huge, date, at, at_tz, time, list, struct)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Timestamps with a time zone are written as the wall clock time where
+	// they are shown, those without one as they are
+	vf, err := NewValueFormat("NULL", "", "America/New_York", -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := NewFormatter(result, vf)
+
+	want := []xlsx.Cell{
+		{Type: xlsx.Number, Value: "1"},
+		{Type: xlsx.Number, Value: "12345.67", Format: "0.00"},
+		// Numbers Excel can't hold exactly are text
+		xlsx.String("1234567890123456.78"),
+		xlsx.String("12345678901234567890"),
+		{Type: xlsx.Number, Value: "45352", Format: "yyyy-mm-dd"},
+		{Type: xlsx.Number, Value: "45352.708333333336", Format: "yyyy-mm-dd hh:mm:ss"},
+		{Type: xlsx.Number, Value: "45352.5", Format: "yyyy-mm-dd hh:mm:ss"},
+		{Type: xlsx.Number, Value: "0.25", Format: "hh:mm:ss"},
+		xlsx.String("[1,null]"),
+		xlsx.String(`{"x":1.50,"y":null}`),
+	}
+	for i, cell := range want {
+		if got := xlsxCell(f, i, result.Rows[0][i]); got != cell {
+			t.Errorf("%s = %+v, want %+v", result.Columns[i], got, cell)
+		}
+		// NULL is a missing cell, not null_display
+		if got := xlsxCell(f, i, result.Rows[1][i]); got.Type != xlsx.Empty {
+			t.Errorf("NULL %s = %+v, want an empty cell", result.Columns[i], got)
+		}
+	}
+
+	f.Decimals = 1
+	if got, want := xlsxCell(f, 1, result.Rows[0][1]), (xlsx.Cell{Type: xlsx.Number, Value: "12345.67", Format: "0.0"}); got != want {
+		t.Errorf("amount with 1 decimal = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,264 @@
+// Package xlsx writes single-sheet Excel workbooks of typed cells: text,
+// numbers, booleans and dates with number formats, so that spreadsheets
+// see values rather than text that looks like them
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CellType is the type of a cell value
+type CellType int
+
+const (
+	Empty CellType = iota
+	Text
+	Number
+	Bool
+)
+
+// Cell is a cell of a sheet
+type Cell struct {
+	Type CellType
+	// Value is the text, the number in decimal notation or true or false
+	Value string
+	// Format is the number format, such as 0.00 or yyyy-mm-dd
+	Format string
+}
+
+// String returns a text cell
+func String(s string) Cell {
+	return Cell{Type: Text, Value: s}
+}
+
+// Int returns a number cell
+func Int(n int64) Cell {
+	return Cell{Type: Number, Value: strconv.FormatInt(n, 10)}
+}
+
+// Float returns a number cell; NaN and infinities are text
+func Float(f float64) Cell {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return String(strconv.FormatFloat(f, 'g', -1, 64))
+	}
+	return Cell{Type: Number, Value: strconv.FormatFloat(f, 'g', -1, 64)}
+}
+
+// Boolean returns a boolean cell
+func Boolean(b bool) Cell {
+	return Cell{Type: Bool, Value: strconv.FormatBool(b)}
+}
+
+// epoch is day zero of Excel's 1900 date system
+var epoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// minDate is the first date after Excel's nonexistent 29 February 1900
+var minDate = time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// Time returns a date cell of the wall clock time of t, with a format such
+// as yyyy-mm-dd hh:mm:ss. Excel has no time zones, and can't represent
+// dates before March 1900; they are text.
+func Time(t time.Time, format string) Cell {
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	if t.Before(minDate) {
+		return String(t.Format(time.RFC3339Nano))
+	}
+	// Seconds rather than a Duration, which only spans 292 years
+	serial := (float64(t.Unix()-epoch.Unix()) + float64(t.Nanosecond())/1e9) / 86400
+	return Cell{Type: Number, Value: strconv.FormatFloat(serial, 'f', -1, 64), Format: format}
+}
+
+// TimeOfDay returns a time cell, a fraction of a day, with a format such
+// as hh:mm:ss
+func TimeOfDay(d time.Duration, format string) Cell {
+	return Cell{Type: Number, Value: strconv.FormatFloat(float64(d)/float64(24*time.Hour), 'f', -1, 64), Format: format}
+}
+
+// Sheet is the sheet of a workbook
+type Sheet struct {
+	Name string
+	Rows [][]Cell
+	// HeaderFooter is printed in the center of the page header and footer,
+	// such as a classification banner
+	HeaderFooter string
+}
+
+// Write writes a workbook of one sheet
+func Write(w io.Writer, sheet Sheet) error {
+	// Number formats get styles; style 0 is the default
+	styles := map[string]int{"": 0}
+	var formats []string
+	for _, row := range sheet.Rows {
+		for _, cell := range row {
+			if _, ok := styles[cell.Format]; !ok {
+				styles[cell.Format] = len(formats) + 1
+				formats = append(formats, cell.Format)
+			}
+		}
+	}
+
+	name := sheet.Name
+	if name == "" {
+		name = "Sheet1"
+	}
+
+	z := zip.NewWriter(w)
+	parts := []struct {
+		name string
+		body string
+	}{
+		{"[Content_Types].xml", contentTypes},
+		{"_rels/.rels", rootRels},
+		{"xl/workbook.xml", fmt.Sprintf(workbook, escape(sheetName(name)))},
+		{"xl/_rels/workbook.xml.rels", workbookRels},
+		{"xl/styles.xml", stylesXML(formats)},
+		{"xl/worksheets/sheet1.xml", sheetXML(sheet, styles)},
+	}
+	for _, part := range parts {
+		f, err := z.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return err
+		}
+	}
+	return z.Close()
+}
+
+func sheetXML(sheet Sheet, styles map[string]int) string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for r, row := range sheet.Rows {
+		fmt.Fprintf(&b, `<row r="%d">`, r+1)
+		for c, cell := range row {
+			if cell.Type == Empty {
+				continue
+			}
+			ref := column(c) + strconv.Itoa(r+1)
+			style := ""
+			if s := styles[cell.Format]; s > 0 {
+				style = fmt.Sprintf(` s="%d"`, s)
+			}
+			switch cell.Type {
+			case Number:
+				fmt.Fprintf(&b, `<c r="%s"%s><v>%s</v></c>`, ref, style, escape(cell.Value))
+			case Bool:
+				v := "0"
+				if cell.Value == "true" {
+					v = "1"
+				}
+				fmt.Fprintf(&b, `<c r="%s" t="b"%s><v>%s</v></c>`, ref, style, v)
+			default:
+				fmt.Fprintf(&b, `<c r="%s" t="inlineStr"%s><is><t xml:space="preserve">%s</t></is></c>`, ref, style, escape(cell.Value))
+			}
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData>`)
+	if sheet.HeaderFooter != "" {
+		text := escape("&C" + strings.ReplaceAll(sheet.HeaderFooter, "&", "&&"))
+		fmt.Fprintf(&b, `<headerFooter><oddHeader>%s</oddHeader><oddFooter>%s</oddFooter></headerFooter>`, text, text)
+	}
+	b.WriteString(`</worksheet>`)
+	return b.String()
+}
+
+func stylesXML(formats []string) string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	// Custom number formats start at id 164
+	if len(formats) > 0 {
+		fmt.Fprintf(&b, `<numFmts count="%d">`, len(formats))
+		for i, f := range formats {
+			fmt.Fprintf(&b, `<numFmt numFmtId="%d" formatCode="%s"/>`, 164+i, escape(f))
+		}
+		b.WriteString(`</numFmts>`)
+	}
+	b.WriteString(`<fonts count="1"><font><sz val="11"/><name val="Calibri"/></font></fonts>`)
+	b.WriteString(`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>`)
+	b.WriteString(`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>`)
+	b.WriteString(`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>`)
+	fmt.Fprintf(&b, `<cellXfs count="%d"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>`, len(formats)+1)
+	for i := range formats {
+		fmt.Fprintf(&b, `<xf numFmtId="%d" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>`, 164+i)
+	}
+	b.WriteString(`</cellXfs></styleSheet>`)
+	return b.String()
+}
+
+// column names a column: A, B, ..., Z, AA, ...
+func column(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// sheetName removes characters Excel doesn't allow in sheet names and
+// shortens it to 31 characters
+func sheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if r := []rune(name); len(r) > 31 {
+		name = string(r[:31])
+	}
+	return name
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		// Control characters other than tab and newlines aren't valid XML
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' {
+			continue
+		}
+		switch r {
+		case '<':
+			b.WriteString("&lt;")
+		case '>':
+			b.WriteString("&gt;")
+		case '&':
+			b.WriteString("&amp;")
+		case '"':
+			b.WriteString("&quot;")
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+const contentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`</Types>`
+
+const rootRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const workbook = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+const workbookRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
@@ -0,0 +1,197 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// readBook is what a spreadsheet reads from a written workbook
+type readBook struct {
+	sheet  string
+	cells  map[string]readCell
+	header string
+	footer string
+}
+
+type readCell struct {
+	typ    string
+	value  string
+	format string
+}
+
+// read unzips a workbook and parses its parts like a spreadsheet would
+func read(t *testing.T, data []byte) readBook {
+	t.Helper()
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := make(map[string][]byte)
+	for _, f := range z.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parts[f.Name], err = io.ReadAll(r); err != nil {
+			t.Fatal(err)
+		}
+		r.Close()
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/_rels/workbook.xml.rels"} {
+		if _, ok := parts[name]; !ok {
+			t.Fatalf("no %s in workbook", name)
+		}
+	}
+
+	var wb struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	unmarshal(t, parts, "xl/workbook.xml", &wb)
+
+	var styles struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		CellXfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	unmarshal(t, parts, "xl/styles.xml", &styles)
+	formats := make(map[int]string)
+	for _, f := range styles.NumFmts {
+		formats[f.ID] = f.Code
+	}
+
+	var sheet struct {
+		Rows []struct {
+			R     int `xml:"r,attr"`
+			Cells []struct {
+				R      string `xml:"r,attr"`
+				T      string `xml:"t,attr"`
+				S      int    `xml:"s,attr"`
+				V      string `xml:"v"`
+				Inline string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+		Header string `xml:"headerFooter>oddHeader"`
+		Footer string `xml:"headerFooter>oddFooter"`
+	}
+	unmarshal(t, parts, "xl/worksheets/sheet1.xml", &sheet)
+
+	result := readBook{cells: make(map[string]readCell), header: sheet.Header, footer: sheet.Footer}
+	if len(wb.Sheets) == 1 {
+		result.sheet = wb.Sheets[0].Name
+	}
+	for i, row := range sheet.Rows {
+		if row.R != i+1 {
+			t.Errorf("row %d numbered %d", i+1, row.R)
+		}
+		for _, c := range row.Cells {
+			cell := readCell{typ: c.T, value: c.V}
+			if c.T == "inlineStr" {
+				cell.value = c.Inline
+			}
+			if c.S >= len(styles.CellXfs) {
+				t.Errorf("%s: style %d not defined", c.R, c.S)
+			} else if c.S > 0 {
+				cell.format = formats[styles.CellXfs[c.S].NumFmtID]
+			}
+			result.cells[c.R] = cell
+		}
+	}
+	return result
+}
+
+func unmarshal(t *testing.T, parts map[string][]byte, name string, v interface{}) {
+	t.Helper()
+	data, ok := parts[name]
+	if !ok {
+		t.Fatalf("no %s in workbook", name)
+	}
+	if err := xml.Unmarshal(data, v); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.FixedZone("EST", -5*3600))
+	header := make([]Cell, 28)
+	for i := range header {
+		header[i] = String("col" + strconv.Itoa(i))
+	}
+	sheet := Sheet{
+		Name: "Results: Q1/Q2",
+		Rows: [][]Cell{
+			header,
+			{
+				String("a < b & \"c\"\x01"),
+				{},
+				Int(-42),
+				Float(1.5),
+				{Type: Number, Value: "12345.67", Format: "0.00"},
+				Boolean(true),
+				Time(at, "yyyy-mm-dd hh:mm:ss"),
+				TimeOfDay(6*time.Hour, "hh:mm:ss"),
+				Time(time.Date(1899, 1, 1, 0, 0, 0, 0, time.UTC), "yyyy-mm-dd"),
+				Float(math.Inf(-1)),
+				String(`{"x":1,"y":[null]}`),
+			},
+			nil,
+			{String("CUI")},
+		},
+		HeaderFooter: "CUI & more",
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, sheet); err != nil {
+		t.Fatal(err)
+	}
+	wb := read(t, buf.Bytes())
+
+	if wb.sheet != "Results_ Q1_Q2" {
+		t.Errorf("sheet name %q", wb.sheet)
+	}
+	if wb.header != "&CCUI && more" || wb.footer != wb.header {
+		t.Errorf("header %q and footer %q", wb.header, wb.footer)
+	}
+
+	want := map[string]readCell{
+		"A2": {typ: "inlineStr", value: "a < b & \"c\""},
+		"C2": {value: "-42"},
+		"D2": {value: "1.5"},
+		"E2": {value: "12345.67", format: "0.00"},
+		"F2": {typ: "b", value: "1"},
+		// The wall clock time, 1 March 2024 is day 45352
+		"G2": {value: "45352.5", format: "yyyy-mm-dd hh:mm:ss"},
+		"H2": {value: "0.25", format: "hh:mm:ss"},
+		"I2": {typ: "inlineStr", value: "1899-01-01T00:00:00Z"},
+		"J2": {typ: "inlineStr", value: "-Inf"},
+		"K2": {typ: "inlineStr", value: `{"x":1,"y":[null]}`},
+		"A4": {typ: "inlineStr", value: "CUI"},
+	}
+	for ref, cell := range want {
+		if got := wb.cells[ref]; !reflect.DeepEqual(got, cell) {
+			t.Errorf("%s = %+v, want %+v", ref, got, cell)
+		}
+	}
+	// Empty cells are left out
+	if _, ok := wb.cells["B2"]; ok {
+		t.Errorf("empty cell B2 written")
+	}
+	if got := wb.cells["AB1"].value; got != "col27" {
+		t.Errorf("AB1 = %q, want col27", got)
+	}
+	if n := len(wb.cells); n != len(header)+len(want) {
+		t.Errorf("got %d cells, want %d", n, len(header)+len(want))
+	}
+}
@@ -21,14 +21,12 @@ func main() {
 		os.Exit(1)
 	}
 	
-	// Initialize security context
-	secCtx, err := security.NewSecurityContext(cfg)
-	if err != nil {
-		log.Errorf("Failed to initialize security context: %v", err)
-		os.Exit(1)
-	}
+	// Initialize security context; AWS credentials are looked up when a
+	// command needs them
+	secCtx := security.NewSecurityContext(cfg)
 	
-	// Execute root command
+	// Execute root command; commands that handle data products encrypt
+	// the log files first
 	if err := cmd.Execute(cfg, secCtx, log); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)